@@ -57,7 +57,7 @@ func exportCmd() *cobra.Command {
 		},
 	}
 
-	cmd.Flags().StringVarP(&exportFormat, "format", "", "yaml", "Format: json|yaml|hcl")
+	cmd.Flags().StringVarP(&exportFormat, "format", "", "yaml", "Format: json|yaml|hcl|toml")
 	cmd.Flags().StringVarP(&exportOutput, "output", "", "", "Output filename")
 
 	return cmd
@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"regexp"
 	"strconv"
+	"strings"
 
 	"github.com/go-gorp/gorp"
 	"github.com/gorilla/mux"
@@ -14,6 +15,7 @@ import (
 	"github.com/ovh/cds/engine/api/cache"
 	"github.com/ovh/cds/engine/api/environment"
 	"github.com/ovh/cds/engine/api/group"
+	"github.com/ovh/cds/engine/api/hook"
 	"github.com/ovh/cds/engine/api/permission"
 	"github.com/ovh/cds/engine/api/pipeline"
 	"github.com/ovh/cds/engine/api/poller"
@@ -37,9 +39,38 @@ func getApplicationsHandler(w http.ResponseWriter, r *http.Request, db *gorp.DbM
 		return err
 	}
 
+	if err := r.ParseForm(); err != nil {
+		return sdk.WrapError(sdk.ErrWrongRequest, "getApplicationsHandler> Cannot parse form: %s", err)
+	}
+	applications = filterApplicationsByMetadata(applications, r.Form["metadata"])
+
 	return WriteJSON(w, r, applications, http.StatusOK)
 }
 
+//filterApplicationsByMetadata returns the applications matching all the filters. A filter is key:value, or key
+//to match the applications having this metadata whatever its value.
+func filterApplicationsByMetadata(apps []sdk.Application, filters []string) []sdk.Application {
+	if len(filters) == 0 {
+		return apps
+	}
+	res := []sdk.Application{}
+	for _, app := range apps {
+		match := true
+		for _, f := range filters {
+			kv := strings.SplitN(f, ":", 2)
+			v, ok := app.Metadata[kv[0]]
+			if !ok || (len(kv) == 2 && v != kv[1]) {
+				match = false
+				break
+			}
+		}
+		if match {
+			res = append(res, app)
+		}
+	}
+	return res
+}
+
 func getApplicationTreeHandler(w http.ResponseWriter, r *http.Request, db *gorp.DbMap, c *businesscontext.Ctx) error {
 
 	vars := mux.Vars(r)
@@ -667,3 +698,69 @@ func updateApplicationHandler(w http.ResponseWriter, r *http.Request, db *gorp.D
 	return WriteJSON(w, r, app, http.StatusOK)
 
 }
+
+//enableApplicationHandler enables the disabled hooks and pollers of an application, such as the ones created
+//by an import with the disabled option
+func enableApplicationHandler(w http.ResponseWriter, r *http.Request, db *gorp.DbMap, c *businesscontext.Ctx) error {
+	vars := mux.Vars(r)
+	projectKey := vars["key"]
+	appName := vars["permApplicationName"]
+
+	app, errL := application.LoadByName(db, projectKey, appName, c.User, application.LoadOptions.WithHooks)
+	if errL != nil {
+		return sdk.WrapError(errL, "enableApplicationHandler> Cannot load application %s", appName)
+	}
+
+	pollers, errP := poller.LoadByApplication(db, app.ID)
+	if errP != nil {
+		return sdk.WrapError(errP, "enableApplicationHandler> Cannot load pollers of application %s", appName)
+	}
+
+	tx, errB := db.Begin()
+	if errB != nil {
+		return sdk.WrapError(errB, "enableApplicationHandler> Cannot start transaction")
+	}
+	defer tx.Rollback()
+
+	msgs := []sdk.Message{}
+	for _, h := range app.Hooks {
+		if h.Enabled {
+			continue
+		}
+		h.Enabled = true
+		if err := hook.UpdateHook(tx, h); err != nil {
+			return sdk.WrapError(err, "enableApplicationHandler> Cannot enable hook of pipeline %s", h.Pipeline.Name)
+		}
+		msgs = append(msgs, sdk.NewMessage(sdk.MsgAppResourceEnabled, "hook", h.Pipeline.Name, app.Name))
+	}
+	for i := range pollers {
+		p := &pollers[i]
+		if p.Enabled {
+			continue
+		}
+		p.Enabled = true
+		if err := poller.Update(tx, p); err != nil {
+			return sdk.WrapError(err, "enableApplicationHandler> Cannot enable poller of pipeline %s", p.Pipeline.Name)
+		}
+		msgs = append(msgs, sdk.NewMessage(sdk.MsgAppResourceEnabled, "poller", p.Pipeline.Name, app.Name))
+	}
+
+	if len(msgs) > 0 {
+		if err := application.UpdateLastModified(tx, app, c.User); err != nil {
+			return sdk.WrapError(err, "enableApplicationHandler> Cannot update application %s", appName)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return sdk.WrapError(err, "enableApplicationHandler> Cannot commit transaction")
+	}
+
+	cache.DeleteAll(cache.Key("application", projectKey, "*"))
+
+	al := r.Header.Get("Accept-Language")
+	msgListString := make([]string, 0, len(msgs))
+	for _, m := range msgs {
+		msgListString = append(msgListString, m.String(al))
+	}
+	return WriteJSON(w, r, msgListString, http.StatusOK)
+}
@@ -0,0 +1,234 @@
+package application
+
+import (
+	"database/sql"
+	"encoding/json"
+	"sort"
+
+	"github.com/go-gorp/gorp"
+
+	"github.com/ovh/cds/engine/api/permission"
+	"github.com/ovh/cds/engine/api/pipeline"
+	"github.com/ovh/cds/sdk"
+	"github.com/ovh/cds/sdk/exportentities"
+)
+
+//Export loads an application with everything which is exported: pipelines, triggers, hooks, pollers,
+//notifications, schedulers, variables and permissions. Secrets are masked. If the user can't write on the
+//application, the secret variables, the secret pipeline parameters and the secret scheduler arguments are
+//omitted, so that their names are not exported either. A nil user exports everything.
+func Export(db gorp.SqlExecutor, proj *sdk.Project, appName string, u *sdk.User, msgChan chan<- sdk.Message) (exportentities.Application, error) {
+	app, errL := LoadByName(db, proj.Key, appName, nil,
+		LoadOptions.WithVariables,
+		LoadOptions.WithPipelines,
+		LoadOptions.WithTriggers,
+		LoadOptions.WithGroups,
+		LoadOptions.WithHooks,
+		LoadOptions.WithNotifs,
+		LoadOptions.WithRepositoryManager,
+		LoadOptions.WithKeys)
+	if errL != nil {
+		return exportentities.Application{}, sdk.WrapError(errL, "application.Export> Unable to load application %s", appName)
+	}
+
+	// The pipelines are exported with their slug
+	slugs, errSl := pipeline.LoadPipelineSlugs(db, app.ProjectID)
+	if errSl != nil {
+		return exportentities.Application{}, sdk.WrapError(errSl, "application.Export> Unable to load pipelines of project %s", proj.Key)
+	}
+	pipSlugs := make(map[int64]string, len(slugs))
+	for slug, p := range slugs {
+		pipSlugs[p.ID] = slug
+	}
+	for i := range app.Pipelines {
+		ap := &app.Pipelines[i]
+		ap.Pipeline.Slug = pipSlugs[ap.Pipeline.ID]
+		for j := range ap.Triggers {
+			if t := &ap.Triggers[j]; t.DestProject.Key == "" || t.DestProject.Key == proj.Key {
+				t.DestPipeline.Slug = pipSlugs[t.DestPipeline.ID]
+			}
+		}
+	}
+
+	var errP error
+	app.RepositoryPollers, errP = loadExportPollers(db, app.ID)
+	if errP != nil {
+		return exportentities.Application{}, sdk.WrapError(errP, "application.Export> Unable to load pollers of application %s", appName)
+	}
+
+	var errS error
+	app.Schedulers, errS = loadExportSchedulers(db, app.ID)
+	if errS != nil {
+		return exportentities.Application{}, sdk.WrapError(errS, "application.Export> Unable to load schedulers of application %s", appName)
+	}
+
+	if u != nil && !permission.AccessToApplication(app.ID, u, permission.PermissionReadWriteExecute) {
+		if omitExportSecrets(app) && msgChan != nil {
+			msgChan <- sdk.NewMessage(sdk.MsgAppExportSecretsOmitted, app.Name)
+		}
+	}
+
+	maskExportSecrets(app)
+	sortExportApplication(app)
+	return *exportentities.NewApplication(app), nil
+}
+
+//sortExportApplication sorts the variables, pipelines, triggers, notifications, hooks, pollers, keys and
+//permissions of an application by name, so that an unchanged application is always exported the same way,
+//whatever the order they are loaded in. When several entries are exported with the same key, the last one wins.
+func sortExportApplication(app *sdk.Application) {
+	sort.SliceStable(app.Variable, func(i, j int) bool { return app.Variable[i].Name < app.Variable[j].Name })
+	sort.SliceStable(app.Pipelines, func(i, j int) bool { return app.Pipelines[i].Pipeline.Name < app.Pipelines[j].Pipeline.Name })
+	for i := range app.Pipelines {
+		ap := &app.Pipelines[i]
+		sort.SliceStable(ap.Parameters, func(x, y int) bool { return ap.Parameters[x].Name < ap.Parameters[y].Name })
+		sort.SliceStable(ap.Triggers, func(x, y int) bool { return exportTriggerKey(ap.Triggers[x]) < exportTriggerKey(ap.Triggers[y]) })
+		for j := range ap.Triggers {
+			prs := ap.Triggers[j].Prerequisites
+			sort.SliceStable(prs, func(x, y int) bool {
+				if prs[x].Parameter != prs[y].Parameter {
+					return prs[x].Parameter < prs[y].Parameter
+				}
+				return prs[x].ExpectedValue < prs[y].ExpectedValue
+			})
+		}
+	}
+	sort.SliceStable(app.Notifications, func(i, j int) bool {
+		ni, nj := app.Notifications[i], app.Notifications[j]
+		if ni.Pipeline.Name != nj.Pipeline.Name {
+			return ni.Pipeline.Name < nj.Pipeline.Name
+		}
+		return ni.Environment.Name < nj.Environment.Name
+	})
+	sort.SliceStable(app.Hooks, func(i, j int) bool {
+		if app.Hooks[i].Pipeline.Name != app.Hooks[j].Pipeline.Name {
+			return app.Hooks[i].Pipeline.Name < app.Hooks[j].Pipeline.Name
+		}
+		return app.Hooks[i].UID < app.Hooks[j].UID
+	})
+	sort.SliceStable(app.RepositoryPollers, func(i, j int) bool {
+		return app.RepositoryPollers[i].Pipeline.Name < app.RepositoryPollers[j].Pipeline.Name
+	})
+	sort.SliceStable(app.Keys, func(i, j int) bool { return app.Keys[i].Name < app.Keys[j].Name })
+	sort.SliceStable(app.ApplicationGroups, func(i, j int) bool {
+		return app.ApplicationGroups[i].Group.Name < app.ApplicationGroups[j].Group.Name
+	})
+}
+
+//exportTriggerKey identifies a trigger by its destination and its environments
+func exportTriggerKey(t sdk.PipelineTrigger) string {
+	return t.DestProject.Key + "/" + t.DestApplication.Name + "/" + t.DestPipeline.Name + "/" + t.SrcEnvironment.Name + "/" + t.DestEnvironment.Name
+}
+
+//maskExportSecrets replaces the value of the secrets of an application by the password placeholder
+func maskExportSecrets(app *sdk.Application) {
+	for i := range app.Variable {
+		if sdk.NeedPlaceholder(app.Variable[i].Type) {
+			app.Variable[i].Value = sdk.PasswordPlaceholder
+		}
+	}
+	for i := range app.Pipelines {
+		maskExportParameters(app.Pipelines[i].Parameters)
+	}
+	for i := range app.Schedulers {
+		maskExportParameters(app.Schedulers[i].Args)
+	}
+}
+
+//omitExportSecrets removes the secret variables, pipeline parameters and scheduler arguments of an
+//application. It returns true if any secret has been removed.
+func omitExportSecrets(app *sdk.Application) bool {
+	vars := make([]sdk.Variable, 0, len(app.Variable))
+	for _, v := range app.Variable {
+		if !sdk.NeedPlaceholder(v.Type) {
+			vars = append(vars, v)
+		}
+	}
+	omitted := len(vars) != len(app.Variable)
+	app.Variable = vars
+
+	for i := range app.Pipelines {
+		var o bool
+		app.Pipelines[i].Parameters, o = omitExportParameters(app.Pipelines[i].Parameters)
+		omitted = omitted || o
+	}
+	for i := range app.Schedulers {
+		var o bool
+		app.Schedulers[i].Args, o = omitExportParameters(app.Schedulers[i].Args)
+		omitted = omitted || o
+	}
+	return omitted
+}
+
+func omitExportParameters(params []sdk.Parameter) ([]sdk.Parameter, bool) {
+	kept := make([]sdk.Parameter, 0, len(params))
+	for _, p := range params {
+		if !sdk.NeedPlaceholder(p.Type) {
+			kept = append(kept, p)
+		}
+	}
+	return kept, len(kept) != len(params)
+}
+
+func maskExportParameters(params []sdk.Parameter) {
+	for i := range params {
+		if sdk.NeedPlaceholder(params[i].Type) {
+			params[i].Value = sdk.PasswordPlaceholder
+		}
+	}
+}
+
+//loadExportPollers loads the pollers of an application with the name of their pipeline.
+//The poller package depends on this package, so the pollers are loaded here.
+func loadExportPollers(db gorp.SqlExecutor, appID int64) ([]sdk.RepositoryPoller, error) {
+	query := `SELECT poller.enabled, pipeline.name
+		FROM poller
+		JOIN pipeline ON pipeline.id = poller.pipeline_id
+		WHERE poller.application_id = $1`
+	rows, err := db.Query(query, appID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	pollers := []sdk.RepositoryPoller{}
+	for rows.Next() {
+		var p sdk.RepositoryPoller
+		if err := rows.Scan(&p.Enabled, &p.Pipeline.Name); err != nil {
+			return nil, err
+		}
+		pollers = append(pollers, p)
+	}
+	return pollers, nil
+}
+
+//loadExportSchedulers loads the schedulers of an application with the name of their environment.
+//The scheduler package depends on this package, so the schedulers are loaded here.
+func loadExportSchedulers(db gorp.SqlExecutor, appID int64) ([]sdk.PipelineScheduler, error) {
+	query := `SELECT pipeline_scheduler.pipeline_id, pipeline_scheduler.crontab, pipeline_scheduler.timezone, pipeline_scheduler.args, environment.name
+		FROM pipeline_scheduler
+		JOIN environment ON environment.id = pipeline_scheduler.environment_id
+		WHERE pipeline_scheduler.application_id = $1`
+	rows, err := db.Query(query, appID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	schedulers := []sdk.PipelineScheduler{}
+	for rows.Next() {
+		var s sdk.PipelineScheduler
+		var args sql.NullString
+		if err := rows.Scan(&s.PipelineID, &s.Crontab, &s.Timezone, &args, &s.EnvironmentName); err != nil {
+			return nil, err
+		}
+		s.Args = []sdk.Parameter{}
+		if args.Valid && args.String != "" {
+			if err := json.Unmarshal([]byte(args.String), &s.Args); err != nil {
+				return nil, err
+			}
+		}
+		schedulers = append(schedulers, s)
+	}
+	return schedulers, nil
+}
@@ -0,0 +1,125 @@
+package application
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ovh/cds/sdk"
+	"github.com/ovh/cds/sdk/exportentities"
+)
+
+func Test_maskExportSecrets(t *testing.T) {
+	app := &sdk.Application{
+		Variable: []sdk.Variable{
+			{Name: "foo", Type: sdk.StringVariable, Value: "bar"},
+			{Name: "password", Type: sdk.SecretVariable, Value: "s3cr3t"},
+		},
+		Pipelines: []sdk.ApplicationPipeline{
+			{Parameters: []sdk.Parameter{{Name: "token", Type: sdk.SecretVariable, Value: "s3cr3t"}}},
+		},
+		Schedulers: []sdk.PipelineScheduler{
+			{Args: []sdk.Parameter{{Name: "key", Type: sdk.KeyVariable, Value: "s3cr3t"}, {Name: "version", Type: sdk.StringVariable, Value: "1.0"}}},
+		},
+	}
+
+	maskExportSecrets(app)
+	assert.Equal(t, "bar", app.Variable[0].Value)
+	assert.Equal(t, sdk.PasswordPlaceholder, app.Variable[1].Value)
+	assert.Equal(t, sdk.PasswordPlaceholder, app.Pipelines[0].Parameters[0].Value)
+	assert.Equal(t, sdk.PasswordPlaceholder, app.Schedulers[0].Args[0].Value)
+	assert.Equal(t, "1.0", app.Schedulers[0].Args[1].Value)
+}
+
+func Test_omitExportSecrets(t *testing.T) {
+	app := &sdk.Application{
+		Variable: []sdk.Variable{
+			{Name: "foo", Type: sdk.StringVariable, Value: "bar"},
+			{Name: "password", Type: sdk.SecretVariable, Value: "s3cr3t"},
+		},
+		Pipelines: []sdk.ApplicationPipeline{
+			{Parameters: []sdk.Parameter{{Name: "token", Type: sdk.SecretVariable, Value: "s3cr3t"}}},
+		},
+		Schedulers: []sdk.PipelineScheduler{
+			{Args: []sdk.Parameter{{Name: "key", Type: sdk.KeyVariable, Value: "s3cr3t"}, {Name: "version", Type: sdk.StringVariable, Value: "1.0"}}},
+		},
+	}
+
+	assert.True(t, omitExportSecrets(app))
+	assert.Equal(t, []sdk.Variable{{Name: "foo", Type: sdk.StringVariable, Value: "bar"}}, app.Variable)
+	assert.Empty(t, app.Pipelines[0].Parameters)
+	assert.Equal(t, []sdk.Parameter{{Name: "version", Type: sdk.StringVariable, Value: "1.0"}}, app.Schedulers[0].Args)
+
+	assert.False(t, omitExportSecrets(app))
+}
+
+func Test_sortExportApplication(t *testing.T) {
+	newApp := func(reversed bool) *sdk.Application {
+		app := &sdk.Application{
+			Name:       "app1",
+			ProjectKey: "KEY",
+			Variable: []sdk.Variable{
+				{Name: "region", Type: sdk.StringVariable, Value: "gra"},
+				{Name: "tier", Type: sdk.StringVariable, Value: "1"},
+			},
+			Pipelines: []sdk.ApplicationPipeline{
+				{
+					Pipeline:   sdk.Pipeline{ID: 1, Name: "build"},
+					Parameters: []sdk.Parameter{{Name: "version", Type: sdk.StringParameter, Value: "1.0"}, {Name: "arch", Type: sdk.StringParameter, Value: "amd64"}},
+					Triggers: []sdk.PipelineTrigger{
+						{
+							DestProject:     sdk.Project{Key: "KEY"},
+							DestApplication: sdk.Application{Name: "app1"},
+							DestPipeline:    sdk.Pipeline{Name: "deploy"},
+							SrcEnvironment:  sdk.DefaultEnv,
+							DestEnvironment: sdk.Environment{Name: "production"},
+							Prerequisites:   []sdk.Prerequisite{{Parameter: "git.branch", ExpectedValue: "master"}, {Parameter: "cds.status", ExpectedValue: "Success"}},
+						},
+					},
+				},
+				{Pipeline: sdk.Pipeline{ID: 2, Name: "deploy"}},
+			},
+			Hooks: []sdk.Hook{
+				{UID: "b", Pipeline: sdk.Pipeline{Name: "deploy"}, Enabled: true},
+				{UID: "a", Pipeline: sdk.Pipeline{Name: "build"}, Enabled: true},
+			},
+			ApplicationGroups: []sdk.GroupPermission{
+				{Group: sdk.Group{Name: "ops"}, Permission: 7},
+				{Group: sdk.Group{Name: "devs"}, Permission: 4},
+			},
+		}
+		if reversed {
+			reverse := func(n int, swap func(i, j int)) {
+				for i := 0; i < n/2; i++ {
+					swap(i, n-1-i)
+				}
+			}
+			reverse(len(app.Variable), func(i, j int) { app.Variable[i], app.Variable[j] = app.Variable[j], app.Variable[i] })
+			reverse(len(app.Pipelines), func(i, j int) { app.Pipelines[i], app.Pipelines[j] = app.Pipelines[j], app.Pipelines[i] })
+			reverse(len(app.Hooks), func(i, j int) { app.Hooks[i], app.Hooks[j] = app.Hooks[j], app.Hooks[i] })
+			reverse(len(app.ApplicationGroups), func(i, j int) {
+				app.ApplicationGroups[i], app.ApplicationGroups[j] = app.ApplicationGroups[j], app.ApplicationGroups[i]
+			})
+			for _, ap := range app.Pipelines {
+				reverse(len(ap.Parameters), func(i, j int) { ap.Parameters[i], ap.Parameters[j] = ap.Parameters[j], ap.Parameters[i] })
+				for _, tr := range ap.Triggers {
+					prs := tr.Prerequisites
+					reverse(len(prs), func(i, j int) { prs[i], prs[j] = prs[j], prs[i] })
+				}
+			}
+		}
+		return app
+	}
+
+	for _, f := range []exportentities.Format{exportentities.FormatJSON, exportentities.FormatYAML, exportentities.FormatHCL, exportentities.FormatTOML} {
+		var outputs [][]byte
+		for _, reversed := range []bool{false, true} {
+			app := newApp(reversed)
+			sortExportApplication(app)
+			out, err := exportentities.Marshal(exportentities.NewApplication(app), f)
+			assert.NoError(t, err)
+			outputs = append(outputs, out)
+		}
+		assert.Equal(t, string(outputs[0]), string(outputs[1]), "format %s", f)
+	}
+}
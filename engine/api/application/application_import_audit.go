@@ -0,0 +1,129 @@
+package application
+
+import (
+	"bytes"
+	"compress/gzip"
+	"database/sql"
+	"encoding/json"
+	"io/ioutil"
+	"time"
+
+	"github.com/go-gorp/gorp"
+
+	"github.com/ovh/cds/engine/api/secret"
+	"github.com/ovh/cds/sdk"
+)
+
+//InsertImportAudit records an import of an application. It is inserted in the transaction of the import,
+//so that only committed imports are recorded. The payload holds the values of the secret variables, it is
+//stored compressed and encrypted.
+func InsertImportAudit(db gorp.SqlExecutor, a *sdk.ImportAudit) error {
+	summary, err := json.Marshal(a.Summary)
+	if err != nil {
+		return sdk.WrapError(err, "InsertImportAudit> Unable to marshal summary")
+	}
+
+	var payload []byte
+	if a.Payload != nil {
+		buf := &bytes.Buffer{}
+		gz := gzip.NewWriter(buf)
+		if _, err := gz.Write(a.Payload); err != nil {
+			return sdk.WrapError(err, "InsertImportAudit> Unable to compress payload")
+		}
+		if err := gz.Close(); err != nil {
+			return sdk.WrapError(err, "InsertImportAudit> Unable to compress payload")
+		}
+		payload, err = secret.Encrypt(buf.Bytes())
+		if err != nil {
+			return sdk.WrapError(err, "InsertImportAudit> Unable to encrypt payload")
+		}
+	}
+
+	query := `INSERT INTO import_audit (project_key, application_name, author, format, force_update, payload_hash, summary, payload)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, creation_date`
+	if err := db.QueryRow(query, a.ProjectKey, a.ApplicationName, a.Author, a.Format, a.ForceUpdate, a.PayloadHash, string(summary), payload).Scan(&a.ID, &a.Created); err != nil {
+		return sdk.WrapError(err, "InsertImportAudit> Unable to insert audit of application %s", a.ApplicationName)
+	}
+	return nil
+}
+
+//LoadImportAudits loads the last imports of a project, most recent first. If appName is set, only the imports
+//of this application are loaded.
+func LoadImportAudits(db gorp.SqlExecutor, projectKey, appName string, limit int) ([]sdk.ImportAudit, error) {
+	query := `SELECT id, project_key, application_name, author, format, force_update, payload_hash, summary, creation_date
+		FROM import_audit
+		WHERE project_key = $1 AND ($2 = '' OR application_name = $2)
+		ORDER BY creation_date DESC, id DESC
+		LIMIT $3`
+	rows, err := db.Query(query, projectKey, appName, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	audits := []sdk.ImportAudit{}
+	for rows.Next() {
+		var a sdk.ImportAudit
+		var summary sql.NullString
+		if err := rows.Scan(&a.ID, &a.ProjectKey, &a.ApplicationName, &a.Author, &a.Format, &a.ForceUpdate, &a.PayloadHash, &summary, &a.Created); err != nil {
+			return nil, err
+		}
+		if summary.Valid {
+			if err := json.Unmarshal([]byte(summary.String), &a.Summary); err != nil {
+				return nil, sdk.WrapError(err, "LoadImportAudits> Unable to unmarshal summary of audit %d", a.ID)
+			}
+		}
+		audits = append(audits, a)
+	}
+	return audits, nil
+}
+
+//LoadImportAudit loads an import of a project with its decrypted and decompressed payload. The payload is nil once the
+//retention delay is over.
+func LoadImportAudit(db gorp.SqlExecutor, projectKey string, id int64) (*sdk.ImportAudit, error) {
+	query := `SELECT id, project_key, application_name, author, format, force_update, payload_hash, summary, creation_date, payload
+		FROM import_audit
+		WHERE project_key = $1 AND id = $2`
+	var a sdk.ImportAudit
+	var summary sql.NullString
+	var payload []byte
+	if err := db.QueryRow(query, projectKey, id).Scan(&a.ID, &a.ProjectKey, &a.ApplicationName, &a.Author, &a.Format, &a.ForceUpdate, &a.PayloadHash, &summary, &a.Created, &payload); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, sdk.ErrNotFound
+		}
+		return nil, sdk.WrapError(err, "LoadImportAudit> Unable to load audit %d", id)
+	}
+	if summary.Valid {
+		if err := json.Unmarshal([]byte(summary.String), &a.Summary); err != nil {
+			return nil, sdk.WrapError(err, "LoadImportAudit> Unable to unmarshal summary of audit %d", a.ID)
+		}
+	}
+	if payload != nil {
+		data, err := secret.Decrypt(payload)
+		if err != nil {
+			return nil, sdk.WrapError(err, "LoadImportAudit> Unable to decrypt payload of audit %d", a.ID)
+		}
+		gz, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, sdk.WrapError(err, "LoadImportAudit> Unable to decompress payload of audit %d", a.ID)
+		}
+		defer gz.Close()
+		a.Payload, err = ioutil.ReadAll(gz)
+		if err != nil {
+			return nil, sdk.WrapError(err, "LoadImportAudit> Unable to decompress payload of audit %d", a.ID)
+		}
+	}
+	return &a, nil
+}
+
+//DeleteImportAuditPayloads removes the payloads of the imports recorded before a date. The audits are kept,
+//they can't be replayed anymore.
+func DeleteImportAuditPayloads(db gorp.SqlExecutor, before time.Time) (int64, error) {
+	res, err := db.Exec(`UPDATE import_audit SET payload = NULL WHERE payload IS NOT NULL AND creation_date < $1`, before)
+	if err != nil {
+		return 0, sdk.WrapError(err, "DeleteImportAuditPayloads> Unable to delete payloads")
+	}
+	n, _ := res.RowsAffected()
+	return n, nil
+}
@@ -0,0 +1,101 @@
+package application
+
+import (
+	"database/sql"
+	"encoding/json"
+
+	"github.com/go-gorp/gorp"
+
+	"github.com/ovh/cds/sdk"
+)
+
+//InsertImportTemplate inserts an import template in a project
+func InsertImportTemplate(db gorp.SqlExecutor, t *sdk.ImportTemplate) error {
+	params, err := json.Marshal(t.Parameters)
+	if err != nil {
+		return sdk.WrapError(err, "InsertImportTemplate> Unable to marshal parameters")
+	}
+	query := `INSERT INTO import_template (project_key, name, format, content, parameters)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, creation_date`
+	if err := db.QueryRow(query, t.ProjectKey, t.Name, t.Format, t.Content, string(params)).Scan(&t.ID, &t.Created); err != nil {
+		return sdk.WrapError(err, "InsertImportTemplate> Unable to insert template %s", t.Name)
+	}
+	return nil
+}
+
+//UpdateImportTemplate updates the format, the content and the parameters of an import template
+func UpdateImportTemplate(db gorp.SqlExecutor, t *sdk.ImportTemplate) error {
+	params, err := json.Marshal(t.Parameters)
+	if err != nil {
+		return sdk.WrapError(err, "UpdateImportTemplate> Unable to marshal parameters")
+	}
+	query := `UPDATE import_template SET format = $3, content = $4, parameters = $5
+		WHERE project_key = $1 AND name = $2`
+	res, err := db.Exec(query, t.ProjectKey, t.Name, t.Format, t.Content, string(params))
+	if err != nil {
+		return sdk.WrapError(err, "UpdateImportTemplate> Unable to update template %s", t.Name)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sdk.ErrNotFound
+	}
+	return nil
+}
+
+//DeleteImportTemplate deletes an import template of a project
+func DeleteImportTemplate(db gorp.SqlExecutor, projectKey, name string) error {
+	res, err := db.Exec("DELETE FROM import_template WHERE project_key = $1 AND name = $2", projectKey, name)
+	if err != nil {
+		return sdk.WrapError(err, "DeleteImportTemplate> Unable to delete template %s", name)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sdk.ErrNotFound
+	}
+	return nil
+}
+
+//LoadImportTemplate loads an import template of a project by its name
+func LoadImportTemplate(db gorp.SqlExecutor, projectKey, name string) (*sdk.ImportTemplate, error) {
+	templates, err := loadImportTemplates(db, "project_key = $1 AND name = $2", projectKey, name)
+	if err != nil {
+		return nil, err
+	}
+	if len(templates) == 0 {
+		return nil, sdk.ErrNotFound
+	}
+	return &templates[0], nil
+}
+
+//LoadImportTemplates loads the import templates of a project, ordered by name
+func LoadImportTemplates(db gorp.SqlExecutor, projectKey string) ([]sdk.ImportTemplate, error) {
+	return loadImportTemplates(db, "project_key = $1", projectKey)
+}
+
+func loadImportTemplates(db gorp.SqlExecutor, where string, args ...interface{}) ([]sdk.ImportTemplate, error) {
+	query := `SELECT id, project_key, name, format, content, parameters, creation_date
+		FROM import_template
+		WHERE ` + where + `
+		ORDER BY name`
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	templates := []sdk.ImportTemplate{}
+	for rows.Next() {
+		var t sdk.ImportTemplate
+		var params sql.NullString
+		if err := rows.Scan(&t.ID, &t.ProjectKey, &t.Name, &t.Format, &t.Content, &params, &t.Created); err != nil {
+			return nil, err
+		}
+		t.Parameters = []sdk.ImportTemplateParameter{}
+		if params.Valid {
+			if err := json.Unmarshal([]byte(params.String), &t.Parameters); err != nil {
+				return nil, sdk.WrapError(err, "loadImportTemplates> Unable to unmarshal parameters of template %s", t.Name)
+			}
+		}
+		templates = append(templates, t)
+	}
+	return templates, nil
+}
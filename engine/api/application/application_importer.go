@@ -1,11 +1,20 @@
 package application
 
 import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
 	"github.com/go-gorp/gorp"
 
 	"github.com/ovh/cds/engine/api/environment"
 	"github.com/ovh/cds/engine/api/group"
 	"github.com/ovh/cds/engine/api/hook"
+	"github.com/ovh/cds/engine/api/notification"
+	"github.com/ovh/cds/engine/api/permission"
 	"github.com/ovh/cds/engine/api/pipeline"
 	"github.com/ovh/cds/engine/api/repositoriesmanager"
 	"github.com/ovh/cds/engine/api/trigger"
@@ -13,8 +22,18 @@ import (
 	"github.com/ovh/cds/sdk/log"
 )
 
-//Import is able to create a new application and all its components
-func Import(db gorp.SqlExecutor, proj *sdk.Project, app *sdk.Application, repomanager *sdk.RepositoriesManager, u *sdk.User, msgChan chan<- sdk.Message) error {
+var namePattern = regexp.MustCompile(sdk.NamePattern)
+
+var variableNamePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+//Import is able to create a new application and all its components. The hooks and notifications which
+//can't be created are returned as a *sdk.MultiError, once all the others are created. If skipHooks is set,
+//the application is attached to the repositories manager but no hook is created.
+func Import(db gorp.SqlExecutor, proj *sdk.Project, app *sdk.Application, repomanager *sdk.RepositoriesManager, skipHooks bool, u *sdk.User, msgChan chan<- sdk.Message) error {
+	if err := checkRetention(app, msgChan); err != nil {
+		return err
+	}
+
 	//Save application in database
 	if err := Insert(db, proj, app, u); err != nil {
 		return sdk.WrapError(err, "application.Import")
@@ -22,6 +41,9 @@ func Import(db gorp.SqlExecutor, proj *sdk.Project, app *sdk.Application, repoma
 
 	if msgChan != nil {
 		msgChan <- sdk.NewMessage(sdk.MsgAppCreated, app.Name)
+		if app.RetentionRuns > 0 || app.RetentionDays > 0 {
+			msgChan <- sdk.NewMessage(sdk.MsgAppImportRetentionSet, app.Name, app.RetentionRuns, app.RetentionDays)
+		}
 	}
 
 	//Inherit project groups if not provided
@@ -56,27 +78,509 @@ func Import(db gorp.SqlExecutor, proj *sdk.Project, app *sdk.Application, repoma
 		}
 	}
 
+	//Hooks and notifications which can't be created don't stop the import
+	errs := &sdk.MultiError{}
+
 	//Set repositories manager
 	app.RepositoriesManager = repomanager
 	if app.RepositoriesManager != nil && app.RepositoryFullname != "" && len(app.Pipelines) > 0 {
 		if err := repositoriesmanager.InsertForApplication(db, app, proj.Key); err != nil {
 			return err
 		}
-		//Manage hook
-		if _, err := hook.CreateHook(db, proj.Key, repomanager, app.RepositoryFullname, app, &app.Pipelines[0].Pipeline); err != nil {
-			return err
+		//Manage hook, if no hook is provided, set it on the first pipeline
+		if !skipHooks && len(app.Hooks) == 0 {
+			p := app.Pipelines[0].Pipeline
+			if err := ImportResource(db, app, "hook", p.Name, errs, msgChan, func() error {
+				if _, err := hook.CreateHooks(db, proj.Key, repomanager, app.RepositoryFullname, app, []sdk.Pipeline{p}, sdk.HookBranches{}); err != nil {
+					return err
+				}
+				if msgChan != nil {
+					msgChan <- sdk.NewMessage(sdk.MsgHookCreated, app.RepositoryFullname, p.Name)
+				}
+				return nil
+			}); err != nil {
+				return err
+			}
+		} else if !skipHooks {
+			if err := importHooks(db, proj, app, nil, errs, msgChan); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := importNotifications(db, app, errs, msgChan); err != nil {
+		return err
+	}
+
+	if !errs.IsEmpty() {
+		return errs
+	}
+	return nil
+}
+
+//ImportResource creates a resource of an imported application, such as a hook, in a savepoint. If the
+//resource can't be created, the savepoint is rolled back, the error is sent as a message and appended to errs,
+//so that the import goes on with the remaining resources. An error is only returned if the import can't go on.
+func ImportResource(db gorp.SqlExecutor, app *sdk.Application, kind, name string, errs *sdk.MultiError, msgChan chan<- sdk.Message, f func() error) error {
+	// Outside of a transaction, a failed statement doesn't abort the next ones
+	_, errS := db.Exec("SAVEPOINT import_resource")
+	savepoint := errS == nil
+
+	errF := f()
+	if errF == nil {
+		if savepoint {
+			if _, err := db.Exec("RELEASE SAVEPOINT import_resource"); err != nil {
+				return sdk.WrapError(err, "ImportResource> Unable to release %s %s", kind, name)
+			}
+		}
+		return nil
+	}
+
+	if savepoint {
+		if _, err := db.Exec("ROLLBACK TO SAVEPOINT import_resource"); err != nil {
+			return sdk.WrapError(err, "ImportResource> Unable to rollback %s %s", kind, name)
+		}
+	}
+	log.Warning("ImportResource> Unable to create %s %s of application %s: %s", kind, name, app.Name, errF)
+	errs.Append(errF)
+	if msgChan != nil {
+		msgChan <- sdk.NewMessage(sdk.MsgAppImportResourceFailed, kind, name, app.Name, errF.Error())
+	}
+	return nil
+}
+
+//ImportUpdate import and update the application in the project. Everything which is not
+//provided in the application is kept as is. As for Import, the hooks and notifications which can't be
+//created are returned as a *sdk.MultiError, and no hook is created if skipHooks is set.
+func ImportUpdate(db gorp.SqlExecutor, proj *sdk.Project, app *sdk.Application, skipHooks bool, msgChan chan<- sdk.Message, u *sdk.User) error {
+	t := time.Now()
+	log.Debug("application.ImportUpdate> Begin")
+	defer func() {
+		log.Debug("application.ImportUpdate> End (%d ns)", time.Since(t).Nanoseconds())
+	}()
+
+	oldApp, errL := LoadByName(db, proj.Key, app.Name, u,
+		LoadOptions.WithVariablesWithClearPassword,
+		LoadOptions.WithGroups,
+		LoadOptions.WithTriggers,
+		LoadOptions.WithHooks,
+		LoadOptions.WithNotifs,
+		LoadOptions.WithRepositoryManager)
+	if errL != nil {
+		return sdk.WrapError(errL, "ImportUpdate> Unable to load application %s %s", proj.Key, app.Name)
+	}
+
+	app.ID = oldApp.ID
+	app.ProjectID = oldApp.ProjectID
+	app.ProjectKey = oldApp.ProjectKey
+
+	if err := checkRetention(app, msgChan); err != nil {
+		return err
+	}
+
+	if err := importUpdateVariables(db, app, oldApp, u, msgChan); err != nil {
+		return err
+	}
+
+	if err := ImportPipelines(db, proj, app, u, msgChan); err != nil {
+		return err
+	}
+
+	//Update parameters of already attached pipelines
+	for _, ap := range app.Pipelines {
+		if len(ap.Parameters) == 0 {
+			continue
+		}
+		for _, oap := range oldApp.Pipelines {
+			if oap.Pipeline.Name != ap.Pipeline.Name || reflect.DeepEqual(oap.Parameters, ap.Parameters) {
+				continue
+			}
+			if err := UpdatePipelineApplication(db, app, ap.Pipeline.ID, ap.Parameters, u); err != nil {
+				return sdk.WrapError(err, "ImportUpdate> Unable to update parameters of pipeline %s in %s", ap.Pipeline.Name, app.Name)
+			}
+			if msgChan != nil {
+				msgChan <- sdk.NewMessage(sdk.MsgAppPipelineParametersUpdated, ap.Pipeline.Name, app.Name)
+				sendParameterDefaults(app, ap.Pipeline.Name, oap.Parameters, ap.Parameters, msgChan)
+			}
+		}
+	}
+
+	if err := importUpdateGroups(db, proj, app, oldApp, u, msgChan); err != nil {
+		return err
+	}
+
+	if err := importUpdateMetadata(db, app, oldApp, msgChan); err != nil {
+		return err
+	}
+
+	//An application without description keeps its description
+	if app.Description == "" {
+		app.Description = oldApp.Description
+	} else if app.Description != oldApp.Description {
+		if err := UpdateDescription(db, app); err != nil {
+			return sdk.WrapError(err, "ImportUpdate> Unable to update description of application %s", app.Name)
+		}
+		if msgChan != nil {
+			msgChan <- sdk.NewMessage(sdk.MsgAppDescriptionUpdated, app.Name)
+		}
+	}
+
+	//An application imported without generation keeps its generation
+	if app.Generation == 0 {
+		app.Generation = oldApp.Generation
+	} else if app.Generation != oldApp.Generation {
+		if err := UpdateGeneration(db, app); err != nil {
+			return sdk.WrapError(err, "ImportUpdate> Unable to update generation of application %s", app.Name)
+		}
+	}
+
+	//A retention which is not set keeps its value
+	if app.RetentionRuns == 0 {
+		app.RetentionRuns = oldApp.RetentionRuns
+	}
+	if app.RetentionDays == 0 {
+		app.RetentionDays = oldApp.RetentionDays
+	}
+	if app.RetentionRuns != oldApp.RetentionRuns || app.RetentionDays != oldApp.RetentionDays {
+		if err := UpdateRetention(db, app); err != nil {
+			return sdk.WrapError(err, "ImportUpdate> Unable to update retention of application %s", app.Name)
 		}
 		if msgChan != nil {
-			msgChan <- sdk.NewMessage(sdk.MsgHookCreated, app.RepositoryFullname, app.Pipelines[0].Pipeline.Name)
+			msgChan <- sdk.NewMessage(sdk.MsgAppImportRetentionSet, app.Name, app.RetentionRuns, app.RetentionDays)
 		}
 	}
 
+	//Hooks and notifications which can't be created don't stop the import
+	errs := &sdk.MultiError{}
+
+	//Set repositories manager
+	if app.RepositoriesManager != nil && app.RepositoryFullname != "" {
+		if oldApp.RepositoriesManager == nil || oldApp.RepositoriesManager.ID != app.RepositoriesManager.ID || oldApp.RepositoryFullname != app.RepositoryFullname {
+			if err := repositoriesmanager.InsertForApplication(db, app, proj.Key); err != nil {
+				return sdk.WrapError(err, "ImportUpdate> Unable to attach %s to repositories manager %s", app.Name, app.RepositoriesManager.Name)
+			}
+		}
+		if !skipHooks {
+			if err := importHooks(db, proj, app, oldApp.Hooks, errs, msgChan); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := importNotifications(db, app, errs, msgChan); err != nil {
+		return err
+	}
+
+	if err := UpdateLastModified(db, app, u); err != nil {
+		return sdk.WrapError(err, "ImportUpdate> Unable to update application %s", app.Name)
+	}
+
+	if msgChan != nil {
+		msgChan <- sdk.NewMessage(sdk.MsgAppUpdated, app.Name)
+	}
+
+	if !errs.IsEmpty() {
+		return errs
+	}
+	return nil
+}
+
+//importUpdateVariables creates or updates the variables of an existing application
+func importUpdateVariables(db gorp.SqlExecutor, app, oldApp *sdk.Application, u *sdk.User, msgChan chan<- sdk.Message) error {
+	for i := range app.Variable {
+		newVar := &app.Variable[i]
+		var oldVar *sdk.Variable
+		for j := range oldApp.Variable {
+			if oldApp.Variable[j].Name == newVar.Name {
+				oldVar = &oldApp.Variable[j]
+				break
+			}
+		}
+
+		// An exported secret is masked, its stored value can't be overridden by the placeholder
+		masked := newVar.Type == sdk.SecretVariable && newVar.Value == sdk.PasswordPlaceholder
+
+		if oldVar == nil {
+			if masked {
+				if msgChan != nil {
+					msgChan <- sdk.NewMessage(sdk.MsgAppImportSecretSkipped, newVar.Name, app.Name)
+				}
+				continue
+			}
+			var errCreate error
+			switch newVar.Type {
+			case sdk.KeyVariable:
+				errCreate = AddKeyPairToApplication(db, app, newVar.Name, u)
+			default:
+				errCreate = InsertVariable(db, app, *newVar, u)
+			}
+			if errCreate != nil {
+				return sdk.WrapError(errCreate, "importUpdateVariables> Cannot add variable %s in application %s", newVar.Name, app.Name)
+			}
+			if msgChan != nil {
+				msgChan <- sdk.NewMessage(sdk.MsgAppVariableCreated, newVar.Name, app.Name)
+			}
+			continue
+		}
+
+		//Keys can't be updated from an import
+		newVar.ID = oldVar.ID
+		//A variable without description keeps its description
+		if newVar.Description == "" {
+			newVar.Description = oldVar.Description
+		}
+		if newVar.Type == sdk.KeyVariable {
+			continue
+		}
+		if masked && newVar.Type == oldVar.Type && newVar.Description == oldVar.Description {
+			if msgChan != nil {
+				msgChan <- sdk.NewMessage(sdk.MsgAppImportSecretPreserved, newVar.Name, app.Name)
+			}
+			continue
+		}
+		if newVar.Type == oldVar.Type && newVar.Value == oldVar.Value && newVar.Description == oldVar.Description {
+			continue
+		}
+
+		//UpdateVariable keeps the stored value of a masked secret
+		if err := UpdateVariable(db, app, newVar, u); err != nil {
+			return sdk.WrapError(err, "importUpdateVariables> Cannot update variable %s in application %s", newVar.Name, app.Name)
+		}
+		if msgChan != nil {
+			if masked {
+				msgChan <- sdk.NewMessage(sdk.MsgAppImportSecretPreserved, newVar.Name, app.Name)
+			} else {
+				msgChan <- sdk.NewMessage(sdk.MsgAppVariableUpdated, newVar.Name, app.Name)
+			}
+		}
+	}
+	return nil
+}
+
+//importUpdateGroups adds or updates the group permissions of an existing application
+func importUpdateGroups(db gorp.SqlExecutor, proj *sdk.Project, app, oldApp *sdk.Application, u *sdk.User, msgChan chan<- sdk.Message) error {
+	for _, gp := range app.ApplicationGroups {
+		var gpFound bool
+		for _, ogp := range oldApp.ApplicationGroups {
+			if gp.Group.Name != ogp.Group.Name {
+				continue
+			}
+			gpFound = true
+			if gp.Permission != ogp.Permission {
+				if err := group.UpdateGroupRoleInApplication(db, proj.Key, app.Name, gp.Group.Name, gp.Permission); err != nil {
+					return sdk.WrapError(err, "importUpdateGroups> Unable to update group %s in %s", gp.Group.Name, app.Name)
+				}
+				if msgChan != nil {
+					msgChan <- sdk.NewMessage(sdk.MsgAppGroupUpdated, gp.Group.Name, app.Name)
+				}
+			}
+			break
+		}
+		if !gpFound {
+			if err := AddGroup(db, proj, app, u, gp); err != nil {
+				return sdk.WrapError(err, "importUpdateGroups> Unable to add group %s in %s", gp.Group.Name, app.Name)
+			}
+			if msgChan != nil {
+				msgChan <- sdk.NewMessage(sdk.MsgAppGroupSetPermission, gp.Group.Name, app.Name)
+			}
+		}
+	}
+	return nil
+}
+
+//ImportPermissions only applies the group permissions of an imported application on the existing one, the
+//other resources of the application are not imported. If prune is set, the permissions of the groups which
+//are not imported are revoked. A group with the read, write and execute permission must remain.
+func ImportPermissions(db gorp.SqlExecutor, proj *sdk.Project, app *sdk.Application, prune bool, u *sdk.User, msgChan chan<- sdk.Message) error {
+	if err := CheckImportPermissions(app, msgChan); err != nil {
+		return err
+	}
+
+	oldApp, errL := LoadByName(db, proj.Key, app.Name, u, LoadOptions.WithGroups)
+	if errL != nil {
+		return sdk.WrapError(errL, "ImportPermissions> Unable to load application %s %s", proj.Key, app.Name)
+	}
+	app.ID = oldApp.ID
+	app.ProjectID = oldApp.ProjectID
+	app.ProjectKey = oldApp.ProjectKey
+
+	imported := make(map[string]bool, len(app.ApplicationGroups))
+	final := make(map[string]int, len(oldApp.ApplicationGroups)+len(app.ApplicationGroups))
+	for _, gp := range app.ApplicationGroups {
+		imported[gp.Group.Name] = true
+		final[gp.Group.Name] = gp.Permission
+	}
+	revoked := []string{}
+	for _, ogp := range oldApp.ApplicationGroups {
+		if imported[ogp.Group.Name] {
+			continue
+		}
+		if prune {
+			revoked = append(revoked, ogp.Group.Name)
+		} else {
+			final[ogp.Group.Name] = ogp.Permission
+		}
+	}
+
+	var hasWrite bool
+	for _, p := range final {
+		if p == permission.PermissionReadWriteExecute {
+			hasWrite = true
+			break
+		}
+	}
+	if !hasWrite {
+		return sdk.WrapError(sdk.ErrGroupNeedWrite, "ImportPermissions> Application %s needs a group with write permission", app.Name)
+	}
+
+	if err := importUpdateGroups(db, proj, app, oldApp, u, msgChan); err != nil {
+		return err
+	}
+	if msgChan != nil {
+		for _, gp := range app.ApplicationGroups {
+			for _, ogp := range oldApp.ApplicationGroups {
+				if gp.Group.Name == ogp.Group.Name && gp.Permission == ogp.Permission {
+					msgChan <- sdk.NewMessage(sdk.MsgAppImportGroupUnchanged, gp.Group.Name, app.Name)
+				}
+			}
+		}
+	}
+
+	sort.Strings(revoked)
+	for _, name := range revoked {
+		if err := group.DeleteGroupFromApplication(db, proj.Key, app.Name, name); err != nil {
+			return sdk.WrapError(err, "ImportPermissions> Unable to revoke group %s on %s", name, app.Name)
+		}
+		if msgChan != nil {
+			msgChan <- sdk.NewMessage(sdk.MsgAppImportGroupRevoked, name, app.Name)
+		}
+	}
+
+	return UpdateLastModified(db, app, u)
+}
+
+//importUpdateMetadata merges the imported metadata into the metadata of an existing application.
+//Metadata which are not imported are kept.
+func importUpdateMetadata(db gorp.SqlExecutor, app, oldApp *sdk.Application, msgChan chan<- sdk.Message) error {
+	metadata := make(sdk.Metadata, len(oldApp.Metadata)+len(app.Metadata))
+	for k, v := range oldApp.Metadata {
+		metadata[k] = v
+	}
+
+	keys := make([]string, 0, len(app.Metadata))
+	for k := range app.Metadata {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var updated bool
+	for _, k := range keys {
+		if v, ok := metadata[k]; ok && v == app.Metadata[k] {
+			continue
+		}
+		metadata[k] = app.Metadata[k]
+		updated = true
+		if msgChan != nil {
+			msgChan <- sdk.NewMessage(sdk.MsgAppMetadataUpdated, k, app.Name)
+		}
+	}
+	app.Metadata = metadata
+
+	if !updated {
+		return nil
+	}
+	if err := UpdateMetadata(db, app); err != nil {
+		return sdk.WrapError(err, "importUpdateMetadata> Unable to update metadata of application %s", app.Name)
+	}
+	return nil
+}
+
+//importHooks creates the hooks of the application which are not already in existingHooks, and updates the
+//branch filters of the existing ones. Hooks are created on the repositories manager once the import is committed.
+func importHooks(db gorp.SqlExecutor, proj *sdk.Project, app *sdk.Application, existingHooks []sdk.Hook, errs *sdk.MultiError, msgChan chan<- sdk.Message) error {
+	for i := range app.Hooks {
+		h := &app.Hooks[i]
+		var existing *sdk.Hook
+		for j := range existingHooks {
+			if existingHooks[j].Pipeline.ID == h.Pipeline.ID {
+				existing = &existingHooks[j]
+				break
+			}
+		}
+		if existing != nil {
+			if msgChan != nil {
+				msgChan <- sdk.NewMessage(sdk.MsgHookExists, app.RepositoryFullname, h.Pipeline.Name)
+			}
+			if reflect.DeepEqual(existing.Branches, h.Branches) {
+				continue
+			}
+			existing.Branches = h.Branches
+			if err := hook.UpdateHook(db, *existing); err != nil {
+				return sdk.WrapError(err, "importHooks> Unable to update branches of hook of pipeline %s", h.Pipeline.Name)
+			}
+			if msgChan != nil {
+				msgChan <- sdk.NewMessage(sdk.MsgHookBranchesUpdated, h.Pipeline.Name, app.Name)
+			}
+			continue
+		}
+
+		p := h.Pipeline
+		if err := ImportResource(db, app, "hook", p.Name, errs, msgChan, func() error {
+			hooks, err := hook.CreateHooks(db, proj.Key, app.RepositoriesManager, app.RepositoryFullname, app, []sdk.Pipeline{p}, h.Branches)
+			if err != nil {
+				return sdk.WrapError(err, "importHooks> Unable to create hook on %s", app.RepositoryFullname)
+			}
+			//The hook is created on the repositories manager, but it doesn't trigger the pipeline
+			if !h.Enabled {
+				for _, ch := range hooks {
+					ch.Enabled = false
+					if err := hook.UpdateHook(db, ch); err != nil {
+						return sdk.WrapError(err, "importHooks> Unable to disable hook of pipeline %s", p.Name)
+					}
+				}
+			}
+			if msgChan != nil {
+				msgChan <- sdk.NewMessage(sdk.MsgHookCreated, app.RepositoryFullname, p.Name)
+				if !h.Enabled {
+					msgChan <- sdk.NewMessage(sdk.MsgAppImportCreatedDisabled, "hook", p.Name, app.Name)
+				}
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//importNotifications inserts or updates the notifications of the application
+func importNotifications(db gorp.SqlExecutor, app *sdk.Application, errs *sdk.MultiError, msgChan chan<- sdk.Message) error {
+	for i := range app.Notifications {
+		n := &app.Notifications[i]
+		if err := ImportResource(db, app, "notification", n.Pipeline.Name, errs, msgChan, func() error {
+			if err := notification.InsertOrUpdateUserNotificationSettings(db, app.ID, n.Pipeline.ID, n.Environment.ID, n); err != nil {
+				return sdk.WrapError(err, "importNotifications> Unable to set notifications of pipeline %s on %s", n.Pipeline.Name, app.Name)
+			}
+			if msgChan != nil {
+				msgChan <- sdk.NewMessage(sdk.MsgAppNotificationUpdated, n.Pipeline.Name, app.Name, n.Environment.Name)
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
 //importVariables is able to create variable on an existing application
 func importVariables(db gorp.SqlExecutor, proj *sdk.Project, app *sdk.Application, u *sdk.User, msgChan chan<- sdk.Message) error {
 	for _, newVar := range app.Variable {
+		if newVar.Type == sdk.SecretVariable && newVar.Value == sdk.PasswordPlaceholder {
+			if msgChan != nil {
+				msgChan <- sdk.NewMessage(sdk.MsgAppImportSecretSkipped, newVar.Name, app.Name)
+			}
+			continue
+		}
 		var errCreate error
 		switch newVar.Type {
 		case sdk.KeyVariable:
@@ -99,6 +603,49 @@ func importVariables(db gorp.SqlExecutor, proj *sdk.Project, app *sdk.Applicatio
 	return nil
 }
 
+//setImportedPipelines sets the pipelines created by the import on the hooks, pollers, notifications and
+//schedulers of the application, which only know their name
+func setImportedPipelines(app *sdk.Application) {
+	pipelines := make(map[string]sdk.Pipeline, len(app.Pipelines))
+	for _, ap := range app.Pipelines {
+		pipelines[ap.Pipeline.Name] = ap.Pipeline
+	}
+	for i := range app.Hooks {
+		if h := &app.Hooks[i]; h.Pipeline.ID == 0 {
+			h.Pipeline = pipelines[h.Pipeline.Name]
+		}
+	}
+	for i := range app.RepositoryPollers {
+		if p := &app.RepositoryPollers[i]; p.Pipeline.ID == 0 {
+			p.Pipeline = pipelines[p.Pipeline.Name]
+		}
+	}
+	for i := range app.Notifications {
+		if n := &app.Notifications[i]; n.Pipeline.ID == 0 {
+			n.Pipeline = pipelines[n.Pipeline.Name]
+		}
+	}
+	for i := range app.Schedulers {
+		if s := &app.Schedulers[i]; s.PipelineID == 0 {
+			s.PipelineID = pipelines[s.PipelineName].ID
+		}
+	}
+}
+
+//sendParameterDefaults tells which default values of the parameters of a pipeline are new or changed
+func sendParameterDefaults(app *sdk.Application, pipName string, oldParams, params []sdk.Parameter, msgChan chan<- sdk.Message) {
+	old := make(map[string]sdk.Parameter, len(oldParams))
+	for _, p := range oldParams {
+		old[p.Name] = p
+	}
+	for _, p := range params {
+		if op, ok := old[p.Name]; ok && op.Type == p.Type && op.Value == p.Value {
+			continue
+		}
+		msgChan <- sdk.NewMessage(sdk.MsgAppImportParamDefaultSet, p.Name, pipName, app.Name)
+	}
+}
+
 //ImportPipelines is able to create pipelines on an existing application
 func ImportPipelines(db gorp.SqlExecutor, proj *sdk.Project, app *sdk.Application, u *sdk.User, msgChan chan<- sdk.Message) error {
 	//Import pipelines
@@ -124,8 +671,19 @@ func ImportPipelines(db gorp.SqlExecutor, proj *sdk.Project, app *sdk.Applicatio
 			if msgChan != nil {
 				msgChan <- sdk.NewMessage(sdk.MsgPipelineAttached, app.Pipelines[i].Pipeline.Name, app.Name)
 			}
+
+			//The default values of the parameters are set on the new attachment
+			if params := app.Pipelines[i].Parameters; len(params) > 0 {
+				if err := UpdatePipelineApplication(db, app, app.Pipelines[i].Pipeline.ID, params, u); err != nil {
+					return sdk.WrapError(err, "ImportPipelines> Unable to set parameters of pipeline %s in %s", app.Pipelines[i].Pipeline.Name, app.Name)
+				}
+				if msgChan != nil {
+					sendParameterDefaults(app, app.Pipelines[i].Pipeline.Name, nil, params, msgChan)
+				}
+			}
 		}
 	}
+	setImportedPipelines(app)
 
 	//Insert triggers
 	for i := range app.Pipelines {
@@ -185,7 +743,7 @@ func ImportPipelines(db gorp.SqlExecutor, proj *sdk.Project, app *sdk.Applicatio
 			}
 
 			//Load or import source environmment
-			if t.SrcEnvironment.Name == "" {
+			if t.SrcEnvironment.Name == "" || t.SrcEnvironment.Name == sdk.DefaultEnv.Name {
 				t.SrcEnvironment = sdk.DefaultEnv
 			} else {
 				if err := environment.Import(db, proj, &t.SrcEnvironment, msgChan, u); err != nil {
@@ -194,7 +752,7 @@ func ImportPipelines(db gorp.SqlExecutor, proj *sdk.Project, app *sdk.Applicatio
 			}
 
 			//Load or import destination environment
-			if t.DestEnvironment.Name == "" {
+			if t.DestEnvironment.Name == "" || t.DestEnvironment.Name == sdk.DefaultEnv.Name {
 				t.DestEnvironment = sdk.DefaultEnv
 			} else {
 				if err := environment.Import(db, proj, &t.DestEnvironment, msgChan, u); err != nil {
@@ -222,9 +780,555 @@ func ImportPipelines(db gorp.SqlExecutor, proj *sdk.Project, app *sdk.Applicatio
 				if msgChan != nil {
 					msgChan <- sdk.NewMessage(sdk.MsgPipelineTriggerCreated, t.SrcPipeline.Name, t.SrcApplication.Name, t.DestPipeline.Name, t.DestApplication.Name)
 				}
+			} else if err := importUpdatePrerequisites(db, app, t, msgChan); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+//importUpdatePrerequisites replaces the prerequisites of a stored trigger by the imported ones. A trigger
+//imported without prerequisites keeps its prerequisites.
+func importUpdatePrerequisites(db gorp.SqlExecutor, app *sdk.Application, t *sdk.PipelineTrigger, msgChan chan<- sdk.Message) error {
+	if len(t.Prerequisites) == 0 {
+		return nil
+	}
+	triggers, err := trigger.LoadTriggersAsSource(db, t.SrcApplication.ID, t.SrcPipeline.ID, t.SrcEnvironment.ID)
+	if err != nil {
+		return sdk.WrapError(err, "importUpdatePrerequisites> Unable to load triggers of pipeline %s", t.SrcPipeline.Name)
+	}
+	for i := range triggers {
+		stored := &triggers[i]
+		if stored.SrcEnvironment.ID != t.SrcEnvironment.ID || stored.DestApplication.ID != t.DestApplication.ID ||
+			stored.DestPipeline.ID != t.DestPipeline.ID || stored.DestEnvironment.ID != t.DestEnvironment.ID {
+			continue
+		}
+		if reflect.DeepEqual(stored.Prerequisites, t.Prerequisites) {
+			return nil
+		}
+		stored.Prerequisites = t.Prerequisites
+		if err := trigger.UpdateTrigger(db, stored); err != nil {
+			return sdk.WrapError(err, "importUpdatePrerequisites> Unable to update trigger %d", stored.ID)
+		}
+		if msgChan != nil {
+			msgChan <- sdk.NewMessage(sdk.MsgAppImportPrerequisitesUpdated, t.SrcPipeline.Name, t.DestPipeline.Name, app.Name)
+		}
+		return nil
+	}
+	return nil
+}
+
+//CheckImportFields checks the required fields and the names of an imported application.
+//A message is sent for each invalid field.
+func CheckImportFields(app *sdk.Application, msgChan chan<- sdk.Message) error {
+	var err error
+	invalid := func(field string, errF error) {
+		if msgChan != nil {
+			msgChan <- sdk.NewMessage(sdk.MsgAppImportFieldInvalid, field, app.Name)
+		}
+		if err == nil {
+			err = errF
+		}
+	}
+
+	if !namePattern.MatchString(app.Name) {
+		invalid("name", sdk.ErrInvalidApplicationPattern)
+	}
+	for _, v := range app.Variable {
+		if strings.TrimSpace(v.Name) == "" {
+			invalid("variables", sdk.ErrWrongRequest)
+		}
+	}
+	for _, k := range app.Keys {
+		if !namePattern.MatchString(k.Name) {
+			invalid("keys."+k.Name, sdk.ErrInvalidKeyPattern)
+		}
+	}
+	for _, ap := range app.Pipelines {
+		if strings.TrimSpace(ap.Pipeline.Name) == "" {
+			invalid("pipelines", sdk.ErrWrongRequest)
+			continue
+		}
+		for _, t := range ap.Triggers {
+			if strings.TrimSpace(t.DestPipeline.Name) == "" {
+				invalid("pipelines."+ap.Pipeline.Name+".triggers", sdk.ErrWrongRequest)
+			}
+		}
+	}
+	return err
+}
+
+//CheckImportVariables checks the names of the variables of an imported application, of the parameters of its
+//pipelines and of the arguments of its schedulers. A name must match [A-Za-z0-9_.-]+ and be unique in its scope.
+//A message is sent for each invalid or duplicated name.
+func CheckImportVariables(app *sdk.Application, msgChan chan<- sdk.Message) error {
+	var err error
+	check := func(scope string, names []string) {
+		counts := map[string]int{}
+		for _, name := range names {
+			if counts[name]++; counts[name] > 1 {
+				continue
+			}
+			// The empty names are reported with the required fields
+			if name != "" && !variableNamePattern.MatchString(name) {
+				if msgChan != nil {
+					msgChan <- sdk.NewMessage(sdk.MsgAppImportVariableInvalid, name, scope, app.Name)
+				}
+				err = sdk.ErrWrongRequest
+			}
+		}
+		for _, name := range names {
+			if n := counts[name]; n > 1 {
+				if msgChan != nil {
+					msgChan <- sdk.NewMessage(sdk.MsgAppImportVariableDuplicate, name, n, scope, app.Name)
+				}
+				counts[name] = 0
+				err = sdk.ErrWrongRequest
+			}
+		}
+	}
+
+	names := make([]string, 0, len(app.Variable))
+	for _, v := range app.Variable {
+		names = append(names, v.Name)
+	}
+	check("variables", names)
+
+	for _, ap := range app.Pipelines {
+		names := make([]string, 0, len(ap.Parameters))
+		for _, p := range ap.Parameters {
+			names = append(names, p.Name)
+		}
+		check("pipelines."+ap.Pipeline.Name+".parameters", names)
+	}
+
+	for _, s := range app.Schedulers {
+		names := make([]string, 0, len(s.Args))
+		for _, a := range s.Args {
+			names = append(names, a.Name)
+		}
+		check("schedulers."+s.PipelineName+"["+importCrontab(s)+"]", names)
+	}
+	return err
+}
+
+//CheckImportSchedulers checks the cron expressions and the timezones of the schedulers of an imported application.
+//A message is sent for each invalid scheduler.
+func CheckImportSchedulers(app *sdk.Application, msgChan chan<- sdk.Message) error {
+	var err error
+	for _, s := range app.Schedulers {
+		crontab := importCrontab(s)
+		if _, _, errC := sdk.ParseCrontab(crontab); errC != nil {
+			if msgChan != nil {
+				msgChan <- sdk.NewMessage(sdk.MsgSchedulerInvalidCron, crontab, s.PipelineName, app.Name, errC.Error())
+			}
+			if err == nil {
+				err = sdk.ErrWrongRequest
+			}
+		}
+	}
+	return err
+}
+
+//CheckImportHooks checks the branch filters of the hooks of an imported application, which are regular
+//expressions. A message is sent for each invalid pattern.
+func CheckImportHooks(app *sdk.Application, msgChan chan<- sdk.Message) error {
+	var err error
+	for _, h := range app.Hooks {
+		for _, p := range append(append([]string{}, h.Branches.Include...), h.Branches.Exclude...) {
+			if _, errR := sdk.CompileHookBranchPattern(p); errR != nil {
+				if msgChan != nil {
+					msgChan <- sdk.NewMessage(sdk.MsgAppImportHookBranchInvalid, p, h.Pipeline.Name, app.Name, errR.Error())
+				}
+				err = sdk.ErrWrongRequest
+			}
+		}
+	}
+	return err
+}
+
+//CheckImportPermissions checks the permission levels of the groups of an imported application, which must be
+//read, read and execute, or read, write and execute. A message is sent for each invalid level.
+func CheckImportPermissions(app *sdk.Application, msgChan chan<- sdk.Message) error {
+	var err error
+	for _, gp := range app.ApplicationGroups {
+		switch gp.Permission {
+		case permission.PermissionRead, permission.PermissionReadExecute, permission.PermissionReadWriteExecute:
+		default:
+			if msgChan != nil {
+				msgChan <- sdk.NewMessage(sdk.MsgAppImportInvalidPermissionLevel, gp.Permission, gp.Group.Name, app.Name)
+			}
+			err = sdk.ErrWrongRequest
+		}
+	}
+	return err
+}
+
+//CheckImportSchedulerArgs checks that the arguments of the schedulers of an imported application are parameters of
+//their pipeline. The parameters of a pipeline imported with its definition are taken from the definition.
+//A message is sent for each unknown argument.
+func CheckImportSchedulerArgs(db gorp.SqlExecutor, proj *sdk.Project, app *sdk.Application, msgChan chan<- sdk.Message) error {
+	params := map[string]map[string]bool{}
+	for _, ap := range app.Pipelines {
+		if ap.Pipeline.Type == "" {
+			continue
+		}
+		params[ap.Pipeline.Name] = map[string]bool{}
+		for _, p := range ap.Pipeline.Parameter {
+			params[ap.Pipeline.Name][p.Name] = true
+		}
+	}
+
+	var err error
+	for _, s := range app.Schedulers {
+		if len(s.Args) == 0 {
+			continue
+		}
+		if _, ok := params[s.PipelineName]; !ok {
+			pip, errP := pipeline.LoadPipeline(db, proj.Key, s.PipelineName, false)
+			if errP == sdk.ErrPipelineNotFound {
+				// The missing pipelines are reported with the dependencies of the application
+				continue
+			}
+			if errP != nil {
+				return sdk.WrapError(errP, "CheckImportSchedulerArgs> Unable to load pipeline %s", s.PipelineName)
+			}
+			pipParams, errL := pipeline.GetAllParametersInPipeline(db, pip.ID)
+			if errL != nil {
+				return sdk.WrapError(errL, "CheckImportSchedulerArgs> Unable to load parameters of pipeline %s", s.PipelineName)
+			}
+			params[s.PipelineName] = map[string]bool{}
+			for _, p := range pipParams {
+				params[s.PipelineName][p.Name] = true
+			}
+		}
+
+		for _, a := range s.Args {
+			if params[s.PipelineName][a.Name] {
+				continue
+			}
+			if msgChan != nil {
+				msgChan <- sdk.NewMessage(sdk.MsgSchedulerArgUnknown, a.Name, s.Crontab, s.PipelineName)
+			}
+			if err == nil {
+				err = sdk.ErrWrongRequest
+			}
+		}
+	}
+	return err
+}
+
+//prerequisiteParameter matches the parameters of a pipeline build replaced in the expected value of a prerequisite
+var prerequisiteParameter = regexp.MustCompile(`\{\{\.[^}]*\}\}`)
+
+//CheckImportPrerequisites checks the prerequisites of the triggers of an imported application: the parameter
+//is required, and the expected value, once its "not " prefix and its parameters are removed, is a regular
+//expression, as it is evaluated when the trigger fires. A message is sent for each invalid prerequisite.
+func CheckImportPrerequisites(app *sdk.Application, msgChan chan<- sdk.Message) error {
+	var err error
+	for _, ap := range app.Pipelines {
+		for _, t := range ap.Triggers {
+			for _, p := range t.Prerequisites {
+				reason := ""
+				if strings.TrimSpace(p.Parameter) == "" {
+					reason = "parameter is required"
+				} else {
+					expr := prerequisiteParameter.ReplaceAllString(strings.TrimPrefix(p.ExpectedValue, "not "), "")
+					if _, errR := regexp.Compile("^" + expr + "$"); errR != nil {
+						reason = errR.Error()
+					}
+				}
+				if reason == "" {
+					continue
+				}
+				if msgChan != nil {
+					src := ap.Pipeline.Name
+					if t.SrcPipeline.Name != "" {
+						src = t.SrcPipeline.Name
+					}
+					msgChan <- sdk.NewMessage(sdk.MsgAppImportPrerequisiteInvalid, p.Parameter+"="+p.ExpectedValue, src, t.DestPipeline.Name, app.Name, reason)
+				}
+				if err == nil {
+					err = sdk.ErrWrongRequest
+				}
+			}
+		}
+	}
+	return err
+}
+
+//importCrontab returns the cron expression of a scheduler prefixed by its timezone
+func importCrontab(s sdk.PipelineScheduler) string {
+	if s.Timezone == "" {
+		return s.Crontab
+	}
+	return sdk.CronTimezonePrefix + s.Timezone + " " + s.Crontab
+}
+
+//CheckImportNotifications checks the type and the settings of the notifications of an imported application.
+//A message is sent for each invalid notification.
+func CheckImportNotifications(app *sdk.Application, msgChan chan<- sdk.Message) error {
+	var err error
+	for _, n := range app.Notifications {
+		types := make([]string, 0, len(n.Notifications))
+		for t := range n.Notifications {
+			types = append(types, string(t))
+		}
+		sort.Strings(types)
+
+		for _, t := range types {
+			field, errN := checkNotification(sdk.UserNotificationSettingsType(t), n.Notifications[sdk.UserNotificationSettingsType(t)])
+			if errN == nil {
+				continue
+			}
+			if msgChan != nil {
+				msgChan <- sdk.NewMessage(sdk.MsgAppImportNotifInvalid, t, n.Pipeline.Name, app.Name, field)
+			}
+			if err == nil {
+				err = errN
+			}
+		}
+	}
+	return err
+}
+
+//checkNotification returns the invalid field of the notification settings, if any
+func checkNotification(t sdk.UserNotificationSettingsType, s sdk.UserNotificationSettings) (string, error) {
+	switch t {
+	case sdk.EmailUserNotification, sdk.JabberUserNotification:
+	default:
+		return "type", sdk.ErrNotSupportedUserNotification
+	}
+
+	je, ok := s.(*sdk.JabberEmailUserNotificationSettings)
+	if !ok || je == nil {
+		return "settings", sdk.ErrParseUserNotification
+	}
+
+	isEventType := func(e sdk.UserNotificationEventType) bool {
+		return e == sdk.UserNotificationAlways || e == sdk.UserNotificationNever || e == sdk.UserNotificationChange
+	}
+	if !isEventType(je.OnSuccess) {
+		return "on_success", sdk.ErrParseUserNotification
+	}
+	if !isEventType(je.OnFailure) {
+		return "on_failure", sdk.ErrParseUserNotification
+	}
+
+	var hasRecipient bool
+	for _, r := range je.Recipients {
+		if strings.TrimSpace(r) != "" {
+			hasRecipient = true
+			break
+		}
+	}
+	if !hasRecipient && !je.SendToGroups && !je.SendToAuthor {
+		return "recipients", sdk.ErrParseUserNotification
+	}
+	return "", nil
+}
+
+//CheckImportTriggers checks the triggers of an imported application before any write: a pipeline cannot
+//trigger itself, and the imported triggers added to the ones already stored must not create a cycle
+func CheckImportTriggers(db gorp.SqlExecutor, proj *sdk.Project, app *sdk.Application, msgChan chan<- sdk.Message) error {
+	stored, errL := trigger.LoadTriggersByProject(db, proj.Key)
+	if errL != nil {
+		return sdk.WrapError(errL, "CheckImportTriggers> Unable to load triggers of project %s", proj.Key)
+	}
+
+	g := triggerGraph{}
+	for _, t := range stored {
+		g.add(newTriggerNode(t.SrcProject.Key, t.SrcApplication.Name, t.SrcPipeline.Name, t.SrcEnvironment.Name),
+			newTriggerNode(t.DestProject.Key, t.DestApplication.Name, t.DestPipeline.Name, t.DestEnvironment.Name))
+	}
+
+	var sameSourceDest bool
+	for _, ap := range app.Pipelines {
+		for _, t := range ap.Triggers {
+			src := newTriggerNode(proj.Key, app.Name, ap.Pipeline.Name, t.SrcEnvironment.Name)
+			dest := newTriggerNode(t.DestProject.Key, t.DestApplication.Name, t.DestPipeline.Name, t.DestEnvironment.Name)
+			if dest.project == "" {
+				dest.project = proj.Key
+			}
+			if dest.application == "" {
+				dest.application = app.Name
+			}
+			if dest.pipeline == "" {
+				dest.pipeline = ap.Pipeline.Name
+			}
+
+			if src == dest {
+				sameSourceDest = true
+				if msgChan != nil {
+					msgChan <- sdk.NewMessage(sdk.MsgAppImportTriggerSameSourceDest, src.String())
+				}
+				continue
+			}
+			g.add(src, dest)
+		}
+	}
+
+	if sameSourceDest {
+		return sdk.ErrInfiniteTriggerLoop
+	}
+
+	if cycle := g.cycle(); cycle != nil {
+		path := make([]string, len(cycle))
+		for i := range cycle {
+			path[i] = cycle[i].String()
+		}
+		if msgChan != nil {
+			msgChan <- sdk.NewMessage(sdk.MsgAppImportTriggerCycle, strings.Join(path, " -> "))
+		}
+		return sdk.ErrInfiniteTriggerLoop
+	}
+
+	return nil
+}
+
+//CheckImportDestPipelines checks that the pipelines triggered on the other applications of the project
+//are attached to these applications
+func CheckImportDestPipelines(db gorp.SqlExecutor, proj *sdk.Project, app *sdk.Application, msgChan chan<- sdk.Message) error {
+	destApps := map[string]bool{}
+	for _, ap := range app.Pipelines {
+		for _, t := range ap.Triggers {
+			if (t.DestProject.Key == "" || t.DestProject.Key == proj.Key) && t.DestApplication.Name != "" && t.DestApplication.Name != app.Name {
+				destApps[t.DestApplication.Name] = true
+			}
+		}
+	}
+	if len(destApps) == 0 {
+		return nil
+	}
+
+	appNames := make([]string, 0, len(destApps))
+	for name := range destApps {
+		appNames = append(appNames, name)
+	}
+	attached, errA := AttachedPipelines(db, proj.Key, appNames)
+	if errA != nil {
+		return sdk.WrapError(errA, "CheckImportDestPipelines> Unable to load pipelines of applications")
+	}
+
+	var notAttached bool
+	for _, ap := range app.Pipelines {
+		for _, t := range ap.Triggers {
+			if !destApps[t.DestApplication.Name] || (t.DestProject.Key != "" && t.DestProject.Key != proj.Key) {
+				continue
+			}
+			destPip := t.DestPipeline.Name
+			if destPip == "" {
+				destPip = ap.Pipeline.Name
+			}
+			if !attached[t.DestApplication.Name][destPip] {
+				notAttached = true
+				if msgChan != nil {
+					msgChan <- sdk.NewMessage(sdk.MsgAppImportDestPipelineNotAttached, destPip, ap.Pipeline.Name, t.DestApplication.Name)
+				}
+			}
+		}
+	}
+
+	if notAttached {
+		return sdk.ErrPipelineNotAttached
+	}
+	return nil
+}
 
+//triggerNode is a pipeline of an application on an environment, identified by names
+type triggerNode struct {
+	project     string
+	application string
+	pipeline    string
+	environment string
+}
+
+func newTriggerNode(projectKey, appName, pipName, envName string) triggerNode {
+	if envName == "" {
+		envName = sdk.DefaultEnv.Name
+	}
+	return triggerNode{project: projectKey, application: appName, pipeline: pipName, environment: envName}
+}
+
+func (n triggerNode) String() string {
+	s := n.project + "/" + n.application + "/" + n.pipeline
+	if n.environment != sdk.DefaultEnv.Name {
+		s += "[" + n.environment + "]"
+	}
+	return s
+}
+
+//triggerGraph is the set of triggers from a source node to its destination nodes
+type triggerGraph map[triggerNode][]triggerNode
+
+func (g triggerGraph) add(src, dest triggerNode) {
+	g[src] = append(g[src], dest)
+}
+
+//cycle returns the nodes of a cycle, the first node being repeated at the end, or nil if there is no cycle
+func (g triggerGraph) cycle() []triggerNode {
+	nodes := make([]triggerNode, 0, len(g))
+	for n := range g {
+		nodes = append(nodes, n)
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].String() < nodes[j].String() })
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := map[triggerNode]int{}
+	stack := []triggerNode{}
+
+	var visit func(n triggerNode) []triggerNode
+	visit = func(n triggerNode) []triggerNode {
+		state[n] = visiting
+		stack = append(stack, n)
+		for _, d := range g[n] {
+			switch state[d] {
+			case visiting:
+				for i := range stack {
+					if stack[i] == d {
+						return append(append([]triggerNode{}, stack[i:]...), d)
+					}
+				}
+			case unvisited:
+				if c := visit(d); c != nil {
+					return c
+				}
 			}
 		}
+		stack = stack[:len(stack)-1]
+		state[n] = visited
+		return nil
+	}
+
+	for _, n := range nodes {
+		if state[n] == unvisited {
+			if c := visit(n); c != nil {
+				return c
+			}
+		}
+	}
+	return nil
+}
+
+//checkRetention checks that the number of runs and the number of days of the retention of an application are
+//positive. A zero value is not set.
+func checkRetention(app *sdk.Application, msgChan chan<- sdk.Message) error {
+	for _, r := range []struct {
+		name  string
+		value int
+	}{{"keep_runs", app.RetentionRuns}, {"keep_days", app.RetentionDays}} {
+		if r.value >= 0 {
+			continue
+		}
+		if msgChan != nil {
+			msgChan <- sdk.NewMessage(sdk.MsgAppImportRetentionInvalid, app.Name, r.name)
+		}
+		return &sdk.Error{ID: sdk.ErrWrongRequest.ID, Status: sdk.ErrWrongRequest.Status, Root: fmt.Errorf("invalid %s %d of application %s", r.name, r.value, app.Name)}
 	}
 	return nil
 }
@@ -0,0 +1,226 @@
+package application
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ovh/cds/sdk"
+)
+
+func Test_triggerGraphCycle(t *testing.T) {
+	build := newTriggerNode("KEY", "app1", "build", "")
+	deploy := newTriggerNode("KEY", "app2", "deploy", "production")
+	it := newTriggerNode("KEY", "app2", "it", sdk.DefaultEnv.Name)
+
+	g := triggerGraph{}
+	g.add(build, deploy)
+	g.add(deploy, it)
+	assert.Nil(t, g.cycle())
+
+	g.add(it, build)
+	c := g.cycle()
+	assert.Len(t, c, 4)
+	assert.Equal(t, c[0], c[len(c)-1])
+	assert.Equal(t, "KEY/app2/deploy[production]", deploy.String())
+}
+
+func TestCheckImportNotifications(t *testing.T) {
+	newApp := func(t sdk.UserNotificationSettingsType, s sdk.UserNotificationSettings) *sdk.Application {
+		return &sdk.Application{
+			Name: "app1",
+			Notifications: []sdk.UserNotification{
+				{
+					Pipeline:      sdk.Pipeline{Name: "build"},
+					Notifications: map[sdk.UserNotificationSettingsType]sdk.UserNotificationSettings{t: s},
+				},
+			},
+		}
+	}
+	valid := func() *sdk.JabberEmailUserNotificationSettings {
+		return &sdk.JabberEmailUserNotificationSettings{
+			OnSuccess:  sdk.UserNotificationChange,
+			OnFailure:  sdk.UserNotificationAlways,
+			Recipients: []string{"foo@bar.com"},
+		}
+	}
+
+	assert.NoError(t, CheckImportNotifications(newApp(sdk.EmailUserNotification, valid()), nil))
+
+	toAuthor := valid()
+	toAuthor.Recipients = nil
+	toAuthor.SendToAuthor = true
+	assert.NoError(t, CheckImportNotifications(newApp(sdk.JabberUserNotification, toAuthor), nil))
+
+	noRecipient := valid()
+	noRecipient.Recipients = []string{""}
+	msgChan := make(chan sdk.Message, 1)
+	assert.Equal(t, sdk.ErrParseUserNotification, CheckImportNotifications(newApp(sdk.EmailUserNotification, noRecipient), msgChan))
+	assert.Equal(t, sdk.NewMessage(sdk.MsgAppImportNotifInvalid, "email", "build", "app1", "recipients"), <-msgChan)
+
+	badEvent := valid()
+	badEvent.OnFailure = "sometimes"
+	assert.Equal(t, sdk.ErrParseUserNotification, CheckImportNotifications(newApp(sdk.EmailUserNotification, badEvent), nil))
+
+	assert.Equal(t, sdk.ErrNotSupportedUserNotification, CheckImportNotifications(newApp("slack", valid()), nil))
+}
+
+func TestCheckImportFields(t *testing.T) {
+	app := &sdk.Application{
+		Name:     "app1",
+		Variable: []sdk.Variable{{Name: "foo"}},
+		Keys:     []sdk.ApplicationKey{{Name: "deploy"}},
+		Pipelines: []sdk.ApplicationPipeline{
+			{
+				Pipeline: sdk.Pipeline{Name: "build"},
+				Triggers: []sdk.PipelineTrigger{{DestPipeline: sdk.Pipeline{Name: "deploy"}}},
+			},
+		},
+	}
+	assert.NoError(t, CheckImportFields(app, nil))
+
+	app.Name = "my app"
+	app.Keys[0].Name = "deploy key"
+	msgChan := make(chan sdk.Message, 2)
+	assert.Equal(t, sdk.ErrInvalidApplicationPattern, CheckImportFields(app, msgChan))
+	close(msgChan)
+	msgs := []sdk.Message{}
+	for m := range msgChan {
+		msgs = append(msgs, m)
+	}
+	assert.Equal(t, []sdk.Message{
+		sdk.NewMessage(sdk.MsgAppImportFieldInvalid, "name", "my app"),
+		sdk.NewMessage(sdk.MsgAppImportFieldInvalid, "keys.deploy key", "my app"),
+	}, msgs)
+}
+
+func TestCheckImportVariables(t *testing.T) {
+	app := &sdk.Application{
+		Name:     "app1",
+		Variable: []sdk.Variable{{Name: "tier"}, {Name: "my var"}, {Name: "tier"}, {Name: "deploy.region-1"}},
+		Pipelines: []sdk.ApplicationPipeline{{
+			Pipeline:   sdk.Pipeline{Name: "build"},
+			Parameters: []sdk.Parameter{{Name: "tier"}, {Name: "version"}},
+		}},
+		Schedulers: []sdk.PipelineScheduler{
+			{PipelineName: "build", Crontab: "0 * * * *", Args: []sdk.Parameter{{Name: "version"}, {Name: "version"}}},
+		},
+	}
+	msgChan := make(chan sdk.Message, 3)
+	assert.Equal(t, sdk.ErrWrongRequest, CheckImportVariables(app, msgChan))
+	assert.Equal(t, sdk.NewMessage(sdk.MsgAppImportVariableInvalid, "my var", "variables", "app1"), <-msgChan)
+	assert.Equal(t, sdk.NewMessage(sdk.MsgAppImportVariableDuplicate, "tier", 2, "variables", "app1"), <-msgChan)
+	assert.Equal(t, sdk.NewMessage(sdk.MsgAppImportVariableDuplicate, "version", 2, "schedulers.build[0 * * * *]", "app1"), <-msgChan)
+
+	//The same name may be used in several scopes
+	app.Variable = []sdk.Variable{{Name: "tier"}, {Name: "deploy.region-1"}}
+	app.Schedulers[0].Args = app.Schedulers[0].Args[:1]
+	assert.NoError(t, CheckImportVariables(app, nil))
+}
+
+func TestCheckImportHooks(t *testing.T) {
+	app := &sdk.Application{
+		Name: "app1",
+		Hooks: []sdk.Hook{
+			{Pipeline: sdk.Pipeline{Name: "build"}, Branches: sdk.HookBranches{Include: []string{"master", `release/\d+`}}},
+			{Pipeline: sdk.Pipeline{Name: "deploy"}, Branches: sdk.HookBranches{Exclude: []string{"feat/(.*"}}},
+		},
+	}
+	msgChan := make(chan sdk.Message, 1)
+	assert.Equal(t, sdk.ErrWrongRequest, CheckImportHooks(app, msgChan))
+	m := <-msgChan
+	assert.Equal(t, sdk.MsgAppImportHookBranchInvalid.ID, m.ID)
+	assert.Contains(t, m.String("en-US"), "feat/(.* of the hook of pipeline deploy")
+
+	app.Hooks = app.Hooks[:1]
+	assert.NoError(t, CheckImportHooks(app, nil))
+}
+
+func TestCheckImportPermissions(t *testing.T) {
+	for _, c := range []struct {
+		level int
+		valid bool
+	}{{0, false}, {3, false}, {4, true}, {5, true}, {7, true}, {8, false}} {
+		app := &sdk.Application{
+			Name:              "app1",
+			ApplicationGroups: []sdk.GroupPermission{{Group: sdk.Group{Name: "grp1"}, Permission: c.level}},
+		}
+		msgChan := make(chan sdk.Message, 1)
+		err := CheckImportPermissions(app, msgChan)
+		if c.valid {
+			assert.NoError(t, err, "level %d", c.level)
+			assert.Empty(t, msgChan, "level %d", c.level)
+			continue
+		}
+		assert.Equal(t, sdk.ErrWrongRequest, err, "level %d", c.level)
+		assert.Equal(t, sdk.NewMessage(sdk.MsgAppImportInvalidPermissionLevel, c.level, "grp1", "app1"), <-msgChan)
+	}
+}
+
+func TestCheckImportSchedulers(t *testing.T) {
+	app := &sdk.Application{
+		Name: "app1",
+		Schedulers: []sdk.PipelineScheduler{
+			{PipelineName: "build", Crontab: "0 * * * *"},
+			{PipelineName: "build", Crontab: "30 0 6 * * * *", Timezone: "Europe/Paris"},
+			{PipelineName: "build", Crontab: "every day"},
+			{PipelineName: "build", Crontab: "0 * * * *", Timezone: "Mars/Olympus"},
+		},
+	}
+	msgChan := make(chan sdk.Message, 2)
+	assert.Equal(t, sdk.ErrWrongRequest, CheckImportSchedulers(app, msgChan))
+	assert.Equal(t, sdk.NewMessage(sdk.MsgSchedulerInvalidCron, "every day", "build", "app1", "missing field(s)"), <-msgChan)
+	m := <-msgChan
+	assert.Equal(t, sdk.MsgSchedulerInvalidCron.ID, m.ID)
+	assert.Contains(t, m.String("en-US"), "CRON_TZ=Mars/Olympus 0 * * * *")
+
+	app.Schedulers = app.Schedulers[:2]
+	assert.NoError(t, CheckImportSchedulers(app, nil))
+}
+
+func TestCheckImportPrerequisites(t *testing.T) {
+	app := &sdk.Application{
+		Name: "app1",
+		Pipelines: []sdk.ApplicationPipeline{{
+			Pipeline: sdk.Pipeline{Name: "build"},
+			Triggers: []sdk.PipelineTrigger{{
+				DestPipeline: sdk.Pipeline{Name: "deploy"},
+				Prerequisites: []sdk.Prerequisite{
+					{Parameter: "git.branch", ExpectedValue: "master|release/.*"},
+					{Parameter: "git.branch", ExpectedValue: "not {{.cds.pip.defaultBranch}}"},
+					{Parameter: "git.author", ExpectedValue: "(bob"},
+					{Parameter: "", ExpectedValue: "true"},
+				},
+			}},
+		}},
+	}
+	msgChan := make(chan sdk.Message, 2)
+	assert.Equal(t, sdk.ErrWrongRequest, CheckImportPrerequisites(app, msgChan))
+	m := <-msgChan
+	assert.Equal(t, sdk.MsgAppImportPrerequisiteInvalid.ID, m.ID)
+	assert.Contains(t, m.String("en-US"), "git.author=(bob")
+	assert.Equal(t, sdk.NewMessage(sdk.MsgAppImportPrerequisiteInvalid, "=true", "build", "deploy", "app1", "parameter is required"), <-msgChan)
+
+	app.Pipelines[0].Triggers[0].Prerequisites = app.Pipelines[0].Triggers[0].Prerequisites[:2]
+	assert.NoError(t, CheckImportPrerequisites(app, nil))
+}
+
+func TestCheckImportSchedulerArgs(t *testing.T) {
+	//The parameters of the pipeline are taken from its definition
+	app := &sdk.Application{
+		Name: "app1",
+		Pipelines: []sdk.ApplicationPipeline{
+			{Pipeline: sdk.Pipeline{Name: "build", Type: sdk.BuildPipeline, Parameter: []sdk.Parameter{{Name: "version"}}}},
+		},
+		Schedulers: []sdk.PipelineScheduler{
+			{PipelineName: "build", Crontab: "0 * * * *", Args: []sdk.Parameter{{Name: "version", Value: "1.0"}}},
+			{PipelineName: "build", Crontab: "30 * * * *", Args: []sdk.Parameter{{Name: "version", Value: "1.0"}, {Name: "tier", Value: "2"}}},
+		},
+	}
+	msgChan := make(chan sdk.Message, 1)
+	assert.Equal(t, sdk.ErrWrongRequest, CheckImportSchedulerArgs(nil, &sdk.Project{Key: "KEY"}, app, msgChan))
+	assert.Equal(t, sdk.NewMessage(sdk.MsgSchedulerArgUnknown, "tier", "30 * * * *", "build"), <-msgChan)
+
+	app.Schedulers = app.Schedulers[:1]
+	assert.NoError(t, CheckImportSchedulerArgs(nil, &sdk.Project{Key: "KEY"}, app, nil))
+}
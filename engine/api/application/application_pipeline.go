@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/go-gorp/gorp"
@@ -70,6 +71,38 @@ func GetAllPipelines(db gorp.SqlExecutor, projectKey, applicationName string) ([
 	return pipelines, nil
 }
 
+// AttachedPipelines returns the names of the pipelines attached to each of the given applications
+func AttachedPipelines(db gorp.SqlExecutor, projectKey string, appNames []string) (map[string]map[string]bool, error) {
+	res := make(map[string]map[string]bool, len(appNames))
+	if len(appNames) == 0 {
+		return res, nil
+	}
+	for _, n := range appNames {
+		res[n] = map[string]bool{}
+	}
+
+	query := `SELECT application.name, pipeline.name
+	          FROM application_pipeline
+	          JOIN application ON application.id = application_pipeline.application_id
+	          JOIN project ON project.id = application.project_id
+	          JOIN pipeline ON pipeline.id = application_pipeline.pipeline_id
+	          WHERE project.projectkey = $1 AND application.name = ANY(string_to_array($2, ','))`
+	rows, err := db.Query(query, projectKey, strings.Join(appNames, ","))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var appName, pipName string
+		if err := rows.Scan(&appName, &pipName); err != nil {
+			return nil, err
+		}
+		res[appName][pipName] = true
+	}
+	return res, nil
+}
+
 // GetAllPipelinesByID Get all pipelines for the given application
 func GetAllPipelinesByID(db gorp.SqlExecutor, applicationID int64) ([]sdk.ApplicationPipeline, error) {
 	appPipelines := []sdk.ApplicationPipeline{}
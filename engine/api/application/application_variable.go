@@ -125,7 +125,7 @@ func GetAllVariable(db gorp.SqlExecutor, key, appName string, args ...FuncArg) (
 
 	variables := []sdk.Variable{}
 	query := `SELECT application_variable.id, application_variable.var_name, application_variable.var_value,
-						application_variable.cipher_value, application_variable.var_type
+						application_variable.cipher_value, application_variable.var_type, application_variable.var_description
 	          FROM application_variable
 	          JOIN application ON application.id = application_variable.application_id
 	          JOIN project ON project.id = application.project_id
@@ -141,7 +141,7 @@ func GetAllVariable(db gorp.SqlExecutor, key, appName string, args ...FuncArg) (
 		var typeVar string
 		var clearVal sql.NullString
 		var cipherVal []byte
-		err = rows.Scan(&v.ID, &v.Name, &clearVal, &cipherVal, &typeVar)
+		err = rows.Scan(&v.ID, &v.Name, &clearVal, &cipherVal, &typeVar, &v.Description)
 		if err != nil {
 			return nil, err
 		}
@@ -169,13 +169,13 @@ func LoadVariableByID(db gorp.SqlExecutor, appID int64, varID int64, fargs ...Fu
 		f(&c)
 	}
 
-	query := `SELECT id, var_name, var_value, var_type, cipher_value FROM application_variable
+	query := `SELECT id, var_name, var_value, var_type, cipher_value, var_description FROM application_variable
 			WHERE application_id = $1 AND id = $2`
 
 	var v sdk.Variable
 	var value sql.NullString
 	var cipher []byte
-	if err := db.QueryRow(query, appID, varID).Scan(&v.ID, &v.Name, &value, &v.Type, &cipher); err != nil {
+	if err := db.QueryRow(query, appID, varID).Scan(&v.ID, &v.Name, &value, &v.Type, &cipher, &v.Description); err != nil {
 		return nil, err
 	}
 
@@ -191,13 +191,13 @@ func LoadVariable(db gorp.SqlExecutor, appID int64, varName string, fargs ...Fun
 		f(&c)
 	}
 
-	query := `SELECT id, var_name, var_value, var_type, cipher_value FROM application_variable
+	query := `SELECT id, var_name, var_value, var_type, cipher_value, var_description FROM application_variable
 			WHERE application_id = $1 AND var_name = $2`
 
 	var v sdk.Variable
 	var value sql.NullString
 	var cipher []byte
-	if err := db.QueryRow(query, appID, varName).Scan(&v.ID, &v.Name, &value, &v.Type, &cipher); err != nil {
+	if err := db.QueryRow(query, appID, varName).Scan(&v.ID, &v.Name, &value, &v.Type, &cipher, &v.Description); err != nil {
 		return nil, err
 	}
 	var errC error
@@ -213,7 +213,7 @@ func GetAllVariableByID(db gorp.SqlExecutor, applicationID int64, fargs ...FuncA
 	}
 
 	variables := []sdk.Variable{}
-	query := `SELECT application_variable.id, application_variable.var_name, application_variable.var_value, application_variable.cipher_value, application_variable.var_type
+	query := `SELECT application_variable.id, application_variable.var_name, application_variable.var_value, application_variable.cipher_value, application_variable.var_type, application_variable.var_description
 	          FROM application_variable
 	          WHERE application_variable.application_id = $1
 	          ORDER BY var_name`
@@ -227,7 +227,7 @@ func GetAllVariableByID(db gorp.SqlExecutor, applicationID int64, fargs ...FuncA
 		var typeVar string
 		var clearVal sql.NullString
 		var cipherVal []byte
-		err = rows.Scan(&v.ID, &v.Name, &clearVal, &cipherVal, &typeVar)
+		err = rows.Scan(&v.ID, &v.Name, &clearVal, &cipherVal, &typeVar, &v.Description)
 		if err != nil {
 			return nil, err
 		}
@@ -253,9 +253,9 @@ func InsertVariable(db gorp.SqlExecutor, app *sdk.Application, variable sdk.Vari
 		return sdk.WrapError(err, "InsertVariable> Cannot encrypt secret")
 	}
 
-	query := `INSERT INTO application_variable(application_id, var_name, var_value, cipher_value, var_type)
-		  VALUES($1, $2, $3, $4, $5) RETURNING id`
-	if err := db.QueryRow(query, app.ID, variable.Name, clear, cipher, string(variable.Type)).Scan(&variable.ID); err != nil && strings.Contains(err.Error(), "application_variable_pkey") {
+	query := `INSERT INTO application_variable(application_id, var_name, var_value, cipher_value, var_type, var_description)
+		  VALUES($1, $2, $3, $4, $5, $6) RETURNING id`
+	if err := db.QueryRow(query, app.ID, variable.Name, clear, cipher, string(variable.Type), variable.Description).Scan(&variable.ID); err != nil && strings.Contains(err.Error(), "application_variable_pkey") {
 		return sdk.ErrVariableExists
 	}
 	if err != nil {
@@ -294,8 +294,8 @@ func UpdateVariable(db gorp.SqlExecutor, app *sdk.Application, variable *sdk.Var
 		return sdk.WrapError(err, "UpdateVariable> Cannot encrypt secret %s", variable.Name)
 	}
 
-	query := `UPDATE application_variable SET var_name= $1, var_value=$2, cipher_value=$3 WHERE id = $4`
-	result, err := db.Exec(query, variable.Name, clear, cipher, variable.ID)
+	query := `UPDATE application_variable SET var_name= $1, var_value=$2, cipher_value=$3, var_description=$4 WHERE id = $5`
+	result, err := db.Exec(query, variable.Name, clear, cipher, variable.Description, variable.ID)
 	if err != nil {
 		return sdk.WrapError(err, "Cannot update variable %s", variable.Name)
 	}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"regexp"
+	"strings"
 	"time"
 
 	"github.com/go-gorp/gorp"
@@ -171,6 +172,49 @@ func unwrap(db gorp.SqlExecutor, u *sdk.User, opts []LoadOptionFunc, dbApp *dbAp
 	return &app, nil
 }
 
+// Exists checks if an application given its name exists
+func Exists(db gorp.SqlExecutor, projectKey, appName string) (bool, error) {
+	var n int
+	query := `SELECT count(1)
+		  FROM application
+		  JOIN project ON project.id = application.project_id
+		  WHERE project.projectkey = $1 AND application.name = $2`
+	if err := db.QueryRow(query, projectKey, appName).Scan(&n); err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+// ExistApplications checks in a single query which of the given applications exist in the project
+func ExistApplications(db gorp.SqlExecutor, projectKey string, names []string) (map[string]bool, error) {
+	res := make(map[string]bool, len(names))
+	if len(names) == 0 {
+		return res, nil
+	}
+	for _, n := range names {
+		res[n] = false
+	}
+
+	query := `SELECT application.name
+		  FROM application
+		  JOIN project ON project.id = application.project_id
+		  WHERE project.projectkey = $1 AND application.name = ANY(string_to_array($2, ','))`
+	rows, err := db.Query(query, projectKey, strings.Join(names, ","))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		res[name] = true
+	}
+	return res, nil
+}
+
 // Insert add an application id database
 func Insert(db gorp.SqlExecutor, proj *sdk.Project, app *sdk.Application, u *sdk.User) error {
 	// check application name pattern
@@ -207,6 +251,39 @@ func Update(db gorp.SqlExecutor, app *sdk.Application, u *sdk.User) error {
 	return UpdateLastModified(db, app, u)
 }
 
+// UpdateMetadata updates the metadata of an application
+func UpdateMetadata(db gorp.SqlExecutor, app *sdk.Application) error {
+	dbApp := dbApplication(*app)
+	if err := dbApp.PostUpdate(db); err != nil {
+		return sdk.WrapError(err, "application.UpdateMetadata %s(%d)", app.Name, app.ID)
+	}
+	return nil
+}
+
+// UpdateDescription updates the description of an application
+func UpdateDescription(db gorp.SqlExecutor, app *sdk.Application) error {
+	if _, err := db.Exec("UPDATE application SET description = $1 WHERE id = $2", app.Description, app.ID); err != nil {
+		return sdk.WrapError(err, "application.UpdateDescription %s(%d)", app.Name, app.ID)
+	}
+	return nil
+}
+
+// UpdateRetention updates the retention of the runs of an application
+func UpdateRetention(db gorp.SqlExecutor, app *sdk.Application) error {
+	if _, err := db.Exec("UPDATE application SET retention_runs = $1, retention_days = $2 WHERE id = $3", app.RetentionRuns, app.RetentionDays, app.ID); err != nil {
+		return sdk.WrapError(err, "application.UpdateRetention %s(%d)", app.Name, app.ID)
+	}
+	return nil
+}
+
+// UpdateGeneration updates the import generation of an application
+func UpdateGeneration(db gorp.SqlExecutor, app *sdk.Application) error {
+	if _, err := db.Exec("UPDATE application SET import_generation = $1 WHERE id = $2", app.Generation, app.ID); err != nil {
+		return sdk.WrapError(err, "application.UpdateGeneration %s(%d)", app.Name, app.ID)
+	}
+	return nil
+}
+
 // UpdateLastModified Update last_modified column in application table
 func UpdateLastModified(db gorp.SqlExecutor, app *sdk.Application, u *sdk.User) error {
 	query := `
@@ -149,3 +149,19 @@ func TestLoadAllAsUser(t *testing.T) {
 
 	assert.Equal(t, 1, len(actual))
 }
+
+func TestExistApplications(t *testing.T) {
+	db := test.SetupPG(t, bootstrap.InitiliazeDB)
+	key := sdk.RandomString(10)
+	proj := assets.InsertTestProject(t, db, key, key, nil)
+	app := sdk.Application{
+		Name: "my-app",
+	}
+
+	test.NoError(t, application.Insert(db, proj, &app, nil))
+
+	actual, err := application.ExistApplications(db, key, []string{"my-app", "my-other-app"})
+	test.NoError(t, err)
+
+	assert.Equal(t, map[string]bool{"my-app": true, "my-other-app": false}, actual)
+}
@@ -0,0 +1,113 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ovh/cds/engine/api/application"
+	"github.com/ovh/cds/engine/api/bootstrap"
+	"github.com/ovh/cds/engine/api/test"
+	"github.com/ovh/cds/engine/api/test/assets"
+	"github.com/ovh/cds/sdk"
+)
+
+func TestImportUpdatePreservesMaskedSecret(t *testing.T) {
+	db := test.SetupPG(t, bootstrap.InitiliazeDB)
+	key := sdk.RandomString(10)
+	proj := assets.InsertTestProject(t, db, key, key, nil)
+	u, _ := assets.InsertAdminUser(db)
+	app := sdk.Application{
+		Name: "my-app",
+	}
+
+	test.NoError(t, application.Insert(db, proj, &app, u))
+	test.NoError(t, application.InsertVariable(db, &app, sdk.Variable{Name: "secret", Type: sdk.SecretVariable, Value: "mysecret"}, u))
+
+	imported := &sdk.Application{
+		Name: "my-app",
+		Variable: []sdk.Variable{
+			{Name: "secret", Type: sdk.SecretVariable, Value: sdk.PasswordPlaceholder},
+			{Name: "other", Type: sdk.SecretVariable, Value: sdk.PasswordPlaceholder},
+		},
+	}
+
+	msgChan := make(chan sdk.Message, 10)
+	test.NoError(t, application.ImportUpdate(db, proj, imported, false, msgChan, u))
+	close(msgChan)
+
+	msgs := []sdk.Message{}
+	for m := range msgChan {
+		msgs = append(msgs, m)
+	}
+	assert.Contains(t, msgs, sdk.NewMessage(sdk.MsgAppImportSecretPreserved, "secret", "my-app"))
+	assert.Contains(t, msgs, sdk.NewMessage(sdk.MsgAppImportSecretSkipped, "other", "my-app"))
+
+	vars, err := application.GetAllVariable(db, key, "my-app", application.WithClearPassword())
+	test.NoError(t, err)
+	assert.Len(t, vars, 1)
+	assert.Equal(t, "mysecret", vars[0].Value)
+}
+
+func TestImportUpdate(t *testing.T) {
+	db := test.SetupPG(t, bootstrap.InitiliazeDB)
+	key := sdk.RandomString(10)
+	proj := assets.InsertTestProject(t, db, key, key, nil)
+	u, _ := assets.InsertAdminUser(db)
+	app := sdk.Application{
+		Name:        "my-app",
+		Description: "my description",
+	}
+
+	test.NoError(t, application.Insert(db, proj, &app, u))
+	test.NoError(t, application.InsertVariable(db, &app, sdk.Variable{Name: "url", Type: sdk.StringVariable, Value: "https://preprod.example.com"}, u))
+	test.NoError(t, application.InsertVariable(db, &app, sdk.Variable{Name: "kept", Type: sdk.StringVariable, Value: "kept value"}, u))
+
+	importUpdate := func() []sdk.Message {
+		imported := &sdk.Application{
+			Name: "my-app",
+			Variable: []sdk.Variable{
+				{Name: "url", Type: sdk.StringVariable, Value: "https://prod.example.com"},
+				{Name: "added", Type: sdk.StringVariable, Value: "added value"},
+			},
+		}
+		msgChan := make(chan sdk.Message, 50)
+		test.NoError(t, application.ImportUpdate(db, proj, imported, false, msgChan, u))
+		close(msgChan)
+
+		msgs := []sdk.Message{}
+		for m := range msgChan {
+			msgs = append(msgs, m)
+		}
+		return msgs
+	}
+
+	msgs := importUpdate()
+	assert.Contains(t, msgs, sdk.NewMessage(sdk.MsgAppVariableUpdated, "url", "my-app"))
+	assert.Contains(t, msgs, sdk.NewMessage(sdk.MsgAppVariableCreated, "added", "my-app"))
+	assert.Contains(t, msgs, sdk.NewMessage(sdk.MsgAppUpdated, "my-app"))
+	assert.NotContains(t, msgs, sdk.NewMessage(sdk.MsgAppDescriptionUpdated, "my-app"))
+
+	//What is not in the import is kept as is
+	vars, err := application.GetAllVariable(db, key, "my-app")
+	test.NoError(t, err)
+	values := map[string]string{}
+	for _, v := range vars {
+		values[v.Name] = v.Value
+	}
+	assert.Equal(t, map[string]string{
+		"url":   "https://prod.example.com",
+		"kept":  "kept value",
+		"added": "added value",
+	}, values)
+
+	actual, err := application.LoadByName(db, key, "my-app", nil)
+	test.NoError(t, err)
+	assert.Equal(t, "my description", actual.Description)
+
+	//The entries which have not changed are not updated again
+	msgs = importUpdate()
+	assert.NotContains(t, msgs, sdk.NewMessage(sdk.MsgAppVariableUpdated, "url", "my-app"))
+	assert.NotContains(t, msgs, sdk.NewMessage(sdk.MsgAppVariableCreated, "added", "my-app"))
+	assert.Contains(t, msgs, sdk.NewMessage(sdk.MsgAppUpdated, "my-app"))
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-gorp/gorp"
+	"github.com/gorilla/mux"
+
+	"github.com/ovh/cds/engine/api/application"
+	"github.com/ovh/cds/engine/api/businesscontext"
+	"github.com/ovh/cds/engine/api/pipeline"
+	"github.com/ovh/cds/engine/api/project"
+	"github.com/ovh/cds/sdk"
+	"github.com/ovh/cds/sdk/exportentities"
+)
+
+func exportApplicationHandler(w http.ResponseWriter, r *http.Request, db *gorp.DbMap, c *businesscontext.Ctx) error {
+	vars := mux.Vars(r)
+	key := vars["key"]
+	appName := vars["permApplicationName"]
+
+	f, errF := exportApplicationFormat(r)
+	if errF != nil {
+		return sdk.WrapError(sdk.ErrWrongRequest, "exportApplicationHandler> Unable to get format : %s", errF)
+	}
+	annotate := FormBool(r, "annotate")
+	if annotate && f == exportentities.FormatJSON {
+		return sdk.WrapError(sdk.ErrWrongRequest, "exportApplicationHandler> Unable to annotate an export in format %s", f)
+	}
+
+	proj, errP := project.Load(db, key, c.User)
+	if errP != nil {
+		return sdk.WrapError(errP, "exportApplicationHandler> Unable to load project %s", key)
+	}
+
+	msgChan, collectMessages := newMessageCollector()
+	a, errL := application.Export(db, proj, appName, c.User, msgChan)
+	if errL != nil {
+		collectMessages()
+		return sdk.WrapError(errL, "exportApplicationHandler> Unable to load application %s", appName)
+	}
+
+	// The omitted fields are noted in a warning header, the body stays importable
+	al := r.Header.Get("Accept-Language")
+	for _, m := range collectMessages() {
+		w.Header().Add("Warning", fmt.Sprintf("199 cds %q", m.String(al)))
+	}
+
+	sum, errS := a.Checksum()
+	if errS != nil {
+		return sdk.WrapError(errS, "exportApplicationHandler> Unable to compute checksum of application %s", appName)
+	}
+
+	// The router sets its own ETag, it is replaced by the checksum of the exported application
+	w.Header().Set("ETag", "\""+sum+"\"")
+
+	var annotations []string
+	if annotate {
+		var errA error
+		annotations, errA = exportApplicationAnnotations(db, proj, appName)
+		if errA != nil {
+			return sdk.WrapError(errA, "exportApplicationHandler> Unable to annotate application %s", appName)
+		}
+	}
+
+	// The application and each of its pipelines are exported in their own file
+	if FormBool(r, "split") {
+		b, errA := exportApplicationArchive(db, proj, a, f, annotations)
+		if errA != nil {
+			return sdk.WrapError(errA, "exportApplicationHandler> Unable to export application %s", appName)
+		}
+		w.Header().Set("Content-Type", "application/gzip")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment;filename=\"%s.tar.gz\"", appName))
+		w.WriteHeader(http.StatusOK)
+		_, err := w.Write(b)
+		return err
+	}
+
+	b, errM := exportentities.Marshal(a, f)
+	if errM != nil {
+		return sdk.WrapError(errM, "exportApplicationHandler> Unable to export application %s", appName)
+	}
+	if annotations != nil {
+		if b, errM = exportentities.Annotate(b, f, annotations); errM != nil {
+			return sdk.WrapError(errM, "exportApplicationHandler> Unable to annotate application %s", appName)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment;filename=\"%s.%s\"", appName, exportFormatExtension(f)))
+	w.WriteHeader(http.StatusOK)
+	_, err := w.Write(b)
+	return err
+}
+
+//exportApplicationArchive exports the application and its pipelines in a tar.gz archive, laid out as an
+//archive to import: the application references its pipelines, which are in the pipelines directory and
+//named by their slug. Each file can also be imported on its own. The annotations are written in the file of the application.
+func exportApplicationArchive(db gorp.SqlExecutor, proj *sdk.Project, a exportentities.Application, f exportentities.Format, annotations []string) ([]byte, error) {
+	ext := exportFormatExtension(f)
+	buf := &bytes.Buffer{}
+	gz := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gz)
+	now := time.Now()
+	add := func(name string, i interface{}, annotations []string) error {
+		b, err := exportentities.Marshal(i, f)
+		if err != nil {
+			return sdk.WrapError(err, "exportApplicationArchive> Unable to export %s", name)
+		}
+		if annotations != nil {
+			if b, err = exportentities.Annotate(b, f, annotations); err != nil {
+				return sdk.WrapError(err, "exportApplicationArchive> Unable to annotate %s", name)
+			}
+		}
+		hdr := &tar.Header{Name: name, Mode: 0644, Size: int64(len(b)), ModTime: now, Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return sdk.WrapError(err, "exportApplicationArchive> Unable to write %s", name)
+		}
+		if _, err := tw.Write(b); err != nil {
+			return sdk.WrapError(err, "exportApplicationArchive> Unable to write %s", name)
+		}
+		return nil
+	}
+
+	if err := add(path.Join(importArchiveApplications, a.Name+"."+ext), a, annotations); err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(a.Pipelines))
+	for name := range a.Pipelines {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		pip, errP := pipeline.LoadPipeline(db, proj.Key, name, true)
+		if errP != nil {
+			return nil, sdk.WrapError(errP, "exportApplicationArchive> Unable to load pipeline %s", name)
+		}
+		if err := add(path.Join(importArchivePipelines, pip.Slug+"."+ext), exportentities.NewPipeline(pip), nil); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, sdk.WrapError(err, "exportApplicationArchive> Unable to close archive")
+	}
+	if err := gz.Close(); err != nil {
+		return nil, sdk.WrapError(err, "exportApplicationArchive> Unable to close archive")
+	}
+	return buf.Bytes(), nil
+}
+
+//exportApplicationAnnotations returns the comments written at the top of an annotated export: when the application
+//was last modified, and by whom it was last imported, as recorded by the import audits
+func exportApplicationAnnotations(db gorp.SqlExecutor, proj *sdk.Project, appName string) ([]string, error) {
+	app, errL := application.LoadByName(db, proj.Key, appName, nil)
+	if errL != nil {
+		return nil, sdk.WrapError(errL, "exportApplicationAnnotations> Unable to load application %s", appName)
+	}
+	audits, errA := application.LoadImportAudits(db, proj.Key, appName, 1)
+	if errA != nil {
+		return nil, sdk.WrapError(errA, "exportApplicationAnnotations> Unable to load imports of application %s", appName)
+	}
+
+	annotations := []string{
+		fmt.Sprintf("Application %s of project %s", app.Name, proj.Key),
+		fmt.Sprintf("Last modified: %s", app.LastModified.UTC().Format(time.RFC3339)),
+	}
+	if len(audits) > 0 && audits[0].Author != "" {
+		annotations = append(annotations, fmt.Sprintf("Last imported: %s by %s", audits[0].Created.UTC().Format(time.RFC3339), audits[0].Author))
+	}
+	return annotations, nil
+}
+
+//exportApplicationFormat returns the format of an export. As for an import, the format is taken from the
+//format value if provided, else from the media types accepted by the client. The default format is yaml.
+func exportApplicationFormat(r *http.Request) (exportentities.Format, error) {
+	if format := r.FormValue("format"); format != "" {
+		return exportentities.GetFormat(format)
+	}
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		if f, err := formatFromMediaType(accept); err == nil {
+			return f, nil
+		}
+	}
+	return exportentities.FormatYAML, nil
+}
+
+//exportFormatExtension returns the file extension of an export
+func exportFormatExtension(f exportentities.Format) string {
+	switch f {
+	case exportentities.FormatJSON:
+		return "json"
+	case exportentities.FormatHCL:
+		return "hcl"
+	case exportentities.FormatTOML:
+		return "toml"
+	default:
+		return "yml"
+	}
+}
+
+//checkApplicationImportPrecondition checks the If-Match header of an import against the checksum of the
+//stored application, so that an application modified since its export is not overwritten. The checksum is
+//computed on the application as it is exported for the user.
+func checkApplicationImportPrecondition(db gorp.SqlExecutor, r *http.Request, proj *sdk.Project, appName string, exist bool, u *sdk.User) error {
+	ifMatch := strings.TrimSpace(r.Header.Get("If-Match"))
+	if ifMatch == "" {
+		return nil
+	}
+	if !exist {
+		return sdk.WrapError(sdk.ErrPreconditionFailed, "checkApplicationImportPrecondition> Application %s does not exist", appName)
+	}
+	if ifMatch == "*" {
+		return nil
+	}
+
+	a, errL := application.Export(db, proj, appName, u, nil)
+	if errL != nil {
+		return sdk.WrapError(errL, "checkApplicationImportPrecondition> Unable to load application %s", appName)
+	}
+	sum, errS := a.Checksum()
+	if errS != nil {
+		return sdk.WrapError(errS, "checkApplicationImportPrecondition> Unable to compute checksum of application %s", appName)
+	}
+
+	for _, etag := range strings.Split(ifMatch, ",") {
+		etag = strings.TrimPrefix(strings.TrimSpace(etag), "W/")
+		if strings.Trim(etag, "\"") == sum {
+			return nil
+		}
+	}
+	return sdk.WrapError(sdk.ErrPreconditionFailed, "checkApplicationImportPrecondition> Application %s has been modified", appName)
+}
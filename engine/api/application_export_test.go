@@ -0,0 +1,201 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ovh/cds/engine/api/auth"
+	"github.com/ovh/cds/engine/api/test"
+	"github.com/ovh/cds/engine/api/test/assets"
+	"github.com/ovh/cds/sdk"
+)
+
+func TestExportApplicationHandlerETag(t *testing.T) {
+	db := test.SetupPG(t)
+
+	router = newRouter(auth.TestLocalAuth(t), mux.NewRouter(), "/TestExportApplicationHandlerETag")
+	router.init()
+
+	u, pass := assets.InsertAdminUser(db)
+	proj := assets.InsertTestProject(t, db, sdk.RandomString(10), sdk.RandomString(10), nil)
+	test.NotNil(t, proj)
+
+	importURI := router.getRoute("POST", importApplicationHandler, map[string]string{"permProjectKey": proj.Key})
+	exportURI := router.getRoute("GET", exportApplicationHandler, map[string]string{"key": proj.Key, "permApplicationName": "app1"})
+	test.NotEmpty(t, importURI)
+	test.NotEmpty(t, exportURI)
+
+	doImport := func(payload, ifMatch string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest("POST", importURI+"?format=yaml&forceUpdate=true", strings.NewReader(payload))
+		test.NoError(t, err)
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		assets.AuthentifyRequest(t, req, u, pass)
+		w := httptest.NewRecorder()
+		router.mux.ServeHTTP(w, req)
+		return w
+	}
+
+	//1. If-Match fails on an unknown application
+	w := doImport("name: app1\n", "*")
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+
+	w = doImport("name: app1\n", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	//2. Export returns the checksum of the application
+	req, err := http.NewRequest("GET", exportURI+"?format=yaml", nil)
+	test.NoError(t, err)
+	assets.AuthentifyRequest(t, req, u, pass)
+	w = httptest.NewRecorder()
+	router.mux.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	etag := w.Header().Get("ETag")
+	assert.NotEmpty(t, etag)
+	assert.Contains(t, w.Body.String(), "app1")
+
+	//3. Import succeeds with the current checksum
+	w = doImport("name: app1\nvariables:\n  foo:\n    value: bar\n", etag)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	//4. The application has been modified since the export
+	w = doImport("name: app1\nvariables:\n  foo:\n    value: baz\n", etag)
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+
+	//5. Export negotiates the format, masks the secrets, and is imported back
+	w = doImport("name: app1\nvariables:\n  password:\n    type: password\n    value: s3cr3t\n", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	req, err = http.NewRequest("GET", exportURI, nil)
+	test.NoError(t, err)
+	req.Header.Set("Accept", "application/json")
+	assets.AuthentifyRequest(t, req, u, pass)
+	w = httptest.NewRecorder()
+	router.mux.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Disposition"), "app1.json")
+	assert.NotContains(t, w.Body.String(), "s3cr3t")
+	assert.Contains(t, w.Body.String(), sdk.PasswordPlaceholder)
+
+	req, err = http.NewRequest("POST", importURI+"?format=json&forceUpdate=true", strings.NewReader(w.Body.String()))
+	test.NoError(t, err)
+	req.Header.Set("If-Match", w.Header().Get("ETag"))
+	assets.AuthentifyRequest(t, req, u, pass)
+	w = httptest.NewRecorder()
+	router.mux.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestExportApplicationHandlerSplit(t *testing.T) {
+	db := test.SetupPG(t)
+
+	router = newRouter(auth.TestLocalAuth(t), mux.NewRouter(), "/TestExportApplicationHandlerSplit")
+	router.init()
+
+	u, pass := assets.InsertAdminUser(db)
+	proj := assets.InsertTestProject(t, db, sdk.RandomString(10), sdk.RandomString(10), nil)
+	test.NotNil(t, proj)
+
+	importURI := router.getRoute("POST", importApplicationHandler, map[string]string{"permProjectKey": proj.Key})
+	exportURI := router.getRoute("GET", exportApplicationHandler, map[string]string{"key": proj.Key, "permApplicationName": "app1"})
+	archiveURI := router.getRoute("POST", importProjectArchiveHandler, map[string]string{"permProjectKey": proj.Key})
+	test.NotEmpty(t, importURI)
+	test.NotEmpty(t, exportURI)
+	test.NotEmpty(t, archiveURI)
+
+	payload := `name: app1
+pipelines:
+  compile:
+    definition:
+      steps:
+      - script: make build
+`
+	req, err := http.NewRequest("POST", importURI+"?format=yaml", strings.NewReader(payload))
+	test.NoError(t, err)
+	assets.AuthentifyRequest(t, req, u, pass)
+	w := httptest.NewRecorder()
+	router.mux.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	req, err = http.NewRequest("GET", exportURI+"?format=yaml&split=true", nil)
+	test.NoError(t, err)
+	assets.AuthentifyRequest(t, req, u, pass)
+	w = httptest.NewRecorder()
+	router.mux.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Disposition"), "app1.tar.gz")
+	archive := w.Body.Bytes()
+
+	//The archive contains the application and its pipeline
+	p, msgs, err := readImportArchive(bytes.NewReader(archive), 1<<20)
+	test.NoError(t, err)
+	assert.Empty(t, msgs)
+	if assert.Len(t, p.Applications, 1) {
+		assert.Equal(t, "compile", p.Applications[0].Pipelines["compile"].Ref)
+	}
+	if assert.Len(t, p.Pipelines, 1) {
+		assert.Equal(t, "compile", p.Pipelines[0].Name)
+	}
+
+	//The archive is imported back
+	req, err = http.NewRequest("POST", archiveURI+"?forceUpdate=true", bytes.NewReader(archive))
+	test.NoError(t, err)
+	assets.AuthentifyRequest(t, req, u, pass)
+	w = httptest.NewRecorder()
+	router.mux.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestExportApplicationHandlerAnnotate(t *testing.T) {
+	db := test.SetupPG(t)
+
+	router = newRouter(auth.TestLocalAuth(t), mux.NewRouter(), "/TestExportApplicationHandlerAnnotate")
+	router.init()
+
+	u, pass := assets.InsertAdminUser(db)
+	proj := assets.InsertTestProject(t, db, sdk.RandomString(10), sdk.RandomString(10), nil)
+	test.NotNil(t, proj)
+
+	importURI := router.getRoute("POST", importApplicationHandler, map[string]string{"permProjectKey": proj.Key})
+	exportURI := router.getRoute("GET", exportApplicationHandler, map[string]string{"key": proj.Key, "permApplicationName": "app1"})
+	test.NotEmpty(t, importURI)
+	test.NotEmpty(t, exportURI)
+
+	do := func(method, uri, body string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(method, uri, strings.NewReader(body))
+		test.NoError(t, err)
+		assets.AuthentifyRequest(t, req, u, pass)
+		w := httptest.NewRecorder()
+		router.mux.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do("POST", importURI+"?format=yaml", "name: app1\nvariables:\n  foo:\n    value: bar\n")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	//1. The export is annotated with the last modification and the last import
+	w = do("GET", exportURI+"?format=yaml&annotate=true", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	body := w.Body.String()
+	assert.True(t, strings.HasPrefix(body, "# Application app1 of project "+proj.Key+"\n# Last modified: "), body)
+	assert.Contains(t, body, " by "+u.Username+"\n")
+
+	//2. The annotated export is imported back
+	w = do("POST", importURI+"?format=yaml&forceUpdate=true", body)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = do("GET", exportURI+"?format=hcl&annotate=true", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, strings.HasPrefix(w.Body.String(), "// Application app1"), w.Body.String())
+
+	//3. JSON has no comments
+	w = do("GET", exportURI+"?format=json&annotate=true", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,2154 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-gorp/gorp"
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+
+	"github.com/ovh/cds/engine/api/application"
+	"github.com/ovh/cds/engine/api/businesscontext"
+	"github.com/ovh/cds/engine/api/cache"
+	"github.com/ovh/cds/engine/api/environment"
+	"github.com/ovh/cds/engine/api/group"
+	"github.com/ovh/cds/engine/api/hook"
+	"github.com/ovh/cds/engine/api/notification"
+	"github.com/ovh/cds/engine/api/pipeline"
+	"github.com/ovh/cds/engine/api/poller"
+	"github.com/ovh/cds/engine/api/project"
+	"github.com/ovh/cds/engine/api/repositoriesmanager"
+	"github.com/ovh/cds/engine/api/sanity"
+	"github.com/ovh/cds/engine/api/scheduler"
+	"github.com/ovh/cds/sdk"
+	"github.com/ovh/cds/sdk/exportentities"
+	"github.com/ovh/cds/sdk/log"
+)
+
+func importApplicationHandler(w http.ResponseWriter, r *http.Request, db *gorp.DbMap, c *businesscontext.Ctx) error {
+	limitImportBody(w, r)
+	if err := checkImportRateLimit(w, importProjectKey(mux.Vars(r))); err != nil {
+		return err
+	}
+
+	if !isImportStream(r) {
+		return doImportApplication(w, r, db, c, nil)
+	}
+
+	// Messages are sent as soon as they are produced, the last event contains the result of the import
+	stream, errS := newImportStream(w, r)
+	if errS != nil {
+		return errS
+	}
+	if err := doImportApplication(w, r, db, c, stream); err != nil {
+		return stream.fail(err)
+	}
+	return nil
+}
+
+//importApplicationByNameHandler imports an application named in the url. The permissions are checked on
+//this application, and the payload must have the same name.
+func importApplicationByNameHandler(w http.ResponseWriter, r *http.Request, db *gorp.DbMap, c *businesscontext.Ctx) error {
+	return importApplicationHandler(w, r, db, c)
+}
+
+//patchApplicationHandler applies a JSON merge patch (RFC 7386) on the exported application of the url, and
+//imports the result as an update of the application. The patch can't rename the application.
+func patchApplicationHandler(w http.ResponseWriter, r *http.Request, db *gorp.DbMap, c *businesscontext.Ctx) error {
+	vars := mux.Vars(r)
+	key := vars["key"]
+	appName := vars["permApplicationName"]
+
+	if err := r.ParseForm(); err != nil {
+		return sdk.WrapError(sdk.ErrWrongRequest, "patchApplicationHandler> Unable to parse form: %s", err)
+	}
+	if r.Form.Get("url") != "" || r.Form.Get("repo") != "" {
+		return sdk.WrapError(sdk.ErrWrongRequest, "patchApplicationHandler> The patch is read from the body only")
+	}
+
+	patch, errR := readImportBody(r.Body, r.Header.Get("Content-Encoding"))
+	if errR != nil {
+		return sdk.WrapError(importBodyError(errR), "patchApplicationHandler> Unable to read body: %s", errR)
+	}
+
+	proj, errP := project.Load(db, key, c.User)
+	if errP != nil {
+		return sdk.WrapError(errP, "patchApplicationHandler> Unable to load project %s", key)
+	}
+
+	// The secrets are exported masked, and the masked values are kept by the import
+	current, errE := application.Export(db, proj, appName, c.User, nil)
+	if errE != nil {
+		return sdk.WrapError(errE, "patchApplicationHandler> Unable to export application %s", appName)
+	}
+	doc, errM := json.Marshal(current)
+	if errM != nil {
+		return sdk.WrapError(errM, "patchApplicationHandler> Unable to marshal application %s", appName)
+	}
+
+	patched, errPatch := exportentities.MergePatch(doc, patch)
+	if errPatch != nil {
+		return sdk.WrapError(sdk.ErrImportParse, "patchApplicationHandler> Unable to patch application %s: %s", appName, errPatch)
+	}
+
+	// The patched application is imported as an update, with the other options of the request
+	r.Body = ioutil.NopCloser(bytes.NewReader(patched))
+	r.Header.Del("Content-Encoding")
+	r.Header.Set("Content-Type", "application/json")
+	r.Form.Set("format", "json")
+	r.Form.Set("forceUpdate", "true")
+	return importApplicationHandler(w, r, db, c)
+}
+
+//doImportApplication imports the application. If stream is set, the messages and the result are sent on the stream.
+func doImportApplication(w http.ResponseWriter, r *http.Request, db *gorp.DbMap, c *businesscontext.Ctx, stream *importStream) error {
+	vars := mux.Vars(r)
+	key := importProjectKey(vars)
+	format := r.FormValue("format")
+	forceUpdate := FormBool(r, "forceUpdate")
+	dryRun := FormBool(r, "dryRun")
+	regenerateKeys := FormBool(r, "regenerateKeys")
+	// By default, the import fails if any resource can't be created
+	allOrNothing := r.FormValue("allOrNothing") == "" || FormBool(r, "allOrNothing")
+	prune := FormBool(r, "prune")
+	keepPermissions := FormBool(r, "keepPermissions")
+	failOnSanityError := FormBool(r, "failOnSanityError")
+	verify := FormBool(r, "verify")
+	caseInsensitiveEnv := FormBool(r, "caseInsensitiveEnv")
+	// The created hooks and pollers don't trigger the pipelines until the application is enabled
+	disabled := FormBool(r, "disabled")
+	// An existing application is only updated if the payload has a greater generation
+	respectGeneration := FormBool(r, "respectGeneration")
+	// The hooks and the pollers are neither created nor pruned, when the repositories manager can't be reached
+	skipHooks := FormBool(r, "skipHooks")
+	// Only the group permissions are applied on the existing application
+	permissionsOnly := r.FormValue("only") == importOnlyPermissions
+	// The application is exported as it is stored once imported, in the result of the import
+	echo := FormBool(r, "echo")
+	structured := r.FormValue("messageFormat") == "structured" || r.Header.Get("Accept") == "application/json" || echo
+
+	if _, err := importVerbosity(r); err != nil {
+		return sdk.WrapError(err, "importApplicationHandler> Invalid verbosity")
+	}
+
+	callbackURL, errC := parseImportCallbackURL(r.FormValue("callbackURL"))
+	if errC != nil {
+		return sdk.WrapError(sdk.ErrWrongRequest, "importApplicationHandler> Invalid callback url: %s", errC)
+	}
+
+	// From here on, the callback is notified of the outcome of the import, with the messages collected so far
+	// if the import stops before its result is computed
+	callbackStatus := importCallbackFail
+	callbackApp := vars["permApplicationName"]
+	var callbackResult *sdk.ImportResult
+	collectCallbackMessages := func() []sdk.Message { return nil }
+	if callbackURL != nil {
+		defer func() {
+			res := callbackResult
+			if res == nil {
+				res = &sdk.ImportResult{Messages: []sdk.StructuredMessage{}}
+				al := r.Header.Get("Accept-Language")
+				for _, m := range sdk.DedupMessages(collectCallbackMessages()) {
+					res.Summary.Add(m)
+					if sm := m.Structured(al); sm.Message != "" {
+						res.Messages = append(res.Messages, sm)
+					}
+				}
+			}
+			sendImportCallback(callbackURL, importCallback{
+				ProjectKey:  key,
+				Application: callbackApp,
+				Status:      callbackStatus,
+				Result:      res,
+			})
+		}()
+	}
+
+	renames, errR := parseImportRenames(r.Form["rename"])
+	if errR != nil {
+		return sdk.WrapError(sdk.ErrWrongRequest, "importApplicationHandler> %s", errR)
+	}
+
+	overrides, errO := parseImportOverrides(r.FormValue("overrides"))
+	if errO != nil {
+		return sdk.WrapError(sdk.ErrWrongRequest, "importApplicationHandler> Invalid overrides: %s", errO)
+	}
+
+	if permissionsOnly && keepPermissions {
+		return sdk.WrapError(sdk.ErrWrongRequest, "importApplicationHandler> keepPermissions can't be set when only permissions are imported")
+	}
+
+	// Load project
+	proj, errp := project.Load(db, key, c.User, project.LoadOptions.Default, project.LoadOptions.WithGroups)
+	if errp != nil {
+		return sdk.WrapError(errp, "importApplicationHandler> Unable to load project %s", key)
+	}
+
+	// A retried import returns the result of the first committed attempt
+	idempotencyKey := importIdempotencyCacheKey(r, "application", proj.Key, c.User)
+	if idempotencyKey != "" {
+		res := sdk.ImportResult{}
+		if cache.Get(idempotencyKey, &res) {
+			if stream != nil {
+				callbackStatus = importCallbackSuccess
+				callbackResult = &res
+				for _, m := range res.Messages {
+					stream.sendMessage(m)
+				}
+				return stream.result(http.StatusOK, true, res.Summary, res.Export)
+			}
+			callbackStatus = importCallbackSuccess
+			callbackResult = &res
+			w.Header().Set("X-Idempotent-Replay", "true")
+			return writeImportResult(w, r, res, structured, http.StatusOK)
+		}
+	}
+
+	app, payload, errA := readApplicationImportPayload(db, r, proj.Key, format)
+	if errA != nil {
+		if len(payload.messages) > 0 {
+			return writeImportPayloadMessages(w, r, payload.messages, errA, structured, stream)
+		}
+		return sdk.WrapError(errA, "importApplicationHandler> Unable to read application")
+	}
+	callbackApp = app.Name
+
+	var onMessage func(sdk.Message)
+	if stream != nil {
+		onMessage = stream.message
+	}
+	msgChan, collectMessages := newMessageCollectorFunc(onMessage)
+	defer collectMessages()
+	collectCallbackMessages = collectMessages
+
+	// The import stops when the client disconnects
+	ctxDB := newContextExecutor(r.Context(), db)
+
+	// The application is always imported in the project of the url
+	renameApplicationImport(proj, app, renames, msgChan)
+	callbackApp = app.Name
+
+	// The permissions were checked on the application of the url
+	if name := vars["permApplicationName"]; name != "" && app.Name != name {
+		return sdk.WrapError(sdk.ErrWrongRequest, "importApplicationHandler> Application name %s does not match %s", app.Name, name)
+	}
+	sendImportRepositorySource(payload.repository, msgChan)
+	sendApplicationImportMerge(app, payload.merge, msgChan)
+	sendDuplicatePipelines(app, payload.duplicates, msgChan)
+	sendNotificationExpansions(app, payload.notifications, msgChan)
+
+	// Only import the selected pipelines
+	if only := r.FormValue("only"); only != "" && !permissionsOnly {
+		if err := filterApplicationImport(proj, app, strings.Split(only, ","), msgChan); err != nil {
+			return sdk.WrapError(err, "importApplicationHandler> Unable to filter pipelines of application %s", app.Name)
+		}
+	}
+
+	if err := overrideApplicationImport(app, overrides, msgChan); err != nil {
+		return sdk.WrapError(err, "importApplicationHandler> Unable to override values of application %s", app.Name)
+	}
+
+	// The references to external secrets are resolved once the values are final
+	if !permissionsOnly {
+		if err := resolveApplicationImportSecrets(importSecretResolver, app, msgChan); err != nil {
+			return sdk.WrapError(err, "importApplicationHandler> Unable to resolve secrets of application %s", app.Name)
+		}
+	}
+
+	// Concurrent imports of the application wait for each other, or fail at once, from the existence check to the commit
+	if !dryRun {
+		wait := time.Duration(viper.GetInt(viperImportLockWait)) * time.Second
+		unlock, errL := lockApplicationImport(r.Context(), db.Db, proj.Key, app.Name, wait)
+		if errL != nil {
+			return sdk.WrapError(errL, "importApplicationHandler> Unable to lock application %s", app.Name)
+		}
+		defer unlock()
+	}
+
+	// Check if application exists
+	exist, errE := application.Exists(db, proj.Key, app.Name)
+	if errE != nil {
+		return sdk.WrapError(errE, "importApplicationHandler> Unable to check if application %s exists", app.Name)
+	}
+
+	if permissionsOnly && !exist {
+		return sdk.WrapError(sdk.ErrApplicationNotFound, "importApplicationHandler> Application %s does not exist", app.Name)
+	}
+
+	if exist && !forceUpdate && !permissionsOnly {
+		return sdk.ErrApplicationExist
+	}
+
+	if err := checkApplicationImportPrecondition(ctxDB, r, proj, app.Name, exist, c.User); err != nil {
+		return sdk.WrapError(err, "importApplicationHandler> Precondition failed for application %s", app.Name)
+	}
+
+	// The permissions of the payload are ignored, the stored ones are kept
+	if exist && keepPermissions {
+		app.ApplicationGroups = nil
+		msgChan <- sdk.NewMessage(sdk.MsgAppImportPermissionsKept, app.Name)
+	}
+
+	if disabled && !permissionsOnly {
+		disableApplicationImport(app, exist)
+	}
+
+	if skipHooks && !permissionsOnly {
+		skipApplicationImportHooks(app, msgChan)
+	}
+
+	if caseInsensitiveEnv && !permissionsOnly {
+		if err := resolveApplicationImportEnvironmentsFold(ctxDB, proj, app, msgChan); err != nil {
+			return sdk.WrapError(err, "importApplicationHandler> Unable to resolve environments of application %s", app.Name)
+		}
+	}
+
+	if permissionsOnly {
+		if err := loadApplicationImportGroups(ctxDB, app, FormBool(r, "ignoreUnknownGroups"), msgChan); err != nil {
+			return sdk.WrapError(err, "importApplicationHandler> Unable to load groups of application %s", app.Name)
+		}
+	} else if err := loadApplicationImportDependencies(ctxDB, proj, app, FormBool(r, "ignoreUnknownGroups"), msgChan); err != nil {
+		return sdk.WrapError(err, "importApplicationHandler> Unable to load dependencies of application %s", app.Name)
+	}
+
+	tx, errBegin := db.Begin()
+	if errBegin != nil {
+		return sdk.WrapError(errBegin, "importApplicationHandler> Cannot start transaction")
+	}
+
+	defer tx.Rollback()
+
+	if err := project.LockByKey(tx, proj.Key); err != nil {
+		return sdk.WrapError(err, "importApplicationHandler> Unable to lock project")
+	}
+
+	var globalError error
+	// The generation is checked once the project is locked, so that concurrent imports are ordered
+	if respectGeneration && exist {
+		globalError = checkApplicationImportGeneration(tx, proj, app, msgChan)
+	}
+	if globalError == nil {
+		globalError = beforeApplicationImport(importHooks, proj, app, msgChan)
+	}
+	if globalError == nil && permissionsOnly {
+		globalError = application.ImportPermissions(newContextExecutor(r.Context(), tx), proj, app, prune, c.User, msgChan)
+	} else if globalError == nil {
+		globalError = importApplication(newContextExecutor(r.Context(), tx), proj, app, exist, regenerateKeys, allOrNothing, prune, skipHooks, msgChan, c.User)
+	}
+
+	allMsg := sdk.DedupMessages(collectMessages())
+	al := r.Header.Get("Accept-Language")
+	msgList := []sdk.StructuredMessage{}
+	summary := sdk.ImportSummary{}
+
+	for _, m := range allMsg {
+		summary.Add(m)
+		sm := m.Structured(al)
+		if sm.Message != "" {
+			msgList = append(msgList, sm)
+		}
+	}
+
+	log.Debug("importApplicationHandler >>> %v", msgList)
+
+	// The callback is notified with the result, once the transaction is committed or has failed
+	callbackResult = &sdk.ImportResult{Messages: msgList, Summary: summary}
+
+	if globalError != nil {
+		myError, ok := globalError.(*sdk.Error)
+		if ok && stream != nil {
+			return globalError
+		}
+		if ok {
+			if structured {
+				errMsg, _ := sdk.ProcessError(myError, al)
+				msgList = append(msgList, sdk.StructuredMessage{Level: sdk.MessageLevelError, Message: errMsg})
+			}
+			return writeImportMessages(w, r, msgList, summary, structured, myError.Status)
+		}
+		return sdk.WrapError(globalError, "importApplicationHandler> Unable import application")
+	}
+
+	if err := project.UpdateLastModified(tx, c.User, proj); err != nil {
+		return sdk.WrapError(err, "importApplicationHandler> Unable to update project")
+	}
+
+	// The resources which are not imported are not checked
+	var ws []sdk.Warning
+	if !permissionsOnly {
+		var errW error
+		ws, errW = sanity.ApplicationWarnings(proj, app, al)
+		if errW != nil {
+			return sdk.WrapError(errW, "importApplicationHandler> Cannot compute warnings")
+		}
+		summary.Schedules = applicationImportSchedules(app, time.Now())
+	}
+	summary.Warnings = len(ws)
+
+	// Warnings don't fail the import, they are returned with the messages. The sanity errors fail it if
+	// failOnSanityError is set, and the transaction is rolled back.
+	var sanityErrors int
+	for _, warn := range ws {
+		level := sdk.MessageLevelWarning
+		if warn.Level == sdk.MessageLevelError {
+			level = sdk.MessageLevelError
+			sanityErrors++
+		}
+		sm := sdk.StructuredMessage{Level: level, Message: warn.Message}
+		msgList = append(msgList, sm)
+		if stream != nil {
+			stream.sendMessage(sm)
+		}
+	}
+	if failOnSanityError && sanityErrors > 0 {
+		log.Warning("importApplicationHandler> %d sanity errors in application %s", sanityErrors, app.Name)
+		if stream != nil {
+			return sdk.ErrSanityCheckFailed
+		}
+		if structured {
+			errMsg, _ := sdk.ProcessError(sdk.ErrSanityCheckFailed, al)
+			msgList = append(msgList, sdk.StructuredMessage{Level: sdk.MessageLevelError, Message: errMsg})
+		}
+		return writeImportMessages(w, r, msgList, summary, structured, sdk.ErrSanityCheckFailed.Status)
+	}
+
+	// In dry run mode, the transaction is rolled back and warnings are only computed. The hooks are only
+	// recorded in the outbox of the transaction: they are discarded before the response and never reach
+	// the repositories manager.
+	if dryRun {
+		if err := tx.Rollback(); err != nil {
+			return sdk.WrapError(err, "importApplicationHandler> Cannot rollback dry run of application %s", app.Name)
+		}
+		callbackStatus = importCallbackDryRun
+		if stream != nil {
+			return stream.result(http.StatusOK, false, summary, "")
+		}
+		return writeImportMessages(w, r, msgList, summary, structured, http.StatusOK)
+	}
+
+	// The import is recorded with the application, in the same transaction
+	audit := &sdk.ImportAudit{
+		ProjectKey:      proj.Key,
+		ApplicationName: app.Name,
+		Author:          c.User.Username,
+		Format:          payload.format.String(),
+		ForceUpdate:     forceUpdate,
+		PayloadHash:     payload.hash,
+		Summary:         summary,
+		Payload:         payload.data,
+	}
+	if err := application.InsertImportAudit(tx, audit); err != nil {
+		return sdk.WrapError(err, "importApplicationHandler> Unable to record import of application %s", app.Name)
+	}
+
+	if err := r.Context().Err(); err != nil {
+		return sdk.WrapError(err, "importApplicationHandler> Import canceled")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return sdk.WrapError(err, "importApplicationHandler> Cannot commit transaction")
+	}
+	callbackStatus = importCallbackSuccess
+	afterApplicationImport(importHooks, proj, app, allMsg)
+
+	// The application is imported, the warnings returned above are stored on a best effort basis
+	if !permissionsOnly {
+		if err := sanity.CheckApplication(db, proj, app); err != nil {
+			log.Warning("importApplicationHandler> Cannot store warnings of application %s: %s", app.Name, err)
+		}
+	}
+
+	// The created resources are reloaded, to catch the ones which are not stored
+	if verify && !permissionsOnly {
+		msgs, errV := verifyApplicationImport(db, proj, app)
+		if errV != nil {
+			return sdk.WrapError(errV, "importApplicationHandler> Unable to verify application %s", app.Name)
+		}
+		for _, m := range msgs {
+			summary.Add(m)
+			sm := m.Structured(al)
+			msgList = append(msgList, sm)
+			if stream != nil {
+				stream.sendMessage(sm)
+			}
+		}
+	}
+
+	var export string
+	if echo {
+		exportChan, collectExport := newMessageCollector()
+		var errE error
+		export, errE = echoApplicationImport(db, proj, app.Name, payload.format, c.User, exportChan)
+		if errE != nil {
+			collectExport()
+			return sdk.WrapError(errE, "importApplicationHandler> Unable to export application %s", app.Name)
+		}
+		// The exported application differs from the payload by the fields omitted by the export
+		for _, m := range collectExport() {
+			summary.Add(m)
+			sm := m.Structured(al)
+			msgList = append(msgList, sm)
+			if stream != nil {
+				stream.sendMessage(sm)
+			}
+		}
+	}
+
+	res := sdk.ImportResult{Messages: msgList, Summary: summary, Export: export}
+	if idempotencyKey != "" {
+		cache.SetWithTTL(idempotencyKey, res, importIdempotencyTTL)
+	}
+
+	if stream != nil {
+		return stream.result(http.StatusOK, true, summary, export)
+	}
+	return writeImportResult(w, r, res, structured, http.StatusOK)
+}
+
+//echoApplicationImport returns the export of an imported application, in the format of its payload
+func echoApplicationImport(db gorp.SqlExecutor, proj *sdk.Project, appName string, f exportentities.Format, u *sdk.User, msgChan chan<- sdk.Message) (string, error) {
+	a, errE := application.Export(db, proj, appName, u, msgChan)
+	if errE != nil {
+		return "", sdk.WrapError(errE, "echoApplicationImport> Unable to load application %s", appName)
+	}
+	b, errM := exportentities.Marshal(a, f)
+	if errM != nil {
+		return "", sdk.WrapError(errM, "echoApplicationImport> Unable to marshal application %s", appName)
+	}
+	return string(b), nil
+}
+
+//getImportAuditsHandler returns the last application imports of a project, most recent first
+func getImportAuditsHandler(w http.ResponseWriter, r *http.Request, db *gorp.DbMap, c *businesscontext.Ctx) error {
+	vars := mux.Vars(r)
+	key := vars["permProjectKey"]
+
+	limit := 20
+	if l := r.FormValue("limit"); l != "" {
+		var errA error
+		limit, errA = strconv.Atoi(l)
+		if errA != nil || limit <= 0 {
+			return sdk.WrapError(sdk.ErrWrongRequest, "getImportAuditsHandler> Invalid limit %s", l)
+		}
+	}
+
+	audits, errL := application.LoadImportAudits(db, key, r.FormValue("application"), limit)
+	if errL != nil {
+		return sdk.WrapError(errL, "getImportAuditsHandler> Unable to load imports of project %s", key)
+	}
+	return WriteJSON(w, r, audits, http.StatusOK)
+}
+
+//replayImportAuditHandler imports again the payload of a recorded import. The request is handled as an
+//import of this payload, with the options of the replay request, so it is validated in the same way and
+//returns its own messages. The force update option of the recorded import is kept if it is not given.
+func replayImportAuditHandler(w http.ResponseWriter, r *http.Request, db *gorp.DbMap, c *businesscontext.Ctx) error {
+	vars := mux.Vars(r)
+	key := vars["permProjectKey"]
+
+	id, errI := strconv.ParseInt(vars["id"], 10, 64)
+	if errI != nil {
+		return sdk.WrapError(sdk.ErrWrongRequest, "replayImportAuditHandler> Invalid id %s", vars["id"])
+	}
+
+	audit, errL := application.LoadImportAudit(db, key, id)
+	if errL != nil {
+		return sdk.WrapError(errL, "replayImportAuditHandler> Unable to load import %d", id)
+	}
+	if audit.Payload == nil {
+		return sdk.WrapError(sdk.ErrImportPayloadExpired, "replayImportAuditHandler> Payload of import %d is no longer kept", id)
+	}
+
+	q := r.URL.Query()
+	q.Del("url")
+	q.Set("format", audit.Format)
+	if q.Get("forceUpdate") == "" {
+		q.Set("forceUpdate", strconv.FormatBool(audit.ForceUpdate))
+	}
+	r.URL.RawQuery = q.Encode()
+	r.Form = nil
+	r.PostForm = nil
+	r.Header.Del("Content-Type")
+	r.Header.Del("Content-Encoding")
+	r.Body = ioutil.NopCloser(bytes.NewReader(audit.Payload))
+
+	return importApplicationHandler(w, r, db, c)
+}
+
+//importAuditCleanerRoutine removes the payloads of the imports older than the retention delay
+func importAuditCleanerRoutine(c context.Context, DBFunc func() *gorp.DbMap) {
+	tick := time.NewTicker(30 * time.Minute).C
+	for {
+		select {
+		case <-c.Done():
+			if c.Err() != nil {
+				log.Error("Exiting importAuditCleanerRoutine: %v", c.Err())
+			}
+			return
+		case <-tick:
+			retention := viper.GetInt(viperImportAuditRetention)
+			if retention <= 0 {
+				retention = defaultImportAuditRetention
+			}
+			db := DBFunc()
+			if db == nil {
+				continue
+			}
+			n, err := application.DeleteImportAuditPayloads(db, time.Now().AddDate(0, 0, -retention))
+			if err != nil {
+				log.Warning("importAuditCleanerRoutine> %s", err)
+				continue
+			}
+			if n > 0 {
+				log.Debug("importAuditCleanerRoutine> %d import payloads deleted", n)
+			}
+		}
+	}
+}
+
+//diffImportApplicationHandler compares the application an import would write with the stored application.
+//The payload goes through the same steps as on import, the stored application is compared as it is exported.
+func diffImportApplicationHandler(w http.ResponseWriter, r *http.Request, db *gorp.DbMap, c *businesscontext.Ctx) error {
+	vars := mux.Vars(r)
+	key := vars["permProjectKey"]
+	format := r.FormValue("format")
+
+	renames, errR := parseImportRenames(r.Form["rename"])
+	if errR != nil {
+		return sdk.WrapError(sdk.ErrWrongRequest, "diffImportApplicationHandler> %s", errR)
+	}
+
+	overrides, errO := parseImportOverrides(r.FormValue("overrides"))
+	if errO != nil {
+		return sdk.WrapError(sdk.ErrWrongRequest, "diffImportApplicationHandler> Invalid overrides: %s", errO)
+	}
+
+	proj, errp := project.Load(db, key, c.User, project.LoadOptions.Default, project.LoadOptions.WithGroups)
+	if errp != nil {
+		return sdk.WrapError(errp, "diffImportApplicationHandler> Unable to load project %s", key)
+	}
+
+	app, _, errA := readApplicationImportPayload(db, r, proj.Key, format)
+	if errA != nil {
+		return sdk.WrapError(errA, "diffImportApplicationHandler> Unable to read application")
+	}
+
+	// The messages of the import are not returned by the diff
+	msgChan, collectMessages := newMessageCollector()
+	defer collectMessages()
+
+	renameApplicationImport(proj, app, renames, msgChan)
+	if only := r.FormValue("only"); only != "" {
+		if err := filterApplicationImport(proj, app, strings.Split(only, ","), msgChan); err != nil {
+			return sdk.WrapError(err, "diffImportApplicationHandler> Unable to filter pipelines of application %s", app.Name)
+		}
+	}
+	if err := overrideApplicationImport(app, overrides, msgChan); err != nil {
+		return sdk.WrapError(err, "diffImportApplicationHandler> Unable to override values of application %s", app.Name)
+	}
+	if err := resolveApplicationImportSecrets(importSecretResolver, app, msgChan); err != nil {
+		return sdk.WrapError(err, "diffImportApplicationHandler> Unable to resolve secrets of application %s", app.Name)
+	}
+	if err := loadApplicationImportDependencies(db, proj, app, FormBool(r, "ignoreUnknownGroups"), msgChan); err != nil {
+		return sdk.WrapError(err, "diffImportApplicationHandler> Unable to load dependencies of application %s", app.Name)
+	}
+
+	exist, errE := application.Exists(db, proj.Key, app.Name)
+	if errE != nil {
+		return sdk.WrapError(errE, "diffImportApplicationHandler> Unable to check if application %s exists", app.Name)
+	}
+
+	old := &sdk.Application{Name: app.Name}
+	if exist {
+		a, errL := application.Export(db, proj, app.Name, c.User, nil)
+		if errL != nil {
+			return sdk.WrapError(errL, "diffImportApplicationHandler> Unable to export application %s", app.Name)
+		}
+		var errOld error
+		old, errOld = a.Application()
+		if errOld != nil {
+			return sdk.WrapError(errOld, "diffImportApplicationHandler> Unable to export application %s", app.Name)
+		}
+	}
+
+	return WriteJSON(w, r, exportentities.DiffApplications(old, app), http.StatusOK)
+}
+
+//previewImportApplicationHooksHandler returns the hooks an import would add or remove on the repository of
+//the application. The current hooks are read on the repositories manager, nothing is written.
+func previewImportApplicationHooksHandler(w http.ResponseWriter, r *http.Request, db *gorp.DbMap, c *businesscontext.Ctx) error {
+	vars := mux.Vars(r)
+	key := vars["permProjectKey"]
+	prune := FormBool(r, "prune")
+
+	app, _, errA := readApplicationImportPayload(db, r, key, r.FormValue("format"))
+	if errA != nil {
+		return sdk.WrapError(errA, "previewImportApplicationHooksHandler> Unable to read application")
+	}
+	if app.RepositoriesManager == nil || app.RepositoryFullname == "" {
+		return sdk.WrapError(sdk.ErrNoReposManager, "previewImportApplicationHooksHandler> Application %s is not attached to a repository", app.Name)
+	}
+
+	exist, errE := application.Exists(db, key, app.Name)
+	if errE != nil {
+		return sdk.WrapError(errE, "previewImportApplicationHooksHandler> Unable to check if application %s exists", app.Name)
+	}
+
+	stored := []sdk.Hook{}
+	if exist {
+		oldApp, errL := application.LoadByName(db, key, app.Name, c.User, application.LoadOptions.WithHooks)
+		if errL != nil {
+			return sdk.WrapError(errL, "previewImportApplicationHooksHandler> Unable to load application %s", app.Name)
+		}
+		stored = oldApp.Hooks
+	}
+
+	d, errP := hook.PreviewHooks(db, key, app.RepositoriesManager, app.RepositoryFullname, stored, applicationImportHookPipelines(app, exist), exist && prune)
+	if errP != nil {
+		return sdk.WrapError(errP, "previewImportApplicationHooksHandler> Unable to preview hooks of application %s", app.Name)
+	}
+	return WriteJSON(w, r, d, http.StatusOK)
+}
+
+//applicationImportHookPipelines returns the pipelines which have a hook once the application is imported.
+//As on import, a new application without hooks gets a hook on its first pipeline.
+func applicationImportHookPipelines(app *sdk.Application, exist bool) []string {
+	pipelines := make([]string, 0, len(app.Hooks))
+	for _, h := range app.Hooks {
+		pipelines = append(pipelines, h.Pipeline.Name)
+	}
+	if !exist && len(pipelines) == 0 && len(app.Pipelines) > 0 {
+		pipelines = append(pipelines, app.Pipelines[0].Pipeline.Name)
+	}
+	return pipelines
+}
+
+//validateApplicationHandler checks an application to import without any write in database, and returns
+//all the problems found as structured messages
+func validateApplicationHandler(w http.ResponseWriter, r *http.Request, db *gorp.DbMap, c *businesscontext.Ctx) error {
+	vars := mux.Vars(r)
+	key := vars["permProjectKey"]
+	al := r.Header.Get("Accept-Language")
+
+	if _, err := importVerbosity(r); err != nil {
+		return sdk.WrapError(err, "validateApplicationHandler> Invalid verbosity")
+	}
+
+	proj, errp := project.Load(db, key, c.User)
+	if errp != nil {
+		return sdk.WrapError(errp, "validateApplicationHandler> Unable to load project %s", key)
+	}
+
+	app, payload, errA := readApplicationImportPayload(db, r, key, r.FormValue("format"))
+	if errA != nil {
+		if len(payload.messages) > 0 {
+			return writeImportPayloadMessages(w, r, payload.messages, errA, true, nil)
+		}
+		errMsg, status := sdk.ProcessError(errA, al)
+		msgList := []sdk.StructuredMessage{{Level: sdk.MessageLevelError, Message: errMsg}}
+		return writeImportMessages(w, r, msgList, sdk.ImportSummary{}, true, status)
+	}
+
+	msgChan, collectMessages := newMessageCollector()
+	defer collectMessages()
+
+	sendImportRepositorySource(payload.repository, msgChan)
+	sendApplicationImportMerge(app, payload.merge, msgChan)
+	sendDuplicatePipelines(app, payload.duplicates, msgChan)
+	sendNotificationExpansions(app, payload.notifications, msgChan)
+	normalizeApplicationImportEnvironments(app)
+	if FormBool(r, "caseInsensitiveEnv") {
+		if err := resolveApplicationImportEnvironmentsFold(db, proj, app, msgChan); err != nil {
+			return sdk.WrapError(err, "validateApplicationHandler> Unable to resolve environments of application %s", app.Name)
+		}
+	}
+
+	// All the checks are run to report every problem at once
+	checks := []func() error{
+		func() error { return application.CheckImportFields(app, msgChan) },
+		func() error { return application.CheckImportVariables(app, msgChan) },
+		func() error { return application.CheckImportPermissions(app, msgChan) },
+		func() error { return application.CheckImportSchedulers(app, msgChan) },
+		func() error { return application.CheckImportHooks(app, msgChan) },
+		func() error { return application.CheckImportSchedulerArgs(db, proj, app, msgChan) },
+		func() error { return application.CheckImportNotifications(app, msgChan) },
+		func() error { return application.CheckImportPrerequisites(app, msgChan) },
+		func() error { return application.CheckImportTriggers(db, proj, app, msgChan) },
+		func() error { return application.CheckImportDestPipelines(db, proj, app, msgChan) },
+		func() error { return beforeApplicationImport(importHooks, proj, app, msgChan) },
+	}
+	var errCheck error
+	for _, check := range checks {
+		if err := check(); err != nil && errCheck == nil {
+			errCheck = err
+		}
+	}
+
+	msgList := []sdk.StructuredMessage{}
+	for _, m := range sdk.DedupMessages(collectMessages()) {
+		if sm := m.Structured(al); sm.Message != "" {
+			msgList = append(msgList, sm)
+		}
+	}
+
+	if errCheck != nil {
+		if _, ok := errors.Cause(errCheck).(*sdk.Error); !ok {
+			return sdk.WrapError(errCheck, "validateApplicationHandler> Unable to validate application %s", app.Name)
+		}
+		return writeImportMessages(w, r, msgList, sdk.ImportSummary{}, true, http.StatusBadRequest)
+	}
+	summary := sdk.ImportSummary{Schedules: applicationImportSchedules(app, time.Now())}
+	return writeImportMessages(w, r, msgList, summary, true, http.StatusOK)
+}
+
+//applicationImportPayload describes the payload of an application import
+type applicationImportPayload struct {
+	format exportentities.Format
+	hash   string
+	// data is the imported payload, once an import template is expanded
+	data []byte
+	// messages explains why the payload is rejected, such as the missing parameters of a template
+	messages []sdk.Message
+	// notifications lists where the notifications of the application are set
+	notifications []exportentities.NotificationExpansion
+	// merge describes how the documents of the payload are merged
+	merge exportentities.MergeResult
+	// duplicates counts the entries of the pipelines listed several times, of which the last one is kept
+	duplicates map[string]int
+	// repository is the file of a repository the payload is fetched from
+	repository *importRepositorySource
+}
+
+//readApplicationImportPayload reads the application to import from the url form value, a file of a repository or the body,
+//and transforms it to a sdk.Application. A payload which references an import template of the project is
+//replaced by the expanded template.
+func readApplicationImportPayload(db gorp.SqlExecutor, r *http.Request, projectKey, format string) (*sdk.Application, applicationImportPayload, error) {
+	var none applicationImportPayload
+	var data []byte
+	var f exportentities.Format
+	src := importRepositorySourceFromRequest(r)
+	if src != nil {
+		// Fetch the application from a file of a repository
+		var errFetch error
+		data, f, errFetch = fetchImportRepository(db, projectKey, src, format)
+		if e, ok := errFetch.(importBodyTooLargeError); ok {
+			msgs := []sdk.Message{sdk.NewMessage(sdk.MsgAppImportPayloadTooLarge, e.maxSize)}
+			return nil, applicationImportPayload{messages: msgs}, sdk.WrapError(sdk.ErrImportPayloadTooLarge, "readApplicationImportPayload> %s", e)
+		}
+		if errFetch != nil {
+			return nil, none, sdk.WrapError(errFetch, "readApplicationImportPayload> Unable to fetch %s from %s", src.path, src.repo)
+		}
+	} else if u := r.FormValue("url"); u != "" {
+		// Fetch the application from the url
+		var errFetch error
+		data, f, errFetch = fetchImportURL(u, format)
+		if errFetch != nil {
+			return nil, none, sdk.WrapError(sdk.ErrWrongRequest, "readApplicationImportPayload> Unable to fetch %s : %s", u, errFetch)
+		}
+	} else {
+		// Get body, or the file part of a multipart form
+		body := io.Reader(r.Body)
+		contentType := r.Header.Get("Content-Type")
+		if strings.HasPrefix(contentType, "multipart/form-data") {
+			if err := r.ParseMultipartForm(64 << 20); err != nil {
+				if isRequestBodyTooLarge(err) {
+					return applicationImportPayloadTooLarge(importBodyTooLargeError{maxSize: importBodyMaxSize()})
+				}
+				return nil, none, sdk.WrapError(sdk.ErrWrongRequest, "readApplicationImportPayload> Unable to parse multipart form: %s", err)
+			}
+			file, header, errF := r.FormFile("file")
+			if errF != nil {
+				return nil, none, sdk.WrapError(sdk.ErrWrongRequest, "readApplicationImportPayload> Unable to read file: %s", errF)
+			}
+			defer file.Close()
+			body = file
+			contentType = header.Header.Get("Content-Type")
+		}
+
+		var errRead error
+		data, errRead = readImportBody(body, r.Header.Get("Content-Encoding"))
+		if e, ok := errRead.(importBodyTooLargeError); ok {
+			return applicationImportPayloadTooLarge(e)
+		}
+		if errRead != nil {
+			return nil, none, sdk.WrapError(importBodyError(errRead), "readApplicationImportPayload> Unable to read body: %s", errRead)
+		}
+
+		// Compute format
+		var errF error
+		f, errF = importFormat(format, contentType)
+		if errF != nil {
+			return nil, none, sdk.WrapError(sdk.ErrImportUnknownFormat, "readApplicationImportPayload> Unable to get format: %s", errF)
+		}
+	}
+
+	// The documents of the payload are merged in a single application
+	data, merge, errM := exportentities.MergeDocuments(data, f, func(d []byte, out interface{}) error {
+		return unmarshalImport(d, f, out)
+	})
+	if errM != nil {
+		log.Warning("readApplicationImportPayload> Unable to merge documents: %s", errM)
+		return nil, none, importParseError(errM)
+	}
+
+	data, f, msgs, errT := expandApplicationImportTemplate(db, projectKey, data, f)
+	if errT != nil {
+		return nil, none, sdk.WrapError(errT, "readApplicationImportPayload> Unable to expand template")
+	}
+	if len(msgs) > 0 {
+		return nil, applicationImportPayload{messages: msgs}, sdk.WrapError(sdk.ErrWrongRequest, "readApplicationImportPayload> %d parameters of template are missing", len(msgs))
+	}
+
+	// Parse the application
+	payload, errorParse := parseApplicationImport(data, f)
+	if errorParse != nil {
+		log.Warning("readApplicationImportPayload> Cannot parsing: %s\n", errorParse)
+		return nil, none, importParseError(errorParse)
+	}
+
+	// In strict mode, the fields which would be ignored are rejected
+	if FormBool(r, "strict") {
+		unknown, errU := exportentities.UnknownFields(data, f, payload)
+		if errU != nil {
+			return nil, none, importParseError(errU)
+		}
+		for _, field := range unknown {
+			msgs = append(msgs, sdk.NewMessage(sdk.MsgAppImportUnknownField, field, payload.Name))
+		}
+		if len(msgs) > 0 {
+			return nil, applicationImportPayload{messages: msgs}, sdk.WrapError(sdk.ErrWrongRequest, "readApplicationImportPayload> %d unknown fields in application %s", len(msgs), payload.Name)
+		}
+	}
+
+	// A pipeline listed several times is rejected, unless the last entry is kept by the policy
+	policy := r.FormValue("duplicatePipelines")
+	if policy != "" && policy != importDuplicatePipelinesReject && policy != importDuplicatePipelinesKeepLast {
+		return nil, none, sdk.WrapError(sdk.ErrWrongRequest, "readApplicationImportPayload> Invalid duplicate pipelines policy %s", policy)
+	}
+	duplicates, errD := exportentities.DuplicatePipelines(data, f)
+	if errD != nil {
+		return nil, none, importParseError(errD)
+	}
+	if len(duplicates) > 0 && policy != importDuplicatePipelinesKeepLast {
+		for _, name := range sortedDuplicatePipelines(duplicates) {
+			msgs = append(msgs, sdk.NewMessage(sdk.MsgAppImportDuplicatePipeline, name, duplicates[name], payload.Name))
+		}
+		return nil, applicationImportPayload{messages: msgs}, sdk.WrapError(sdk.ErrWrongRequest, "readApplicationImportPayload> %d duplicate pipelines in application %s", len(duplicates), payload.Name)
+	}
+
+	// The decoders keep only the last of the variables declared several times, whatever the policy
+	duplicateVars, errV := exportentities.DuplicateVariables(data, f)
+	if errV != nil {
+		return nil, none, importParseError(errV)
+	}
+	if len(duplicateVars) > 0 {
+		for _, name := range sortedDuplicatePipelines(duplicateVars) {
+			msgs = append(msgs, sdk.NewMessage(sdk.MsgAppImportVariableDuplicate, name, duplicateVars[name], "variables", payload.Name))
+		}
+		return nil, applicationImportPayload{messages: msgs}, sdk.WrapError(sdk.ErrWrongRequest, "readApplicationImportPayload> %d duplicate variables in application %s", len(duplicateVars), payload.Name)
+	}
+
+	//Transform payload to a sdk.Application
+	app, errA := payload.Application()
+	if errA != nil {
+		log.Warning("readApplicationImportPayload> Unable to parse application %s: %s", payload.Name, errA)
+		return nil, none, sdk.ErrImportParse
+	}
+	sum := sha256.Sum256(data)
+	return app, applicationImportPayload{format: f, hash: hex.EncodeToString(sum[:]), data: data, notifications: payload.ExpandNotifications(), merge: merge, duplicates: duplicates, repository: src}, nil
+}
+
+//applicationImportPayloadTooLarge returns the rejection of a payload bigger than the max size
+func applicationImportPayloadTooLarge(e importBodyTooLargeError) (*sdk.Application, applicationImportPayload, error) {
+	msgs := []sdk.Message{sdk.NewMessage(sdk.MsgAppImportPayloadTooLarge, e.maxSize)}
+	return nil, applicationImportPayload{messages: msgs}, sdk.WrapError(sdk.ErrImportPayloadTooLarge, "readApplicationImportPayload> %s", e)
+}
+
+//parseApplicationImport unmarshals the application according to its format
+func parseApplicationImport(data []byte, f exportentities.Format) (*exportentities.Application, error) {
+	payload := &exportentities.Application{}
+	return payload, unmarshalImport(data, f, payload)
+}
+
+//writeImportMessages writes the messages as localized strings, or as structured messages with the import
+//summary if it has been asked with messageFormat=structured or Accept: application/json. Only the messages
+//kept by the verbosity of the request are written, the summary counts all of them.
+func writeImportMessages(w http.ResponseWriter, r *http.Request, msgList []sdk.StructuredMessage, summary sdk.ImportSummary, structured bool, status int) error {
+	return writeImportResult(w, r, sdk.ImportResult{Messages: msgList, Summary: summary}, structured, status)
+}
+
+//writeImportResult is like writeImportMessages, with the export of the imported application in a structured result
+func writeImportResult(w http.ResponseWriter, r *http.Request, res sdk.ImportResult, structured bool, status int) error {
+	// The verbosity is checked by the handlers before the import
+	verbosity, _ := importVerbosity(r)
+	kept := make([]sdk.StructuredMessage, 0, len(res.Messages))
+	for _, m := range res.Messages {
+		if m.Level.AtLeast(verbosity) {
+			kept = append(kept, m)
+		}
+	}
+
+	if structured {
+		return WriteJSON(w, r, sdk.ImportResult{Messages: kept, Summary: res.Summary, Export: res.Export}, status)
+	}
+	msgListString := make([]string, len(kept))
+	for i := range kept {
+		msgListString[i] = kept[i].Message
+	}
+	return WriteJSON(w, r, msgListString, status)
+}
+
+//verifyApplicationImport reloads the hooks, pollers and notifications of an imported application and returns
+//a message for each of them which is not found
+func verifyApplicationImport(db gorp.SqlExecutor, proj *sdk.Project, app *sdk.Application) ([]sdk.Message, error) {
+	stored, errL := application.LoadByName(db, proj.Key, app.Name, nil)
+	if errL != nil {
+		return nil, sdk.WrapError(errL, "verifyApplicationImport> Unable to load application %s", app.Name)
+	}
+
+	var msgs []sdk.Message
+	hooks, errH := hook.LoadApplicationHooks(db, stored.ID)
+	if errH != nil {
+		return nil, sdk.WrapError(errH, "verifyApplicationImport> Unable to load hooks of application %s", app.Name)
+	}
+	for _, h := range app.Hooks {
+		var found bool
+		for _, sh := range hooks {
+			if sh.Pipeline.ID == h.Pipeline.ID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			msgs = append(msgs, sdk.NewMessage(sdk.MsgAppImportVerifyFailed, "hook", h.Pipeline.Name, app.Name))
+		}
+	}
+
+	pollers, errP := poller.LoadByApplication(db, stored.ID)
+	if errP != nil {
+		return nil, sdk.WrapError(errP, "verifyApplicationImport> Unable to load pollers of application %s", app.Name)
+	}
+	for _, p := range app.RepositoryPollers {
+		var found bool
+		for _, sp := range pollers {
+			if sp.PipelineID == p.Pipeline.ID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			msgs = append(msgs, sdk.NewMessage(sdk.MsgAppImportVerifyFailed, "poller", p.Pipeline.Name, app.Name))
+		}
+	}
+
+	notifs, errN := notification.LoadAllUserNotificationSettings(db, stored.ID)
+	if errN != nil {
+		return nil, sdk.WrapError(errN, "verifyApplicationImport> Unable to load notifications of application %s", app.Name)
+	}
+	for _, n := range app.Notifications {
+		var found bool
+		for _, sn := range notifs {
+			if sn.Pipeline.ID == n.Pipeline.ID && sn.Environment.ID == n.Environment.ID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			msgs = append(msgs, sdk.NewMessage(sdk.MsgAppImportVerifyFailed, "notification", n.Pipeline.Name+"/"+n.Environment.Name, app.Name))
+		}
+	}
+	return msgs, nil
+}
+
+//sendApplicationImportMerge tells from how many documents the application is merged, and which values are overridden
+func sendApplicationImportMerge(app *sdk.Application, merge exportentities.MergeResult, msgChan chan<- sdk.Message) {
+	if merge.Documents <= 1 {
+		return
+	}
+	msgChan <- sdk.NewMessage(sdk.MsgAppImportDocumentsMerged, app.Name, merge.Documents)
+	for _, o := range merge.Overrides {
+		msgChan <- sdk.NewMessage(sdk.MsgAppImportValueOverridden, o.Path, app.Name, o.Document)
+	}
+}
+
+//sendDuplicatePipelines tells which pipelines are listed several times, and only kept once
+func sendDuplicatePipelines(app *sdk.Application, duplicates map[string]int, msgChan chan<- sdk.Message) {
+	for _, name := range sortedDuplicatePipelines(duplicates) {
+		msgChan <- sdk.NewMessage(sdk.MsgAppImportDuplicatePipelineKept, name, duplicates[name], app.Name)
+	}
+}
+
+func sortedDuplicatePipelines(duplicates map[string]int) []string {
+	names := make([]string, 0, len(duplicates))
+	for name := range duplicates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+//sendNotificationExpansions tells on which pipelines and environments the notifications of the application are set
+func sendNotificationExpansions(app *sdk.Application, expansions []exportentities.NotificationExpansion, msgChan chan<- sdk.Message) {
+	for _, e := range expansions {
+		msgChan <- sdk.NewMessage(sdk.MsgAppImportNotificationExpanded, e.Type, app.Name, e.Pipeline, e.Environment)
+	}
+}
+
+//writeImportPayloadMessages rejects an import whose payload can't be read, with the messages explaining
+//why. If stream is set, the messages and the result are sent on the stream.
+func writeImportPayloadMessages(w http.ResponseWriter, r *http.Request, msgs []sdk.Message, err error, structured bool, stream *importStream) error {
+	status := http.StatusBadRequest
+	if e, ok := errors.Cause(err).(*sdk.Error); ok {
+		status = e.Status
+	}
+	al := r.Header.Get("Accept-Language")
+	msgList := make([]sdk.StructuredMessage, len(msgs))
+	summary := sdk.ImportSummary{}
+	for i, m := range msgs {
+		summary.Add(m)
+		msgList[i] = m.Structured(al)
+	}
+
+	if stream != nil {
+		for _, sm := range msgList {
+			stream.sendMessage(sm)
+		}
+		return stream.result(status, false, summary, "")
+	}
+	return writeImportMessages(w, r, msgList, summary, structured, status)
+}
+
+//parseImportRenames parses the rename form values, formatted as oldName:newName
+func parseImportRenames(values []string) (map[string]string, error) {
+	renames := make(map[string]string, len(values))
+	for _, v := range values {
+		t := strings.SplitN(v, ":", 2)
+		if len(t) != 2 || t[0] == "" || t[1] == "" {
+			return nil, fmt.Errorf("Invalid rename %s, expected oldName:newName", v)
+		}
+		renames[t[0]] = t[1]
+	}
+	return renames, nil
+}
+
+//applicationImportOverride overrides the value of a variable of an imported application. The variable is
+//an application variable if there is no pipeline, else a parameter of the pipeline, or of its schedulers
+//on the environment if it is set.
+type applicationImportOverride struct {
+	Pipeline    string `json:"pipeline,omitempty"`
+	Environment string `json:"environment,omitempty"`
+	Key         string `json:"key"`
+	Value       string `json:"value"`
+}
+
+//parseImportOverrides parses the overrides form value, formatted as a json list
+func parseImportOverrides(value string) ([]applicationImportOverride, error) {
+	if value == "" {
+		return nil, nil
+	}
+	overrides := []applicationImportOverride{}
+	if err := json.Unmarshal([]byte(value), &overrides); err != nil {
+		return nil, err
+	}
+	for _, o := range overrides {
+		if o.Key == "" {
+			return nil, fmt.Errorf("Invalid override, key is mandatory")
+		}
+		if o.Pipeline == "" && o.Environment != "" {
+			return nil, fmt.Errorf("Invalid override of %s, an environment requires a pipeline", o.Key)
+		}
+	}
+	return overrides, nil
+}
+
+//overrideApplicationImport applies the overrides on the application. It fails if one of the
+//overridden variables doesn't exist in the application.
+func overrideApplicationImport(app *sdk.Application, overrides []applicationImportOverride, msgChan chan<- sdk.Message) error {
+	for _, o := range overrides {
+		var found bool
+		target := app.Name
+		switch {
+		case o.Pipeline == "":
+			for i := range app.Variable {
+				if app.Variable[i].Name == o.Key {
+					app.Variable[i].Value = o.Value
+					found = true
+				}
+			}
+		case o.Environment == "":
+			target = app.Name + "/" + o.Pipeline
+			for i := range app.Pipelines {
+				if app.Pipelines[i].Pipeline.Name != o.Pipeline {
+					continue
+				}
+				for j := range app.Pipelines[i].Parameters {
+					if app.Pipelines[i].Parameters[j].Name == o.Key {
+						app.Pipelines[i].Parameters[j].Value = o.Value
+						found = true
+					}
+				}
+			}
+		default:
+			target = app.Name + "/" + o.Pipeline + "[" + o.Environment + "]"
+			for i := range app.Schedulers {
+				s := &app.Schedulers[i]
+				if s.PipelineName != o.Pipeline || s.EnvironmentName != o.Environment {
+					continue
+				}
+				for j := range s.Args {
+					if s.Args[j].Name == o.Key {
+						s.Args[j].Value = o.Value
+						found = true
+					}
+				}
+			}
+		}
+
+		if !found {
+			return sdk.WrapError(sdk.ErrNoVariable, "overrideApplicationImport> Variable %s not found on %s", o.Key, target)
+		}
+		if msgChan != nil {
+			msgChan <- sdk.NewMessage(sdk.MsgAppImportOverrideApplied, o.Key, target)
+		}
+	}
+	return nil
+}
+
+//renameApplicationImport renames the application and the applications and environments referenced by
+//its triggers, notifications and schedulers. Triggers which pointed to another project are rewritten
+//to target the project the application is imported in.
+func renameApplicationImport(proj *sdk.Project, app *sdk.Application, renames map[string]string, msgChan chan<- sdk.Message) {
+	rename := func(name *string) {
+		if newName, ok := renames[*name]; ok && *name != "" {
+			if msgChan != nil {
+				msgChan <- sdk.NewMessage(sdk.MsgAppImportRenamed, *name, newName)
+			}
+			*name = newName
+		}
+	}
+
+	rename(&app.Name)
+	for i := range app.Pipelines {
+		for j := range app.Pipelines[i].Triggers {
+			t := &app.Pipelines[i].Triggers[j]
+			if t.DestProject.Key != "" && t.DestProject.Key != proj.Key {
+				if msgChan != nil {
+					msgChan <- sdk.NewMessage(sdk.MsgAppImportTriggerProjectRewritten, t.DestPipeline.Name, proj.Key, t.DestProject.Key)
+				}
+				t.DestProject.Key = proj.Key
+			}
+			rename(&t.DestApplication.Name)
+			rename(&t.SrcEnvironment.Name)
+			rename(&t.DestEnvironment.Name)
+		}
+	}
+	for i := range app.Notifications {
+		rename(&app.Notifications[i].Environment.Name)
+	}
+	for i := range app.Schedulers {
+		rename(&app.Schedulers[i].EnvironmentName)
+	}
+}
+
+//filterApplicationImport keeps only the given pipelines of the application, with their hooks, pollers,
+//notifications and schedulers. Triggers to a pipeline of the application which is not kept are skipped.
+func filterApplicationImport(proj *sdk.Project, app *sdk.Application, only []string, msgChan chan<- sdk.Message) error {
+	keep := make(map[string]bool, len(only))
+	for _, name := range only {
+		if name = strings.TrimSpace(name); name != "" {
+			keep[name] = true
+		}
+	}
+	for name := range keep {
+		var found bool
+		for _, ap := range app.Pipelines {
+			if ap.Pipeline.Name == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return sdk.WrapError(sdk.ErrPipelineNotFound, "filterApplicationImport> Pipeline %s is not in application %s", name, app.Name)
+		}
+	}
+
+	pipelines := []sdk.ApplicationPipeline{}
+	for _, ap := range app.Pipelines {
+		if !keep[ap.Pipeline.Name] {
+			continue
+		}
+		triggers := []sdk.PipelineTrigger{}
+		for _, t := range ap.Triggers {
+			sameProject := t.DestProject.Key == "" || t.DestProject.Key == proj.Key
+			sameApp := t.DestApplication.Name == "" || t.DestApplication.Name == app.Name
+			if sameProject && sameApp && !keep[t.DestPipeline.Name] {
+				if msgChan != nil {
+					msgChan <- sdk.NewMessage(sdk.MsgAppImportTriggerSkipped, ap.Pipeline.Name, t.DestPipeline.Name)
+				}
+				continue
+			}
+			triggers = append(triggers, t)
+		}
+		ap.Triggers = triggers
+		pipelines = append(pipelines, ap)
+	}
+	app.Pipelines = pipelines
+
+	hooks := []sdk.Hook{}
+	for _, h := range app.Hooks {
+		if keep[h.Pipeline.Name] {
+			hooks = append(hooks, h)
+		}
+	}
+	app.Hooks = hooks
+
+	pollers := []sdk.RepositoryPoller{}
+	for _, p := range app.RepositoryPollers {
+		if keep[p.Pipeline.Name] {
+			pollers = append(pollers, p)
+		}
+	}
+	app.RepositoryPollers = pollers
+
+	notifications := []sdk.UserNotification{}
+	for _, n := range app.Notifications {
+		if keep[n.Pipeline.Name] {
+			notifications = append(notifications, n)
+		}
+	}
+	app.Notifications = notifications
+
+	schedulers := []sdk.PipelineScheduler{}
+	for _, s := range app.Schedulers {
+		if keep[s.PipelineName] {
+			schedulers = append(schedulers, s)
+		}
+	}
+	app.Schedulers = schedulers
+
+	return nil
+}
+
+//loadApplicationImportGroups loads the groups of the permissions of an imported application. If ignoreUnknownGroups
+//is set, the permissions of the groups which don't exist are dropped instead of failing.
+func loadApplicationImportGroups(db gorp.SqlExecutor, app *sdk.Application, ignoreUnknownGroups bool, msgChan chan<- sdk.Message) error {
+	groups := make([]sdk.GroupPermission, 0, len(app.ApplicationGroups))
+	for _, eg := range app.ApplicationGroups {
+		g, errg := group.LoadGroup(db, eg.Group.Name)
+		if errg == sdk.ErrGroupNotFound && ignoreUnknownGroups {
+			if msgChan != nil {
+				msgChan <- sdk.NewMessage(sdk.MsgAppImportGroupNotFound, eg.Group.Name, app.Name)
+			}
+			continue
+		}
+		if errg != nil {
+			return sdk.WrapError(importGroupError(errg), "loadApplicationImportGroups> Error loading group %s for permission", eg.Group.Name)
+		}
+		eg.Group = *g
+		groups = append(groups, eg)
+	}
+	app.ApplicationGroups = groups
+	return nil
+}
+
+//loadApplicationImportDependencies loads groups, pipelines, environments and repositories manager
+//referenced by an imported application. If ignoreUnknownGroups is set, the permissions of the groups
+//which don't exist are dropped instead of failing.
+func loadApplicationImportDependencies(db gorp.SqlExecutor, proj *sdk.Project, app *sdk.Application, ignoreUnknownGroups bool, msgChan chan<- sdk.Message) error {
+	normalizeApplicationImportEnvironments(app)
+
+	if err := resolveApplicationImportPipelines(db, proj, app, msgChan); err != nil {
+		return sdk.WrapError(err, "loadApplicationImportDependencies> Unable to resolve pipelines")
+	}
+
+	if err := loadApplicationImportGroups(db, app, ignoreUnknownGroups, msgChan); err != nil {
+		return sdk.WrapError(err, "loadApplicationImportDependencies> Unable to load groups")
+	}
+
+	// Check all the pipelines, applications and environments referenced by the application at once
+	pipNames, appNames, envNames := applicationImportReferences(proj, app)
+	existPips, errP := pipeline.ExistPipelines(db, proj.ID, pipNames)
+	if errP != nil {
+		return sdk.WrapError(errP, "loadApplicationImportDependencies> Unable to check if pipelines exist")
+	}
+	existApps, errA := application.ExistApplications(db, proj.Key, appNames)
+	if errA != nil {
+		return sdk.WrapError(errA, "loadApplicationImportDependencies> Unable to check if applications exist")
+	}
+	existEnvs, errE := environment.ExistEnvironments(db, proj.Key, envNames)
+	if errE != nil {
+		return sdk.WrapError(errE, "loadApplicationImportDependencies> Unable to check if environments exist")
+	}
+
+	// Embedded pipelines which don't exist are created with the application
+	embedded := applicationImportEmbeddedPipelines(app)
+	for _, ap := range app.Pipelines {
+		if !existPips[ap.Pipeline.Name] && embedded[ap.Pipeline.Name] == nil {
+			return sdk.WrapError(sdk.ErrPipelineNotFound, "loadApplicationImportDependencies> Pipeline %s does not exist", ap.Pipeline.Name)
+		}
+		for _, t := range ap.Triggers {
+			if t.DestProject.Key != "" && t.DestProject.Key != proj.Key {
+				continue
+			}
+			if t.DestPipeline.Name != "" && !existPips[t.DestPipeline.Name] && embedded[t.DestPipeline.Name] == nil {
+				return sdk.WrapError(sdk.ErrPipelineNotFound, "loadApplicationImportDependencies> Pipeline %s does not exist", t.DestPipeline.Name)
+			}
+			if t.DestApplication.Name != "" && t.DestApplication.Name != app.Name && !existApps[t.DestApplication.Name] {
+				return sdk.WrapError(sdk.ErrApplicationNotFound, "loadApplicationImportDependencies> Application %s does not exist", t.DestApplication.Name)
+			}
+			for _, envName := range []string{t.SrcEnvironment.Name, t.DestEnvironment.Name} {
+				if envName != sdk.DefaultEnv.Name && !existEnvs[envName] {
+					return sdk.WrapError(sdk.ErrNoEnvironment, "loadApplicationImportDependencies> Environment %s does not exist", envName)
+				}
+			}
+		}
+	}
+
+	// Load repositories manager
+	if app.RepositoriesManager != nil {
+		rm, errRM := repositoriesmanager.LoadForProject(db, proj.Key, app.RepositoriesManager.Name)
+		if errRM != nil {
+			return sdk.WrapError(sdk.ErrNoReposManagerClientAuth, "loadApplicationImportDependencies> Unable to load repositories manager %s: %s", app.RepositoriesManager.Name, errRM)
+		}
+		app.RepositoriesManager = rm
+	} else if len(app.Hooks) > 0 || len(app.RepositoryPollers) > 0 {
+		return sdk.WrapError(sdk.ErrNoReposManagerClientAuth, "loadApplicationImportDependencies> Hooks and pollers need a repositories manager")
+	}
+
+	// Each pipeline and environment is loaded only once
+	pipelines := map[string]*sdk.Pipeline{}
+	loadPipeline := func(name string) (*sdk.Pipeline, error) {
+		if pip, ok := pipelines[name]; ok {
+			return pip, nil
+		}
+		if !existPips[name] {
+			// Its id is set once it is created
+			if pip, ok := embedded[name]; ok {
+				return pip, nil
+			}
+			return nil, sdk.ErrPipelineNotFound
+		}
+		pip, err := pipeline.LoadPipeline(db, proj.Key, name, false)
+		if err != nil {
+			return nil, err
+		}
+		pipelines[name] = pip
+		return pip, nil
+	}
+	environments := map[string]*sdk.Environment{sdk.DefaultEnv.Name: &sdk.DefaultEnv}
+	loadEnvironment := func(name string) (*sdk.Environment, error) {
+		if env, ok := environments[name]; ok {
+			return env, nil
+		}
+		if !existEnvs[name] {
+			return nil, sdk.ErrNoEnvironment
+		}
+		env, err := environment.LoadEnvironmentByName(db, proj.Key, name)
+		if err != nil {
+			return nil, err
+		}
+		environments[name] = env
+		return env, nil
+	}
+
+	for i := range app.Hooks {
+		pip, errP := loadPipeline(app.Hooks[i].Pipeline.Name)
+		if errP != nil {
+			return sdk.WrapError(errP, "loadApplicationImportDependencies> Unable to load pipeline %s", app.Hooks[i].Pipeline.Name)
+		}
+		app.Hooks[i].Pipeline = *pip
+	}
+
+	for i := range app.RepositoryPollers {
+		pip, errP := loadPipeline(app.RepositoryPollers[i].Pipeline.Name)
+		if errP != nil {
+			return sdk.WrapError(errP, "loadApplicationImportDependencies> Unable to load pipeline %s", app.RepositoryPollers[i].Pipeline.Name)
+		}
+		app.RepositoryPollers[i].Pipeline = *pip
+	}
+
+	for i := range app.Notifications {
+		n := &app.Notifications[i]
+		pip, errP := loadPipeline(n.Pipeline.Name)
+		if errP != nil {
+			return sdk.WrapError(errP, "loadApplicationImportDependencies> Unable to load pipeline %s", n.Pipeline.Name)
+		}
+		n.Pipeline = *pip
+
+		env, errEnv := loadEnvironment(n.Environment.Name)
+		if errEnv != nil {
+			return sdk.WrapError(errEnv, "loadApplicationImportDependencies> Unable to load environment %s", n.Environment.Name)
+		}
+		n.Environment = *env
+	}
+
+	for i := range app.Schedulers {
+		s := &app.Schedulers[i]
+		pip, errP := loadPipeline(s.PipelineName)
+		if errP != nil {
+			return sdk.WrapError(sdk.ErrPipelineNotFound, "loadApplicationImportDependencies> Unable to load pipeline %s for scheduler %s: %s", s.PipelineName, s.Crontab, errP)
+		}
+		s.PipelineID = pip.ID
+
+		env, errEnv := loadEnvironment(s.EnvironmentName)
+		if errEnv != nil {
+			return sdk.WrapError(sdk.ErrNoEnvironment, "loadApplicationImportDependencies> Unable to load environment %s for scheduler %s: %s", s.EnvironmentName, s.Crontab, errEnv)
+		}
+		s.EnvironmentID = env.ID
+
+		//Parsing cronexpr
+		if s.Timezone == "" {
+			s.Timezone = "UTC"
+		}
+		if _, _, err := sdk.ParseCrontab(sdk.CronTimezonePrefix + s.Timezone + " " + s.Crontab); err != nil {
+			return sdk.NewError(sdk.ErrWrongRequest, fmt.Errorf("Invalid cron expression %s on pipeline %s: %s", s.Crontab, s.PipelineName, err))
+		}
+	}
+
+	return nil
+}
+
+//applicationImportEmbeddedPipelines returns the pipelines of the application which come with their definition,
+//so that they are created if they don't exist. A pipeline referenced by its name only has no type.
+func applicationImportEmbeddedPipelines(app *sdk.Application) map[string]*sdk.Pipeline {
+	embedded := map[string]*sdk.Pipeline{}
+	for i := range app.Pipelines {
+		if p := &app.Pipelines[i].Pipeline; p.Type != "" {
+			embedded[p.Name] = p
+		}
+	}
+	return embedded
+}
+
+//resolveApplicationImportPipelines replaces the name of the pipelines referenced by their slug with the current
+//name of the pipeline, so that a renamed pipeline is still found. A pipeline without slug is referenced by its name.
+func resolveApplicationImportPipelines(db gorp.SqlExecutor, proj *sdk.Project, app *sdk.Application, msgChan chan<- sdk.Message) error {
+	slugs, errS := pipeline.LoadPipelineSlugs(db, proj.ID)
+	if errS != nil {
+		return sdk.WrapError(errS, "resolveApplicationImportPipelines> Unable to load pipelines of project %s", proj.Key)
+	}
+	return resolveApplicationPipelineRefs(app, slugs, msgChan)
+}
+
+//resolveApplicationPipelineRefs resolves the pipelines of the application with the pipelines of the project by slug
+func resolveApplicationPipelineRefs(app *sdk.Application, slugs map[string]sdk.Pipeline, msgChan chan<- sdk.Message) error {
+	embedded := applicationImportEmbeddedPipelines(app)
+	resolve := func(p *sdk.Pipeline) error {
+		if p.Slug == "" {
+			if msgChan != nil {
+				msgChan <- sdk.NewMessage(sdk.MsgAppImportPipelineRefMissing, p.Name, app.Name)
+			}
+			return nil
+		}
+		pip, ok := slugs[p.Slug]
+		if !ok {
+			// An embedded pipeline which doesn't exist yet is created with its name
+			if embedded[p.Name] != nil {
+				return nil
+			}
+			return sdk.WrapError(sdk.ErrPipelineNotFound, "resolveApplicationPipelineRefs> Pipeline %s does not exist", p.Slug)
+		}
+		if pip.Name != p.Name && msgChan != nil {
+			msgChan <- sdk.NewMessage(sdk.MsgAppImportPipelineRefResolved, p.Slug, app.Name, pip.Name)
+		}
+		p.Name = pip.Name
+		return nil
+	}
+
+	// Hooks, pollers, notifications and schedulers reference the pipelines of the application by their name
+	names := make(map[string]string, len(app.Pipelines))
+	for i := range app.Pipelines {
+		p := &app.Pipelines[i].Pipeline
+		name := p.Name
+		if err := resolve(p); err != nil {
+			return err
+		}
+		names[name] = p.Name
+
+		for j := range app.Pipelines[i].Triggers {
+			if err := resolve(&app.Pipelines[i].Triggers[j].DestPipeline); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Several entries which reference the same pipeline can't be merged
+	counts := make(map[string]int, len(app.Pipelines))
+	for _, ap := range app.Pipelines {
+		counts[ap.Pipeline.Name]++
+	}
+	for name, n := range counts {
+		if n < 2 {
+			delete(counts, name)
+		}
+	}
+	if len(counts) > 0 {
+		if msgChan != nil {
+			for _, name := range sortedDuplicatePipelines(counts) {
+				msgChan <- sdk.NewMessage(sdk.MsgAppImportDuplicatePipeline, name, counts[name], app.Name)
+			}
+		}
+		return sdk.WrapError(sdk.ErrWrongRequest, "resolveApplicationPipelineRefs> %d pipelines referenced several times", len(counts))
+	}
+
+	rename := func(name *string) {
+		if newName, ok := names[*name]; ok {
+			*name = newName
+		}
+	}
+	for i := range app.Hooks {
+		rename(&app.Hooks[i].Pipeline.Name)
+	}
+	for i := range app.RepositoryPollers {
+		rename(&app.RepositoryPollers[i].Pipeline.Name)
+	}
+	for i := range app.Notifications {
+		rename(&app.Notifications[i].Pipeline.Name)
+	}
+	for i := range app.Schedulers {
+		rename(&app.Schedulers[i].PipelineName)
+	}
+	return nil
+}
+
+//normalizeApplicationImportEnvironments replaces the empty environment names of the triggers, notifications and
+//schedulers of an imported application by the default environment, as an omitted environment
+func normalizeApplicationImportEnvironments(app *sdk.Application) {
+	normalize := func(name *string) {
+		if *name = strings.TrimSpace(*name); *name == "" {
+			*name = sdk.DefaultEnv.Name
+		}
+	}
+	for i := range app.Pipelines {
+		for j := range app.Pipelines[i].Triggers {
+			t := &app.Pipelines[i].Triggers[j]
+			normalize(&t.SrcEnvironment.Name)
+			normalize(&t.DestEnvironment.Name)
+		}
+	}
+	for i := range app.Notifications {
+		normalize(&app.Notifications[i].Environment.Name)
+	}
+	for i := range app.Schedulers {
+		normalize(&app.Schedulers[i].EnvironmentName)
+	}
+}
+
+//resolveApplicationImportEnvironmentsFold replaces the names of the environments referenced by an imported
+//application which don't exist by the name of the environment of the project matching them case-insensitively.
+//A warning is sent for each corrected name, so that the file is fixed.
+func resolveApplicationImportEnvironmentsFold(db gorp.SqlExecutor, proj *sdk.Project, app *sdk.Application, msgChan chan<- sdk.Message) error {
+	normalizeApplicationImportEnvironments(app)
+	_, _, envNames := applicationImportReferences(proj, app)
+	names, errR := environment.ResolveEnvironmentNamesFold(db, proj.Key, envNames)
+	if errR != nil {
+		return sdk.WrapError(errR, "resolveApplicationImportEnvironmentsFold> Unable to load environments of project %s", proj.Key)
+	}
+	if len(names) == 0 {
+		return nil
+	}
+
+	resolve := func(name *string) {
+		if n, ok := names[*name]; ok {
+			*name = n
+		}
+	}
+	for i := range app.Pipelines {
+		for j := range app.Pipelines[i].Triggers {
+			t := &app.Pipelines[i].Triggers[j]
+			if t.DestProject.Key != "" && t.DestProject.Key != proj.Key {
+				continue
+			}
+			resolve(&t.SrcEnvironment.Name)
+			resolve(&t.DestEnvironment.Name)
+		}
+	}
+	for i := range app.Notifications {
+		resolve(&app.Notifications[i].Environment.Name)
+	}
+	for i := range app.Schedulers {
+		resolve(&app.Schedulers[i].EnvironmentName)
+	}
+
+	if msgChan != nil {
+		corrected := make([]string, 0, len(names))
+		for n := range names {
+			corrected = append(corrected, n)
+		}
+		sort.Strings(corrected)
+		for _, n := range corrected {
+			msgChan <- sdk.NewMessage(sdk.MsgAppImportEnvCaseCorrected, n, app.Name, names[n])
+		}
+	}
+	return nil
+}
+
+//applicationImportReferences returns the names of the pipelines, applications and environments of
+//the project referenced by an imported application
+func applicationImportReferences(proj *sdk.Project, app *sdk.Application) (pipNames, appNames, envNames []string) {
+	pips := map[string]struct{}{}
+	apps := map[string]struct{}{}
+	envs := map[string]struct{}{}
+	add := func(m map[string]struct{}, name string) {
+		if name != "" {
+			m[name] = struct{}{}
+		}
+	}
+
+	for _, ap := range app.Pipelines {
+		add(pips, ap.Pipeline.Name)
+		for _, t := range ap.Triggers {
+			if t.DestProject.Key != "" && t.DestProject.Key != proj.Key {
+				continue
+			}
+			add(pips, t.DestPipeline.Name)
+			if t.DestApplication.Name != app.Name {
+				add(apps, t.DestApplication.Name)
+			}
+			add(envs, t.SrcEnvironment.Name)
+			add(envs, t.DestEnvironment.Name)
+		}
+	}
+	for _, h := range app.Hooks {
+		add(pips, h.Pipeline.Name)
+	}
+	for _, p := range app.RepositoryPollers {
+		add(pips, p.Pipeline.Name)
+	}
+	for _, n := range app.Notifications {
+		add(pips, n.Pipeline.Name)
+		add(envs, n.Environment.Name)
+	}
+	for _, s := range app.Schedulers {
+		add(pips, s.PipelineName)
+		add(envs, s.EnvironmentName)
+	}
+	delete(envs, sdk.DefaultEnv.Name)
+
+	for k := range pips {
+		pipNames = append(pipNames, k)
+	}
+	for k := range apps {
+		appNames = append(appNames, k)
+	}
+	for k := range envs {
+		envNames = append(envNames, k)
+	}
+	return
+}
+
+//importApplication creates or updates the application with its pollers and schedulers. Its dependencies
+//must have been loaded with loadApplicationImportDependencies. If prune is set, the stored pipelines, hooks,
+//pollers and notifications of an updated application which are not imported anymore are deleted.
+func importApplication(db gorp.SqlExecutor, proj *sdk.Project, app *sdk.Application, exist, regenerateKeys, allOrNothing, prune, skipHooks bool, msgChan chan<- sdk.Message, u *sdk.User) error {
+	// Check variables, permissions, hooks, triggers, notifications and prerequisites before any write
+	if err := application.CheckImportVariables(app, msgChan); err != nil {
+		return err
+	}
+	if err := application.CheckImportPermissions(app, msgChan); err != nil {
+		return err
+	}
+	if err := application.CheckImportHooks(app, msgChan); err != nil {
+		return err
+	}
+	if err := application.CheckImportTriggers(db, proj, app, msgChan); err != nil {
+		return err
+	}
+	if err := application.CheckImportDestPipelines(db, proj, app, msgChan); err != nil {
+		return err
+	}
+	if err := application.CheckImportNotifications(app, msgChan); err != nil {
+		return err
+	}
+	if err := application.CheckImportPrerequisites(app, msgChan); err != nil {
+		return err
+	}
+	if err := application.CheckImportSchedulerArgs(db, proj, app, msgChan); err != nil {
+		return err
+	}
+
+	// Hooks, pollers, notifications and schedulers which can't be created are sent as messages,
+	// the remaining ones are created anyway
+	errs := &sdk.MultiError{}
+	var errI error
+	if exist {
+		errI = application.ImportUpdate(db, proj, app, skipHooks, msgChan, u)
+	} else {
+		errI = application.Import(db, proj, app, app.RepositoriesManager, skipHooks, u, msgChan)
+	}
+	if e, ok := errI.(*sdk.MultiError); ok {
+		*errs = append(*errs, *e...)
+	} else if errI != nil {
+		return errI
+	}
+
+	if err := importApplicationKeys(db, app, regenerateKeys, msgChan); err != nil {
+		return err
+	}
+
+	if err := importApplicationPollers(db, app, errs, msgChan); err != nil {
+		return err
+	}
+
+	if err := importApplicationSchedulers(db, app, errs, msgChan); err != nil {
+		return err
+	}
+
+	if exist && prune {
+		if err := pruneApplicationImport(db, proj, app, skipHooks, msgChan, u); err != nil {
+			return err
+		}
+	}
+
+	if !errs.IsEmpty() && allOrNothing {
+		log.Warning("importApplication> %d resources of application %s can't be created: %s", len(*errs), app.Name, errs)
+		return sdk.ErrImportResourcesFailed
+	}
+	return nil
+}
+
+//importApplicationKeys generates the keys of the application which don't exist yet. Existing keys
+//are kept, unless regenerate is set. Private parts of the keys are never sent in the messages.
+func importApplicationKeys(db gorp.SqlExecutor, app *sdk.Application, regenerate bool, msgChan chan<- sdk.Message) error {
+	if len(app.Keys) == 0 {
+		return nil
+	}
+
+	for _, k := range app.Keys {
+		if !keyNameRegexp.MatchString(k.Name) {
+			return sdk.WrapError(sdk.ErrInvalidKeyPattern, "importApplicationKeys> Key name %s do not respect pattern %s", k.Name, sdk.NamePattern)
+		}
+		if k.Type != sdk.KeyTypeSsh && k.Type != sdk.KeyTypePgp {
+			return sdk.WrapError(sdk.ErrUnknownKeyType, "importApplicationKeys> unknown key of type: %s", k.Type)
+		}
+	}
+
+	existing := &sdk.Application{ID: app.ID}
+	if err := application.LoadAllKeys(db, existing); err != nil {
+		return sdk.WrapError(err, "importApplicationKeys> Cannot load keys of application %s", app.Name)
+	}
+	existingKeys := make(map[string]sdk.ApplicationKey, len(existing.Keys))
+	for _, k := range existing.Keys {
+		existingKeys[k.Name] = k
+	}
+
+	for i := range app.Keys {
+		k := &app.Keys[i]
+		k.ApplicationID = app.ID
+
+		old, exist := existingKeys[k.Name]
+		if exist && !regenerate {
+			*k = old
+			if msgChan != nil {
+				msgChan <- sdk.NewMessage(sdk.MsgAppImportKeyExists, k.Name, app.Name)
+			}
+			continue
+		}
+		if exist {
+			if err := application.DeleteApplicationKey(db, app.ID, k.Name); err != nil {
+				return sdk.WrapError(err, "importApplicationKeys> Cannot delete key %s", k.Name)
+			}
+		}
+
+		if err := generateApplicationKey(k); err != nil {
+			return sdk.WrapError(err, "importApplicationKeys> Cannot generate key %s", k.Name)
+		}
+		if err := application.InsertKey(db, k); err != nil {
+			return sdk.WrapError(err, "importApplicationKeys> Cannot insert key %s", k.Name)
+		}
+
+		if msgChan != nil {
+			if exist {
+				msgChan <- sdk.NewMessage(sdk.MsgAppImportKeyRegenerated, k.Name, k.Type, app.Name)
+			} else {
+				msgChan <- sdk.NewMessage(sdk.MsgAppImportKeyCreated, k.Name, k.Type, app.Name)
+			}
+		}
+	}
+	return nil
+}
+
+//importApplicationPollers creates the pollers of the application which don't exist yet
+func importApplicationPollers(db gorp.SqlExecutor, app *sdk.Application, errs *sdk.MultiError, msgChan chan<- sdk.Message) error {
+	for i := range app.RepositoryPollers {
+		p := &app.RepositoryPollers[i]
+		_, errL := poller.LoadByApplicationAndPipeline(db, app.ID, p.Pipeline.ID)
+		if errL == nil {
+			if msgChan != nil {
+				msgChan <- sdk.NewMessage(sdk.MsgPollerExists, app.RepositoryFullname, p.Pipeline.Name)
+			}
+			continue
+		}
+		if errL != sql.ErrNoRows {
+			return sdk.WrapError(errL, "importApplicationPollers> Unable to load poller of pipeline %s", p.Pipeline.Name)
+		}
+
+		if err := application.ImportResource(db, app, "poller", p.Pipeline.Name, errs, msgChan, func() error {
+			p.Application = *app
+			p.Name = app.RepositoriesManager.Name
+			if err := poller.Insert(db, p); err != nil {
+				return sdk.WrapError(err, "importApplicationPollers> Unable to insert poller on pipeline %s", p.Pipeline.Name)
+			}
+			if msgChan != nil {
+				msgChan <- sdk.NewMessage(sdk.MsgPollerCreated, app.RepositoryFullname, p.Pipeline.Name)
+				if !p.Enabled {
+					msgChan <- sdk.NewMessage(sdk.MsgAppImportCreatedDisabled, "poller", p.Pipeline.Name, app.Name)
+				}
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//disableApplicationImport sets the hooks and the pollers of an imported application disabled. A new application
+//without hooks gets a hook on its first pipeline, this hook is set here so that it is created disabled too.
+func disableApplicationImport(app *sdk.Application, exist bool) {
+	if !exist && len(app.Hooks) == 0 && app.RepositoriesManager != nil && app.RepositoryFullname != "" && len(app.Pipelines) > 0 {
+		app.Hooks = []sdk.Hook{{Pipeline: sdk.Pipeline{Name: app.Pipelines[0].Pipeline.Name}}}
+	}
+	for i := range app.Hooks {
+		app.Hooks[i].Enabled = false
+	}
+	for i := range app.RepositoryPollers {
+		app.RepositoryPollers[i].Enabled = false
+	}
+}
+
+//skipApplicationImportHooks removes the hooks and the pollers of an imported application, so that the rest of the
+//application is imported without the repositories manager
+func skipApplicationImportHooks(app *sdk.Application, msgChan chan<- sdk.Message) {
+	msgChan <- sdk.NewMessage(sdk.MsgAppImportHooksSkipped, len(app.Hooks), len(app.RepositoryPollers), app.Name)
+	app.Hooks = nil
+	app.RepositoryPollers = nil
+}
+
+//importApplicationSchedulers creates the schedulers of the application which don't exist yet
+func importApplicationSchedulers(db gorp.SqlExecutor, app *sdk.Application, errs *sdk.MultiError, msgChan chan<- sdk.Message) error {
+	for i := range app.Schedulers {
+		s := &app.Schedulers[i]
+		pip := &sdk.Pipeline{ID: s.PipelineID, Name: s.PipelineName}
+		env := &sdk.Environment{ID: s.EnvironmentID, Name: s.EnvironmentName}
+		if s.Args == nil {
+			s.Args = []sdk.Parameter{}
+		}
+
+		existing, errL := scheduler.GetByApplicationPipelineEnv(db, app, pip, env)
+		if errL != nil {
+			return sdk.WrapError(errL, "importApplicationSchedulers> Unable to load schedulers of pipeline %s", s.PipelineName)
+		}
+
+		var found bool
+		for _, es := range existing {
+			if es.Crontab == s.Crontab && es.Timezone == s.Timezone && reflect.DeepEqual(es.Args, s.Args) {
+				found = true
+				break
+			}
+		}
+		if found {
+			if msgChan != nil {
+				msgChan <- sdk.NewMessage(sdk.MsgSchedulerExists, s.Crontab, pip.Name, env.Name)
+			}
+			continue
+		}
+
+		if err := application.ImportResource(db, app, "scheduler", pip.Name, errs, msgChan, func() error {
+			newScheduler, errN := scheduler.New(app, pip, env, s.Crontab, s.Args...)
+			if errN != nil {
+				return sdk.WrapError(errN, "importApplicationSchedulers> Invalid scheduler %s on pipeline %s", s.Crontab, pip.Name)
+			}
+			newScheduler.Timezone = s.Timezone
+			if err := scheduler.Insert(db, newScheduler); err != nil {
+				return sdk.WrapError(err, "importApplicationSchedulers> Unable to insert scheduler on pipeline %s", s.PipelineName)
+			}
+			*s = *newScheduler
+			s.PipelineName = pip.Name
+			s.EnvironmentName = env.Name
+
+			if msgChan != nil {
+				msgChan <- sdk.NewMessage(sdk.MsgSchedulerCreated, s.Crontab, pip.Name, env.Name)
+				if len(s.Args) > 0 {
+					msgChan <- sdk.NewMessage(sdk.MsgSchedulerArgsSet, s.Crontab, pip.Name, env.Name, schedulerArgsString(s.Args))
+				}
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//schedulerArgsString lists the arguments of a scheduler as name=value
+func schedulerArgsString(args []sdk.Parameter) string {
+	l := make([]string, len(args))
+	for i, a := range args {
+		l[i] = a.Name + "=" + a.Value
+	}
+	return strings.Join(l, ", ")
+}
+
+//applicationImportSchedules computes the next execution of the schedulers of an imported application,
+//so that the schedules can be checked in the summary of the import
+func applicationImportSchedules(app *sdk.Application, now time.Time) []sdk.ImportSchedule {
+	schedules := make([]sdk.ImportSchedule, 0, len(app.Schedulers))
+	for _, s := range app.Schedulers {
+		tz := s.Timezone
+		if tz == "" {
+			tz = "UTC"
+		}
+		cronExpr, loc, err := sdk.ParseCrontab(sdk.CronTimezonePrefix + tz + " " + s.Crontab)
+		if err != nil {
+			continue
+		}
+		schedules = append(schedules, sdk.ImportSchedule{
+			Pipeline:      s.PipelineName,
+			Environment:   s.EnvironmentName,
+			Crontab:       s.Crontab,
+			Timezone:      tz,
+			NextExecution: cronExpr.Next(now.In(loc)),
+		})
+	}
+	return schedules
+}
+
+//pruneApplicationImport deletes the stored pipelines, hooks, pollers and notifications of the application which
+//are not in the import. Hooks are deleted from the repositories manager once the import is committed. If skipHooks
+//is set, the hooks and the pollers of the kept pipelines are kept.
+func pruneApplicationImport(db gorp.SqlExecutor, proj *sdk.Project, app *sdk.Application, skipHooks bool, msgChan chan<- sdk.Message, u *sdk.User) error {
+	stored, errL := application.LoadByName(db, proj.Key, app.Name, u,
+		application.LoadOptions.WithPipelines,
+		application.LoadOptions.WithHooks,
+		application.LoadOptions.WithNotifs,
+		application.LoadOptions.WithRepositoryManager)
+	if errL != nil {
+		return sdk.WrapError(errL, "pruneApplicationImport> Unable to load application %s", app.Name)
+	}
+	pollers, errP := poller.LoadByApplication(db, stored.ID)
+	if errP != nil {
+		return sdk.WrapError(errP, "pruneApplicationImport> Unable to load pollers of application %s", app.Name)
+	}
+
+	pruned := func(kind, name string) {
+		if msgChan != nil {
+			msgChan <- sdk.NewMessage(sdk.MsgAppImportResourcePruned, kind, name, app.Name)
+		}
+	}
+
+	pipelines := make(map[string]bool, len(app.Pipelines))
+	for _, ap := range app.Pipelines {
+		pipelines[ap.Pipeline.Name] = true
+	}
+	hooks := make(map[string]bool, len(app.Hooks))
+	for _, h := range app.Hooks {
+		hooks[h.Pipeline.Name] = true
+	}
+	polled := make(map[string]bool, len(app.RepositoryPollers))
+	for _, p := range app.RepositoryPollers {
+		polled[p.Pipeline.Name] = true
+	}
+	if skipHooks {
+		for _, h := range stored.Hooks {
+			hooks[h.Pipeline.Name] = true
+		}
+		for _, p := range pollers {
+			polled[p.Pipeline.Name] = true
+		}
+	}
+	notifs := make(map[string]bool, len(app.Notifications))
+	for _, n := range app.Notifications {
+		notifs[n.Pipeline.Name+"/"+n.Environment.Name] = true
+	}
+
+	// The deletion of the hooks on the repositories manager is recorded in the transaction, a rolled back
+	// import doesn't delete them
+	if stored.RepositoriesManager != nil {
+		for _, h := range stored.Hooks {
+			if pipelines[h.Pipeline.Name] && hooks[h.Pipeline.Name] {
+				continue
+			}
+			if err := hook.EnqueueHookDeletion(db, proj.Key, stored.RepositoriesManager.Name, h); err != nil {
+				return sdk.WrapError(err, "pruneApplicationImport> Unable to delete hook of pipeline %s", h.Pipeline.Name)
+			}
+		}
+	}
+
+	// Hooks, pollers and notifications of a detached pipeline are deleted with it
+	for _, ap := range stored.Pipelines {
+		if pipelines[ap.Pipeline.Name] {
+			continue
+		}
+		if err := application.RemovePipeline(db, proj.Key, app.Name, ap.Pipeline.Name); err != nil {
+			return sdk.WrapError(err, "pruneApplicationImport> Unable to detach pipeline %s from %s", ap.Pipeline.Name, app.Name)
+		}
+		pruned("pipeline", ap.Pipeline.Name)
+	}
+
+	for _, h := range stored.Hooks {
+		if !pipelines[h.Pipeline.Name] || hooks[h.Pipeline.Name] {
+			continue
+		}
+		if err := hook.DeleteHook(db, h.ID); err != nil {
+			return sdk.WrapError(err, "pruneApplicationImport> Unable to delete hook of pipeline %s", h.Pipeline.Name)
+		}
+		pruned("hook", h.Pipeline.Name)
+	}
+
+	for i := range pollers {
+		p := &pollers[i]
+		if !pipelines[p.Pipeline.Name] || polled[p.Pipeline.Name] {
+			continue
+		}
+		if err := poller.Delete(db, p); err != nil {
+			return sdk.WrapError(err, "pruneApplicationImport> Unable to delete poller of pipeline %s", p.Pipeline.Name)
+		}
+		pruned("poller", p.Pipeline.Name)
+	}
+
+	for _, n := range stored.Notifications {
+		if !pipelines[n.Pipeline.Name] || notifs[n.Pipeline.Name+"/"+n.Environment.Name] {
+			continue
+		}
+		if err := notification.DeleteNotification(db, stored.ID, n.Pipeline.ID, n.Environment.ID); err != nil {
+			return sdk.WrapError(err, "pruneApplicationImport> Unable to delete notifications of pipeline %s", n.Pipeline.Name)
+		}
+		pruned("notification", n.Pipeline.Name+"/"+n.Environment.Name)
+	}
+
+	return nil
+}
+
+func getApplicationImportSchemaHandler(w http.ResponseWriter, r *http.Request, db *gorp.DbMap, c *businesscontext.Ctx) error {
+	return WriteJSON(w, r, exportentities.JSONSchema(exportentities.Application{}), http.StatusOK)
+}
+
+//checkApplicationImportGeneration refuses to update a stored application whose generation is greater than or
+//equal to the generation of the imported application, so that an older payload can't overwrite a newer one
+func checkApplicationImportGeneration(db gorp.SqlExecutor, proj *sdk.Project, app *sdk.Application, msgChan chan<- sdk.Message) error {
+	stored, errL := application.LoadByName(db, proj.Key, app.Name, nil)
+	if errL != nil {
+		return sdk.WrapError(errL, "checkApplicationImportGeneration> Unable to load application %s", app.Name)
+	}
+	if stored.Generation < app.Generation {
+		return nil
+	}
+	msgChan <- sdk.NewMessage(sdk.MsgAppImportGenerationConflict, app.Generation, app.Name, stored.Generation)
+	return sdk.ErrImportGenerationConflict
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/url"
+
+	"github.com/go-gorp/gorp"
+	"github.com/gorilla/mux"
+
+	"github.com/ovh/cds/engine/api/businesscontext"
+	"github.com/ovh/cds/sdk"
+)
+
+//importBatchResponse records the response of the import of a line of a batch
+type importBatchResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (r *importBatchResponse) Header() http.Header {
+	return r.header
+}
+
+func (r *importBatchResponse) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+}
+
+func (r *importBatchResponse) Write(b []byte) (int, error) {
+	r.WriteHeader(http.StatusOK)
+	return r.body.Write(b)
+}
+
+//importApplicationsBatchHandler imports the applications of a newline-delimited JSON payload, one application
+//per line. Each line is imported in its own transaction with the options of the request, as an import of its
+//own. The import goes on when a line fails, unless stopOnError is set: the following lines are then skipped.
+func importApplicationsBatchHandler(w http.ResponseWriter, r *http.Request, db *gorp.DbMap, c *businesscontext.Ctx) error {
+	limitImportBody(w, r)
+	if err := checkImportRateLimit(w, importProjectKey(mux.Vars(r))); err != nil {
+		return err
+	}
+	stopOnError := FormBool(r, "stopOnError")
+	if r.FormValue("url") != "" || r.FormValue("repo") != "" {
+		return sdk.WrapError(sdk.ErrWrongRequest, "importApplicationsBatchHandler> A batch is read from the body")
+	}
+
+	data, errRead := readImportBody(r.Body, r.Header.Get("Content-Encoding"))
+	if errRead != nil {
+		return sdk.WrapError(importBodyError(errRead), "importApplicationsBatchHandler> Unable to read body: %s", errRead)
+	}
+
+	// The options of the request apply to each line, which is imported as a structured JSON import
+	q := r.URL.Query()
+	for _, k := range []string{"stopOnError", "callbackURL", "url", "repo", "path", "ref"} {
+		q.Del(k)
+	}
+	q.Set("format", "json")
+	q.Set("messageFormat", "structured")
+	uri := *r.URL
+	uri.RawQuery = q.Encode()
+
+	res := sdk.ImportBatchResult{Lines: []sdk.ImportBatchLine{}}
+	s := bufio.NewScanner(bytes.NewReader(data))
+	s.Buffer(make([]byte, 64*1024), len(data)+1)
+	var stopped bool
+	for n := 1; s.Scan(); n++ {
+		line := bytes.TrimSpace(s.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		l := sdk.ImportBatchLine{Line: n}
+		var doc struct {
+			Name string `json:"name"`
+		}
+		if err := json.Unmarshal(line, &doc); err == nil {
+			l.Application = doc.Name
+		}
+
+		if stopped {
+			l.Status = sdk.ImportBatchSkipped
+			res.Skipped++
+			res.Lines = append(res.Lines, l)
+			continue
+		}
+
+		importApplicationsBatchLine(r, &uri, db, c, line, &l)
+		if l.Status == sdk.ImportBatchSuccess {
+			res.Imported++
+		} else {
+			res.Failed++
+			stopped = stopOnError
+		}
+		res.Lines = append(res.Lines, l)
+	}
+	if err := s.Err(); err != nil {
+		return sdk.WrapError(sdk.ErrWrongRequest, "importApplicationsBatchHandler> Unable to read batch: %s", err)
+	}
+
+	return WriteJSON(w, r, res, http.StatusOK)
+}
+
+//importApplicationsBatchLine imports a line of a batch, and sets its status from the response of the import
+func importApplicationsBatchLine(r *http.Request, uri *url.URL, db *gorp.DbMap, c *businesscontext.Ctx, line []byte, l *sdk.ImportBatchLine) {
+	al := r.Header.Get("Accept-Language")
+	fail := func(status int, msg string) {
+		l.Status = sdk.ImportBatchFail
+		l.HTTPStatus = status
+		l.Error = msg
+	}
+
+	sub, errR := http.NewRequest(http.MethodPost, uri.String(), bytes.NewReader(line))
+	if errR != nil {
+		msg, status := sdk.ProcessError(errR, al)
+		fail(status, msg)
+		return
+	}
+	// The context keeps the variables of the route and is canceled with the batch
+	sub = sub.WithContext(r.Context())
+	for k, v := range r.Header {
+		switch k {
+		case "Content-Type", "Content-Encoding", "Content-Length", "X-Idempotency-Key":
+		default:
+			sub.Header[k] = v
+		}
+	}
+	sub.Header.Set("Content-Type", "application/json")
+	sub.Header.Del("Accept")
+
+	resp := &importBatchResponse{header: http.Header{}}
+	if err := doImportApplication(resp, sub, db, c, nil); err != nil {
+		msg, status := sdk.ProcessError(err, al)
+		fail(status, msg)
+		return
+	}
+
+	result := sdk.ImportResult{}
+	errJ := json.Unmarshal(resp.body.Bytes(), &result)
+	if errJ == nil {
+		l.Messages = result.Messages
+	}
+	if resp.status != http.StatusOK {
+		fail(resp.status, http.StatusText(resp.status))
+		return
+	}
+	if errJ != nil {
+		fail(http.StatusInternalServerError, errJ.Error())
+		return
+	}
+	l.Status = sdk.ImportBatchSuccess
+	l.HTTPStatus = resp.status
+	l.Summary = &result.Summary
+	l.Export = result.Export
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ovh/cds/engine/api/auth"
+	"github.com/ovh/cds/engine/api/test"
+	"github.com/ovh/cds/engine/api/test/assets"
+	"github.com/ovh/cds/sdk"
+)
+
+func TestImportApplicationsBatchHandler(t *testing.T) {
+	db := test.SetupPG(t)
+
+	router = newRouter(auth.TestLocalAuth(t), mux.NewRouter(), "/TestImportApplicationsBatchHandler")
+	router.init()
+
+	u, pass := assets.InsertAdminUser(db)
+	proj := assets.InsertTestProject(t, db, sdk.RandomString(10), sdk.RandomString(10), nil)
+	test.NotNil(t, proj)
+
+	uri := router.getRoute("POST", importApplicationsBatchHandler, map[string]string{"permProjectKey": proj.Key})
+	test.NotEmpty(t, uri)
+
+	do := func(query, body string) sdk.ImportBatchResult {
+		req, err := http.NewRequest("POST", uri+query, strings.NewReader(body))
+		test.NoError(t, err)
+		assets.AuthentifyRequest(t, req, u, pass)
+		w := httptest.NewRecorder()
+		router.mux.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		res := sdk.ImportBatchResult{}
+		test.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		return res
+	}
+
+	batch := `{"name": "app1", "variables": {"foo": {"value": "bar"}}}
+
+{"name": "app2", "variables": {"in valid": {"value": "bar"}}}
+{"name": "app3"}
+`
+
+	//1. A failed line does not stop the batch
+	res := do("", batch)
+	assert.Equal(t, 2, res.Imported)
+	assert.Equal(t, 1, res.Failed)
+	assert.Equal(t, 0, res.Skipped)
+	if assert.Len(t, res.Lines, 3) {
+		assert.Equal(t, 1, res.Lines[0].Line)
+		assert.Equal(t, "app1", res.Lines[0].Application)
+		assert.Equal(t, sdk.ImportBatchSuccess, res.Lines[0].Status)
+		assert.NotNil(t, res.Lines[0].Summary)
+
+		assert.Equal(t, 3, res.Lines[1].Line)
+		assert.Equal(t, "app2", res.Lines[1].Application)
+		assert.Equal(t, sdk.ImportBatchFail, res.Lines[1].Status)
+		assert.Equal(t, http.StatusBadRequest, res.Lines[1].HTTPStatus)
+
+		assert.Equal(t, sdk.ImportBatchSuccess, res.Lines[2].Status)
+	}
+
+	//2. With stopOnError, the lines after the first failure are skipped
+	batch = `{"name": "app4", "variables": {"in valid": {"value": "bar"}}}
+{"name": "app5"}
+`
+	res = do("?stopOnError=true", batch)
+	assert.Equal(t, 0, res.Imported)
+	assert.Equal(t, 1, res.Failed)
+	assert.Equal(t, 1, res.Skipped)
+	if assert.Len(t, res.Lines, 2) {
+		assert.Equal(t, sdk.ImportBatchSkipped, res.Lines[1].Status)
+		assert.Equal(t, "app5", res.Lines[1].Application)
+	}
+}
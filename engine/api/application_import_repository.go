@@ -0,0 +1,127 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/go-gorp/gorp"
+	"github.com/spf13/viper"
+
+	"github.com/ovh/cds/engine/api/businesscontext"
+	"github.com/ovh/cds/engine/api/repositoriesmanager"
+	"github.com/ovh/cds/sdk"
+	"github.com/ovh/cds/sdk/exportentities"
+)
+
+//importRepositorySource is a file of a repository to import, with the commit of the ref once resolved
+type importRepositorySource struct {
+	manager string
+	repo    string
+	path    string
+	ref     string
+	commit  string
+}
+
+//importApplicationFromRepositoryHandler imports an application from a file of a repository, fetched with the
+//repositories manager of the project
+func importApplicationFromRepositoryHandler(w http.ResponseWriter, r *http.Request, db *gorp.DbMap, c *businesscontext.Ctx) error {
+	if r.FormValue("repo") == "" || r.FormValue("path") == "" {
+		return sdk.WrapError(sdk.ErrWrongRequest, "importApplicationFromRepositoryHandler> repo and path are required")
+	}
+	return importApplicationHandler(w, r, db, c)
+}
+
+//importRepositorySourceFromRequest returns the file of a repository to import, if the repo form value is set
+func importRepositorySourceFromRequest(r *http.Request) *importRepositorySource {
+	if r.FormValue("repo") == "" {
+		return nil
+	}
+	return &importRepositorySource{
+		manager: r.FormValue("repositoriesManager"),
+		repo:    r.FormValue("repo"),
+		path:    r.FormValue("path"),
+		ref:     r.FormValue("ref"),
+	}
+}
+
+//fetchImportRepository fetches the file to import with the credentials of the repositories manager of the project.
+//The manager may be omitted if the project is linked to a single one.
+func fetchImportRepository(db gorp.SqlExecutor, projectKey string, src *importRepositorySource, format string) ([]byte, exportentities.Format, error) {
+	if src.manager == "" {
+		rms, errL := repositoriesmanager.LoadAllForProject(db, projectKey)
+		if errL != nil {
+			return nil, exportentities.UnknownFormat, sdk.WrapError(errL, "fetchImportRepository> Unable to load repositories managers of project %s", projectKey)
+		}
+		if len(rms) != 1 {
+			return nil, exportentities.UnknownFormat, sdk.WrapError(sdk.ErrWrongRequest, "fetchImportRepository> Project %s has %d repositories managers, repositoriesManager is required", projectKey, len(rms))
+		}
+		src.manager = rms[0].Name
+	}
+
+	client, errC := repositoriesmanager.AuthorizedClient(db, projectKey, src.manager)
+	if errC != nil {
+		return nil, exportentities.UnknownFormat, sdk.WrapError(sdk.ErrNoReposManagerClientAuth, "fetchImportRepository> Unable to get client of %s: %s", src.manager, errC)
+	}
+	return fetchImportRepositoryFile(client, src, format)
+}
+
+//fetchImportRepositoryFile resolves the ref to a commit, and fetches the file at this commit. Only the file is
+//downloaded, without cloning the repository nor its submodules. The ref defaults to the default branch.
+func fetchImportRepositoryFile(client sdk.RepositoriesManagerClient, src *importRepositorySource, format string) ([]byte, exportentities.Format, error) {
+	if src.path == "" {
+		return nil, exportentities.UnknownFormat, sdk.WrapError(sdk.ErrWrongRequest, "fetchImportRepositoryFile> path is required")
+	}
+
+	if src.ref == "" {
+		branches, errB := client.Branches(src.repo)
+		if errB != nil {
+			return nil, exportentities.UnknownFormat, sdk.WrapError(errB, "fetchImportRepositoryFile> Unable to get branches of %s", src.repo)
+		}
+		for _, b := range branches {
+			if b.Default {
+				src.ref = b.DisplayID
+			}
+		}
+		if src.ref == "" {
+			return nil, exportentities.UnknownFormat, sdk.WrapError(sdk.ErrWrongRequest, "fetchImportRepositoryFile> No default branch in %s, ref is required", src.repo)
+		}
+	}
+
+	commit, errC := client.Commit(src.repo, src.ref)
+	if errC != nil {
+		return nil, exportentities.UnknownFormat, sdk.WrapError(sdk.ErrWrongRequest, "fetchImportRepositoryFile> Unable to resolve %s in %s: %s", src.ref, src.repo, errC)
+	}
+	if commit.Hash == "" {
+		return nil, exportentities.UnknownFormat, sdk.WrapError(sdk.ErrWrongRequest, "fetchImportRepositoryFile> Ref %s not found in %s", src.ref, src.repo)
+	}
+	src.commit = commit.Hash
+
+	data, errD := client.FileContent(src.repo, src.path, src.commit)
+	if errD != nil {
+		return nil, exportentities.UnknownFormat, sdk.WrapError(sdk.ErrWrongRequest, "fetchImportRepositoryFile> Unable to fetch %s at %s in %s: %s", src.path, src.commit, src.repo, errD)
+	}
+
+	maxSize := viper.GetInt64(viperImportURLMaxSize)
+	if maxSize <= 0 {
+		maxSize = defaultImportURLMaxSize
+	}
+	if int64(len(data)) > maxSize {
+		return nil, exportentities.UnknownFormat, importBodyTooLargeError{maxSize: maxSize}
+	}
+
+	f, errF := exportentities.GetFormatFromPath(src.path)
+	if format != "" {
+		f, errF = exportentities.GetFormat(format)
+	}
+	if errF != nil {
+		return nil, exportentities.UnknownFormat, sdk.WrapError(sdk.ErrImportUnknownFormat, "fetchImportRepositoryFile> Unable to get format of %s: %s", src.path, errF)
+	}
+	return data, f, nil
+}
+
+//sendImportRepositorySource tells from which commit of a repository the file is imported
+func sendImportRepositorySource(src *importRepositorySource, msgChan chan<- sdk.Message) {
+	if src == nil {
+		return
+	}
+	msgChan <- sdk.NewMessage(sdk.MsgAppImportRepositoryFetched, src.path, src.repo, src.commit, src.ref)
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ovh/cds/sdk"
+	"github.com/ovh/cds/sdk/exportentities"
+)
+
+type importRepositoryClient struct {
+	sdk.RepositoriesManagerClient
+	commits map[string]string
+	files   map[string]string
+}
+
+func (c importRepositoryClient) Branches(repo string) ([]sdk.VCSBranch, error) {
+	return []sdk.VCSBranch{{DisplayID: "develop"}, {DisplayID: "master", Default: true}}, nil
+}
+
+func (c importRepositoryClient) Commit(repo, ref string) (sdk.VCSCommit, error) {
+	hash, ok := c.commits[ref]
+	if !ok {
+		return sdk.VCSCommit{}, fmt.Errorf("unknown ref %s", ref)
+	}
+	return sdk.VCSCommit{Hash: hash}, nil
+}
+
+func (c importRepositoryClient) FileContent(repo, path, ref string) ([]byte, error) {
+	content, ok := c.files[ref+":"+path]
+	if !ok {
+		return nil, sdk.ErrNotFound
+	}
+	return []byte(content), nil
+}
+
+func Test_fetchImportRepositoryFile(t *testing.T) {
+	client := importRepositoryClient{
+		commits: map[string]string{"master": "a1b2c3", "v1.0": "d4e5f6"},
+		files: map[string]string{
+			"a1b2c3:.cds/app.yml": "name: app1\n",
+			"d4e5f6:.cds/app.yml": "name: app0\n",
+		},
+	}
+
+	//The ref defaults to the default branch, and the file is fetched at the resolved commit
+	src := &importRepositorySource{repo: "team/app", path: ".cds/app.yml"}
+	data, f, err := fetchImportRepositoryFile(client, src, "")
+	assert.NoError(t, err)
+	assert.Equal(t, "name: app1\n", string(data))
+	assert.Equal(t, exportentities.FormatYAML, f)
+	assert.Equal(t, "master", src.ref)
+	assert.Equal(t, "a1b2c3", src.commit)
+
+	src = &importRepositorySource{repo: "team/app", path: ".cds/app.yml", ref: "v1.0"}
+	data, _, err = fetchImportRepositoryFile(client, src, "yaml")
+	assert.NoError(t, err)
+	assert.Equal(t, "name: app0\n", string(data))
+	assert.Equal(t, "d4e5f6", src.commit)
+
+	src = &importRepositorySource{repo: "team/app", path: ".cds/app.yml", ref: "unknown"}
+	_, _, err = fetchImportRepositoryFile(client, src, "")
+	assert.Error(t, err)
+
+	src = &importRepositorySource{repo: "team/app", path: ".cds/other.yml", ref: "master"}
+	_, _, err = fetchImportRepositoryFile(client, src, "")
+	assert.Error(t, err)
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+
+	"github.com/go-gorp/gorp"
+	"github.com/gorilla/mux"
+
+	"github.com/ovh/cds/engine/api/application"
+	"github.com/ovh/cds/engine/api/businesscontext"
+	"github.com/ovh/cds/sdk"
+	"github.com/ovh/cds/sdk/exportentities"
+)
+
+var importTemplateParameterName = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+func getImportTemplatesHandler(w http.ResponseWriter, r *http.Request, db *gorp.DbMap, c *businesscontext.Ctx) error {
+	key := mux.Vars(r)["permProjectKey"]
+
+	templates, errL := application.LoadImportTemplates(db, key)
+	if errL != nil {
+		return sdk.WrapError(errL, "getImportTemplatesHandler> Unable to load templates of project %s", key)
+	}
+	return WriteJSON(w, r, templates, http.StatusOK)
+}
+
+func getImportTemplateHandler(w http.ResponseWriter, r *http.Request, db *gorp.DbMap, c *businesscontext.Ctx) error {
+	vars := mux.Vars(r)
+	key := vars["permProjectKey"]
+	name := vars["name"]
+
+	t, errL := application.LoadImportTemplate(db, key, name)
+	if errL != nil {
+		return sdk.WrapError(errL, "getImportTemplateHandler> Unable to load template %s", name)
+	}
+	return WriteJSON(w, r, t, http.StatusOK)
+}
+
+func addImportTemplateHandler(w http.ResponseWriter, r *http.Request, db *gorp.DbMap, c *businesscontext.Ctx) error {
+	key := mux.Vars(r)["permProjectKey"]
+
+	t := &sdk.ImportTemplate{}
+	if err := UnmarshalBody(r, t); err != nil {
+		return sdk.WrapError(err, "addImportTemplateHandler> Unable to read template")
+	}
+	t.ProjectKey = key
+	if err := checkImportTemplate(t); err != nil {
+		return sdk.WrapError(sdk.ErrWrongRequest, "addImportTemplateHandler> Invalid template %s: %s", t.Name, err)
+	}
+
+	if _, err := application.LoadImportTemplate(db, key, t.Name); err == nil {
+		return sdk.WrapError(sdk.ErrAlreadyExist, "addImportTemplateHandler> Template %s already exists", t.Name)
+	} else if err != sdk.ErrNotFound {
+		return sdk.WrapError(err, "addImportTemplateHandler> Unable to check if template %s exists", t.Name)
+	}
+
+	if err := application.InsertImportTemplate(db, t); err != nil {
+		return sdk.WrapError(err, "addImportTemplateHandler> Unable to insert template %s", t.Name)
+	}
+	return WriteJSON(w, r, t, http.StatusCreated)
+}
+
+func updateImportTemplateHandler(w http.ResponseWriter, r *http.Request, db *gorp.DbMap, c *businesscontext.Ctx) error {
+	vars := mux.Vars(r)
+	key := vars["permProjectKey"]
+	name := vars["name"]
+
+	t := &sdk.ImportTemplate{}
+	if err := UnmarshalBody(r, t); err != nil {
+		return sdk.WrapError(err, "updateImportTemplateHandler> Unable to read template")
+	}
+	t.ProjectKey = key
+	t.Name = name
+	if err := checkImportTemplate(t); err != nil {
+		return sdk.WrapError(sdk.ErrWrongRequest, "updateImportTemplateHandler> Invalid template %s: %s", name, err)
+	}
+
+	if err := application.UpdateImportTemplate(db, t); err != nil {
+		return sdk.WrapError(err, "updateImportTemplateHandler> Unable to update template %s", name)
+	}
+
+	updated, errL := application.LoadImportTemplate(db, key, name)
+	if errL != nil {
+		return sdk.WrapError(errL, "updateImportTemplateHandler> Unable to load template %s", name)
+	}
+	return WriteJSON(w, r, updated, http.StatusOK)
+}
+
+func deleteImportTemplateHandler(w http.ResponseWriter, r *http.Request, db *gorp.DbMap, c *businesscontext.Ctx) error {
+	vars := mux.Vars(r)
+	key := vars["permProjectKey"]
+	name := vars["name"]
+
+	if err := application.DeleteImportTemplate(db, key, name); err != nil {
+		return sdk.WrapError(err, "deleteImportTemplateHandler> Unable to delete template %s", name)
+	}
+	return nil
+}
+
+//checkImportTemplate checks the name, the format and the parameters of an import template
+func checkImportTemplate(t *sdk.ImportTemplate) error {
+	if t.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if t.Content == "" {
+		return fmt.Errorf("content is required")
+	}
+	if _, err := exportentities.GetFormat(t.Format); err != nil {
+		return fmt.Errorf("invalid format %s", t.Format)
+	}
+
+	names := make(map[string]bool, len(t.Parameters))
+	for _, p := range t.Parameters {
+		if !importTemplateParameterName.MatchString(p.Name) {
+			return fmt.Errorf("invalid parameter name %s", p.Name)
+		}
+		if names[p.Name] {
+			return fmt.Errorf("parameter %s is declared twice", p.Name)
+		}
+		names[p.Name] = true
+	}
+	return nil
+}
+
+//expandApplicationImportTemplate replaces an imported application which references an import template by
+//the expanded template, in the format of the template. Other payloads are returned unchanged. The required
+//parameters which have no value are returned as messages.
+func expandApplicationImportTemplate(db gorp.SqlExecutor, projectKey string, data []byte, f exportentities.Format) ([]byte, exportentities.Format, []sdk.Message, error) {
+	ref := exportentities.ApplicationTemplateRef{}
+	if err := unmarshalImport(data, f, &ref); err != nil || ref.Template == "" {
+		return data, f, nil, nil
+	}
+
+	t, errL := application.LoadImportTemplate(db, projectKey, ref.Template)
+	if errL != nil {
+		if errL == sdk.ErrNotFound {
+			return nil, f, nil, sdk.WrapError(sdk.ErrWrongRequest, "expandApplicationImportTemplate> Template %s not found", ref.Template)
+		}
+		return nil, f, nil, sdk.WrapError(errL, "expandApplicationImportTemplate> Unable to load template %s", ref.Template)
+	}
+
+	tf, errF := exportentities.GetFormat(t.Format)
+	if errF != nil {
+		return nil, f, nil, sdk.WrapError(errF, "expandApplicationImportTemplate> Invalid format of template %s", t.Name)
+	}
+
+	content, missing := t.Expand(ref.Parameters)
+	var msgs []sdk.Message
+	for _, name := range missing {
+		msgs = append(msgs, sdk.NewMessage(sdk.MsgAppImportTemplateParamMissing, name, t.Name))
+	}
+	return []byte(content), tf, msgs, nil
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ovh/cds/engine/api/application"
+	"github.com/ovh/cds/engine/api/auth"
+	"github.com/ovh/cds/engine/api/test"
+	"github.com/ovh/cds/engine/api/test/assets"
+	"github.com/ovh/cds/sdk"
+)
+
+func Test_checkImportTemplate(t *testing.T) {
+	tmpl := sdk.ImportTemplate{
+		Name:       "service",
+		Format:     "yaml",
+		Content:    "name: {{.name}}\n",
+		Parameters: []sdk.ImportTemplateParameter{{Name: "name", Required: true}},
+	}
+	assert.NoError(t, checkImportTemplate(&tmpl))
+
+	invalid := tmpl
+	invalid.Format = "xml"
+	assert.Error(t, checkImportTemplate(&invalid))
+
+	invalid = tmpl
+	invalid.Parameters = []sdk.ImportTemplateParameter{{Name: "name"}, {Name: "name"}}
+	assert.Error(t, checkImportTemplate(&invalid))
+
+	invalid = tmpl
+	invalid.Parameters = []sdk.ImportTemplateParameter{{Name: "my name"}}
+	assert.Error(t, checkImportTemplate(&invalid))
+}
+
+func TestImportApplicationHandlerTemplate(t *testing.T) {
+	db := test.SetupPG(t)
+
+	router = newRouter(auth.TestLocalAuth(t), mux.NewRouter(), "/TestImportApplicationHandlerTemplate")
+	router.init()
+
+	u, pass := assets.InsertAdminUser(db)
+	proj := assets.InsertTestProject(t, db, sdk.RandomString(10), sdk.RandomString(10), nil)
+	test.NotNil(t, proj)
+
+	tmpl := sdk.ImportTemplate{
+		Name:    "service",
+		Format:  "yaml",
+		Content: "name: {{.name}}\nvariables:\n  tier:\n    value: \"{{.tier}}\"\n",
+		Parameters: []sdk.ImportTemplateParameter{
+			{Name: "name", Required: true},
+			{Name: "tier", Default: "3"},
+		},
+	}
+	body, err := json.Marshal(tmpl)
+	test.NoError(t, err)
+	uri := router.getRoute("POST", addImportTemplateHandler, map[string]string{"permProjectKey": proj.Key})
+	test.NotEmpty(t, uri)
+	req, err := http.NewRequest("POST", uri, bytes.NewReader(body))
+	test.NoError(t, err)
+	assets.AuthentifyRequest(t, req, u, pass)
+	w := httptest.NewRecorder()
+	router.mux.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	uri = router.getRoute("POST", importApplicationHandler, map[string]string{"permProjectKey": proj.Key})
+	test.NotEmpty(t, uri)
+	doImport := func(payload string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest("POST", uri+"?format=yaml&messageFormat=structured", strings.NewReader(payload))
+		test.NoError(t, err)
+		assets.AuthentifyRequest(t, req, u, pass)
+		w := httptest.NewRecorder()
+		router.mux.ServeHTTP(w, req)
+		return w
+	}
+
+	//The name is required
+	w = doImport("template: service\nparameters:\n  tier: \"1\"\n")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	res := sdk.ImportResult{}
+	test.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+	if assert.Len(t, res.Messages, 1) {
+		assert.Equal(t, sdk.MsgAppImportTemplateParamMissing.ID, res.Messages[0].ID)
+	}
+
+	w = doImport("template: service\nparameters:\n  name: billing\n")
+	assert.Equal(t, http.StatusOK, w.Code)
+	app, err := application.LoadByName(db, proj.Key, "billing", u, application.LoadOptions.WithVariables)
+	test.NoError(t, err)
+	if assert.Len(t, app.Variable, 1) {
+		assert.Equal(t, "3", app.Variable[0].Value)
+	}
+}
@@ -0,0 +1,1936 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
+
+	"github.com/ovh/cds/engine/api/application"
+	"github.com/ovh/cds/engine/api/auth"
+	"github.com/ovh/cds/engine/api/bootstrap"
+	"github.com/ovh/cds/engine/api/environment"
+	"github.com/ovh/cds/engine/api/group"
+	"github.com/ovh/cds/engine/api/pipeline"
+	"github.com/ovh/cds/engine/api/test"
+	"github.com/ovh/cds/engine/api/test/assets"
+	"github.com/ovh/cds/sdk"
+	"github.com/ovh/cds/sdk/exportentities"
+)
+
+func Test_parseImportRenames(t *testing.T) {
+	renames, err := parseImportRenames([]string{"app1:app2", "staging:preprod"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"app1": "app2", "staging": "preprod"}, renames)
+
+	_, err = parseImportRenames([]string{"app1"})
+	assert.Error(t, err)
+
+	_, err = parseImportRenames([]string{"app1:"})
+	assert.Error(t, err)
+}
+
+func Test_renameApplicationImport(t *testing.T) {
+	proj := &sdk.Project{Key: "DEST"}
+	app := &sdk.Application{
+		Name: "app1",
+		Pipelines: []sdk.ApplicationPipeline{
+			{
+				Pipeline: sdk.Pipeline{Name: "build"},
+				Triggers: []sdk.PipelineTrigger{
+					{
+						DestProject:     sdk.Project{Key: "SRC"},
+						DestApplication: sdk.Application{Name: "app1"},
+						DestPipeline:    sdk.Pipeline{Name: "deploy"},
+						DestEnvironment: sdk.Environment{Name: "staging"},
+					},
+				},
+			},
+		},
+		Notifications: []sdk.UserNotification{
+			{Pipeline: sdk.Pipeline{Name: "deploy"}, Environment: sdk.Environment{Name: "staging"}},
+		},
+		Schedulers: []sdk.PipelineScheduler{
+			{PipelineName: "deploy", EnvironmentName: "production"},
+		},
+	}
+
+	msgChan, collect := newMessageCollector()
+	renameApplicationImport(proj, app, map[string]string{"app1": "app2", "staging": "preprod"}, msgChan)
+	msgs := sdk.DedupMessages(collect())
+
+	assert.Equal(t, "app2", app.Name)
+	tr := app.Pipelines[0].Triggers[0]
+	assert.Equal(t, "DEST", tr.DestProject.Key)
+	assert.Equal(t, "app2", tr.DestApplication.Name)
+	assert.Equal(t, "preprod", tr.DestEnvironment.Name)
+	assert.Equal(t, "preprod", app.Notifications[0].Environment.Name)
+	assert.Equal(t, "production", app.Schedulers[0].EnvironmentName)
+
+	assert.Equal(t, []sdk.Message{
+		sdk.NewMessage(sdk.MsgAppImportRenamed, "app1", "app2"),
+		sdk.NewMessage(sdk.MsgAppImportTriggerProjectRewritten, "deploy", "DEST", "SRC"),
+		sdk.NewMessage(sdk.MsgAppImportRenamed, "staging", "preprod"),
+	}, msgs)
+}
+
+func Test_filterApplicationImport(t *testing.T) {
+	proj := &sdk.Project{Key: "KEY"}
+	build := sdk.Pipeline{Name: "build"}
+	deploy := sdk.Pipeline{Name: "deploy"}
+	app := &sdk.Application{
+		Name: "app1",
+		Pipelines: []sdk.ApplicationPipeline{
+			{
+				Pipeline: build,
+				Triggers: []sdk.PipelineTrigger{
+					{DestPipeline: deploy},
+					{DestPipeline: deploy, DestApplication: sdk.Application{Name: "app2"}},
+				},
+			},
+			{Pipeline: deploy},
+		},
+		Hooks:             []sdk.Hook{{Pipeline: build}, {Pipeline: deploy}},
+		RepositoryPollers: []sdk.RepositoryPoller{{Pipeline: deploy}},
+		Notifications:     []sdk.UserNotification{{Pipeline: build}, {Pipeline: deploy}},
+		Schedulers:        []sdk.PipelineScheduler{{PipelineName: deploy.Name}},
+	}
+
+	msgChan, collect := newMessageCollector()
+	assert.NoError(t, filterApplicationImport(proj, app, []string{"build"}, msgChan))
+	msgs := collect()
+
+	assert.Len(t, app.Pipelines, 1)
+	assert.Equal(t, "build", app.Pipelines[0].Pipeline.Name)
+	assert.Len(t, app.Pipelines[0].Triggers, 1)
+	assert.Equal(t, "app2", app.Pipelines[0].Triggers[0].DestApplication.Name)
+	assert.Equal(t, []sdk.Hook{{Pipeline: build}}, app.Hooks)
+	assert.Empty(t, app.RepositoryPollers)
+	assert.Equal(t, []sdk.UserNotification{{Pipeline: build}}, app.Notifications)
+	assert.Empty(t, app.Schedulers)
+	assert.Equal(t, []sdk.Message{sdk.NewMessage(sdk.MsgAppImportTriggerSkipped, "build", "deploy")}, msgs)
+
+	assert.Error(t, filterApplicationImport(proj, app, []string{"unknown"}, nil))
+}
+
+func Test_disableApplicationImport(t *testing.T) {
+	app := &sdk.Application{
+		Name:                "app1",
+		RepositoriesManager: &sdk.RepositoriesManager{Name: "github"},
+		RepositoryFullname:  "ovh/cds",
+		Pipelines:           []sdk.ApplicationPipeline{{Pipeline: sdk.Pipeline{Name: "build"}}, {Pipeline: sdk.Pipeline{Name: "deploy"}}},
+		RepositoryPollers:   []sdk.RepositoryPoller{{Pipeline: sdk.Pipeline{Name: "deploy"}, Enabled: true}},
+	}
+
+	//The default hook of a new application is created disabled
+	disableApplicationImport(app, false)
+	if assert.Len(t, app.Hooks, 1) {
+		assert.Equal(t, "build", app.Hooks[0].Pipeline.Name)
+		assert.False(t, app.Hooks[0].Enabled)
+	}
+	assert.False(t, app.RepositoryPollers[0].Enabled)
+
+	//An existing application doesn't get a default hook
+	app.Hooks = nil
+	disableApplicationImport(app, true)
+	assert.Len(t, app.Hooks, 0)
+}
+
+func TestImportApplicationHandlerIdempotencyKey(t *testing.T) {
+	db := test.SetupPG(t)
+
+	router = newRouter(auth.TestLocalAuth(t), mux.NewRouter(), "/TestImportApplicationHandlerIdempotencyKey")
+	router.init()
+
+	u, pass := assets.InsertAdminUser(db)
+	proj := assets.InsertTestProject(t, db, sdk.RandomString(10), sdk.RandomString(10), nil)
+	test.NotNil(t, proj)
+
+	uri := router.getRoute("POST", importApplicationHandler, map[string]string{"permProjectKey": proj.Key})
+	test.NotEmpty(t, uri)
+
+	key := sdk.RandomString(10)
+	doImport := func(payload string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest("POST", uri+"?format=yaml", strings.NewReader(payload))
+		test.NoError(t, err)
+		assets.AuthentifyRequest(t, req, u, pass)
+		req.Header.Set("X-Idempotency-Key", key)
+		w := httptest.NewRecorder()
+		router.mux.ServeHTTP(w, req)
+		return w
+	}
+
+	//1. First attempt fails: the key must stay reusable
+	w := doImport("name: app1\npipelines:\n  unknown: {}\n")
+	assert.NotEqual(t, 200, w.Code)
+
+	//2. Second attempt succeeds
+	w = doImport("name: app1\n")
+	assert.Equal(t, 200, w.Code)
+	assert.Empty(t, w.Header().Get("X-Idempotent-Replay"))
+
+	//3. Retry returns the result of the second attempt without importing anything
+	w = doImport("name: app2\n")
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "true", w.Header().Get("X-Idempotent-Replay"))
+
+	exist, err := application.Exists(db, proj.Key, "app1")
+	test.NoError(t, err)
+	assert.True(t, exist)
+
+	exist, err = application.Exists(db, proj.Key, "app2")
+	test.NoError(t, err)
+	assert.False(t, exist)
+}
+
+func TestImportApplicationHandlerUnknownGroup(t *testing.T) {
+	db := test.SetupPG(t)
+
+	router = newRouter(auth.TestLocalAuth(t), mux.NewRouter(), "/TestImportApplicationHandlerUnknownGroup")
+	router.init()
+
+	u, pass := assets.InsertAdminUser(db)
+	proj := assets.InsertTestProject(t, db, sdk.RandomString(10), sdk.RandomString(10), nil)
+	test.NotNil(t, proj)
+
+	uri := router.getRoute("POST", importApplicationHandler, map[string]string{"permProjectKey": proj.Key})
+	test.NotEmpty(t, uri)
+
+	payload := "name: app1\npermissions:\n  " + sdk.RandomString(10) + ": 7\n"
+	doImport := func(query string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest("POST", uri+"?format=yaml&messageFormat=structured"+query, strings.NewReader(payload))
+		test.NoError(t, err)
+		assets.AuthentifyRequest(t, req, u, pass)
+		w := httptest.NewRecorder()
+		router.mux.ServeHTTP(w, req)
+		return w
+	}
+
+	//1. By default, an unknown group fails the import
+	w := doImport("")
+	assert.Equal(t, sdk.ErrGroupNotFound.Status, w.Code)
+
+	exist, err := application.Exists(db, proj.Key, "app1")
+	test.NoError(t, err)
+	assert.False(t, exist)
+
+	//2. With ignoreUnknownGroups, the permission is dropped
+	w = doImport("&ignoreUnknownGroups=true")
+	assert.Equal(t, 200, w.Code)
+
+	res := sdk.ImportResult{}
+	test.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+	var found bool
+	for _, m := range res.Messages {
+		if m.ID == sdk.MsgAppImportGroupNotFound.ID {
+			found = true
+		}
+	}
+	assert.True(t, found, "MsgAppImportGroupNotFound not found in %v", res.Messages)
+
+	exist, err = application.Exists(db, proj.Key, "app1")
+	test.NoError(t, err)
+	assert.True(t, exist)
+}
+
+func TestImportApplicationHandlerKeys(t *testing.T) {
+	db := test.SetupPG(t)
+
+	router = newRouter(auth.TestLocalAuth(t), mux.NewRouter(), "/TestImportApplicationHandlerKeys")
+	router.init()
+
+	u, pass := assets.InsertAdminUser(db)
+	proj := assets.InsertTestProject(t, db, sdk.RandomString(10), sdk.RandomString(10), nil)
+	test.NotNil(t, proj)
+
+	uri := router.getRoute("POST", importApplicationHandler, map[string]string{"permProjectKey": proj.Key})
+	test.NotEmpty(t, uri)
+
+	payload := "name: app1\nkeys:\n  deploy:\n    type: ssh\n  sign:\n    type: pgp\n"
+	doImport := func(query string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest("POST", uri+"?format=yaml&messageFormat=structured"+query, strings.NewReader(payload))
+		test.NoError(t, err)
+		assets.AuthentifyRequest(t, req, u, pass)
+		w := httptest.NewRecorder()
+		router.mux.ServeHTTP(w, req)
+		return w
+	}
+	loadKeys := func() map[string]sdk.ApplicationKey {
+		app, err := application.LoadByName(db, proj.Key, "app1", u, application.LoadOptions.WithKeys)
+		test.NoError(t, err)
+		keys := map[string]sdk.ApplicationKey{}
+		for _, k := range app.Keys {
+			keys[k.Name] = k
+		}
+		return keys
+	}
+
+	//1. Keys are generated on creation
+	w := doImport("")
+	assert.Equal(t, 200, w.Code)
+	assert.Contains(t, w.Body.String(), sdk.MsgAppImportKeyCreated.ID)
+	assert.NotContains(t, w.Body.String(), "PRIVATE KEY")
+
+	keys := loadKeys()
+	assert.Equal(t, sdk.KeyTypeSsh, keys["deploy"].Type)
+	assert.Equal(t, sdk.KeyTypePgp, keys["sign"].Type)
+
+	//2. Existing keys are kept on update
+	w = doImport("&forceUpdate=true")
+	assert.Equal(t, 200, w.Code)
+	assert.Contains(t, w.Body.String(), sdk.MsgAppImportKeyExists.ID)
+	assert.Equal(t, keys["deploy"].Public, loadKeys()["deploy"].Public)
+
+	//3. Existing keys are regenerated with regenerateKeys
+	w = doImport("&forceUpdate=true&regenerateKeys=true")
+	assert.Equal(t, 200, w.Code)
+	assert.Contains(t, w.Body.String(), sdk.MsgAppImportKeyRegenerated.ID)
+	assert.NotEqual(t, keys["deploy"].Public, loadKeys()["deploy"].Public)
+}
+
+func Test_parseApplicationImportJSON(t *testing.T) {
+	payload := `{
+  "name": "app1",
+  "pipelines": {
+    "deploy": {
+      "options": [
+        {"environment": "preprod", "schedulers": [{"cron_expr": "0 * * * *"}, {"cron_expr": "30 * * * *"}]},
+        {"environment": "prod", "schedulers": [{"cron_expr": "0 0 * * *"}]}
+      ]
+    }
+  }
+}`
+
+	a, err := parseApplicationImport([]byte(payload), exportentities.FormatJSON)
+	test.NoError(t, err)
+
+	options := a.Pipelines["deploy"].Options
+	if assert.Len(t, options, 2) {
+		assert.Equal(t, "preprod", *options[0].Environment)
+		assert.Len(t, options[0].Schedulers, 2)
+		assert.Equal(t, "prod", *options[1].Environment)
+		assert.Len(t, options[1].Schedulers, 1)
+	}
+
+	//HCL syntax is not valid JSON
+	_, err = parseApplicationImport([]byte(`name = "app1"`), exportentities.FormatJSON)
+	assert.Error(t, err)
+
+	a, err = parseApplicationImport([]byte(`name = "app1"`), exportentities.FormatHCL)
+	test.NoError(t, err)
+	assert.Equal(t, "app1", a.Name)
+}
+
+func Test_overrideApplicationImport(t *testing.T) {
+	overrides, err := parseImportOverrides(`[
+		{"key": "url", "value": "https://prod.example.com"},
+		{"pipeline": "deploy", "key": "region", "value": "eu-west"},
+		{"pipeline": "deploy", "environment": "prod", "key": "region", "value": "us-east"}
+	]`)
+	test.NoError(t, err)
+
+	app := &sdk.Application{
+		Name:     "app1",
+		Variable: []sdk.Variable{{Name: "url", Value: "https://preprod.example.com"}},
+		Pipelines: []sdk.ApplicationPipeline{
+			{
+				Pipeline:   sdk.Pipeline{Name: "deploy"},
+				Parameters: []sdk.Parameter{{Name: "region", Value: "local"}},
+			},
+		},
+		Schedulers: []sdk.PipelineScheduler{
+			{PipelineName: "deploy", EnvironmentName: "preprod", Args: []sdk.Parameter{{Name: "region", Value: "local"}}},
+			{PipelineName: "deploy", EnvironmentName: "prod", Args: []sdk.Parameter{{Name: "region", Value: "local"}}},
+		},
+	}
+
+	msgChan, collect := newMessageCollector()
+	test.NoError(t, overrideApplicationImport(app, overrides, msgChan))
+	assert.Equal(t, []sdk.Message{
+		sdk.NewMessage(sdk.MsgAppImportOverrideApplied, "url", "app1"),
+		sdk.NewMessage(sdk.MsgAppImportOverrideApplied, "region", "app1/deploy"),
+		sdk.NewMessage(sdk.MsgAppImportOverrideApplied, "region", "app1/deploy[prod]"),
+	}, collect())
+
+	assert.Equal(t, "https://prod.example.com", app.Variable[0].Value)
+	assert.Equal(t, "eu-west", app.Pipelines[0].Parameters[0].Value)
+	assert.Equal(t, "local", app.Schedulers[0].Args[0].Value)
+	assert.Equal(t, "us-east", app.Schedulers[1].Args[0].Value)
+
+	//The overridden variable must exist
+	err = overrideApplicationImport(app, []applicationImportOverride{{Pipeline: "build", Key: "region"}}, nil)
+	assert.Equal(t, sdk.ErrNoVariable, errors.Cause(err))
+
+	_, err = parseImportOverrides(`[{"environment": "prod", "key": "region"}]`)
+	assert.Error(t, err)
+}
+
+func Test_resolveApplicationPipelineRefs(t *testing.T) {
+	app := &sdk.Application{
+		Name: "app1",
+		Pipelines: []sdk.ApplicationPipeline{
+			{
+				Pipeline: sdk.Pipeline{Name: "build", Slug: "build"},
+				Triggers: []sdk.PipelineTrigger{
+					{DestPipeline: sdk.Pipeline{Name: "deploy"}},
+				},
+			},
+			{Pipeline: sdk.Pipeline{Name: "deploy"}},
+		},
+		Hooks:      []sdk.Hook{{Pipeline: sdk.Pipeline{Name: "build"}}},
+		Schedulers: []sdk.PipelineScheduler{{PipelineName: "build"}},
+	}
+	slugs := map[string]sdk.Pipeline{
+		"build":  {ID: 1, Name: "build-renamed", Slug: "build"},
+		"deploy": {ID: 2, Name: "deploy", Slug: "deploy"},
+	}
+
+	msgChan, collect := newMessageCollector()
+	assert.NoError(t, resolveApplicationPipelineRefs(app, slugs, msgChan))
+	msgs := collect()
+
+	assert.Equal(t, "build-renamed", app.Pipelines[0].Pipeline.Name)
+	assert.Equal(t, "build-renamed", app.Hooks[0].Pipeline.Name)
+	assert.Equal(t, "build-renamed", app.Schedulers[0].PipelineName)
+	assert.Equal(t, "deploy", app.Pipelines[0].Triggers[0].DestPipeline.Name)
+	assert.Contains(t, msgs, sdk.NewMessage(sdk.MsgAppImportPipelineRefResolved, "build", "app1", "build-renamed"))
+	assert.Contains(t, msgs, sdk.NewMessage(sdk.MsgAppImportPipelineRefMissing, "deploy", "app1"))
+
+	//An unknown slug fails
+	app.Pipelines[1].Pipeline.Slug = "unknown"
+	err := resolveApplicationPipelineRefs(app, slugs, nil)
+	assert.Equal(t, sdk.ErrPipelineNotFound, errors.Cause(err))
+
+	//Two entries referencing the same pipeline fail
+	app.Pipelines[1].Pipeline = sdk.Pipeline{Name: "build2", Slug: "build"}
+	msgChan, collect = newMessageCollector()
+	err = resolveApplicationPipelineRefs(app, slugs, msgChan)
+	assert.Equal(t, sdk.ErrWrongRequest, errors.Cause(err))
+	assert.Contains(t, collect(), sdk.NewMessage(sdk.MsgAppImportDuplicatePipeline, "build-renamed", 2, "app1"))
+}
+
+func TestImportApplicationHandlerDuplicatePipeline(t *testing.T) {
+	db := test.SetupPG(t)
+
+	router = newRouter(auth.TestLocalAuth(t), mux.NewRouter(), "/TestImportApplicationHandlerDuplicatePipeline")
+	router.init()
+
+	u, pass := assets.InsertAdminUser(db)
+	proj := assets.InsertTestProject(t, db, sdk.RandomString(10), sdk.RandomString(10), nil)
+	test.NotNil(t, proj)
+
+	uri := router.getRoute("POST", importApplicationHandler, map[string]string{"permProjectKey": proj.Key})
+	test.NotEmpty(t, uri)
+	doImport := func(query string) (int, []string) {
+		payload := `name: app1
+pipelines:
+  build:
+    definition:
+      steps:
+      - script: make build
+  build:
+    definition:
+      steps:
+      - script: make all
+`
+		req, err := http.NewRequest("POST", uri+"?format=yaml&messageFormat=structured"+query, strings.NewReader(payload))
+		test.NoError(t, err)
+		assets.AuthentifyRequest(t, req, u, pass)
+		w := httptest.NewRecorder()
+		router.mux.ServeHTTP(w, req)
+		res := sdk.ImportResult{}
+		test.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		ids := []string{}
+		for _, m := range res.Messages {
+			ids = append(ids, m.ID)
+		}
+		return w.Code, ids
+	}
+
+	code, ids := doImport("")
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, []string{sdk.MsgAppImportDuplicatePipeline.ID}, ids)
+	_, err := application.LoadByName(db, proj.Key, "app1", u)
+	assert.Error(t, err)
+
+	code, _ = doImport("&duplicatePipelines=first")
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	code, ids = doImport("&duplicatePipelines=keepLast")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, ids, sdk.MsgAppImportDuplicatePipelineKept.ID)
+	app, err := application.LoadByName(db, proj.Key, "app1", u, application.LoadOptions.WithPipelines)
+	test.NoError(t, err)
+	assert.Len(t, app.Pipelines, 1)
+}
+
+func TestImportApplicationHandlerOnlyPermissions(t *testing.T) {
+	db := test.SetupPG(t)
+
+	router = newRouter(auth.TestLocalAuth(t), mux.NewRouter(), "/TestImportApplicationHandlerOnlyPermissions")
+	router.init()
+
+	u, pass := assets.InsertAdminUser(db)
+	proj := assets.InsertTestProject(t, db, sdk.RandomString(10), sdk.RandomString(10), nil)
+	test.NotNil(t, proj)
+	g := &sdk.Group{Name: sdk.RandomString(10)}
+	test.NoError(t, group.InsertGroup(db, g))
+	projGroup := proj.ProjectGroups[0].Group.Name
+
+	uri := router.getRoute("POST", importApplicationHandler, map[string]string{"permProjectKey": proj.Key})
+	test.NotEmpty(t, uri)
+	doImport := func(payload, query string) (int, []string) {
+		req, err := http.NewRequest("POST", uri+"?format=yaml&messageFormat=structured"+query, strings.NewReader(payload))
+		test.NoError(t, err)
+		assets.AuthentifyRequest(t, req, u, pass)
+		w := httptest.NewRecorder()
+		router.mux.ServeHTTP(w, req)
+		res := sdk.ImportResult{}
+		test.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		ids := []string{}
+		for _, m := range res.Messages {
+			ids = append(ids, m.ID)
+		}
+		return w.Code, ids
+	}
+
+	//The application must exist
+	code, _ := doImport("name: app1\npermissions:\n  "+projGroup+": 7\n", "&only=permissions")
+	assert.Equal(t, http.StatusNotFound, code)
+
+	code, _ = doImport("name: app1\nvariables:\n  tier:\n    value: \"1\"\npermissions:\n  "+projGroup+": 7\n", "")
+	assert.Equal(t, http.StatusOK, code)
+
+	//The variables are not imported
+	payload := "name: app1\nvariables:\n  tier:\n    value: \"2\"\npermissions:\n  " + g.Name + ": 4\n  " + projGroup + ": 7\n"
+	code, ids := doImport(payload, "&only=permissions")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, ids, sdk.MsgAppGroupSetPermission.ID)
+	assert.Contains(t, ids, sdk.MsgAppImportGroupUnchanged.ID)
+	app, err := application.LoadByName(db, proj.Key, "app1", u, application.LoadOptions.WithVariables, application.LoadOptions.WithGroups)
+	test.NoError(t, err)
+	assert.Len(t, app.ApplicationGroups, 2)
+	if assert.Len(t, app.Variable, 1) {
+		assert.Equal(t, "1", app.Variable[0].Value)
+	}
+
+	//A group with the write permission is required
+	code, _ = doImport("name: app1\npermissions:\n  "+g.Name+": 4\n", "&only=permissions&prune=true")
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	code, ids = doImport("name: app1\npermissions:\n  "+projGroup+": 7\n", "&only=permissions&prune=true")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, ids, sdk.MsgAppImportGroupRevoked.ID)
+	app, err = application.LoadByName(db, proj.Key, "app1", u, application.LoadOptions.WithGroups)
+	test.NoError(t, err)
+	if assert.Len(t, app.ApplicationGroups, 1) {
+		assert.Equal(t, projGroup, app.ApplicationGroups[0].Group.Name)
+	}
+}
+
+func TestImportApplicationHandlerVerify(t *testing.T) {
+	db := test.SetupPG(t)
+
+	router = newRouter(auth.TestLocalAuth(t), mux.NewRouter(), "/TestImportApplicationHandlerVerify")
+	router.init()
+
+	u, pass := assets.InsertAdminUser(db)
+	proj := assets.InsertTestProject(t, db, sdk.RandomString(10), sdk.RandomString(10), nil)
+	test.NotNil(t, proj)
+
+	payload := `name: app1
+pipelines:
+  build:
+    definition:
+      steps:
+      - script: make build
+    options:
+    - notifications:
+        email:
+          on_success: change
+          on_failure: always
+`
+	uri := router.getRoute("POST", importApplicationHandler, map[string]string{"permProjectKey": proj.Key})
+	test.NotEmpty(t, uri)
+	req, err := http.NewRequest("POST", uri+"?format=yaml&messageFormat=structured&verify=true", strings.NewReader(payload))
+	test.NoError(t, err)
+	assets.AuthentifyRequest(t, req, u, pass)
+	w := httptest.NewRecorder()
+	router.mux.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), sdk.MsgAppImportVerifyFailed.ID)
+
+	//A resource which is not stored is reported
+	app, err := application.LoadByName(db, proj.Key, "app1", u, application.LoadOptions.WithNotifs)
+	test.NoError(t, err)
+	app.Hooks = []sdk.Hook{{Pipeline: app.Notifications[0].Pipeline}}
+	msgs, err := verifyApplicationImport(db, proj, app)
+	test.NoError(t, err)
+	if assert.Len(t, msgs, 1) {
+		assert.Equal(t, sdk.MsgAppImportVerifyFailed.ID, msgs[0].ID)
+	}
+}
+
+func TestImportApplicationHandlerPayloadTooLarge(t *testing.T) {
+	db := test.SetupPG(t)
+
+	router = newRouter(auth.TestLocalAuth(t), mux.NewRouter(), "/TestImportApplicationHandlerPayloadTooLarge")
+	router.init()
+
+	u, pass := assets.InsertAdminUser(db)
+	proj := assets.InsertTestProject(t, db, sdk.RandomString(10), sdk.RandomString(10), nil)
+	test.NotNil(t, proj)
+
+	viper.Set(viperImportBodyMaxSize, 64)
+	defer viper.Set(viperImportBodyMaxSize, nil)
+
+	uri := router.getRoute("POST", importApplicationHandler, map[string]string{"permProjectKey": proj.Key})
+	test.NotEmpty(t, uri)
+	payload := "name: app1\nvariables:\n  tier:\n    value: " + strings.Repeat("a", 64) + "\n"
+	req, err := http.NewRequest("POST", uri+"?format=yaml&messageFormat=structured", strings.NewReader(payload))
+	test.NoError(t, err)
+	assets.AuthentifyRequest(t, req, u, pass)
+	w := httptest.NewRecorder()
+	router.mux.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.Contains(t, w.Body.String(), sdk.MsgAppImportPayloadTooLarge.ID)
+
+	//A multipart body is limited before its form is parsed
+	body := &bytes.Buffer{}
+	mw := multipart.NewWriter(body)
+	part, err := mw.CreateFormFile("file", "app1.yml")
+	test.NoError(t, err)
+	_, err = part.Write([]byte(payload))
+	test.NoError(t, err)
+	test.NoError(t, mw.Close())
+	req, err = http.NewRequest("POST", uri+"?format=yaml&messageFormat=structured", body)
+	test.NoError(t, err)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	assets.AuthentifyRequest(t, req, u, pass)
+	w = httptest.NewRecorder()
+	router.mux.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.Contains(t, w.Body.String(), sdk.MsgAppImportPayloadTooLarge.ID)
+
+	_, err = application.LoadByName(db, proj.Key, "app1", u)
+	assert.Error(t, err)
+}
+
+func TestImportApplicationHandlerCaseInsensitiveEnv(t *testing.T) {
+	db := test.SetupPG(t)
+
+	router = newRouter(auth.TestLocalAuth(t), mux.NewRouter(), "/TestImportApplicationHandlerCaseInsensitiveEnv")
+	router.init()
+
+	u, pass := assets.InsertAdminUser(db)
+	proj := assets.InsertTestProject(t, db, sdk.RandomString(10), sdk.RandomString(10), nil)
+	test.NotNil(t, proj)
+	test.NoError(t, environment.InsertEnvironment(db, &sdk.Environment{Name: "production", ProjectID: proj.ID}))
+
+	payload := `name: app1
+pipelines:
+  build:
+    definition:
+      steps:
+      - script: make build
+    options:
+    - environment: Production
+      notifications:
+        email:
+          on_success: change
+          on_failure: always
+`
+	uri := router.getRoute("POST", importApplicationHandler, map[string]string{"permProjectKey": proj.Key})
+	test.NotEmpty(t, uri)
+	doImport := func(query string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest("POST", uri+"?format=yaml&messageFormat=structured"+query, strings.NewReader(payload))
+		test.NoError(t, err)
+		assets.AuthentifyRequest(t, req, u, pass)
+		w := httptest.NewRecorder()
+		router.mux.ServeHTTP(w, req)
+		return w
+	}
+
+	//The names are case sensitive by default
+	w := doImport("")
+	assert.NotEqual(t, http.StatusOK, w.Code)
+
+	w = doImport("&caseInsensitiveEnv=true")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), sdk.MsgAppImportEnvCaseCorrected.ID)
+	app, err := application.LoadByName(db, proj.Key, "app1", u, application.LoadOptions.WithNotifs)
+	test.NoError(t, err)
+	if assert.Len(t, app.Notifications, 1) {
+		assert.Equal(t, "production", app.Notifications[0].Environment.Name)
+	}
+}
+
+func TestValidateApplicationHandler(t *testing.T) {
+	db := test.SetupPG(t)
+
+	router = newRouter(auth.TestLocalAuth(t), mux.NewRouter(), "/TestValidateApplicationHandler")
+	router.init()
+
+	u, pass := assets.InsertAdminUser(db)
+	proj := assets.InsertTestProject(t, db, sdk.RandomString(10), sdk.RandomString(10), nil)
+	test.NotNil(t, proj)
+
+	uri := router.getRoute("POST", validateApplicationHandler, map[string]string{"permProjectKey": proj.Key})
+	test.NotEmpty(t, uri)
+
+	doValidate := func(payload string) (*httptest.ResponseRecorder, sdk.ImportResult) {
+		req, err := http.NewRequest("POST", uri+"?format=yaml", strings.NewReader(payload))
+		test.NoError(t, err)
+		assets.AuthentifyRequest(t, req, u, pass)
+		w := httptest.NewRecorder()
+		router.mux.ServeHTTP(w, req)
+		res := sdk.ImportResult{}
+		test.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		return w, res
+	}
+
+	w, _ := doValidate("name: app1\n")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	//All the problems are returned, unknown pipelines are not checked
+	w, res := doValidate(`name: app1
+pipelines:
+  build:
+    options:
+    - schedulers:
+      - cron_expr: every day
+      notifications:
+        sms:
+          on_success: never
+          on_failure: always
+          send_to_author: true
+`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Len(t, res.Messages, 2)
+	assert.Contains(t, w.Body.String(), sdk.MsgSchedulerInvalidCron.ID)
+	assert.Contains(t, w.Body.String(), sdk.MsgAppImportNotifInvalid.ID)
+
+	_, err := application.LoadByName(db, proj.Key, "app1", u)
+	assert.Error(t, err)
+}
+
+func TestImportApplicationHandlerWarnings(t *testing.T) {
+	db := test.SetupPG(t)
+
+	router = newRouter(auth.TestLocalAuth(t), mux.NewRouter(), "/TestImportApplicationHandlerWarnings")
+	router.init()
+
+	u, pass := assets.InsertAdminUser(db)
+	proj := assets.InsertTestProject(t, db, sdk.RandomString(10), sdk.RandomString(10), nil)
+	test.NotNil(t, proj)
+
+	uri := router.getRoute("POST", importApplicationHandler, map[string]string{"permProjectKey": proj.Key})
+	test.NotEmpty(t, uri)
+
+	//The project has no environment variable
+	payload := "name: app1\nvariables:\n  foo:\n    value: \"{{.cds.env.bar}}\"\n"
+	req, err := http.NewRequest("POST", uri+"?format=yaml&messageFormat=structured", strings.NewReader(payload))
+	test.NoError(t, err)
+	assets.AuthentifyRequest(t, req, u, pass)
+	w := httptest.NewRecorder()
+	router.mux.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	res := sdk.ImportResult{}
+	test.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+	assert.Equal(t, 1, res.Summary.Warnings)
+
+	var warnings int
+	for _, m := range res.Messages {
+		if m.Level == sdk.MessageLevelWarning {
+			warnings++
+		}
+	}
+	assert.Equal(t, 1, warnings)
+}
+
+func TestImportApplicationHandlerFailOnSanityError(t *testing.T) {
+	db := test.SetupPG(t)
+
+	router = newRouter(auth.TestLocalAuth(t), mux.NewRouter(), "/TestImportApplicationHandlerFailOnSanityError")
+	router.init()
+
+	u, pass := assets.InsertAdminUser(db)
+	proj := assets.InsertTestProject(t, db, sdk.RandomString(10), sdk.RandomString(10), nil)
+	test.NotNil(t, proj)
+
+	uri := router.getRoute("POST", importApplicationHandler, map[string]string{"permProjectKey": proj.Key})
+	test.NotEmpty(t, uri)
+	doImport := func(payload, query string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest("POST", uri+"?format=yaml&messageFormat=structured"+query, strings.NewReader(payload))
+		test.NoError(t, err)
+		assets.AuthentifyRequest(t, req, u, pass)
+		w := httptest.NewRecorder()
+		router.mux.ServeHTTP(w, req)
+		return w
+	}
+
+	//A badly formatted variable is a sanity error, a missing environment is only a warning
+	w := doImport("name: app1\nvariables:\n  foo:\n    value: \"{{cds.app.bar}}\"\n", "&failOnSanityError=true")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	_, err := application.LoadByName(db, proj.Key, "app1", u)
+	assert.Error(t, err)
+
+	w = doImport("name: app1\nvariables:\n  foo:\n    value: \"{{.cds.env.bar}}\"\n", "&failOnSanityError=true")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = doImport("name: app2\nvariables:\n  foo:\n    value: \"{{cds.app.bar}}\"\n", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	res := sdk.ImportResult{}
+	test.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+	assert.Equal(t, 1, res.Summary.Warnings)
+}
+
+func TestImportApplicationHandlerAllOrNothing(t *testing.T) {
+	db := test.SetupPG(t)
+
+	router = newRouter(auth.TestLocalAuth(t), mux.NewRouter(), "/TestImportApplicationHandlerAllOrNothing")
+	router.init()
+
+	u, pass := assets.InsertAdminUser(db)
+	proj := assets.InsertTestProject(t, db, sdk.RandomString(10), sdk.RandomString(10), nil)
+	test.NotNil(t, proj)
+
+	uri := router.getRoute("POST", importApplicationHandler, map[string]string{"permProjectKey": proj.Key})
+	test.NotEmpty(t, uri)
+
+	//Both schedulers are invalid, the pipeline is valid
+	payload := `pipelines:
+  build:
+    options:
+    - schedulers:
+      - cron_expr: every day
+      - cron_expr: every night
+`
+	doImport := func(name, query string) (*httptest.ResponseRecorder, sdk.ImportResult) {
+		req, err := http.NewRequest("POST", uri+"?format=yaml&messageFormat=structured"+query, strings.NewReader("name: "+name+"\n"+payload))
+		test.NoError(t, err)
+		assets.AuthentifyRequest(t, req, u, pass)
+		w := httptest.NewRecorder()
+		router.mux.ServeHTTP(w, req)
+		res := sdk.ImportResult{}
+		test.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		return w, res
+	}
+	countFailed := func(res sdk.ImportResult) int {
+		var n int
+		for _, m := range res.Messages {
+			if m.ID == sdk.MsgAppImportResourceFailed.ID {
+				n++
+			}
+		}
+		return n
+	}
+
+	//1. All the errors are returned and nothing is created
+	w, res := doImport("app1", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, 2, countFailed(res))
+	_, err := application.LoadByName(db, proj.Key, "app1", u)
+	assert.Error(t, err)
+
+	//2. The application is created without the schedulers
+	w, res = doImport("app2", "&allOrNothing=false")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 2, countFailed(res))
+	app, err := application.LoadByName(db, proj.Key, "app2", u, application.LoadOptions.WithPipelines)
+	test.NoError(t, err)
+	assert.Len(t, app.Pipelines, 1)
+}
+
+func TestImportApplicationHandlerPrune(t *testing.T) {
+	db := test.SetupPG(t)
+
+	router = newRouter(auth.TestLocalAuth(t), mux.NewRouter(), "/TestImportApplicationHandlerPrune")
+	router.init()
+
+	u, pass := assets.InsertAdminUser(db)
+	proj := assets.InsertTestProject(t, db, sdk.RandomString(10), sdk.RandomString(10), nil)
+	test.NotNil(t, proj)
+	for _, name := range []string{"build", "deploy"} {
+		pip := &sdk.Pipeline{Name: name, Type: sdk.BuildPipeline, ProjectKey: proj.Key, ProjectID: proj.ID}
+		test.NoError(t, pipeline.InsertPipeline(db, proj, pip, u))
+	}
+
+	uri := router.getRoute("POST", importApplicationHandler, map[string]string{"permProjectKey": proj.Key})
+	test.NotEmpty(t, uri)
+
+	doImport := func(payload, query string) sdk.ImportResult {
+		req, err := http.NewRequest("POST", uri+"?format=yaml&forceUpdate=true&messageFormat=structured"+query, strings.NewReader(payload))
+		test.NoError(t, err)
+		assets.AuthentifyRequest(t, req, u, pass)
+		w := httptest.NewRecorder()
+		router.mux.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		res := sdk.ImportResult{}
+		test.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		return res
+	}
+	countPruned := func(res sdk.ImportResult) int {
+		var n int
+		for _, m := range res.Messages {
+			if m.ID == sdk.MsgAppImportResourcePruned.ID {
+				n++
+			}
+		}
+		return n
+	}
+	loadPipelines := func() []sdk.ApplicationPipeline {
+		app, err := application.LoadByName(db, proj.Key, "app1", u, application.LoadOptions.WithPipelines)
+		test.NoError(t, err)
+		return app.Pipelines
+	}
+
+	doImport("name: app1\npipelines:\n  build: {}\n  deploy: {}\n", "")
+	assert.Len(t, loadPipelines(), 2)
+
+	//1. Without prune, the import is additive
+	res := doImport("name: app1\npipelines:\n  build: {}\n", "")
+	assert.Equal(t, 0, countPruned(res))
+	assert.Len(t, loadPipelines(), 2)
+
+	//2. With prune, the missing pipeline is detached
+	res = doImport("name: app1\npipelines:\n  build: {}\n", "&prune=true")
+	assert.Equal(t, 1, countPruned(res))
+	pips := loadPipelines()
+	if assert.Len(t, pips, 1) {
+		assert.Equal(t, "build", pips[0].Pipeline.Name)
+	}
+}
+
+func TestImportApplicationHandlerEmbeddedPipeline(t *testing.T) {
+	db := test.SetupPG(t)
+
+	router = newRouter(auth.TestLocalAuth(t), mux.NewRouter(), "/TestImportApplicationHandlerEmbeddedPipeline")
+	router.init()
+
+	u, pass := assets.InsertAdminUser(db)
+	proj := assets.InsertTestProject(t, db, sdk.RandomString(10), sdk.RandomString(10), nil)
+	test.NotNil(t, proj)
+
+	uri := router.getRoute("POST", importApplicationHandler, map[string]string{"permProjectKey": proj.Key})
+	test.NotEmpty(t, uri)
+
+	//The deploy pipeline is triggered by the build pipeline, both are created by the import
+	payload := `name: app1
+pipelines:
+  build:
+    definition:
+      steps:
+      - script: make build
+    triggers:
+      deploy: {}
+  deploy:
+    definition:
+      steps:
+      - script: make deploy
+`
+	req, err := http.NewRequest("POST", uri+"?format=yaml&messageFormat=structured", strings.NewReader(payload))
+	test.NoError(t, err)
+	assets.AuthentifyRequest(t, req, u, pass)
+	w := httptest.NewRecorder()
+	router.mux.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	res := sdk.ImportResult{}
+	test.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+	var created int
+	for _, m := range res.Messages {
+		if m.ID == sdk.MsgPipelineCreated.ID {
+			created++
+		}
+	}
+	assert.Equal(t, 2, created)
+
+	app, err := application.LoadByName(db, proj.Key, "app1", u, application.LoadOptions.WithPipelines, application.LoadOptions.WithTriggers)
+	test.NoError(t, err)
+	if assert.Len(t, app.Pipelines, 2) {
+		for _, ap := range app.Pipelines {
+			pip, err := pipeline.LoadPipeline(db, proj.Key, ap.Pipeline.Name, true)
+			test.NoError(t, err)
+			assert.Len(t, pip.Stages, 1)
+		}
+	}
+}
+
+func TestImportApplicationHandlerDestPipelineNotAttached(t *testing.T) {
+	db := test.SetupPG(t)
+
+	router = newRouter(auth.TestLocalAuth(t), mux.NewRouter(), "/TestImportApplicationHandlerDestPipelineNotAttached")
+	router.init()
+
+	u, pass := assets.InsertAdminUser(db)
+	proj := assets.InsertTestProject(t, db, sdk.RandomString(10), sdk.RandomString(10), nil)
+	test.NotNil(t, proj)
+
+	uri := router.getRoute("POST", importApplicationHandler, map[string]string{"permProjectKey": proj.Key})
+	test.NotEmpty(t, uri)
+	doImport := func(payload string) (*httptest.ResponseRecorder, sdk.ImportResult) {
+		req, err := http.NewRequest("POST", uri+"?format=yaml&messageFormat=structured", strings.NewReader(payload))
+		test.NoError(t, err)
+		assets.AuthentifyRequest(t, req, u, pass)
+		w := httptest.NewRecorder()
+		router.mux.ServeHTTP(w, req)
+		res := sdk.ImportResult{}
+		test.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		return w, res
+	}
+
+	//The build pipeline is attached to app2, the deploy pipeline exists but is only attached to app3
+	w, _ := doImport("name: app2\npipelines:\n  build:\n    definition:\n      steps:\n      - script: make build\n")
+	assert.Equal(t, http.StatusOK, w.Code)
+	w, _ = doImport("name: app3\npipelines:\n  deploy:\n    definition:\n      steps:\n      - script: make deploy\n")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w, res := doImport(`name: app1
+pipelines:
+  build:
+    triggers:
+      deploy:
+        application_name: app2
+`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	ids := []string{}
+	for _, m := range res.Messages {
+		ids = append(ids, m.ID)
+	}
+	assert.Contains(t, ids, sdk.MsgAppImportDestPipelineNotAttached.ID)
+	_, err := application.LoadByName(db, proj.Key, "app1", u)
+	assert.Error(t, err)
+
+	w, _ = doImport(`name: app1
+pipelines:
+  build:
+    triggers:
+      build:
+        application_name: app2
+`)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestImportApplicationHandlerParameterDefaults(t *testing.T) {
+	db := test.SetupPG(t)
+
+	router = newRouter(auth.TestLocalAuth(t), mux.NewRouter(), "/TestImportApplicationHandlerParameterDefaults")
+	router.init()
+
+	u, pass := assets.InsertAdminUser(db)
+	proj := assets.InsertTestProject(t, db, sdk.RandomString(10), sdk.RandomString(10), nil)
+	test.NotNil(t, proj)
+
+	uri := router.getRoute("POST", importApplicationHandler, map[string]string{"permProjectKey": proj.Key})
+	test.NotEmpty(t, uri)
+	doImport := func(version, query string) sdk.ImportResult {
+		payload := `name: app1
+pipelines:
+  build:
+    definition:
+      parameters:
+        version:
+          type: string
+      steps:
+      - script: make build
+    parameters:
+      version:
+        type: string
+        value: "` + version + `"
+`
+		req, err := http.NewRequest("POST", uri+"?format=yaml&messageFormat=structured"+query, strings.NewReader(payload))
+		test.NoError(t, err)
+		assets.AuthentifyRequest(t, req, u, pass)
+		w := httptest.NewRecorder()
+		router.mux.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		res := sdk.ImportResult{}
+		test.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		return res
+	}
+	countDefaults := func(res sdk.ImportResult) int {
+		var n int
+		for _, m := range res.Messages {
+			if m.ID == sdk.MsgAppImportParamDefaultSet.ID {
+				n++
+			}
+		}
+		return n
+	}
+	version := func() string {
+		app, err := application.LoadByName(db, proj.Key, "app1", u, application.LoadOptions.WithPipelines)
+		test.NoError(t, err)
+		test.Equal(t, 1, len(app.Pipelines))
+		test.Equal(t, 1, len(app.Pipelines[0].Parameters))
+		return app.Pipelines[0].Parameters[0].Value
+	}
+
+	//The defaults are set when the pipeline is attached, and updated when they change
+	assert.Equal(t, 1, countDefaults(doImport("1.0", "")))
+	assert.Equal(t, "1.0", version())
+	assert.Equal(t, 0, countDefaults(doImport("1.0", "&forceUpdate=true")))
+	assert.Equal(t, 1, countDefaults(doImport("2.0", "&forceUpdate=true")))
+	assert.Equal(t, "2.0", version())
+}
+
+func TestImportApplicationHandlerKeepPermissions(t *testing.T) {
+	db := test.SetupPG(t)
+
+	router = newRouter(auth.TestLocalAuth(t), mux.NewRouter(), "/TestImportApplicationHandlerKeepPermissions")
+	router.init()
+
+	u, pass := assets.InsertAdminUser(db)
+	name := sdk.RandomString(10)
+	proj := assets.InsertTestProject(t, db, sdk.RandomString(10), name, nil)
+	test.NotNil(t, proj)
+
+	uri := router.getRoute("POST", importApplicationHandler, map[string]string{"permProjectKey": proj.Key})
+	test.NotEmpty(t, uri)
+
+	doImport := func(payload, query string) sdk.ImportResult {
+		req, err := http.NewRequest("POST", uri+"?format=yaml&forceUpdate=true&messageFormat=structured"+query, strings.NewReader(payload))
+		test.NoError(t, err)
+		assets.AuthentifyRequest(t, req, u, pass)
+		w := httptest.NewRecorder()
+		router.mux.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		res := sdk.ImportResult{}
+		test.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		return res
+	}
+	loadPermission := func() int {
+		app, err := application.LoadByName(db, proj.Key, "app1", u, application.LoadOptions.WithGroups)
+		test.NoError(t, err)
+		for _, gp := range app.ApplicationGroups {
+			if gp.Group.Name == name+"-group" {
+				return gp.Permission
+			}
+		}
+		return 0
+	}
+
+	doImport("name: app1\npermissions:\n  "+name+"-group: 7\n", "")
+	assert.Equal(t, 7, loadPermission())
+
+	//1. With keepPermissions, the permissions of the payload are ignored
+	res := doImport("name: app1\npermissions:\n  "+name+"-group: 4\n", "&keepPermissions=true")
+	var kept bool
+	for _, m := range res.Messages {
+		if m.ID == sdk.MsgAppImportPermissionsKept.ID {
+			kept = true
+		}
+	}
+	assert.True(t, kept)
+	assert.Equal(t, 7, loadPermission())
+
+	//2. Without it, they are updated
+	doImport("name: app1\npermissions:\n  "+name+"-group: 4\n", "")
+	assert.Equal(t, 4, loadPermission())
+}
+
+func Test_applicationImportSchedules(t *testing.T) {
+	app := &sdk.Application{
+		Schedulers: []sdk.PipelineScheduler{
+			{PipelineName: "build", EnvironmentName: sdk.DefaultEnv.Name, Crontab: "0 6 * * *"},
+			{PipelineName: "deploy", EnvironmentName: "production", Crontab: "0 6 * * *", Timezone: "Europe/Paris"},
+			{PipelineName: "deploy", EnvironmentName: "production", Crontab: "every day"},
+		},
+	}
+	now := time.Date(2017, 6, 1, 12, 0, 0, 0, time.UTC)
+	schedules := applicationImportSchedules(app, now)
+	if assert.Len(t, schedules, 2) {
+		assert.Equal(t, "UTC", schedules[0].Timezone)
+		assert.True(t, schedules[0].NextExecution.Equal(time.Date(2017, 6, 2, 6, 0, 0, 0, time.UTC)))
+		assert.Equal(t, "Europe/Paris", schedules[1].Timezone)
+		assert.True(t, schedules[1].NextExecution.Equal(time.Date(2017, 6, 2, 4, 0, 0, 0, time.UTC)))
+	}
+}
+
+func TestImportApplicationHandlerAudit(t *testing.T) {
+	db := test.SetupPG(t)
+
+	router = newRouter(auth.TestLocalAuth(t), mux.NewRouter(), "/TestImportApplicationHandlerAudit")
+	router.init()
+
+	u, pass := assets.InsertAdminUser(db)
+	proj := assets.InsertTestProject(t, db, sdk.RandomString(10), sdk.RandomString(10), nil)
+	test.NotNil(t, proj)
+
+	uri := router.getRoute("POST", importApplicationHandler, map[string]string{"permProjectKey": proj.Key})
+	test.NotEmpty(t, uri)
+
+	doImport := func(query string) int {
+		req, err := http.NewRequest("POST", uri+"?format=yaml"+query, strings.NewReader("name: app1\n"))
+		test.NoError(t, err)
+		assets.AuthentifyRequest(t, req, u, pass)
+		w := httptest.NewRecorder()
+		router.mux.ServeHTTP(w, req)
+		return w.Code
+	}
+	assert.Equal(t, http.StatusOK, doImport(""))
+	//A dry run and a failed import are not recorded
+	assert.Equal(t, http.StatusOK, doImport("&forceUpdate=true&dryRun=true"))
+	assert.NotEqual(t, http.StatusOK, doImport(""))
+	assert.Equal(t, http.StatusOK, doImport("&forceUpdate=true"))
+
+	uri = router.getRoute("GET", getImportAuditsHandler, map[string]string{"permProjectKey": proj.Key})
+	test.NotEmpty(t, uri)
+	req, err := http.NewRequest("GET", uri+"?application=app1", nil)
+	test.NoError(t, err)
+	assets.AuthentifyRequest(t, req, u, pass)
+	w := httptest.NewRecorder()
+	router.mux.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	audits := []sdk.ImportAudit{}
+	test.NoError(t, json.Unmarshal(w.Body.Bytes(), &audits))
+	if assert.Len(t, audits, 2) {
+		assert.True(t, audits[0].ForceUpdate)
+		assert.False(t, audits[1].ForceUpdate)
+		assert.Equal(t, u.Username, audits[0].Author)
+		assert.Equal(t, "yaml", audits[0].Format)
+		assert.Equal(t, audits[0].PayloadHash, audits[1].PayloadHash)
+		assert.Len(t, audits[0].PayloadHash, 64)
+	}
+}
+
+func TestImportApplicationHandlerAuditSecrets(t *testing.T) {
+	db := test.SetupPG(t)
+
+	router = newRouter(auth.TestLocalAuth(t), mux.NewRouter(), "/TestImportApplicationHandlerAuditSecrets")
+	router.init()
+
+	u, pass := assets.InsertAdminUser(db)
+	proj := assets.InsertTestProject(t, db, sdk.RandomString(10), sdk.RandomString(10), nil)
+	test.NotNil(t, proj)
+
+	clearSecret := "s3cr3t-" + sdk.RandomString(10)
+	payload := "name: app1\nvariables:\n  password:\n    type: password\n    value: " + clearSecret + "\n"
+	uri := router.getRoute("POST", importApplicationHandler, map[string]string{"permProjectKey": proj.Key})
+	test.NotEmpty(t, uri)
+	req, err := http.NewRequest("POST", uri+"?format=yaml", strings.NewReader(payload))
+	test.NoError(t, err)
+	assets.AuthentifyRequest(t, req, u, pass)
+	w := httptest.NewRecorder()
+	router.mux.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	//The stored payload is encrypted, the secret is neither in clear nor only compressed
+	var stored []byte
+	test.NoError(t, db.QueryRow("SELECT payload FROM import_audit WHERE project_key = $1", proj.Key).Scan(&stored))
+	assert.NotContains(t, string(stored), clearSecret)
+	if gz, err := gzip.NewReader(bytes.NewReader(stored)); err == nil {
+		data, _ := ioutil.ReadAll(gz)
+		assert.NotContains(t, string(data), clearSecret)
+	}
+
+	//The payload is decrypted to be replayed
+	audits, err := application.LoadImportAudits(db, proj.Key, "app1", 1)
+	test.NoError(t, err)
+	if assert.Len(t, audits, 1) {
+		audit, err := application.LoadImportAudit(db, proj.Key, audits[0].ID)
+		test.NoError(t, err)
+		assert.Equal(t, payload, string(audit.Payload))
+	}
+}
+
+func TestImportApplicationHandlerMultiDocument(t *testing.T) {
+	db := test.SetupPG(t)
+
+	router = newRouter(auth.TestLocalAuth(t), mux.NewRouter(), "/TestImportApplicationHandlerMultiDocument")
+	router.init()
+
+	u, pass := assets.InsertAdminUser(db)
+	proj := assets.InsertTestProject(t, db, sdk.RandomString(10), sdk.RandomString(10), nil)
+	test.NotNil(t, proj)
+
+	uri := router.getRoute("POST", importApplicationHandler, map[string]string{"permProjectKey": proj.Key})
+	test.NotEmpty(t, uri)
+	payload := "name: app1\nvariables:\n  tier:\n    value: \"1\"\n---\nvariables:\n  tier:\n    value: \"2\"\n"
+	req, err := http.NewRequest("POST", uri+"?format=yaml&messageFormat=structured", strings.NewReader(payload))
+	test.NoError(t, err)
+	assets.AuthentifyRequest(t, req, u, pass)
+	w := httptest.NewRecorder()
+	router.mux.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	res := sdk.ImportResult{}
+	test.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+	ids := []string{}
+	for _, m := range res.Messages {
+		ids = append(ids, m.ID)
+	}
+	assert.Contains(t, ids, sdk.MsgAppImportDocumentsMerged.ID)
+	assert.Contains(t, ids, sdk.MsgAppImportValueOverridden.ID)
+
+	app, err := application.LoadByName(db, proj.Key, "app1", u, application.LoadOptions.WithVariables)
+	test.NoError(t, err)
+	if assert.Len(t, app.Variable, 1) {
+		assert.Equal(t, "2", app.Variable[0].Value)
+	}
+}
+
+func TestReplayImportAuditHandler(t *testing.T) {
+	db := test.SetupPG(t)
+
+	router = newRouter(auth.TestLocalAuth(t), mux.NewRouter(), "/TestReplayImportAuditHandler")
+	router.init()
+
+	u, pass := assets.InsertAdminUser(db)
+	proj := assets.InsertTestProject(t, db, sdk.RandomString(10), sdk.RandomString(10), nil)
+	test.NotNil(t, proj)
+
+	uri := router.getRoute("POST", importApplicationHandler, map[string]string{"permProjectKey": proj.Key})
+	test.NotEmpty(t, uri)
+	req, err := http.NewRequest("POST", uri+"?format=yaml", strings.NewReader("name: app1\nvariables:\n  tier:\n    value: \"1\"\n"))
+	test.NoError(t, err)
+	assets.AuthentifyRequest(t, req, u, pass)
+	w := httptest.NewRecorder()
+	router.mux.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	audits, err := application.LoadImportAudits(db, proj.Key, "app1", 1)
+	test.NoError(t, err)
+	test.Equal(t, 1, len(audits))
+	audit, err := application.LoadImportAudit(db, proj.Key, audits[0].ID)
+	test.NoError(t, err)
+	assert.Equal(t, "yaml", audit.Format)
+
+	doReplay := func(id int64) *httptest.ResponseRecorder {
+		uri := router.getRoute("POST", replayImportAuditHandler, map[string]string{"permProjectKey": proj.Key, "id": strconv.FormatInt(id, 10)})
+		test.NotEmpty(t, uri)
+		req, err := http.NewRequest("POST", uri+"?forceUpdate=true&messageFormat=structured", nil)
+		test.NoError(t, err)
+		assets.AuthentifyRequest(t, req, u, pass)
+		w := httptest.NewRecorder()
+		router.mux.ServeHTTP(w, req)
+		return w
+	}
+
+	//The replay is recorded as a new import of the same payload
+	w = doReplay(audit.ID)
+	assert.Equal(t, http.StatusOK, w.Code)
+	res := sdk.ImportResult{}
+	test.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+	audits, err = application.LoadImportAudits(db, proj.Key, "app1", 2)
+	test.NoError(t, err)
+	if assert.Len(t, audits, 2) {
+		assert.Equal(t, audit.PayloadHash, audits[0].PayloadHash)
+		assert.True(t, audits[0].ForceUpdate)
+	}
+
+	//Once the retention delay is over, the payload can't be replayed
+	_, err = application.DeleteImportAuditPayloads(db, time.Now().Add(time.Hour))
+	test.NoError(t, err)
+	assert.Equal(t, http.StatusGone, doReplay(audit.ID).Code)
+	assert.Equal(t, http.StatusNotFound, doReplay(0).Code)
+}
+
+func Test_normalizeApplicationImportEnvironments(t *testing.T) {
+	app := &sdk.Application{
+		Pipelines: []sdk.ApplicationPipeline{{
+			Pipeline: sdk.Pipeline{Name: "build"},
+			Triggers: []sdk.PipelineTrigger{
+				{DestPipeline: sdk.Pipeline{Name: "deploy"}, DestEnvironment: sdk.Environment{Name: " production "}},
+				{DestPipeline: sdk.Pipeline{Name: "test"}, SrcEnvironment: sdk.Environment{Name: " "}},
+			},
+		}},
+		Notifications: []sdk.UserNotification{{Pipeline: sdk.Pipeline{Name: "build"}}},
+		Schedulers:    []sdk.PipelineScheduler{{PipelineName: "build", Crontab: "0 * * * *"}},
+	}
+	normalizeApplicationImportEnvironments(app)
+
+	triggers := app.Pipelines[0].Triggers
+	assert.Equal(t, sdk.DefaultEnv.Name, triggers[0].SrcEnvironment.Name)
+	assert.Equal(t, "production", triggers[0].DestEnvironment.Name)
+	assert.Equal(t, sdk.DefaultEnv.Name, triggers[1].SrcEnvironment.Name)
+	assert.Equal(t, sdk.DefaultEnv.Name, triggers[1].DestEnvironment.Name)
+	assert.Equal(t, sdk.DefaultEnv.Name, app.Notifications[0].Environment.Name)
+	assert.Equal(t, sdk.DefaultEnv.Name, app.Schedulers[0].EnvironmentName)
+
+	_, _, envNames := applicationImportReferences(&sdk.Project{Key: "KEY"}, app)
+	assert.Equal(t, []string{"production"}, envNames)
+}
+
+func Test_writeImportMessagesVerbosity(t *testing.T) {
+	msgList := []sdk.StructuredMessage{
+		{Level: sdk.MessageLevelInfo, Message: "created"},
+		{Level: sdk.MessageLevelWarning, Message: "skipped"},
+		{Level: sdk.MessageLevelError, Message: "failed"},
+	}
+	write := func(verbosity string) []string {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/project/KEY/import/application?verbosity="+verbosity, nil)
+		assert.NoError(t, writeImportMessages(w, r, msgList, sdk.ImportSummary{}, false, http.StatusOK))
+		msgs := []string{}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &msgs))
+		return msgs
+	}
+
+	assert.Equal(t, []string{"created", "skipped", "failed"}, write(""))
+	assert.Equal(t, []string{"skipped", "failed"}, write("warnings"))
+	assert.Equal(t, []string{"failed"}, write("errors"))
+}
+
+func Test_applicationImportHookPipelines(t *testing.T) {
+	app := &sdk.Application{
+		Pipelines: []sdk.ApplicationPipeline{{Pipeline: sdk.Pipeline{Name: "build"}}, {Pipeline: sdk.Pipeline{Name: "deploy"}}},
+	}
+	// A new application gets a hook on its first pipeline
+	assert.Equal(t, []string{"build"}, applicationImportHookPipelines(app, false))
+	assert.Empty(t, applicationImportHookPipelines(app, true))
+
+	app.Hooks = []sdk.Hook{{Pipeline: sdk.Pipeline{Name: "deploy"}}}
+	assert.Equal(t, []string{"deploy"}, applicationImportHookPipelines(app, false))
+}
+
+func TestImportApplicationHandlerMetadata(t *testing.T) {
+	db := test.SetupPG(t)
+
+	router = newRouter(auth.TestLocalAuth(t), mux.NewRouter(), "/TestImportApplicationHandlerMetadata")
+	router.init()
+
+	u, pass := assets.InsertAdminUser(db)
+	proj := assets.InsertTestProject(t, db, sdk.RandomString(10), sdk.RandomString(10), nil)
+	test.NotNil(t, proj)
+
+	uri := router.getRoute("POST", importApplicationHandler, map[string]string{"permProjectKey": proj.Key})
+	test.NotEmpty(t, uri)
+
+	doImport := func(payload string) {
+		req, err := http.NewRequest("POST", uri+"?format=yaml&forceUpdate=true", strings.NewReader(payload))
+		test.NoError(t, err)
+		assets.AuthentifyRequest(t, req, u, pass)
+		w := httptest.NewRecorder()
+		router.mux.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+
+	doImport("name: app1\nmetadata:\n  owner: team-a\n  tier: \"1\"\n")
+	//The imported metadata are merged with the stored ones
+	doImport("name: app1\nmetadata:\n  owner: team-b\n  cost-center: \"42\"\n")
+
+	app, err := application.LoadByName(db, proj.Key, "app1", u)
+	test.NoError(t, err)
+	assert.Equal(t, sdk.Metadata{"owner": "team-b", "tier": "1", "cost-center": "42"}, app.Metadata)
+
+	uri = router.getRoute("GET", getApplicationsHandler, map[string]string{"permProjectKey": proj.Key})
+	test.NotEmpty(t, uri)
+	req, err := http.NewRequest("GET", uri+"?metadata=owner:team-b", nil)
+	test.NoError(t, err)
+	assets.AuthentifyRequest(t, req, u, pass)
+	w := httptest.NewRecorder()
+	router.mux.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	apps := []sdk.Application{}
+	test.NoError(t, json.Unmarshal(w.Body.Bytes(), &apps))
+	assert.Len(t, apps, 1)
+}
+
+func TestImportApplicationHandlerDescriptions(t *testing.T) {
+	db := test.SetupPG(t)
+
+	router = newRouter(auth.TestLocalAuth(t), mux.NewRouter(), "/TestImportApplicationHandlerDescriptions")
+	router.init()
+
+	u, pass := assets.InsertAdminUser(db)
+	proj := assets.InsertTestProject(t, db, sdk.RandomString(10), sdk.RandomString(10), nil)
+	test.NotNil(t, proj)
+
+	uri := router.getRoute("POST", importApplicationHandler, map[string]string{"permProjectKey": proj.Key})
+	test.NotEmpty(t, uri)
+
+	doImport := func(payload string) {
+		req, err := http.NewRequest("POST", uri+"?format=yaml&forceUpdate=true", strings.NewReader(payload))
+		test.NoError(t, err)
+		assets.AuthentifyRequest(t, req, u, pass)
+		w := httptest.NewRecorder()
+		router.mux.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+
+	desc := "Billing service.\nOwned by \"team-a\"\n"
+	doImport("name: app1\ndescription: |\n  Billing service.\n  Owned by \"team-a\"\nvariables:\n  tier:\n    value: \"1\"\n    description: Support tier\n")
+	//An update without descriptions keeps the stored ones
+	doImport("name: app1\nvariables:\n  tier:\n    value: \"2\"\n")
+
+	app, err := application.LoadByName(db, proj.Key, "app1", u, application.LoadOptions.WithVariables)
+	test.NoError(t, err)
+	assert.Equal(t, desc, app.Description)
+	if assert.Len(t, app.Variable, 1) {
+		assert.Equal(t, "2", app.Variable[0].Value)
+		assert.Equal(t, "Support tier", app.Variable[0].Description)
+	}
+
+	exported, err := application.Export(db, proj, "app1", u, nil)
+	test.NoError(t, err)
+	assert.Equal(t, desc, exported.Description)
+	assert.Equal(t, "Support tier", exported.Variables["tier"].Description)
+}
+
+func TestImportApplicationHandlerRetention(t *testing.T) {
+	db := test.SetupPG(t)
+
+	router = newRouter(auth.TestLocalAuth(t), mux.NewRouter(), "/TestImportApplicationHandlerRetention")
+	router.init()
+
+	u, pass := assets.InsertAdminUser(db)
+	proj := assets.InsertTestProject(t, db, sdk.RandomString(10), sdk.RandomString(10), nil)
+	test.NotNil(t, proj)
+
+	uri := router.getRoute("POST", importApplicationHandler, map[string]string{"permProjectKey": proj.Key})
+	test.NotEmpty(t, uri)
+
+	doImport := func(payload string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest("POST", uri+"?format=yaml&forceUpdate=true", strings.NewReader(payload))
+		test.NoError(t, err)
+		assets.AuthentifyRequest(t, req, u, pass)
+		w := httptest.NewRecorder()
+		router.mux.ServeHTTP(w, req)
+		return w
+	}
+
+	w := doImport("name: app1\nretention:\n  keep_runs: 20\n  keep_days: 30\n")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "Retention of application app1 is set: 20 runs, 30 days")
+
+	//An update without retention keeps the stored one
+	assert.Equal(t, http.StatusOK, doImport("name: app1\nretention:\n  keep_days: 7\n").Code)
+	app, err := application.LoadByName(db, proj.Key, "app1", u)
+	test.NoError(t, err)
+	assert.Equal(t, 20, app.RetentionRuns)
+	assert.Equal(t, 7, app.RetentionDays)
+
+	exported, err := application.Export(db, proj, "app1", u, nil)
+	test.NoError(t, err)
+	assert.Equal(t, &exportentities.ApplicationRetention{KeepRuns: 20, KeepDays: 7}, exported.Retention)
+
+	//A negative value is rejected
+	w = doImport("name: app1\nretention:\n  keep_runs: -1\n")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "keep_runs must be positive")
+}
+
+func TestImportApplicationHandlerGeneration(t *testing.T) {
+	db := test.SetupPG(t)
+
+	router = newRouter(auth.TestLocalAuth(t), mux.NewRouter(), "/TestImportApplicationHandlerGeneration")
+	router.init()
+
+	u, pass := assets.InsertAdminUser(db)
+	proj := assets.InsertTestProject(t, db, sdk.RandomString(10), sdk.RandomString(10), nil)
+	test.NotNil(t, proj)
+
+	uri := router.getRoute("POST", importApplicationHandler, map[string]string{"permProjectKey": proj.Key})
+	test.NotEmpty(t, uri)
+
+	doImport := func(payload string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest("POST", uri+"?format=yaml&forceUpdate=true&respectGeneration=true", strings.NewReader(payload))
+		test.NoError(t, err)
+		assets.AuthentifyRequest(t, req, u, pass)
+		w := httptest.NewRecorder()
+		router.mux.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusOK, doImport("name: app1\ngeneration: 2\nvariables:\n  tier:\n    value: \"2\"\n").Code)
+
+	//An older or the same generation is refused
+	for _, payload := range []string{
+		"name: app1\ngeneration: 1\nvariables:\n  tier:\n    value: \"1\"\n",
+		"name: app1\ngeneration: 2\nvariables:\n  tier:\n    value: \"1\"\n",
+	} {
+		w := doImport(payload)
+		assert.Equal(t, http.StatusConflict, w.Code)
+		assert.Contains(t, w.Body.String(), "is not greater than its stored generation 2")
+	}
+
+	assert.Equal(t, http.StatusOK, doImport("name: app1\ngeneration: 3\nvariables:\n  tier:\n    value: \"3\"\n").Code)
+	app, err := application.LoadByName(db, proj.Key, "app1", u, application.LoadOptions.WithVariables)
+	test.NoError(t, err)
+	assert.Equal(t, 3, app.Generation)
+	if assert.Len(t, app.Variable, 1) {
+		assert.Equal(t, "3", app.Variable[0].Value)
+	}
+}
+
+func TestImportApplicationByNameHandler(t *testing.T) {
+	db := test.SetupPG(t)
+
+	router = newRouter(auth.TestLocalAuth(t), mux.NewRouter(), "/TestImportApplicationByNameHandler")
+	router.init()
+
+	u, pass := assets.InsertAdminUser(db)
+	proj := assets.InsertTestProject(t, db, sdk.RandomString(10), sdk.RandomString(10), nil)
+	test.NotNil(t, proj)
+
+	uri := router.getRoute("POST", importApplicationByNameHandler, map[string]string{"key": proj.Key, "permApplicationName": "app1"})
+	test.NotEmpty(t, uri)
+
+	doImport := func(payload string) int {
+		req, err := http.NewRequest("POST", uri+"?format=yaml", strings.NewReader(payload))
+		test.NoError(t, err)
+		assets.AuthentifyRequest(t, req, u, pass)
+		w := httptest.NewRecorder()
+		router.mux.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	//The payload must have the name of the url
+	assert.Equal(t, http.StatusBadRequest, doImport("name: app2\n"))
+	_, err := application.LoadByName(db, proj.Key, "app2", u)
+	assert.Error(t, err)
+
+	assert.Equal(t, http.StatusOK, doImport("name: app1\n"))
+	_, err = application.LoadByName(db, proj.Key, "app1", u)
+	assert.NoError(t, err)
+}
+
+func TestImportApplicationHandlerConcurrent(t *testing.T) {
+	db := test.SetupPG(t)
+
+	router = newRouter(auth.TestLocalAuth(t), mux.NewRouter(), "/TestImportApplicationHandlerConcurrent")
+	router.init()
+
+	u, pass := assets.InsertAdminUser(db)
+	proj := assets.InsertTestProject(t, db, sdk.RandomString(10), sdk.RandomString(10), nil)
+	test.NotNil(t, proj)
+
+	uri := router.getRoute("POST", importApplicationHandler, map[string]string{"permProjectKey": proj.Key})
+	test.NotEmpty(t, uri)
+
+	//Both applications update the same pipeline and the same project
+	names := []string{"app1", "app2"}
+	codes := make([]int, len(names))
+	wg := sync.WaitGroup{}
+	for i := range names {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			payload := "name: " + names[i] + "\npipelines:\n  build:\n    definition:\n      steps:\n      - script: make build\n"
+			req, err := http.NewRequest("POST", uri+"?format=yaml&forceUpdate=true", strings.NewReader(payload))
+			test.NoError(t, err)
+			assets.AuthentifyRequest(t, req, u, pass)
+			w := httptest.NewRecorder()
+			router.mux.ServeHTTP(w, req)
+			codes[i] = w.Code
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(t, []int{http.StatusOK, http.StatusOK}, codes)
+	for _, name := range names {
+		_, err := application.LoadByName(db, proj.Key, name, u)
+		assert.NoError(t, err)
+	}
+}
+
+func TestImportApplicationHandlerStrict(t *testing.T) {
+	db := test.SetupPG(t)
+
+	router = newRouter(auth.TestLocalAuth(t), mux.NewRouter(), "/TestImportApplicationHandlerStrict")
+	router.init()
+
+	u, pass := assets.InsertAdminUser(db)
+	proj := assets.InsertTestProject(t, db, sdk.RandomString(10), sdk.RandomString(10), nil)
+	test.NotNil(t, proj)
+
+	uri := router.getRoute("POST", importApplicationHandler, map[string]string{"permProjectKey": proj.Key})
+	test.NotEmpty(t, uri)
+
+	doImport := func(query string) *httptest.ResponseRecorder {
+		payload := "name: app1\nvaraibles:\n  foo:\n    value: bar\n"
+		req, err := http.NewRequest("POST", uri+"?format=yaml&messageFormat=structured"+query, strings.NewReader(payload))
+		test.NoError(t, err)
+		assets.AuthentifyRequest(t, req, u, pass)
+		w := httptest.NewRecorder()
+		router.mux.ServeHTTP(w, req)
+		return w
+	}
+
+	//The unknown fields are rejected in strict mode
+	w := doImport("&strict=true")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	res := sdk.ImportResult{}
+	test.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+	if assert.Len(t, res.Messages, 1) {
+		assert.Equal(t, sdk.MsgAppImportUnknownField.ID, res.Messages[0].ID)
+		assert.Equal(t, []interface{}{"varaibles", "app1"}, res.Messages[0].Args)
+	}
+
+	//And ignored by default
+	w = doImport("")
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestPatchApplicationHandler(t *testing.T) {
+	db := test.SetupPG(t)
+
+	router = newRouter(auth.TestLocalAuth(t), mux.NewRouter(), "/TestPatchApplicationHandler")
+	router.init()
+
+	u, pass := assets.InsertAdminUser(db)
+	proj := assets.InsertTestProject(t, db, sdk.RandomString(10), sdk.RandomString(10), nil)
+	test.NotNil(t, proj)
+
+	uri := router.getRoute("POST", importApplicationHandler, map[string]string{"permProjectKey": proj.Key})
+	test.NotEmpty(t, uri)
+	req, err := http.NewRequest("POST", uri+"?format=yaml", strings.NewReader("name: app1\nvariables:\n  tier:\n    value: \"1\"\n  region:\n    value: gra\n"))
+	test.NoError(t, err)
+	assets.AuthentifyRequest(t, req, u, pass)
+	w := httptest.NewRecorder()
+	router.mux.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	uri = router.getRoute("PATCH", patchApplicationHandler, map[string]string{"key": proj.Key, "permApplicationName": "app1"})
+	test.NotEmpty(t, uri)
+	doPatch := func(patch string) int {
+		req, err := http.NewRequest("PATCH", uri, strings.NewReader(patch))
+		test.NoError(t, err)
+		req.Header.Set("Content-Type", "application/merge-patch+json")
+		assets.AuthentifyRequest(t, req, u, pass)
+		w := httptest.NewRecorder()
+		router.mux.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	//Only the patched variable is changed
+	assert.Equal(t, http.StatusOK, doPatch(`{"variables": {"tier": {"value": "2"}}}`))
+	app, err := application.LoadByName(db, proj.Key, "app1", u, application.LoadOptions.WithVariables)
+	test.NoError(t, err)
+	values := map[string]string{}
+	for _, v := range app.Variable {
+		values[v.Name] = v.Value
+	}
+	assert.Equal(t, map[string]string{"tier": "2", "region": "gra"}, values)
+
+	//The application can't be renamed
+	assert.Equal(t, http.StatusBadRequest, doPatch(`{"name": "app2"}`))
+	assert.Equal(t, http.StatusBadRequest, doPatch(`{"variables": `))
+}
+
+func TestImportApplicationHandlerSkipHooks(t *testing.T) {
+	db := test.SetupPG(t)
+
+	router = newRouter(auth.TestLocalAuth(t), mux.NewRouter(), "/TestImportApplicationHandlerSkipHooks")
+	router.init()
+
+	u, pass := assets.InsertAdminUser(db)
+	proj := assets.InsertTestProject(t, db, sdk.RandomString(10), sdk.RandomString(10), nil)
+	test.NotNil(t, proj)
+	pip := &sdk.Pipeline{Name: "build", Type: sdk.BuildPipeline, ProjectKey: proj.Key, ProjectID: proj.ID}
+	test.NoError(t, pipeline.InsertPipeline(db, proj, pip, u))
+
+	uri := router.getRoute("POST", importApplicationHandler, map[string]string{"permProjectKey": proj.Key})
+	test.NotEmpty(t, uri)
+	doImport := func(query string) *httptest.ResponseRecorder {
+		payload := "name: app1\nvariables:\n  tier:\n    value: \"1\"\npipelines:\n  build:\n    options:\n    - hook: true\n      polling: true\n"
+		req, err := http.NewRequest("POST", uri+"?format=yaml&messageFormat=structured"+query, strings.NewReader(payload))
+		test.NoError(t, err)
+		assets.AuthentifyRequest(t, req, u, pass)
+		w := httptest.NewRecorder()
+		router.mux.ServeHTTP(w, req)
+		return w
+	}
+
+	//The hooks and the pollers can't be created without repositories manager
+	assert.NotEqual(t, http.StatusOK, doImport("").Code)
+
+	w := doImport("&skipHooks=true")
+	assert.Equal(t, http.StatusOK, w.Code)
+	res := sdk.ImportResult{}
+	test.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+	var skipped bool
+	for _, m := range res.Messages {
+		skipped = skipped || m.ID == sdk.MsgAppImportHooksSkipped.ID
+	}
+	assert.True(t, skipped)
+
+	app, err := application.LoadByName(db, proj.Key, "app1", u, application.LoadOptions.WithVariables, application.LoadOptions.WithPipelines, application.LoadOptions.WithHooks)
+	test.NoError(t, err)
+	assert.Len(t, app.Variable, 1)
+	assert.Len(t, app.Pipelines, 1)
+	assert.Empty(t, app.Hooks)
+}
+
+func TestImportApplicationHandlerDryRunHooks(t *testing.T) {
+	db := test.SetupPG(t, bootstrap.InitiliazeDB)
+
+	router = newRouter(auth.TestLocalAuth(t), mux.NewRouter(), "/TestImportApplicationHandlerDryRunHooks")
+	router.init()
+
+	u, pass := assets.InsertAdminUser(db)
+	proj, rm := testfindLinkedProject(t, db)
+	test.NotNil(t, proj)
+	pip := &sdk.Pipeline{Name: sdk.RandomString(10), Type: sdk.BuildPipeline, ProjectKey: proj.Key, ProjectID: proj.ID}
+	test.NoError(t, pipeline.InsertPipeline(db, proj, pip, u))
+
+	uri := router.getRoute("POST", importApplicationHandler, map[string]string{"permProjectKey": proj.Key})
+	test.NotEmpty(t, uri)
+	appName := sdk.RandomString(10)
+	payload := "name: " + appName + "\nrepo_manager: " + rm.Name + "\nrepo_name: test/" + appName + "\npipelines:\n  " + pip.Name + ":\n    options:\n    - hook: true\n"
+	req, err := http.NewRequest("POST", uri+"?format=yaml&dryRun=true", strings.NewReader(payload))
+	test.NoError(t, err)
+	assets.AuthentifyRequest(t, req, u, pass)
+	w := httptest.NewRecorder()
+	router.mux.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	//Nothing is left to create on the repositories manager
+	var n int
+	test.NoError(t, db.QueryRow("SELECT COUNT(1) FROM hook_outbox WHERE repo_fullname = $1", "test/"+appName).Scan(&n))
+	assert.Equal(t, 0, n)
+	exist, err := application.Exists(db, proj.Key, appName)
+	test.NoError(t, err)
+	assert.False(t, exist)
+}
+
+func TestImportApplicationHandlerEcho(t *testing.T) {
+	db := test.SetupPG(t)
+
+	router = newRouter(auth.TestLocalAuth(t), mux.NewRouter(), "/TestImportApplicationHandlerEcho")
+	router.init()
+
+	u, pass := assets.InsertAdminUser(db)
+	proj := assets.InsertTestProject(t, db, sdk.RandomString(10), sdk.RandomString(10), nil)
+	test.NotNil(t, proj)
+
+	uri := router.getRoute("POST", importApplicationHandler, map[string]string{"permProjectKey": proj.Key})
+	test.NotEmpty(t, uri)
+	do := func(query, payload string) sdk.ImportResult {
+		req, err := http.NewRequest("POST", uri+query, strings.NewReader(payload))
+		test.NoError(t, err)
+		assets.AuthentifyRequest(t, req, u, pass)
+		w := httptest.NewRecorder()
+		router.mux.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		res := sdk.ImportResult{}
+		test.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		return res
+	}
+
+	//The stored application is exported in the format of the payload, the result is structured
+	res := do("?format=yaml&echo=true", "name: app1\nvariables:\n  foo:\n    value: bar\n")
+	exported := exportentities.Application{}
+	test.NoError(t, yaml.Unmarshal([]byte(res.Export), &exported))
+	assert.Equal(t, "app1", exported.Name)
+	assert.Equal(t, "bar", exported.Variables["foo"].Value)
+
+	res = do("?format=json&forceUpdate=true&echo=true", `{"name": "app1"}`)
+	exported = exportentities.Application{}
+	test.NoError(t, json.Unmarshal([]byte(res.Export), &exported))
+	assert.Equal(t, "app1", exported.Name)
+
+	//Without echo, the application is not exported
+	res = do("?format=yaml&forceUpdate=true&messageFormat=structured", "name: app1\n")
+	assert.Empty(t, res.Export)
+}
+
+func TestDiffImportApplicationHandler(t *testing.T) {
+	db := test.SetupPG(t)
+
+	router = newRouter(auth.TestLocalAuth(t), mux.NewRouter(), "/TestDiffImportApplicationHandler")
+	router.init()
+
+	u, pass := assets.InsertAdminUser(db)
+	proj := assets.InsertTestProject(t, db, sdk.RandomString(10), sdk.RandomString(10), nil)
+	test.NotNil(t, proj)
+	pip := &sdk.Pipeline{Name: "build", Type: sdk.BuildPipeline, ProjectKey: proj.Key, ProjectID: proj.ID}
+	test.NoError(t, pipeline.InsertPipeline(db, proj, pip, u))
+
+	payload := "name: app1\nvariables:\n  tier:\n    type: string\n    value: \"1\"\npipelines:\n  build: {}\n"
+	do := func(route Handler, query string) *httptest.ResponseRecorder {
+		uri := router.getRoute("POST", route, map[string]string{"permProjectKey": proj.Key})
+		test.NotEmpty(t, uri)
+		req, err := http.NewRequest("POST", uri+"?format=yaml"+query, strings.NewReader(payload))
+		test.NoError(t, err)
+		assets.AuthentifyRequest(t, req, u, pass)
+		w := httptest.NewRecorder()
+		router.mux.ServeHTTP(w, req)
+		return w
+	}
+	test.Equal(t, http.StatusOK, do(importApplicationHandler, "").Code)
+
+	diff := func(query string) exportentities.ApplicationDiff {
+		w := do(diffImportApplicationHandler, query)
+		test.Equal(t, http.StatusOK, w.Code)
+		d := exportentities.ApplicationDiff{}
+		test.NoError(t, json.Unmarshal(w.Body.Bytes(), &d))
+		return d
+	}
+
+	//The exported application is the imported one
+	assert.Equal(t, exportentities.ApplicationDiff{}, diff(""))
+
+	//The options of the import are applied on the payload
+	d := diff("&overrides=" + url.QueryEscape(`[{"key":"tier","value":"2"}]`))
+	assert.Equal(t, []string{"tier"}, d.Variables.Changed)
+
+	d = diff("&rename=app1:app2")
+	assert.Equal(t, []string{"tier"}, d.Variables.Added)
+	assert.Equal(t, []string{"build"}, d.Pipelines.Added)
+}
+
+func TestImportApplicationHandlerCallbackFail(t *testing.T) {
+	db := test.SetupPG(t)
+
+	router = newRouter(auth.TestLocalAuth(t), mux.NewRouter(), "/TestImportApplicationHandlerCallbackFail")
+	router.init()
+
+	received := make(chan importCallback, 1)
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cb := importCallback{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&cb))
+		received <- cb
+	}))
+	defer s.Close()
+	u, err := url.Parse(s.URL)
+	test.NoError(t, err)
+	viper.Set(viperImportURLAllowlist, []string{u.Hostname()})
+	defer viper.Set(viperImportURLAllowlist, nil)
+
+	user, pass := assets.InsertAdminUser(db)
+	proj := assets.InsertTestProject(t, db, sdk.RandomString(10), sdk.RandomString(10), nil)
+	test.NotNil(t, proj)
+
+	uri := router.getRoute("POST", importApplicationHandler, map[string]string{"permProjectKey": proj.Key})
+	test.NotEmpty(t, uri)
+	doImport := func(query string) int {
+		req, err := http.NewRequest("POST", uri+"?format=yaml"+query, strings.NewReader("name: app1\n"))
+		test.NoError(t, err)
+		assets.AuthentifyRequest(t, req, user, pass)
+		w := httptest.NewRecorder()
+		router.mux.ServeHTTP(w, req)
+		return w.Code
+	}
+	assert.Equal(t, http.StatusOK, doImport(""))
+
+	//The import of an existing application fails before its transaction, the callback is notified anyway
+	assert.Equal(t, http.StatusConflict, doImport("&callbackURL="+url.QueryEscape(s.URL)))
+	select {
+	case cb := <-received:
+		assert.Equal(t, proj.Key, cb.ProjectKey)
+		assert.Equal(t, "app1", cb.Application)
+		assert.Equal(t, importCallbackFail, cb.Status)
+	case <-time.After(5 * time.Second):
+		t.Fatal("callback not sent")
+	}
+}
@@ -13,6 +13,8 @@ import (
 	"github.com/ovh/cds/sdk"
 )
 
+var keyNameRegexp = regexp.MustCompile(sdk.NamePattern)
+
 func getKeysInApplicationHandler(w http.ResponseWriter, r *http.Request, db *gorp.DbMap, c *businesscontext.Ctx) error {
 	vars := mux.Vars(r)
 	key := vars["key"]
@@ -75,8 +77,7 @@ func addKeyInApplicationHandler(w http.ResponseWriter, r *http.Request, db *gorp
 	}
 
 	// check application name pattern
-	regexp := regexp.MustCompile(sdk.NamePattern)
-	if !regexp.MatchString(newKey.Name) {
+	if !keyNameRegexp.MatchString(newKey.Name) {
 		return sdk.WrapError(sdk.ErrInvalidKeyPattern, "addKeyInApplicationHandler: Key name %s do not respect pattern %s", newKey.Name, sdk.NamePattern)
 	}
 
@@ -86,24 +87,8 @@ func addKeyInApplicationHandler(w http.ResponseWriter, r *http.Request, db *gorp
 	}
 	newKey.ApplicationID = app.ID
 
-	switch newKey.Type {
-	case sdk.KeyTypeSsh:
-		pub, priv, errGenerate := keys.Generatekeypair(newKey.Name)
-		if errGenerate != nil {
-			return sdk.WrapError(errGenerate, "addKeyInApplicationHandler> Cannot generate sshKey")
-		}
-		newKey.Public = pub
-		newKey.Private = priv
-	case sdk.KeyTypePgp:
-		keyID, pub, priv, errGenerate := keys.GeneratePGPKeyPair(newKey.Name)
-		if errGenerate != nil {
-			return sdk.WrapError(errGenerate, "addKeyInApplicationHandler> Cannot generate pgpKey")
-		}
-		newKey.Public = pub
-		newKey.Private = priv
-		newKey.KeyID = keyID
-	default:
-		return sdk.WrapError(sdk.ErrUnknownKeyType, "addKeyInApplicationHandler> unknown key of type: %s", newKey.Type)
+	if err := generateApplicationKey(&newKey); err != nil {
+		return sdk.WrapError(err, "addKeyInApplicationHandler> Cannot generate key %s", newKey.Name)
 	}
 
 	tx, errT := db.Begin()
@@ -126,3 +111,27 @@ func addKeyInApplicationHandler(w http.ResponseWriter, r *http.Request, db *gorp
 
 	return WriteJSON(w, r, newKey, http.StatusOK)
 }
+
+//generateApplicationKey generates the public and private parts of the key according to its type
+func generateApplicationKey(k *sdk.ApplicationKey) error {
+	switch k.Type {
+	case sdk.KeyTypeSsh:
+		pub, priv, errGenerate := keys.Generatekeypair(k.Name)
+		if errGenerate != nil {
+			return sdk.WrapError(errGenerate, "generateApplicationKey> Cannot generate sshKey")
+		}
+		k.Public = pub
+		k.Private = priv
+	case sdk.KeyTypePgp:
+		keyID, pub, priv, errGenerate := keys.GeneratePGPKeyPair(k.Name)
+		if errGenerate != nil {
+			return sdk.WrapError(errGenerate, "generateApplicationKey> Cannot generate pgpKey")
+		}
+		k.Public = pub
+		k.Private = priv
+		k.KeyID = keyID
+	default:
+		return sdk.WrapError(sdk.ErrUnknownKeyType, "generateApplicationKey> unknown key of type: %s", k.Type)
+	}
+	return nil
+}
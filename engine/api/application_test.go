@@ -178,3 +178,24 @@ func TestGetApplicationWithTriggersHandler(t *testing.T) {
 	assert.Equal(t, checkPip2, true)
 	assert.Equal(t, checkPip3, true)
 }
+
+func Test_filterApplicationsByMetadata(t *testing.T) {
+	apps := []sdk.Application{
+		{Name: "app1", Metadata: sdk.Metadata{"owner": "team-a", "tier": "1"}},
+		{Name: "app2", Metadata: sdk.Metadata{"owner": "team-b", "tier": "1"}},
+		{Name: "app3"},
+	}
+	names := func(apps []sdk.Application) []string {
+		res := []string{}
+		for _, a := range apps {
+			res = append(res, a.Name)
+		}
+		return res
+	}
+
+	assert.Equal(t, []string{"app1", "app2", "app3"}, names(filterApplicationsByMetadata(apps, nil)))
+	assert.Equal(t, []string{"app1", "app2"}, names(filterApplicationsByMetadata(apps, []string{"tier:1"})))
+	assert.Equal(t, []string{"app2"}, names(filterApplicationsByMetadata(apps, []string{"tier:1", "owner:team-b"})))
+	assert.Equal(t, []string{"app1", "app2"}, names(filterApplicationsByMetadata(apps, []string{"owner"})))
+	assert.Equal(t, []string{}, names(filterApplicationsByMetadata(apps, []string{"owner:team-c"})))
+}
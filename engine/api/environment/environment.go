@@ -3,6 +3,7 @@ package environment
 import (
 	"database/sql"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/go-gorp/gorp"
@@ -139,6 +140,82 @@ func Exists(db gorp.SqlExecutor, projectKey, envName string) (bool, error) {
 	return n == 1, nil
 }
 
+// ExistEnvironments checks in a single query which of the given environments exist in the project
+func ExistEnvironments(db gorp.SqlExecutor, projectKey string, names []string) (map[string]bool, error) {
+	res := make(map[string]bool, len(names))
+	if len(names) == 0 {
+		return res, nil
+	}
+	for _, n := range names {
+		res[n] = false
+	}
+
+	query := `SELECT environment.name
+		  FROM environment
+		  JOIN project ON project.id = environment.project_id
+		  WHERE project.projectKey = $1 AND environment.name = ANY(string_to_array($2, ','))`
+	rows, err := db.Query(query, projectKey, strings.Join(names, ","))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		res[name] = true
+	}
+	return res, nil
+}
+
+// ResolveEnvironmentNamesFold returns, for each of the given names which is not an environment of the project,
+// the name of the environment of the project which matches it case-insensitively. Names which match several
+// environments are not resolved.
+func ResolveEnvironmentNamesFold(db gorp.SqlExecutor, projectKey string, names []string) (map[string]string, error) {
+	res := make(map[string]string, len(names))
+	if len(names) == 0 {
+		return res, nil
+	}
+
+	query := `SELECT environment.name
+		  FROM environment
+		  JOIN project ON project.id = environment.project_id
+		  WHERE project.projectKey = $1 AND lower(environment.name) = ANY(string_to_array(lower($2), ','))`
+	rows, err := db.Query(query, projectKey, strings.Join(names, ","))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stored := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		stored = append(stored, name)
+	}
+
+	for _, n := range names {
+		matches := []string{}
+		for _, s := range stored {
+			if s == n {
+				matches = nil
+				break
+			}
+			if strings.EqualFold(s, n) {
+				matches = append(matches, s)
+			}
+		}
+		if len(matches) == 1 {
+			res[n] = matches[0]
+		}
+	}
+	return res, nil
+}
+
 // CheckDefaultEnv create default env if not exists
 func CheckDefaultEnv(db gorp.SqlExecutor) error {
 	var env sdk.Environment
@@ -53,6 +53,9 @@ func Import(db gorp.SqlExecutor, proj *sdk.Project, env *sdk.Environment, msgCha
 		if err := InsertVariable(db, env.ID, &env.Variable[i], u); err != nil {
 			return err
 		}
+		if msgChan != nil {
+			msgChan <- sdk.NewMessage(sdk.MsgEnvironmentVariableCreated, env.Variable[i].Name, env.Name)
+		}
 	}
 
 	if msgChan != nil {
@@ -1,17 +1,19 @@
 package main
 
 import (
-	"io/ioutil"
 	"net/http"
+	"regexp"
+	"sort"
+	"strings"
 
 	"github.com/go-gorp/gorp"
 	"github.com/gorilla/mux"
 	"github.com/hashicorp/hcl"
-	"gopkg.in/yaml.v2"
 
 	"github.com/ovh/cds/engine/api/businesscontext"
 	"github.com/ovh/cds/engine/api/environment"
 	"github.com/ovh/cds/engine/api/group"
+	"github.com/ovh/cds/engine/api/permission"
 	"github.com/ovh/cds/engine/api/project"
 	"github.com/ovh/cds/engine/api/sanity"
 	"github.com/ovh/cds/sdk"
@@ -19,27 +21,41 @@ import (
 	"github.com/ovh/cds/sdk/log"
 )
 
+func importEnvironmentHandler(w http.ResponseWriter, r *http.Request, db *gorp.DbMap, c *businesscontext.Ctx) error {
+	return doImportEnvironment(w, r, db, c, FormBool(r, "forceUpdate"))
+}
+
 func importNewEnvironmentHandler(w http.ResponseWriter, r *http.Request, db *gorp.DbMap, c *businesscontext.Ctx) error {
+	return doImportEnvironment(w, r, db, c, false)
+}
+
+//doImportEnvironment imports the environment of the request body in the project of the url. An existing
+//environment is only updated if forceUpdate is set.
+func doImportEnvironment(w http.ResponseWriter, r *http.Request, db *gorp.DbMap, c *businesscontext.Ctx, forceUpdate bool) error {
 	vars := mux.Vars(r)
 	key := vars["permProjectKey"]
 	format := r.FormValue("format")
+	verbosity, errV := importVerbosity(r)
+	if errV != nil {
+		return sdk.WrapError(errV, "doImportEnvironment> Invalid verbosity")
+	}
 
 	proj, errProj := project.Load(db, key, c.User, project.LoadOptions.Default, project.LoadOptions.WithGroups, project.LoadOptions.WithPermission)
 	if errProj != nil {
-		return sdk.WrapError(errProj, "importNewEnvironmentHandler> Cannot load %s", key)
+		return sdk.WrapError(errProj, "doImportEnvironment> Cannot load %s", key)
 	}
 
 	var payload = &exportentities.Environment{}
 
 	// Get body
-	data, errRead := ioutil.ReadAll(r.Body)
+	data, errRead := readImportBody(r.Body, "")
 	if errRead != nil {
-		return sdk.WrapError(sdk.ErrWrongRequest, "importNewEnvironmentHandler> Unable to read body: %s", errRead)
+		return sdk.WrapError(importBodyError(errRead), "doImportEnvironment> Unable to read body: %s", errRead)
 	}
 
-	f, errF := exportentities.GetFormat(format)
+	f, errF := importFormat(format, r.Header.Get("Content-Type"))
 	if errF != nil {
-		return sdk.WrapError(sdk.ErrWrongRequest, "importNewEnvironmentHandler> Unable to get format: %s", errF)
+		return sdk.WrapError(sdk.ErrImportUnknownFormat, "doImportEnvironment> Unable to get format: %s", errF)
 	}
 
 	var errorParse error
@@ -47,11 +63,25 @@ func importNewEnvironmentHandler(w http.ResponseWriter, r *http.Request, db *gor
 	case exportentities.FormatJSON, exportentities.FormatHCL:
 		errorParse = hcl.Unmarshal(data, payload)
 	case exportentities.FormatYAML:
-		errorParse = yaml.Unmarshal(data, payload)
+		errorParse = unmarshalImportYAML(data, payload)
+	default:
+		errorParse = exportentities.ErrUnsupportedFormat
 	}
 
 	if errorParse != nil {
-		return sdk.WrapError(sdk.ErrWrongRequest, "importNewEnvironmentHandler> Cannot parsing: %s", errorParse)
+		return sdk.WrapError(importParseError(errorParse), "doImportEnvironment> Cannot parsing: %s", errorParse)
+	}
+
+	if err := inheritEnvironmentImport(db, proj, payload, c.User); err != nil {
+		return sdk.WrapError(err, "doImportEnvironment> Unable to resolve inheritance of environment %s", payload.Name)
+	}
+
+	refs, unresolved, errR := resolveEnvironmentReferences(db, proj, []exportentities.Environment{*payload}, c.User)
+	if errR != nil {
+		return sdk.WrapError(errR, "doImportEnvironment> Unable to resolve references of environment %s", payload.Name)
+	}
+	if len(unresolved) > 0 {
+		return WriteJSON(w, r, importMessageStrings(r, unresolved), http.StatusBadRequest)
 	}
 
 	env := payload.Environment()
@@ -60,56 +90,76 @@ func importNewEnvironmentHandler(w http.ResponseWriter, r *http.Request, db *gor
 		eg := &env.EnvironmentGroups[i]
 		g, err := group.LoadGroup(db, eg.Group.Name)
 		if err != nil {
-			return sdk.WrapError(err, "importNewEnvironmentHandler> Error on import")
+			return sdk.WrapError(importGroupError(err), "doImportEnvironment> Error loading group %s for permission", eg.Group.Name)
 		}
 		eg.Group = *g
 	}
 
-	allMsg := []sdk.Message{}
-	msgChan := make(chan sdk.Message, 10)
-	done := make(chan bool)
-
-	go func() {
-		for {
-			msg, ok := <-msgChan
-			log.Debug("importNewEnvironmentHandler >>> %s", msg)
-			allMsg = append(allMsg, msg)
-			if !ok {
-				done <- true
-			}
-		}
-	}()
+	msgChan, collectMessages := newMessageCollector()
+	defer collectMessages()
+	for _, m := range refs {
+		msgChan <- m
+	}
 
 	tx, errBegin := db.Begin()
 	if errBegin != nil {
-		return sdk.WrapError(errBegin, "importNewEnvironmentHandler: Cannot start transaction")
+		return sdk.WrapError(errBegin, "doImportEnvironment> Cannot start transaction")
 	}
 
 	defer tx.Rollback()
 
-	if err := environment.Import(db, proj, env, msgChan, c.User); err != nil {
-		return sdk.WrapError(err, "importNewEnvironmentHandler> Error on import")
+	if err := project.LockByKey(tx, proj.Key); err != nil {
+		return sdk.WrapError(err, "doImportEnvironment> Unable to lock project")
+	}
+
+	exist, errE := environment.Exists(tx, proj.Key, env.Name)
+	if errE != nil {
+		return sdk.WrapError(errE, "doImportEnvironment> Unable to check if environment %s exists", env.Name)
 	}
 
-	close(msgChan)
-	<-done
+	if exist {
+		if !forceUpdate {
+			return sdk.ErrEnvironmentExist
+		}
+
+		if err := environment.Lock(tx, proj.Key, env.Name); err != nil {
+			return sdk.WrapError(err, "doImportEnvironment> Cannot lock env %s/%s", proj.Key, env.Name)
+		}
+
+		oldEnv, errEnv := environment.LoadEnvironmentByName(tx, proj.Key, env.Name)
+		if errEnv != nil {
+			return sdk.WrapError(errEnv, "doImportEnvironment> Cannot load env %s/%s", proj.Key, env.Name)
+		}
+
+		if err := environment.ImportInto(tx, proj, env, oldEnv, msgChan, c.User); err != nil {
+			return sdk.WrapError(err, "doImportEnvironment> Error on import")
+		}
+	} else if err := environment.Import(tx, proj, env, msgChan, c.User); err != nil {
+		return sdk.WrapError(err, "doImportEnvironment> Error on import")
+	}
+
+	if err := project.UpdateLastModified(tx, c.User, proj); err != nil {
+		return sdk.WrapError(err, "doImportEnvironment> Cannot update project last modified date")
+	}
 
+	allMsg := sdk.DedupMessages(collectMessages())
+	log.Debug("doImportEnvironment >>> %v", allMsg)
 	al := r.Header.Get("Accept-Language")
 	msgListString := []string{}
 
 	for _, m := range allMsg {
 		s := m.String(al)
-		if s != "" {
+		if s != "" && m.Level().AtLeast(verbosity) {
 			msgListString = append(msgListString, s)
 		}
 	}
 
-	if err := sanity.CheckProjectPipelines(db, proj); err != nil {
-		return sdk.WrapError(err, "importNewEnvironmentHandler> Cannot check warnings")
+	if err := tx.Commit(); err != nil {
+		return sdk.WrapError(err, "doImportEnvironment> Cannot commit transaction")
 	}
 
-	if err := tx.Commit(); err != nil {
-		return sdk.WrapError(err, "importNewEnvironmentHandler> Cannot commit transaction")
+	if err := sanity.CheckProjectPipelines(db, proj); err != nil {
+		return sdk.WrapError(err, "doImportEnvironment> Cannot check warnings")
 	}
 
 	return WriteJSON(w, r, msgListString, http.StatusOK)
@@ -120,6 +170,10 @@ func importIntoEnvironmentHandler(w http.ResponseWriter, r *http.Request, db *go
 	key := vars["permProjectKey"]
 	envName := vars["permEnvironmentName"]
 	format := r.FormValue("format")
+	verbosity, errV := importVerbosity(r)
+	if errV != nil {
+		return sdk.WrapError(errV, "importIntoEnvironmentHandler> Invalid verbosity")
+	}
 
 	proj, errProj := project.Load(db, key, c.User, project.LoadOptions.Default, project.LoadOptions.WithGroups, project.LoadOptions.WithPermission)
 	if errProj != nil {
@@ -145,14 +199,14 @@ func importIntoEnvironmentHandler(w http.ResponseWriter, r *http.Request, db *go
 	var payload = &exportentities.Environment{}
 
 	// Get body
-	data, errRead := ioutil.ReadAll(r.Body)
+	data, errRead := readImportBody(r.Body, "")
 	if errRead != nil {
-		return sdk.WrapError(sdk.ErrWrongRequest, "importIntoEnvironmentHandler> Unable to read body: %s", errRead)
+		return sdk.WrapError(importBodyError(errRead), "importIntoEnvironmentHandler> Unable to read body: %s", errRead)
 	}
 
-	f, errF := exportentities.GetFormat(format)
+	f, errF := importFormat(format, r.Header.Get("Content-Type"))
 	if errF != nil {
-		return sdk.WrapError(sdk.ErrWrongRequest, "importIntoEnvironmentHandler> Unable to get format: %s", errF)
+		return sdk.WrapError(sdk.ErrImportUnknownFormat, "importIntoEnvironmentHandler> Unable to get format: %s", errF)
 	}
 
 	var errorParse error
@@ -160,11 +214,23 @@ func importIntoEnvironmentHandler(w http.ResponseWriter, r *http.Request, db *go
 	case exportentities.FormatJSON, exportentities.FormatHCL:
 		errorParse = hcl.Unmarshal(data, payload)
 	case exportentities.FormatYAML:
-		errorParse = yaml.Unmarshal(data, payload)
+		errorParse = unmarshalImportYAML(data, payload)
 	}
 
 	if errorParse != nil {
-		return sdk.WrapError(sdk.ErrWrongRequest, "importIntoEnvironmentHandler> Cannot parsing: %s", errorParse)
+		return sdk.WrapError(importParseError(errorParse), "importIntoEnvironmentHandler> Cannot parsing: %s", errorParse)
+	}
+
+	if err := inheritEnvironmentImport(db, proj, payload, c.User); err != nil {
+		return sdk.WrapError(err, "importIntoEnvironmentHandler> Unable to resolve inheritance of environment %s", payload.Name)
+	}
+
+	refs, unresolved, errR := resolveEnvironmentReferences(db, proj, []exportentities.Environment{*payload}, c.User)
+	if errR != nil {
+		return sdk.WrapError(errR, "importIntoEnvironmentHandler> Unable to resolve references of environment %s", payload.Name)
+	}
+	if len(unresolved) > 0 {
+		return WriteJSON(w, r, importMessageStrings(r, unresolved), http.StatusBadRequest)
 	}
 
 	newEnv := payload.Environment()
@@ -173,25 +239,16 @@ func importIntoEnvironmentHandler(w http.ResponseWriter, r *http.Request, db *go
 		eg := &newEnv.EnvironmentGroups[i]
 		g, err := group.LoadGroup(tx, eg.Group.Name)
 		if err != nil {
-			return sdk.WrapError(err, "importIntoEnvironmentHandler> Error on import")
+			return sdk.WrapError(importGroupError(err), "importIntoEnvironmentHandler> Error loading group %s for permission", eg.Group.Name)
 		}
 		eg.Group = *g
 	}
 
-	allMsg := []sdk.Message{}
-	msgChan := make(chan sdk.Message, 10)
-	done := make(chan bool)
-
-	go func() {
-		for {
-			msg, ok := <-msgChan
-			log.Debug("importIntoEnvironmentHandler >>> %s", msg)
-			allMsg = append(allMsg, msg)
-			if !ok {
-				done <- true
-			}
-		}
-	}()
+	msgChan, collectMessages := newMessageCollector()
+	defer collectMessages()
+	for _, m := range refs {
+		msgChan <- m
+	}
 
 	if err := environment.ImportInto(tx, proj, newEnv, env, msgChan, c.User); err != nil {
 		return sdk.WrapError(err, "importIntoEnvironmentHandler> Error on import")
@@ -201,15 +258,14 @@ func importIntoEnvironmentHandler(w http.ResponseWriter, r *http.Request, db *go
 		return sdk.WrapError(err, "importIntoEnvironmentHandler> Cannot update project last modified date")
 	}
 
-	close(msgChan)
-	<-done
-
+	allMsg := sdk.DedupMessages(collectMessages())
+	log.Debug("importIntoEnvironmentHandler >>> %v", allMsg)
 	al := r.Header.Get("Accept-Language")
 	msgListString := []string{}
 
 	for _, m := range allMsg {
 		s := m.String(al)
-		if s != "" {
+		if s != "" && m.Level().AtLeast(verbosity) {
 			msgListString = append(msgListString, s)
 		}
 	}
@@ -224,3 +280,242 @@ func importIntoEnvironmentHandler(w http.ResponseWriter, r *http.Request, db *go
 
 	return WriteJSON(w, r, msgListString, http.StatusOK)
 }
+
+//inheritEnvironmentImport merges the values of the environment of the project an imported environment
+//inherits from into it
+func inheritEnvironmentImport(db gorp.SqlExecutor, proj *sdk.Project, payload *exportentities.Environment, u *sdk.User) error {
+	envs := []exportentities.Environment{*payload}
+	cycle, err := resolveEnvironmentInheritance(db, proj, envs, u)
+	if err != nil {
+		return err
+	}
+	if cycle != nil {
+		return sdk.WrapError(sdk.ErrWrongRequest, "inheritEnvironmentImport> Environment %s inherits from itself", payload.Name)
+	}
+	*payload = envs[0]
+	return nil
+}
+
+//resolveEnvironmentInheritance merges the values of the parent of each imported environment into it, the
+//values of the environment winning. The parent is looked for in the imported environments first, then in
+//the environments of the project. The stored environments have no parent, as their values are already
+//merged, and must be readable by the user. If imported environments inherit from each other, the cycle is returned.
+func resolveEnvironmentInheritance(db gorp.SqlExecutor, proj *sdk.Project, envs []exportentities.Environment, u *sdk.User) ([]string, error) {
+	index := make(map[string]int, len(envs))
+	for i := range envs {
+		index[envs[i].Name] = i
+	}
+
+	var resolve func(i int, stack []string) ([]string, error)
+	resolve = func(i int, stack []string) ([]string, error) {
+		env := &envs[i]
+		if env.Inherits == "" {
+			return nil, nil
+		}
+		for j, name := range stack {
+			if name == env.Name {
+				return append(append([]string{}, stack[j:]...), env.Name), nil
+			}
+		}
+		stack = append(stack, env.Name)
+
+		var parent map[string]exportentities.VariableValue
+		if p, ok := index[env.Inherits]; ok {
+			if cycle, err := resolve(p, stack); cycle != nil || err != nil {
+				return cycle, err
+			}
+			parent = envs[p].Values
+		} else {
+			exist, errE := environment.Exists(db, proj.Key, env.Inherits)
+			if errE != nil {
+				return nil, sdk.WrapError(errE, "resolveEnvironmentInheritance> Unable to check if environment %s exists", env.Inherits)
+			}
+			if !exist {
+				return nil, sdk.WrapError(sdk.ErrNoEnvironment, "resolveEnvironmentInheritance> Environment %s inherits from unknown environment %s", env.Name, env.Inherits)
+			}
+			if err := checkEnvironmentImportAccess(db, proj, env.Inherits, u); err != nil {
+				return nil, sdk.WrapError(err, "resolveEnvironmentInheritance> Environment %s can't inherit from environment %s", env.Name, env.Inherits)
+			}
+			vars, errV := environment.GetAllVariable(db, proj.Key, env.Inherits, environment.WithClearPassword())
+			if errV != nil {
+				return nil, sdk.WrapError(errV, "resolveEnvironmentInheritance> Unable to load variables of environment %s", env.Inherits)
+			}
+			parent = make(map[string]exportentities.VariableValue, len(vars))
+			for _, v := range vars {
+				parent[v.Name] = exportentities.VariableValue{Type: string(v.Type), Value: v.Value}
+			}
+		}
+
+		values := make(map[string]exportentities.VariableValue, len(parent)+len(env.Values))
+		for k, v := range parent {
+			values[k] = v
+		}
+		for k, v := range env.Values {
+			values[k] = v
+		}
+		env.Values = values
+		env.Inherits = ""
+		return nil, nil
+	}
+
+	for i := range envs {
+		if cycle, err := resolve(i, nil); cycle != nil || err != nil {
+			return cycle, err
+		}
+	}
+	return nil, nil
+}
+
+//checkEnvironmentImportAccess checks that the user can read a stored environment whose values are copied in
+//clear by an import, so that its secrets can't be copied into an environment the user owns
+func checkEnvironmentImportAccess(db gorp.SqlExecutor, proj *sdk.Project, envName string, u *sdk.User) error {
+	env, err := environment.LoadEnvironmentByName(db, proj.Key, envName)
+	if err != nil {
+		return sdk.WrapError(err, "checkEnvironmentImportAccess> Unable to load environment %s", envName)
+	}
+	if !permission.AccessToEnvironment(env.ID, u, permission.PermissionRead) {
+		return sdk.WrapError(sdk.ErrForbidden, "checkEnvironmentImportAccess> User %s can't read environment %s", u.Username, envName)
+	}
+	return nil
+}
+
+//environmentReference matches the references {{.env.<environment>.<variable>}} to a value of another environment
+var environmentReference = regexp.MustCompile(`\{\{\s*\.env\.([a-zA-Z0-9_-]+)\.([a-zA-Z0-9._-]+)\s*\}\}`)
+
+//resolveEnvironmentReferences expands the references to the values of other environments in the values of the
+//imported environments. The referenced environment is looked for in the imported environments first, then in
+//the environments of the project, whose values are not expanded and which must be readable by the user. The values are updated in the maps of envs.
+//The resolved references are returned as messages, and the ones which can't be resolved, because they are
+//unknown, secrets or keys referenced by other types or in a cycle, are returned as error messages. The other {{
+//sequences are kept.
+func resolveEnvironmentReferences(db gorp.SqlExecutor, proj *sdk.Project, envs []exportentities.Environment, u *sdk.User) ([]sdk.Message, []sdk.Message, error) {
+	index := make(map[string]int, len(envs))
+	for i := range envs {
+		index[envs[i].Name] = i
+	}
+	stored := map[string]map[string]sdk.Variable{}
+	done := map[string]bool{}
+	failed := map[string]bool{}
+	resolved := []sdk.Message{}
+	unresolved := []sdk.Message{}
+
+	inPayload := func(envName, name string) bool {
+		i, ok := index[envName]
+		if !ok {
+			return false
+		}
+		_, ok = envs[i].Values[name]
+		return ok
+	}
+
+	var value func(envName, name string, stack []string) (*exportentities.VariableValue, error)
+	// lookup returns the value of a referenced variable, or nil if it is unknown or in a cycle
+	lookup := func(envName, name string, stack []string) (*exportentities.VariableValue, error) {
+		if _, ok := index[envName]; ok {
+			return value(envName, name, stack)
+		}
+		vars, ok := stored[envName]
+		if !ok {
+			exist, errE := environment.Exists(db, proj.Key, envName)
+			if errE != nil {
+				return nil, sdk.WrapError(errE, "resolveEnvironmentReferences> Unable to check if environment %s exists", envName)
+			}
+			vars = map[string]sdk.Variable{}
+			if exist {
+				if err := checkEnvironmentImportAccess(db, proj, envName, u); err != nil {
+					return nil, sdk.WrapError(err, "resolveEnvironmentReferences> Unable to reference environment %s", envName)
+				}
+				l, errV := environment.GetAllVariable(db, proj.Key, envName, environment.WithClearPassword())
+				if errV != nil {
+					return nil, sdk.WrapError(errV, "resolveEnvironmentReferences> Unable to load variables of environment %s", envName)
+				}
+				for _, v := range l {
+					vars[v.Name] = v
+				}
+			}
+			stored[envName] = vars
+		}
+		v, ok := vars[name]
+		if !ok {
+			return nil, nil
+		}
+		return &exportentities.VariableValue{Type: string(v.Type), Value: v.Value}, nil
+	}
+
+	value = func(envName, name string, stack []string) (*exportentities.VariableValue, error) {
+		env := &envs[index[envName]]
+		v, ok := env.Values[name]
+		if !ok {
+			return nil, nil
+		}
+		node := envName + "." + name
+		if failed[node] {
+			return nil, nil
+		}
+		if done[node] {
+			return &v, nil
+		}
+		for j, n := range stack {
+			if n == node {
+				cycle := append(append([]string{}, stack[j:]...), node)
+				unresolved = append(unresolved, sdk.NewMessage(sdk.MsgEnvironmentReferenceCycle, strings.Join(cycle, " -> ")))
+				return nil, nil
+			}
+		}
+		stack = append(stack, node)
+
+		var errL error
+		var unknown bool
+		v.Value = environmentReference.ReplaceAllStringFunc(v.Value, func(ref string) string {
+			if errL != nil {
+				return ref
+			}
+			m := environmentReference.FindStringSubmatch(ref)
+			target, err := lookup(m[1], m[2], stack)
+			if err != nil {
+				errL = err
+				return ref
+			}
+			if target == nil {
+				// A reference in a cycle, or to a value which can't be resolved, is already reported
+				if !inPayload(m[1], m[2]) {
+					unresolved = append(unresolved, sdk.NewMessage(sdk.MsgEnvironmentReferenceUnknown, ref, name, envName))
+				}
+				unknown = true
+				return ref
+			}
+			// The clear values of the secrets and the keys are only copied into secrets or keys
+			if sdk.NeedPlaceholder(target.Type) && !sdk.NeedPlaceholder(v.Type) {
+				unresolved = append(unresolved, sdk.NewMessage(sdk.MsgEnvironmentReferenceSecret, ref, name, envName))
+				unknown = true
+				return ref
+			}
+			resolved = append(resolved, sdk.NewMessage(sdk.MsgEnvironmentReferenceResolved, ref, name, envName))
+			return target.Value
+		})
+		if errL != nil {
+			return nil, errL
+		}
+		if unknown {
+			failed[node] = true
+			return nil, nil
+		}
+		env.Values[name] = v
+		done[node] = true
+		return &v, nil
+	}
+
+	for i := range envs {
+		names := make([]string, 0, len(envs[i].Values))
+		for k := range envs[i].Values {
+			names = append(names, k)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if _, err := value(envs[i].Name, name, nil); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	return resolved, sdk.DedupMessages(unresolved), nil
+}
@@ -278,6 +278,11 @@ func processHook(db *gorp.DbMap, h hook.ReceivedHook) error {
 
 		found = true
 
+		if !hooks[i].Branches.Match(h.Branch) {
+			log.Info("processHook> Branch %s of %s/%s is filtered by hook %d", h.Branch, h.ProjectKey, h.Repository, hooks[i].ID)
+			continue
+		}
+
 		// create pipeline object
 		p, err := pipeline.LoadPipelineByID(tx, hooks[i].Pipeline.ID, true)
 		if err != nil {
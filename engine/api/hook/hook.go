@@ -4,9 +4,11 @@ import (
 	"crypto/rand"
 	"database/sql"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"net/url"
 	"strings"
+	"sync"
 
 	"github.com/go-gorp/gorp"
 
@@ -17,11 +19,20 @@ import (
 	"github.com/ovh/cds/sdk/log"
 )
 
-var apiURL string
+var (
+	apiURL              string
+	creationConcurrency = defaultCreationConcurrency
+)
+
+const defaultCreationConcurrency = 4
 
-// Init initialize the hook package
-func Init(url string) {
+// Init initialize the hook package. concurrency is the max number of hooks created
+// at the same time on the repositories managers by the OutboxWorker
+func Init(url string, concurrency int) {
 	apiURL = url
+	if concurrency > 0 {
+		creationConcurrency = concurrency
+	}
 }
 
 //ReceivedHook is a temporary struct to manage received hook
@@ -54,9 +65,13 @@ func InsertReceivedHook(db gorp.SqlExecutor, link string, data string) error {
 
 // UpdateHook update the given hook
 func UpdateHook(db gorp.SqlExecutor, h sdk.Hook) error {
-	query := `UPDATE hook set pipeline_id=$1, kind=$2, host=$3, project=$4, repository=$5, application_id=$6, enabled=$7 WHERE id=$8`
+	query := `UPDATE hook set pipeline_id=$1, kind=$2, host=$3, project=$4, repository=$5, application_id=$6, enabled=$7, branches=$8 WHERE id=$9`
 
-	res, err := db.Exec(query, h.Pipeline.ID, h.Kind, h.Host, h.Project, h.Repository, h.ApplicationID, h.Enabled, h.ID)
+	branches, err := json.Marshal(h.Branches)
+	if err != nil {
+		return err
+	}
+	res, err := db.Exec(query, h.Pipeline.ID, h.Kind, h.Host, h.Project, h.Repository, h.ApplicationID, h.Enabled, string(branches), h.ID)
 	if err != nil {
 		return err
 	}
@@ -72,7 +87,7 @@ func UpdateHook(db gorp.SqlExecutor, h sdk.Hook) error {
 
 // InsertHook add link between git repository and pipeline in database
 func InsertHook(db gorp.SqlExecutor, h *sdk.Hook) error {
-	query := `INSERT INTO hook (pipeline_id, kind, host, project, repository, application_id, enabled, uid) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id`
+	query := `INSERT INTO hook (pipeline_id, kind, host, project, repository, application_id, enabled, uid, branches) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id`
 
 	// Generate UID
 	uid, err := generateHash()
@@ -81,7 +96,11 @@ func InsertHook(db gorp.SqlExecutor, h *sdk.Hook) error {
 	}
 	h.UID = uid
 
-	err = db.QueryRow(query, h.Pipeline.ID, h.Kind, h.Host, h.Project, h.Repository, h.ApplicationID, h.Enabled, h.UID).Scan(&h.ID)
+	branches, err := json.Marshal(h.Branches)
+	if err != nil {
+		return err
+	}
+	err = db.QueryRow(query, h.Pipeline.ID, h.Kind, h.Host, h.Project, h.Repository, h.ApplicationID, h.Enabled, h.UID, string(branches)).Scan(&h.ID)
 	if err != nil {
 		return err
 	}
@@ -92,20 +111,21 @@ func InsertHook(db gorp.SqlExecutor, h *sdk.Hook) error {
 // LoadHook loads a single hook
 func LoadHook(db gorp.SqlExecutor, id int64) (sdk.Hook, error) {
 	h := sdk.Hook{ID: id}
-	query := `SELECT application_id, pipeline_id, kind, host, project, repository, enabled FROM hook WHERE id = $1`
+	query := `SELECT application_id, pipeline_id, kind, host, project, repository, enabled, branches FROM hook WHERE id = $1`
 
-	err := db.QueryRow(query, id).Scan(&h.ApplicationID, &h.Pipeline.ID, &h.Kind, &h.Host, &h.Project, &h.Repository, &h.Enabled)
+	var branches string
+	err := db.QueryRow(query, id).Scan(&h.ApplicationID, &h.Pipeline.ID, &h.Kind, &h.Host, &h.Project, &h.Repository, &h.Enabled, &branches)
 	if err != nil {
 		return h, err
 	}
 
-	return h, nil
+	return h, unmarshalBranches(branches, &h)
 }
 
 //FindHook loads a hook from its attributes
 func FindHook(db gorp.SqlExecutor, applicationID, pipelineID int64, kind, host, project, repository string) (sdk.Hook, error) {
 	h := sdk.Hook{}
-	query := `SELECT 	id, application_id, pipeline_id, kind, host, project, repository, uid
+	query := `SELECT 	id, application_id, pipeline_id, kind, host, project, repository, uid, enabled, branches
 						FROM 		hook
 						WHERE  	application_id=$1
 						AND 		pipeline_id=$2
@@ -114,11 +134,12 @@ func FindHook(db gorp.SqlExecutor, applicationID, pipelineID int64, kind, host,
 						AND 		project=$5
 						AND 		repository=$6`
 
-	err := db.QueryRow(query, applicationID, pipelineID, kind, host, project, repository).Scan(&h.ID, &h.ApplicationID, &h.Pipeline.ID, &h.Kind, &h.Host, &h.Project, &h.Repository, &h.UID)
+	var branches string
+	err := db.QueryRow(query, applicationID, pipelineID, kind, host, project, repository).Scan(&h.ID, &h.ApplicationID, &h.Pipeline.ID, &h.Kind, &h.Host, &h.Project, &h.Repository, &h.UID, &h.Enabled, &branches)
 	if err != nil {
 		return h, err
 	}
-	return h, nil
+	return h, unmarshalBranches(branches, &h)
 }
 
 // DeleteHook removes hook from database
@@ -143,7 +164,7 @@ func DeleteHook(db gorp.SqlExecutor, id int64) error {
 // LoadApplicationHooks will load all hooks related to given application
 func LoadApplicationHooks(db gorp.SqlExecutor, applicationID int64) ([]sdk.Hook, error) {
 	hooks := []sdk.Hook{}
-	query := `SELECT hook.id, hook.kind, hook.host, hook.project, hook.repository, hook.enabled, hook.uid, pipeline.id, pipeline.name
+	query := `SELECT hook.id, hook.kind, hook.host, hook.project, hook.repository, hook.enabled, hook.uid, hook.branches, pipeline.id, pipeline.name
 		  FROM hook
 		  JOIN pipeline ON pipeline.id = hook.pipeline_id
 		  WHERE application_id= $1
@@ -157,11 +178,15 @@ func LoadApplicationHooks(db gorp.SqlExecutor, applicationID int64) ([]sdk.Hook,
 
 	for rows.Next() {
 		var h sdk.Hook
+		var branches string
 		h.ApplicationID = applicationID
-		err = rows.Scan(&h.ID, &h.Kind, &h.Host, &h.Project, &h.Repository, &h.Enabled, &h.UID, &h.Pipeline.ID, &h.Pipeline.Name)
+		err = rows.Scan(&h.ID, &h.Kind, &h.Host, &h.Project, &h.Repository, &h.Enabled, &h.UID, &branches, &h.Pipeline.ID, &h.Pipeline.Name)
 		if err != nil {
 			return hooks, err
 		}
+		if err := unmarshalBranches(branches, &h); err != nil {
+			return hooks, err
+		}
 		link := apiURL + HookLink
 		h.Link = fmt.Sprintf(link, h.UID, h.Project, h.Repository)
 		hooks = append(hooks, h)
@@ -172,7 +197,7 @@ func LoadApplicationHooks(db gorp.SqlExecutor, applicationID int64) ([]sdk.Hook,
 
 // LoadPipelineHooks will load all hooks related to given pipeline
 func LoadPipelineHooks(db gorp.SqlExecutor, pipelineID int64, applicationID int64) ([]sdk.Hook, error) {
-	query := `SELECT id, kind, host, project, repository, uid, enabled FROM hook WHERE pipeline_id = $1 AND application_id= $2`
+	query := `SELECT id, kind, host, project, repository, uid, enabled, branches FROM hook WHERE pipeline_id = $1 AND application_id= $2`
 
 	rows, err := db.Query(query, pipelineID, applicationID)
 	if err != nil {
@@ -183,9 +208,13 @@ func LoadPipelineHooks(db gorp.SqlExecutor, pipelineID int64, applicationID int6
 	var hooks []sdk.Hook
 	for rows.Next() {
 		var h sdk.Hook
+		var branches string
 		h.Pipeline.ID = pipelineID
 		h.ApplicationID = applicationID
-		if err = rows.Scan(&h.ID, &h.Kind, &h.Host, &h.Project, &h.Repository, &h.UID, &h.Enabled); err != nil {
+		if err = rows.Scan(&h.ID, &h.Kind, &h.Host, &h.Project, &h.Repository, &h.UID, &h.Enabled, &branches); err != nil {
+			return nil, err
+		}
+		if err := unmarshalBranches(branches, &h); err != nil {
 			return nil, err
 		}
 		link := apiURL + HookLink
@@ -198,7 +227,7 @@ func LoadPipelineHooks(db gorp.SqlExecutor, pipelineID int64, applicationID int6
 
 // LoadHooks related to given repository
 func LoadHooks(db gorp.SqlExecutor, project string, repository string) ([]sdk.Hook, error) {
-	query := `SELECT id, pipeline_id, application_id, kind, host, enabled, uid FROM hook WHERE project = $1 AND repository = $2`
+	query := `SELECT id, pipeline_id, application_id, kind, host, enabled, uid, branches FROM hook WHERE project = $1 AND repository = $2`
 
 	rows, err := db.Query(query, project, repository)
 	if err != nil {
@@ -209,18 +238,30 @@ func LoadHooks(db gorp.SqlExecutor, project string, repository string) ([]sdk.Ho
 	var hooks []sdk.Hook
 	for rows.Next() {
 		var h sdk.Hook
+		var branches string
 		h.Project = project
 		h.Repository = repository
-		err = rows.Scan(&h.ID, &h.Pipeline.ID, &h.ApplicationID, &h.Kind, &h.Host, &h.Enabled, &h.UID)
+		err = rows.Scan(&h.ID, &h.Pipeline.ID, &h.ApplicationID, &h.Kind, &h.Host, &h.Enabled, &h.UID, &branches)
 		if err != nil {
 			return nil, err
 		}
+		if err := unmarshalBranches(branches, &h); err != nil {
+			return nil, err
+		}
 		hooks = append(hooks, h)
 	}
 
 	return hooks, nil
 }
 
+//unmarshalBranches reads the branch filters of a hook stored in database
+func unmarshalBranches(branches string, h *sdk.Hook) error {
+	if err := json.Unmarshal([]byte(branches), &h.Branches); err != nil {
+		return sdk.WrapError(err, "unmarshalBranches> Unable to read branches of hook %d", h.ID)
+	}
+	return nil
+}
+
 func generateHash() (string, error) {
 	size := 128
 	bs := make([]byte, size)
@@ -247,20 +288,70 @@ func DeleteBranchBuilds(db gorp.SqlExecutor, hooks []sdk.Hook, branch string) er
 }
 
 // CreateHook in CDS db + repo manager webhook
-func CreateHook(tx gorp.SqlExecutor, projectKey string, rm *sdk.RepositoriesManager, repoFullName string, application *sdk.Application, pipeline *sdk.Pipeline) (*sdk.Hook, error) {
+func CreateHook(tx gorp.SqlExecutor, projectKey string, rm *sdk.RepositoriesManager, repoFullName string, application *sdk.Application, pipeline *sdk.Pipeline, branches sdk.HookBranches) (*sdk.Hook, error) {
 	client, err := repositoriesmanager.AuthorizedClient(tx, projectKey, rm.Name)
 	if err != nil {
 		return nil, sdk.WrapError(err, "CreateHook> Cannot get client, got  %s %s", projectKey, rm.Name)
 	}
 
+	h, err := prepareHook(tx, rm, repoFullName, application, pipeline, branches)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := client.CreateHook(repoFullName, h.Link); err != nil {
+		log.Warning("Cannot create hook on repository manager: %s", err)
+		if strings.Contains(err.Error(), "Not yet implemented") {
+			return nil, sdk.WrapError(sdk.ErrNotImplemented, "CreateHook> Cannot create hook on repository manager")
+		}
+		if err := DeleteHook(tx, h.ID); err != nil {
+			return nil, sdk.WrapError(err, "CreateHook> Cannot rollback hook creation")
+		}
+	}
+	return h, nil
+}
+
+//CreateHooks creates the hooks of the pipelines. Hooks are inserted in database and their creation on the
+//repositories manager is recorded in the transaction. They are created on the repositories manager by the
+//OutboxWorker, once the transaction is committed. The new hooks are only triggered by the filtered branches.
+func CreateHooks(tx gorp.SqlExecutor, projectKey string, rm *sdk.RepositoriesManager, repoFullName string, application *sdk.Application, pipelines []sdk.Pipeline, branches sdk.HookBranches) ([]sdk.Hook, error) {
+	if len(pipelines) == 0 {
+		return nil, nil
+	}
+
+	prm, err := repositoriesmanager.LoadForProject(tx, projectKey, rm.Name)
+	if err == nil && prm == nil {
+		err = sdk.ErrNoReposManager
+	}
+	if err != nil {
+		return nil, sdk.WrapError(err, "CreateHooks> Cannot get repositories manager, got  %s %s", projectKey, rm.Name)
+	}
+	if !prm.HooksSupported {
+		return nil, sdk.WrapError(sdk.ErrNotImplemented, "CreateHooks> Cannot create hook on repository manager %s", rm.Name)
+	}
+
+	hooks := make([]sdk.Hook, len(pipelines))
+	for i := range pipelines {
+		h, err := prepareHook(tx, rm, repoFullName, application, &pipelines[i], branches)
+		if err != nil {
+			return nil, err
+		}
+		if err := enqueueHookCreation(tx, projectKey, rm.Name, repoFullName, h); err != nil {
+			return nil, sdk.WrapError(err, "CreateHooks> Cannot record creation of hook of pipeline %s", pipelines[i].Name)
+		}
+		hooks[i] = *h
+	}
+	return hooks, nil
+}
+
+//prepareHook loads or inserts the hook in database and computes its link. The branches are the filters of an inserted hook.
+func prepareHook(tx gorp.SqlExecutor, rm *sdk.RepositoriesManager, repoFullName string, application *sdk.Application, pipeline *sdk.Pipeline, branches sdk.HookBranches) (*sdk.Hook, error) {
 	t := strings.Split(repoFullName, "/")
 	if len(t) != 2 {
 		return nil, sdk.WrapError(fmt.Errorf("CreateHook> Wrong repo fullname %s.", repoFullName), "")
 	}
 
-	var h sdk.Hook
-
-	h, err = FindHook(tx, application.ID, pipeline.ID, string(rm.Type), rm.URL, t[0], t[1])
+	h, err := FindHook(tx, application.ID, pipeline.ID, string(rm.Type), rm.URL, t[0], t[1])
 	if err == sql.ErrNoRows {
 		h = sdk.Hook{
 			Pipeline:      *pipeline,
@@ -270,6 +361,7 @@ func CreateHook(tx gorp.SqlExecutor, projectKey string, rm *sdk.RepositoriesMana
 			Project:       t[0],
 			Repository:    t[1],
 			Enabled:       true,
+			Branches:      branches,
 		}
 		if err := InsertHook(tx, &h); err != nil {
 			return nil, sdk.WrapError(err, "CreateHook> Cannot insert hook")
@@ -279,20 +371,36 @@ func CreateHook(tx gorp.SqlExecutor, projectKey string, rm *sdk.RepositoriesMana
 	}
 
 	s := apiURL + HookLink
-	link := fmt.Sprintf(s, h.UID, t[0], t[1])
+	h.Link = fmt.Sprintf(s, h.UID, t[0], t[1])
+	return &h, nil
+}
 
-	h.Link = link
+//runConcurrently calls f for each index in [0, n), with at most concurrency calls at the same time.
+//It waits for all the calls and returns the error of the lowest index, if any.
+func runConcurrently(n, concurrency int, f func(i int) error) error {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	errs := make([]error, n)
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			errs[i] = f(i)
+		}(i)
+	}
+	wg.Wait()
 
-	if err := client.CreateHook(repoFullName, link); err != nil {
-		log.Warning("Cannot create hook on repository manager: %s", err)
-		if strings.Contains(err.Error(), "Not yet implemented") {
-			return nil, sdk.WrapError(sdk.ErrNotImplemented, "CreateHook> Cannot create hook on repository manager")
-		}
-		if err := DeleteHook(tx, h.ID); err != nil {
-			return nil, sdk.WrapError(err, "CreateHook> Cannot rollback hook creation")
+	for _, err := range errs {
+		if err != nil {
+			return err
 		}
 	}
-	return &h, nil
+	return nil
 }
 
 //Recovery try to recovers hook in case of error
@@ -0,0 +1,105 @@
+package hook
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ovh/cds/sdk"
+)
+
+func Test_runConcurrently(t *testing.T) {
+	var mutex sync.Mutex
+	var running, maxRunning int
+	done := make([]bool, 10)
+
+	err := runConcurrently(len(done), 3, func(i int) error {
+		mutex.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		mutex.Unlock()
+
+		done[i] = true
+
+		mutex.Lock()
+		running--
+		mutex.Unlock()
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.True(t, maxRunning <= 3, "%d calls at the same time", maxRunning)
+	for i := range done {
+		assert.True(t, done[i], "call %d not done", i)
+	}
+}
+
+func Test_runConcurrentlyError(t *testing.T) {
+	err := runConcurrently(5, 2, func(i int) error {
+		if i >= 2 {
+			return fmt.Errorf("error %d", i)
+		}
+		return nil
+	})
+	assert.EqualError(t, err, "error 2")
+}
+
+func Test_diffExternalHooks(t *testing.T) {
+	stored := []sdk.Hook{
+		{Pipeline: sdk.Pipeline{Name: "build"}, Link: "http://cds/hook?uid=1"},
+		{Pipeline: sdk.Pipeline{Name: "deploy"}, Link: "http://cds/hook?uid=2"},
+		{Pipeline: sdk.Pipeline{Name: "test"}, Link: "http://cds/hook?uid=3"},
+	}
+	external := []sdk.VCSHook{
+		{URL: "http://cds/hook?uid=1", Enabled: true},
+		{URL: "http://cds/hook?uid=3", Enabled: true},
+		{URL: "http://other/hook", Enabled: true},
+	}
+
+	d := diffExternalHooks(external, stored, []string{"build", "deploy", "release", "release"}, false)
+	assert.Equal(t, []sdk.ExternalHook{{Pipeline: "release"}}, d.Added)
+	assert.Equal(t, []sdk.ExternalHook{{Pipeline: "build", URL: "http://cds/hook?uid=1"}}, d.Unchanged)
+	assert.Equal(t, []sdk.ExternalHook{{Pipeline: "deploy", URL: "http://cds/hook?uid=2"}}, d.Missing)
+	assert.Empty(t, d.Removed)
+
+	// Only the hooks of CDS are removed
+	d = diffExternalHooks(external, stored, []string{"build"}, true)
+	assert.Equal(t, []sdk.ExternalHook{{Pipeline: "test", URL: "http://cds/hook?uid=3"}}, d.Removed)
+}
+
+type outboxClient struct {
+	sdk.RepositoriesManagerClient
+	hooks            []sdk.VCSHook
+	created, deleted []string
+}
+
+func (c *outboxClient) Hooks(repo string) ([]sdk.VCSHook, error) {
+	return c.hooks, nil
+}
+
+func (c *outboxClient) CreateHook(repo, url string) error {
+	c.created = append(c.created, repo+" "+url)
+	return nil
+}
+
+func (c *outboxClient) DeleteHook(repo, url string) error {
+	c.deleted = append(c.deleted, repo+" "+url)
+	return fmt.Errorf("Not yet implemented")
+}
+
+func Test_processOutboxEntry(t *testing.T) {
+	c := &outboxClient{}
+	assert.NoError(t, processOutboxEntry(c, outboxEntry{RepoFullname: "proj/repo", Link: "http://cds/hook?uid=1", Action: outboxActionCreate}))
+	// A deletion not implemented on the repositories manager is not retried
+	assert.NoError(t, processOutboxEntry(c, outboxEntry{RepoFullname: "proj/repo", Link: "http://cds/hook?uid=2", Action: outboxActionDelete}))
+	assert.Equal(t, []string{"proj/repo http://cds/hook?uid=1"}, c.created)
+	assert.Equal(t, []string{"proj/repo http://cds/hook?uid=2"}, c.deleted)
+
+	// A hook already on the repositories manager is not created again
+	c = &outboxClient{hooks: []sdk.VCSHook{{URL: "http://cds/hook?uid=3", Enabled: true}}}
+	assert.NoError(t, processOutboxEntry(c, outboxEntry{RepoFullname: "proj/repo", Link: "http://cds/hook?uid=3", Action: outboxActionCreate}))
+	assert.Empty(t, c.created)
+}
@@ -0,0 +1,269 @@
+package hook
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/go-gorp/gorp"
+
+	"github.com/ovh/cds/engine/api/repositoriesmanager"
+	"github.com/ovh/cds/sdk"
+	"github.com/ovh/cds/sdk/log"
+)
+
+// outboxMaxAttempts is the number of tries to create or delete a hook on the repositories manager
+// before the operation is given up
+const outboxMaxAttempts = 10
+
+// outboxClaimDuration is the time given to an instance to create or delete the hooks it has claimed,
+// before another instance can claim them
+const outboxClaimDuration = 5 * time.Minute
+
+// Operations of the outbox entries on the repositories manager
+const (
+	outboxActionCreate = "create"
+	outboxActionDelete = "delete"
+)
+
+//outboxEntry is a hook to create or delete on a repositories manager once the transaction which has
+//inserted it is committed. The hook of a deletion doesn't exist anymore in database, its HookID is 0.
+type outboxEntry struct {
+	ID                  int64
+	HookID              int64
+	ProjectKey          string
+	RepositoriesManager string
+	RepoFullname        string
+	Link                string
+	Action              string
+	Attempts            int
+}
+
+//enqueueHookCreation records in the transaction the creation of the hook on the repositories manager.
+//A hook is recorded only once.
+func enqueueHookCreation(db gorp.SqlExecutor, projectKey, rmName, repoFullName string, h *sdk.Hook) error {
+	query := `INSERT INTO hook_outbox (hook_id, project_key, repositories_manager, repo_fullname, link)
+		SELECT $1, $2, $3, $4, $5
+		WHERE NOT EXISTS (SELECT 1 FROM hook_outbox WHERE hook_id = $1)`
+	_, err := db.Exec(query, h.ID, projectKey, rmName, repoFullName, h.Link)
+	return err
+}
+
+//EnqueueHookDeletion records in the transaction the deletion of the hook on the repositories manager, before
+//the hook is deleted from database. A hook whose creation is still recorded and not claimed has never been
+//created on the repositories manager: its creation is only discarded. The deletion of a hook being created
+//is not claimed before the claim of its creation expires.
+func EnqueueHookDeletion(db gorp.SqlExecutor, projectKey, rmName string, h sdk.Hook) error {
+	res, err := db.Exec(`DELETE FROM hook_outbox WHERE hook_id = $1 AND action = $2
+		AND (locked_until IS NULL OR locked_until < now())`, h.ID, outboxActionCreate)
+	if err != nil {
+		return sdk.WrapError(err, "EnqueueHookDeletion> Unable to discard creation of hook %d", h.ID)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return sdk.WrapError(err, "EnqueueHookDeletion> Unable to discard creation of hook %d", h.ID)
+	} else if n > 0 {
+		return nil
+	}
+
+	query := `INSERT INTO hook_outbox (project_key, repositories_manager, repo_fullname, link, action, locked_until)
+		VALUES ($1, $2, $3, $4, $5, (SELECT locked_until FROM hook_outbox WHERE hook_id = $6 AND action = $7))`
+	if _, err := db.Exec(query, projectKey, rmName, h.Project+"/"+h.Repository, h.Link, outboxActionDelete, h.ID, outboxActionCreate); err != nil {
+		return sdk.WrapError(err, "EnqueueHookDeletion> Unable to record deletion of hook %d", h.ID)
+	}
+	return nil
+}
+
+//claimOutboxEntries claims the hooks to create or delete for outboxClaimDuration. The claim is committed at once,
+//so that no lock is held while the repositories managers are called. Entries being claimed by another instance
+//are skipped.
+func claimOutboxEntries(db gorp.SqlExecutor, limit int) ([]outboxEntry, error) {
+	query := `WITH claimed AS (
+			UPDATE hook_outbox SET locked_until = now() + $3::int * interval '1 second'
+			WHERE id IN (
+				SELECT id FROM hook_outbox
+				WHERE attempts < $1 AND (locked_until IS NULL OR locked_until < now())
+				ORDER BY id
+				LIMIT $2
+				FOR UPDATE SKIP LOCKED)
+			RETURNING id, COALESCE(hook_id, 0) AS hook_id, project_key, repositories_manager, repo_fullname, link, action, attempts)
+		SELECT id, hook_id, project_key, repositories_manager, repo_fullname, link, action, attempts FROM claimed ORDER BY id`
+	rows, err := db.Query(query, outboxMaxAttempts, limit, int64(outboxClaimDuration/time.Second))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []outboxEntry{}
+	for rows.Next() {
+		var e outboxEntry
+		if err := rows.Scan(&e.ID, &e.HookID, &e.ProjectKey, &e.RepositoriesManager, &e.RepoFullname, &e.Link, &e.Action, &e.Attempts); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+func deleteOutboxEntry(db gorp.SqlExecutor, id int64) error {
+	_, err := db.Exec("DELETE FROM hook_outbox WHERE id = $1", id)
+	return err
+}
+
+//updateOutboxEntryError counts the failed attempt and releases the claim of the entry
+func updateOutboxEntryError(db gorp.SqlExecutor, id int64, errC error) error {
+	_, err := db.Exec("UPDATE hook_outbox SET attempts = attempts + 1, last_error = $2, locked_until = NULL WHERE id = $1", id, errC.Error())
+	return err
+}
+
+//GivenUpHook is a hook whose creation or deletion on the repositories manager has been given up
+type GivenUpHook struct {
+	Action string
+	Link   string
+	Error  string
+}
+
+//LoadGivenUpHooks loads the hooks of the repository whose creation or deletion has been given up
+func LoadGivenUpHooks(db gorp.SqlExecutor, projectKey, repoFullName string) ([]GivenUpHook, error) {
+	query := `SELECT action, link, COALESCE(last_error, '') FROM hook_outbox
+		WHERE project_key = $1 AND repo_fullname = $2 AND attempts >= $3
+		ORDER BY id`
+	rows, err := db.Query(query, projectKey, repoFullName, outboxMaxAttempts)
+	if err != nil {
+		return nil, sdk.WrapError(err, "LoadGivenUpHooks> Unable to load hooks of %s/%s", projectKey, repoFullName)
+	}
+	defer rows.Close()
+
+	hooks := []GivenUpHook{}
+	for rows.Next() {
+		var h GivenUpHook
+		if err := rows.Scan(&h.Action, &h.Link, &h.Error); err != nil {
+			return nil, sdk.WrapError(err, "LoadGivenUpHooks> Unable to load hooks of %s/%s", projectKey, repoFullName)
+		}
+		hooks = append(hooks, h)
+	}
+	return hooks, rows.Err()
+}
+
+//GivenUpFunc is called with the repository of the hooks given up by ProcessOutbox
+type GivenUpFunc func(db gorp.SqlExecutor, projectKey, repoFullName string) error
+
+type authorizedClientFunc func(db gorp.SqlExecutor, projectKey, rmName string) (sdk.RepositoriesManagerClient, error)
+
+//OutboxWorker creates and deletes on the repositories managers the hooks recorded by the imports, once they are
+//committed. givenUp is called for the hooks which reach outboxMaxAttempts.
+func OutboxWorker(c context.Context, DBFunc func() *gorp.DbMap, givenUp GivenUpFunc) {
+	tick := time.NewTicker(5 * time.Second).C
+	for {
+		select {
+		case <-c.Done():
+			if c.Err() != nil {
+				log.Error("Exiting hook.OutboxWorker: %v", c.Err())
+				return
+			}
+		case <-tick:
+			if err := ProcessOutbox(DBFunc(), 10, givenUp); err != nil {
+				log.Warning("hook.OutboxWorker> %s", err)
+			}
+		}
+	}
+}
+
+//ProcessOutbox creates or deletes at most limit recorded hooks on their repositories manager. A hook may be created
+//again after a failure: it is skipped if the repositories manager already has its link, and CreateHook must be
+//idempotent for the repositories managers which can't list their hooks.
+func ProcessOutbox(db *gorp.DbMap, limit int, givenUp GivenUpFunc) error {
+	if db == nil {
+		return sdk.WrapError(sdk.ErrServiceUnavailable, "ProcessOutbox> Database not available")
+	}
+	return processOutbox(db, limit, repositoriesmanager.AuthorizedClient, givenUp)
+}
+
+func processOutbox(db gorp.SqlExecutor, limit int, authorizedClient authorizedClientFunc, givenUp GivenUpFunc) error {
+	entries, errC := claimOutboxEntries(db, limit)
+	if errC != nil {
+		return sdk.WrapError(errC, "ProcessOutbox> Unable to claim pending hooks")
+	}
+
+	// Hooks are created or deleted concurrently on the repositories managers
+	clients := make([]sdk.RepositoriesManagerClient, len(entries))
+	errs := make([]error, len(entries))
+	for i, e := range entries {
+		clients[i], errs[i] = authorizedClient(db, e.ProjectKey, e.RepositoriesManager)
+	}
+	_ = runConcurrently(len(entries), creationConcurrency, func(i int) error {
+		if errs[i] == nil {
+			errs[i] = processOutboxEntry(clients[i], entries[i])
+		}
+		return nil
+	})
+
+	// The outcome of each entry is recorded on its own: a failure leaves the entry to the next claim
+	for i, e := range entries {
+		err := errs[i]
+		if err == nil {
+			if errD := deleteOutboxEntry(db, e.ID); errD != nil {
+				log.Warning("ProcessOutbox> Unable to delete outbox entry %d: %s", e.ID, errD)
+			}
+			continue
+		}
+
+		log.Warning("ProcessOutbox> Cannot %s hook %s on %s (attempt %d): %s", e.Action, e.Link, e.RepoFullname, e.Attempts+1, err)
+		if errU := updateOutboxEntryError(db, e.ID, err); errU != nil {
+			log.Warning("ProcessOutbox> Unable to update outbox entry %d: %s", e.ID, errU)
+			continue
+		}
+		if e.Attempts+1 >= outboxMaxAttempts {
+			log.Error("ProcessOutbox> Giving up %s of hook %s on %s", e.Action, e.Link, e.RepoFullname)
+			if givenUp != nil {
+				if errG := givenUp(db, e.ProjectKey, e.RepoFullname); errG != nil {
+					log.Warning("ProcessOutbox> Unable to report hooks given up on %s: %s", e.RepoFullname, errG)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func processOutboxEntry(client sdk.RepositoriesManagerClient, e outboxEntry) error {
+	var err error
+	if e.Action == outboxActionDelete {
+		err = client.DeleteHook(e.RepoFullname, e.Link)
+	} else {
+		exists, errH := outboxHookExists(client, e)
+		if errH != nil {
+			log.Warning("processOutboxEntry> Unable to list hooks of %s: %s", e.RepoFullname, errH)
+		}
+		if exists {
+			return nil
+		}
+		err = client.CreateHook(e.RepoFullname, e.Link)
+	}
+	if err != nil {
+		// The repositories manager will never do it
+		if strings.Contains(err.Error(), "Not yet implemented") {
+			log.Warning("processOutboxEntry> Hook %s not implemented on %s", e.Action, e.RepositoriesManager)
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+//outboxHookExists checks if the repositories manager already has the link of a hook, so that a creation replayed
+//after a failure is skipped
+func outboxHookExists(client sdk.RepositoriesManagerClient, e outboxEntry) (bool, error) {
+	hooks, err := client.Hooks(e.RepoFullname)
+	if err != nil {
+		if strings.Contains(err.Error(), "Not yet implemented") {
+			return false, nil
+		}
+		return false, err
+	}
+	for _, h := range hooks {
+		if h.URL == e.Link {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,181 @@
+package hook
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/go-gorp/gorp"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ovh/cds/engine/api/test"
+	"github.com/ovh/cds/sdk"
+)
+
+func insertOutboxTestHook(t *testing.T, db gorp.SqlExecutor, repo string) sdk.Hook {
+	h := sdk.Hook{Kind: "stash", Host: "http://stash", Project: "PROJ", Repository: repo, UID: sdk.RandomString(10), Enabled: true}
+	test.NoError(t, db.QueryRow(`INSERT INTO hook (kind, host, project, repository, uid, enabled) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`,
+		h.Kind, h.Host, h.Project, h.Repository, h.UID, h.Enabled).Scan(&h.ID))
+	h.Link = "http://cds/hook?uid=" + h.UID
+	return h
+}
+
+func loadOutboxTestEntries(t *testing.T, db gorp.SqlExecutor, repoFullName string) []outboxEntry {
+	rows, err := db.Query(`SELECT id, COALESCE(hook_id, 0), link, action, attempts FROM hook_outbox WHERE repo_fullname = $1 ORDER BY id`, repoFullName)
+	test.NoError(t, err)
+	defer rows.Close()
+	entries := []outboxEntry{}
+	for rows.Next() {
+		var e outboxEntry
+		test.NoError(t, rows.Scan(&e.ID, &e.HookID, &e.Link, &e.Action, &e.Attempts))
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+func claimedOutboxTestEntries(entries []outboxEntry, repoFullName string) []string {
+	links := []string{}
+	for _, e := range entries {
+		if e.RepoFullname == repoFullName {
+			links = append(links, e.Action+" "+e.Link)
+		}
+	}
+	return links
+}
+
+func TestEnqueueHookCreation(t *testing.T) {
+	db := test.SetupPG(t)
+
+	repo := sdk.RandomString(10)
+	h := insertOutboxTestHook(t, db, repo)
+
+	// A hook is recorded only once
+	test.NoError(t, enqueueHookCreation(db, "PROJ", "stash", "PROJ/"+repo, &h))
+	test.NoError(t, enqueueHookCreation(db, "PROJ", "stash", "PROJ/"+repo, &h))
+
+	entries := loadOutboxTestEntries(t, db, "PROJ/"+repo)
+	if assert.Len(t, entries, 1) {
+		assert.Equal(t, h.ID, entries[0].HookID)
+		assert.Equal(t, h.Link, entries[0].Link)
+		assert.Equal(t, outboxActionCreate, entries[0].Action)
+	}
+}
+
+func TestEnqueueHookDeletion(t *testing.T) {
+	db := test.SetupPG(t)
+
+	// The pending creation of a hook is only discarded
+	repo := sdk.RandomString(10)
+	h := insertOutboxTestHook(t, db, repo)
+	test.NoError(t, enqueueHookCreation(db, "PROJ", "stash", "PROJ/"+repo, &h))
+	test.NoError(t, EnqueueHookDeletion(db, "PROJ", "stash", h))
+	assert.Empty(t, loadOutboxTestEntries(t, db, "PROJ/"+repo))
+
+	// A hook created on the repositories manager is deleted from it
+	test.NoError(t, EnqueueHookDeletion(db, "PROJ", "stash", h))
+	entries := loadOutboxTestEntries(t, db, "PROJ/"+repo)
+	if assert.Len(t, entries, 1) {
+		assert.Equal(t, int64(0), entries[0].HookID)
+		assert.Equal(t, outboxActionDelete, entries[0].Action)
+	}
+	_, err := db.Exec("DELETE FROM hook_outbox WHERE repo_fullname = $1", "PROJ/"+repo)
+	test.NoError(t, err)
+
+	// The deletion of a hook being created waits for the claim of its creation
+	test.NoError(t, enqueueHookCreation(db, "PROJ", "stash", "PROJ/"+repo, &h))
+	claimed, err := claimOutboxEntries(db, 1000)
+	test.NoError(t, err)
+	assert.Equal(t, []string{"create " + h.Link}, claimedOutboxTestEntries(claimed, "PROJ/"+repo))
+	test.NoError(t, EnqueueHookDeletion(db, "PROJ", "stash", h))
+	assert.Len(t, loadOutboxTestEntries(t, db, "PROJ/"+repo), 2)
+
+	claimed, err = claimOutboxEntries(db, 1000)
+	test.NoError(t, err)
+	assert.Empty(t, claimedOutboxTestEntries(claimed, "PROJ/"+repo))
+}
+
+func TestClaimOutboxEntries(t *testing.T) {
+	db := test.SetupPG(t)
+
+	repo := sdk.RandomString(10)
+	h1 := insertOutboxTestHook(t, db, repo)
+	h2 := insertOutboxTestHook(t, db, repo)
+	test.NoError(t, enqueueHookCreation(db, "PROJ", "stash", "PROJ/"+repo, &h1))
+	test.NoError(t, enqueueHookCreation(db, "PROJ", "stash", "PROJ/"+repo, &h2))
+
+	// The entries locked by another instance are skipped
+	tx, err := db.Begin()
+	test.NoError(t, err)
+	_, err = tx.Exec("SELECT id FROM hook_outbox WHERE hook_id = $1 FOR UPDATE", h1.ID)
+	test.NoError(t, err)
+
+	claimed, err := claimOutboxEntries(db, 1000)
+	test.NoError(t, err)
+	assert.Equal(t, []string{"create " + h2.Link}, claimedOutboxTestEntries(claimed, "PROJ/"+repo))
+	test.NoError(t, tx.Rollback())
+
+	// The claimed entries are not claimed again
+	claimed, err = claimOutboxEntries(db, 1000)
+	test.NoError(t, err)
+	assert.Equal(t, []string{"create " + h1.Link}, claimedOutboxTestEntries(claimed, "PROJ/"+repo))
+	claimed, err = claimOutboxEntries(db, 1000)
+	test.NoError(t, err)
+	assert.Empty(t, claimedOutboxTestEntries(claimed, "PROJ/"+repo))
+}
+
+type failingOutboxClient struct {
+	sdk.RepositoriesManagerClient
+}
+
+func (c *failingOutboxClient) Hooks(repo string) ([]sdk.VCSHook, error) {
+	return nil, fmt.Errorf("repositories manager unavailable")
+}
+
+func (c *failingOutboxClient) CreateHook(repo, url string) error {
+	return fmt.Errorf("repositories manager unavailable")
+}
+
+func TestProcessOutboxAttempts(t *testing.T) {
+	db := test.SetupPG(t)
+
+	repo := sdk.RandomString(10)
+	h := insertOutboxTestHook(t, db, repo)
+	test.NoError(t, enqueueHookCreation(db, "PROJ", "stash", "PROJ/"+repo, &h))
+
+	failing := func(db gorp.SqlExecutor, projectKey, rmName string) (sdk.RepositoriesManagerClient, error) {
+		return &failingOutboxClient{}, nil
+	}
+	var givenUp []string
+	onGivenUp := func(db gorp.SqlExecutor, projectKey, repoFullName string) error {
+		givenUp = append(givenUp, projectKey+" "+repoFullName)
+		return nil
+	}
+
+	// Each failure is counted and releases the entry for the next claim
+	for i := 1; i <= outboxMaxAttempts; i++ {
+		test.NoError(t, processOutbox(db, 1000, failing, onGivenUp))
+		entries := loadOutboxTestEntries(t, db, "PROJ/"+repo)
+		if assert.Len(t, entries, 1) {
+			assert.Equal(t, i, entries[0].Attempts)
+		}
+	}
+	assert.Contains(t, givenUp, "PROJ PROJ/"+repo)
+
+	// The given up entries are not claimed anymore
+	claimed, err := claimOutboxEntries(db, 1000)
+	test.NoError(t, err)
+	assert.Empty(t, claimedOutboxTestEntries(claimed, "PROJ/"+repo))
+
+	hooks, err := LoadGivenUpHooks(db, "PROJ", "PROJ/"+repo)
+	test.NoError(t, err)
+	assert.Equal(t, []GivenUpHook{{Action: outboxActionCreate, Link: h.Link, Error: "repositories manager unavailable"}}, hooks)
+
+	// A recorded hook is removed from the outbox once created
+	h2 := insertOutboxTestHook(t, db, repo)
+	test.NoError(t, enqueueHookCreation(db, "PROJ", "stash", "PROJ/"+repo, &h2))
+	c := &outboxClient{}
+	test.NoError(t, processOutbox(db, 1000, func(db gorp.SqlExecutor, projectKey, rmName string) (sdk.RepositoriesManagerClient, error) {
+		return c, nil
+	}, onGivenUp))
+	assert.Contains(t, c.created, "PROJ/"+repo+" "+h2.Link)
+	assert.Len(t, loadOutboxTestEntries(t, db, "PROJ/"+repo), 1)
+}
@@ -0,0 +1,91 @@
+package hook
+
+import (
+	"strings"
+
+	"github.com/go-gorp/gorp"
+
+	"github.com/ovh/cds/engine/api/repositoriesmanager"
+	"github.com/ovh/cds/sdk"
+)
+
+//PreviewHooks returns the changes an import would make on the hooks of the repository: stored are the hooks
+//of the application in database, pipelines are the pipelines which have a hook in the payload. If prune is set,
+//the stored hooks of the other pipelines are removed. The hooks of the repository are only read.
+func PreviewHooks(db gorp.SqlExecutor, projectKey string, rm *sdk.RepositoriesManager, repoFullName string, stored []sdk.Hook, pipelines []string, prune bool) (*sdk.ExternalHooksDiff, error) {
+	prm, err := repositoriesmanager.LoadForProject(db, projectKey, rm.Name)
+	if err == nil && prm == nil {
+		err = sdk.ErrNoReposManager
+	}
+	if err != nil {
+		return nil, sdk.WrapError(err, "PreviewHooks> Cannot get repositories manager, got  %s %s", projectKey, rm.Name)
+	}
+	if !prm.HooksSupported {
+		return nil, sdk.WrapError(sdk.ErrNotImplemented, "PreviewHooks> Hooks are not supported by repository manager %s", rm.Name)
+	}
+
+	client, err := repositoriesmanager.AuthorizedClient(db, projectKey, rm.Name)
+	if err != nil {
+		return nil, sdk.WrapError(err, "PreviewHooks> Cannot get client, got  %s %s", projectKey, rm.Name)
+	}
+
+	external, err := client.Hooks(repoFullName)
+	if err != nil {
+		if strings.Contains(err.Error(), "Not yet implemented") {
+			return nil, sdk.WrapError(sdk.ErrNotImplemented, "PreviewHooks> Cannot list hooks on repository manager")
+		}
+		return nil, sdk.WrapError(err, "PreviewHooks> Cannot list hooks of %s", repoFullName)
+	}
+
+	d := diffExternalHooks(external, stored, pipelines, prune)
+	d.RepositoriesManager = rm.Name
+	d.Repository = repoFullName
+	return d, nil
+}
+
+//diffExternalHooks compares the hooks found on the repository with the ones the import keeps or creates.
+//Hooks are matched on their link, the repository hooks which are not stored in CDS are ignored.
+func diffExternalHooks(external []sdk.VCSHook, stored []sdk.Hook, pipelines []string, prune bool) *sdk.ExternalHooksDiff {
+	links := make(map[string]bool, len(external))
+	for _, h := range external {
+		links[h.URL] = true
+	}
+	storedByPipeline := make(map[string]sdk.Hook, len(stored))
+	for _, h := range stored {
+		storedByPipeline[h.Pipeline.Name] = h
+	}
+	imported := make(map[string]bool, len(pipelines))
+
+	d := &sdk.ExternalHooksDiff{
+		Added:     []sdk.ExternalHook{},
+		Removed:   []sdk.ExternalHook{},
+		Unchanged: []sdk.ExternalHook{},
+		Missing:   []sdk.ExternalHook{},
+	}
+	for _, p := range pipelines {
+		if imported[p] {
+			continue
+		}
+		imported[p] = true
+
+		h, ok := storedByPipeline[p]
+		switch {
+		case !ok:
+			d.Added = append(d.Added, sdk.ExternalHook{Pipeline: p})
+		case links[h.Link]:
+			d.Unchanged = append(d.Unchanged, sdk.ExternalHook{Pipeline: p, URL: h.Link})
+		default:
+			d.Missing = append(d.Missing, sdk.ExternalHook{Pipeline: p, URL: h.Link})
+		}
+	}
+
+	if !prune {
+		return d
+	}
+	for _, h := range stored {
+		if !imported[h.Pipeline.Name] && links[h.Link] {
+			d.Removed = append(d.Removed, sdk.ExternalHook{Pipeline: h.Pipeline.Name, URL: h.Link})
+		}
+	}
+	return d
+}
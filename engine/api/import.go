@@ -0,0 +1,525 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/hashicorp/hcl"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v2"
+
+	"github.com/ovh/cds/engine/api/cache"
+	"github.com/ovh/cds/sdk"
+	"github.com/ovh/cds/sdk/exportentities"
+	"github.com/ovh/cds/sdk/log"
+)
+
+// importIdempotencyTTL is the time in seconds an import result is kept for its idempotency key
+const importIdempotencyTTL = 24 * 60 * 60
+
+const (
+	defaultImportURLTimeout     = 10 * time.Second
+	defaultImportURLMaxSize     = 1 << 20
+	defaultImportGzipMaxSize    = 10 << 20
+	defaultImportBodyMaxSize    = 5 << 20
+	defaultImportYAMLMaxNodes   = 100000
+	defaultImportYAMLMaxDepth   = 100
+	defaultImportRateLimitBurst = 10
+	// defaultImportAuditRetention is the number of days the payload of an import is kept to be replayed
+	defaultImportAuditRetention = 30
+)
+
+// importOnlyPermissions is the value of the only option which imports only the group permissions of an application
+const importOnlyPermissions = "permissions"
+
+// Policies of the duplicatePipelines option, when a pipeline is listed several times by an application
+const (
+	importDuplicatePipelinesReject   = "reject"
+	importDuplicatePipelinesKeepLast = "keepLast"
+)
+
+// Status of an import sent to the callback url
+const (
+	importCallbackSuccess = "Success"
+	importCallbackFail    = "Fail"
+	importCallbackDryRun  = "DryRun"
+)
+
+var (
+	importCallbackRetries = 5
+	importCallbackBackoff = time.Second
+)
+
+//privateNetworks are the networks not covered by the checks of net.IP in isPrivateIP
+var privateNetworks = []string{
+	"0.0.0.0/8",
+	"100.64.0.0/10",
+}
+
+//embeddedIPv4Networks are the IPv6 networks whose addresses embed an IPv4 address in their last 32 bits:
+//NAT64 and IPv4-compatible addresses
+var embeddedIPv4Networks = []string{
+	"64:ff9b::/96",
+	"::/96",
+}
+
+//newMessageCollector starts a goroutine collecting all the messages sent on the returned channel.
+//The returned function closes the channel, waits for the goroutine and returns the collected messages.
+//It can be called several times and must be deferred to ensure the goroutine ends on every exit path.
+func newMessageCollector() (chan<- sdk.Message, func() []sdk.Message) {
+	return newMessageCollectorFunc(nil)
+}
+
+//importMessageStrings translates messages in the language accepted by the request
+func importMessageStrings(r *http.Request, msgs []sdk.Message) []string {
+	al := r.Header.Get("Accept-Language")
+	res := make([]string, len(msgs))
+	for i := range msgs {
+		res[i] = msgs[i].String(al)
+	}
+	return res
+}
+
+//newMessageCollectorFunc is like newMessageCollector, and calls f, if not nil, on each message as soon as it is collected
+func newMessageCollectorFunc(f func(sdk.Message)) (chan<- sdk.Message, func() []sdk.Message) {
+	allMsg := []sdk.Message{}
+	msgChan := make(chan sdk.Message, 10)
+	done := make(chan bool)
+
+	go func() {
+		for msg := range msgChan {
+			allMsg = append(allMsg, msg)
+			if f != nil {
+				f(msg)
+			}
+		}
+		done <- true
+	}()
+
+	var once sync.Once
+	collect := func() []sdk.Message {
+		once.Do(func() {
+			close(msgChan)
+			<-done
+		})
+		return allMsg
+	}
+
+	return msgChan, collect
+}
+
+//importIdempotencyCacheKey returns the cache key of the result of an import for the X-Idempotency-Key
+//header of the request, or an empty string if there is no such header
+func importIdempotencyCacheKey(r *http.Request, kind, projectKey string, u *sdk.User) string {
+	k := strings.TrimSpace(r.Header.Get("X-Idempotency-Key"))
+	if k == "" {
+		return ""
+	}
+	var username string
+	if u != nil {
+		username = u.Username
+	}
+	return cache.Key("import", kind, projectKey, username, k)
+}
+
+//importCallback is the body posted on the callback url of an import
+type importCallback struct {
+	ProjectKey  string            `json:"project_key"`
+	Application string            `json:"application"`
+	Status      string            `json:"status"`
+	Result      *sdk.ImportResult `json:"result"`
+}
+
+//parseImportCallbackURL checks the callback url of an import, if any
+func parseImportCallbackURL(rawurl string) (*url.URL, error) {
+	if rawurl == "" {
+		return nil, nil
+	}
+	u, errP := url.Parse(rawurl)
+	if errP != nil {
+		return nil, errP
+	}
+	if err := checkImportURL(u); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+//sendImportCallback posts the import result on the callback url in a new goroutine. The post is retried
+//with an exponential backoff until the callback answers with a 2xx status. The returned channel is closed
+//when the goroutine ends.
+func sendImportCallback(u *url.URL, cb importCallback) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		b, errM := json.Marshal(cb)
+		if errM != nil {
+			log.Warning("sendImportCallback> Unable to marshal callback: %s", errM)
+			return
+		}
+
+		client := newImportHTTPClient(defaultImportURLTimeout)
+		backoff := importCallbackBackoff
+		for i := 0; i < importCallbackRetries; i++ {
+			if i > 0 {
+				time.Sleep(backoff)
+				backoff *= 2
+			}
+			resp, errP := client.Post(u.String(), "application/json", bytes.NewReader(b))
+			if errP != nil {
+				log.Warning("sendImportCallback> Unable to post on %s: %s", u.Host, errP)
+				continue
+			}
+			resp.Body.Close()
+			if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+				return
+			}
+			log.Warning("sendImportCallback> Unexpected status %s from %s", resp.Status, u.Host)
+		}
+		log.Warning("sendImportCallback> Giving up callback on %s after %d tries", u.Host, importCallbackRetries)
+	}()
+	return done
+}
+
+//importBodyTooLargeError is returned when the body of an import request is bigger than the max body size,
+//or than the max decompressed size once decompressed
+type importBodyTooLargeError struct {
+	maxSize      int64
+	decompressed bool
+}
+
+func (e importBodyTooLargeError) Error() string {
+	if e.decompressed {
+		return fmt.Sprintf("payload too large: decompressed body is bigger than %d bytes", e.maxSize)
+	}
+	return fmt.Sprintf("payload too large: body is bigger than %d bytes", e.maxSize)
+}
+
+//importBodyError returns the error sent when the body of an import request can't be read
+func importBodyError(err error) error {
+	if _, ok := err.(importBodyTooLargeError); ok {
+		return sdk.ErrImportPayloadTooLarge
+	}
+	return sdk.ErrWrongRequest
+}
+
+//importBodyMaxSize returns the max size of the body of an import request
+func importBodyMaxSize() int64 {
+	if maxSize := viper.GetInt64(viperImportBodyMaxSize); maxSize > 0 {
+		return maxSize
+	}
+	return defaultImportBodyMaxSize
+}
+
+//limitImportBody limits the size of the body of an import request. It must be called before the form of the
+//request is parsed, which reads a multipart body before the payload itself is read.
+func limitImportBody(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, importBodyMaxSize())
+}
+
+//isRequestBodyTooLarge returns true if err is returned by a body limited by http.MaxBytesReader once its
+//limit is reached. The error is wrapped by the multipart reader.
+func isRequestBodyTooLarge(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "http: request body too large")
+}
+
+//readImportBody reads the body of an import request, and fails if it is bigger than the max body size.
+//A gzip encoded body is decompressed, and fails if it is bigger than the max decompressed size.
+func readImportBody(body io.Reader, encoding string) ([]byte, error) {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "", "identity", "gzip", "x-gzip":
+	default:
+		return nil, fmt.Errorf("unsupported content encoding %s", encoding)
+	}
+
+	maxBodySize := importBodyMaxSize()
+	raw, errR := ioutil.ReadAll(io.LimitReader(body, maxBodySize+1))
+	if isRequestBodyTooLarge(errR) {
+		return nil, importBodyTooLargeError{maxSize: maxBodySize}
+	}
+	if errR != nil {
+		return nil, errR
+	}
+	if int64(len(raw)) > maxBodySize {
+		return nil, importBodyTooLargeError{maxSize: maxBodySize}
+	}
+	if enc := strings.ToLower(strings.TrimSpace(encoding)); enc == "" || enc == "identity" {
+		return raw, nil
+	}
+
+	maxSize := viper.GetInt64(viperImportGzipMaxSize)
+	if maxSize <= 0 {
+		maxSize = defaultImportGzipMaxSize
+	}
+
+	gz, errG := gzip.NewReader(bytes.NewReader(raw))
+	if errG != nil {
+		return nil, fmt.Errorf("unable to read gzip body: %s", errG)
+	}
+	defer gz.Close()
+
+	data, errR := ioutil.ReadAll(io.LimitReader(gz, maxSize+1))
+	if errR != nil {
+		return nil, fmt.Errorf("unable to read gzip body: %s", errR)
+	}
+	if int64(len(data)) > maxSize {
+		return nil, importBodyTooLargeError{maxSize: maxSize, decompressed: true}
+	}
+	return data, nil
+}
+
+//unmarshalImportYAML unmarshals a YAML import. Anchors and aliases are supported, but the document is rejected
+//if it is too big once its aliases are expanded.
+func unmarshalImportYAML(data []byte, out interface{}) error {
+	maxNodes := viper.GetInt(viperImportYAMLMaxNodes)
+	if maxNodes <= 0 {
+		maxNodes = defaultImportYAMLMaxNodes
+	}
+	maxDepth := viper.GetInt(viperImportYAMLMaxDepth)
+	if maxDepth <= 0 {
+		maxDepth = defaultImportYAMLMaxDepth
+	}
+
+	if err := exportentities.CheckYAMLExpansion(data, maxNodes, maxDepth); err != nil {
+		return &sdk.Error{ID: sdk.ErrImportParse.ID, Status: sdk.ErrImportParse.Status, Root: err}
+	}
+	return yaml.Unmarshal(data, out)
+}
+
+//importProjectKey returns the project key of an import route. The routes scoped on an application use
+//the key variable, so that the permissions are checked on the application instead of the project.
+func importProjectKey(vars map[string]string) string {
+	if key := vars["permProjectKey"]; key != "" {
+		return key
+	}
+	return vars["key"]
+}
+
+//importVerbosity returns the lowest level of the import messages sent back, from the verbosity value:
+//errors, warnings or all. All the messages are sent by default.
+func importVerbosity(r *http.Request) (sdk.MessageLevel, error) {
+	l, err := sdk.VerbosityLevel(r.FormValue("verbosity"))
+	if err != nil {
+		return l, sdk.WrapError(sdk.ErrWrongRequest, "importVerbosity> %s", err)
+	}
+	return l, nil
+}
+
+//unmarshalImport unmarshals an imported entity according to its format
+func unmarshalImport(data []byte, f exportentities.Format, out interface{}) error {
+	switch f {
+	case exportentities.FormatJSON:
+		return json.Unmarshal(data, out)
+	case exportentities.FormatHCL:
+		return hcl.Unmarshal(data, out)
+	case exportentities.FormatYAML:
+		return unmarshalImportYAML(data, out)
+	case exportentities.FormatTOML:
+		return toml.Unmarshal(data, out)
+	default:
+		return exportentities.ErrUnsupportedFormat
+	}
+}
+
+//importParseError returns the error sent when an import can't be parsed, keeping the reason of a rejected YAML document
+func importParseError(err error) error {
+	if e, ok := err.(*sdk.Error); ok {
+		return e
+	}
+	if err == exportentities.ErrUnsupportedFormat {
+		return sdk.ErrImportUnknownFormat
+	}
+	return sdk.ErrImportParse
+}
+
+//importGroupError returns the error sent when a group of the imported permissions can't be loaded
+func importGroupError(err error) error {
+	if err == sdk.ErrGroupNotFound {
+		return sdk.ErrImportGroupNotFound
+	}
+	return err
+}
+
+//fetchImportURL fetches a file to import. Only HTTPS urls on public networks are allowed, unless
+//the host is in the import allowlist. The format is taken from the format value if provided,
+//else from the content type or the extension of the url.
+func fetchImportURL(rawurl, format string) ([]byte, exportentities.Format, error) {
+	u, errP := url.Parse(rawurl)
+	if errP != nil {
+		return nil, exportentities.UnknownFormat, errP
+	}
+	if err := checkImportURL(u); err != nil {
+		return nil, exportentities.UnknownFormat, err
+	}
+
+	timeout := time.Duration(viper.GetInt(viperImportURLTimeout)) * time.Second
+	if timeout <= 0 {
+		timeout = defaultImportURLTimeout
+	}
+	maxSize := viper.GetInt64(viperImportURLMaxSize)
+	if maxSize <= 0 {
+		maxSize = defaultImportURLMaxSize
+	}
+
+	client := newImportHTTPClient(timeout)
+	resp, errG := client.Get(u.String())
+	if errG != nil {
+		return nil, exportentities.UnknownFormat, errG
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, exportentities.UnknownFormat, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	body, errR := ioutil.ReadAll(http.MaxBytesReader(nil, resp.Body, maxSize))
+	if errR != nil {
+		return nil, exportentities.UnknownFormat, fmt.Errorf("unable to read body (max size is %d bytes): %s", maxSize, errR)
+	}
+
+	if format != "" {
+		f, err := exportentities.GetFormat(format)
+		return body, f, err
+	}
+	if f, err := formatFromMediaType(resp.Header.Get("Content-Type")); err == nil {
+		return body, f, nil
+	}
+	f, err := exportentities.GetFormatFromPath(u.Path)
+	return body, f, err
+}
+
+//importFormat returns the format of an imported body: the format value if provided, else the format of its
+//content type, such as application/json, application/x-yaml or application/hcl
+func importFormat(format, contentType string) (exportentities.Format, error) {
+	if format != "" {
+		return exportentities.GetFormat(format)
+	}
+	f, err := formatFromMediaType(contentType)
+	if err != nil {
+		return exportentities.UnknownFormat, fmt.Errorf("no format provided and unsupported content type %q", contentType)
+	}
+	return f, nil
+}
+
+//formatFromMediaType returns the format of a media type such as application/x-yaml or text/yaml
+func formatFromMediaType(v string) (exportentities.Format, error) {
+	ct, _, err := mime.ParseMediaType(v)
+	if err != nil {
+		return exportentities.UnknownFormat, err
+	}
+	subtype := ct[strings.LastIndex(ct, "/")+1:]
+	subtype = strings.TrimPrefix(subtype, "x-")
+	return exportentities.GetFormat(subtype)
+}
+
+//newImportHTTPClient returns an http client which only reaches HTTPS urls on public networks,
+//unless the host is in the import allowlist. No proxy is used: the dialed address has to be the one of
+//the imported url for its check to be relevant.
+func newImportHTTPClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout}
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			Proxy: nil,
+			// Check the resolved address to avoid any DNS rebinding on a private network
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				host, port, err := net.SplitHostPort(addr)
+				if err != nil {
+					return nil, err
+				}
+				if isImportURLAllowed(host) {
+					return dialer.DialContext(ctx, network, addr)
+				}
+				ip, err := resolvePublicIP(ctx, host)
+				if err != nil {
+					return nil, err
+				}
+				return dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+			},
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return fmt.Errorf("stopped after 10 redirects")
+			}
+			return checkImportURL(req.URL)
+		},
+	}
+}
+
+//checkImportURL checks the scheme and the host of an url to import
+func checkImportURL(u *url.URL) error {
+	if isImportURLAllowed(u.Hostname()) {
+		return nil
+	}
+	if u.Scheme != "https" {
+		return fmt.Errorf("only https urls are allowed")
+	}
+	if ip := net.ParseIP(u.Hostname()); ip != nil && isPrivateIP(ip) {
+		return fmt.Errorf("%s is on a private network", u.Hostname())
+	}
+	return nil
+}
+
+//isImportURLAllowed returns true if the host is in the import allowlist
+func isImportURLAllowed(host string) bool {
+	for _, h := range viper.GetStringSlice(viperImportURLAllowlist) {
+		if strings.EqualFold(h, host) {
+			return true
+		}
+	}
+	return false
+}
+
+//resolvePublicIP resolves the host and fails if one of its addresses is on a private network
+func resolvePublicIP(ctx context.Context, host string) (net.IP, error) {
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("unable to resolve %s", host)
+	}
+	for _, a := range addrs {
+		if isPrivateIP(a.IP) {
+			return nil, fmt.Errorf("%s is on a private network", host)
+		}
+	}
+	return addrs[0].IP, nil
+}
+
+//isPrivateIP checks if the address is unspecified, a loopback, private or link local address. The IPv4
+//address embedded in a NAT64 or IPv4-compatible address is checked too.
+func isPrivateIP(ip net.IP) bool {
+	if ip.IsUnspecified() || ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() {
+		return true
+	}
+	for _, n := range privateNetworks {
+		_, cidr, _ := net.ParseCIDR(n)
+		if cidr.Contains(ip) {
+			return true
+		}
+	}
+	if ip.To4() == nil {
+		for _, n := range embeddedIPv4Networks {
+			_, cidr, _ := net.ParseCIDR(n)
+			if cidr.Contains(ip) {
+				return isPrivateIP(net.IPv4(ip[12], ip[13], ip[14], ip[15]))
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ovh/cds/sdk"
+)
+
+//ImportHook runs custom logic during the import of an application, such as policy checks or naming conventions
+type ImportHook interface {
+	//BeforeImport is called before the application is written. An error aborts the import, its message is
+	//returned to the client.
+	BeforeImport(proj *sdk.Project, app *sdk.Application) error
+	//AfterImport is called once the import is committed, with the messages of the import
+	AfterImport(proj *sdk.Project, app *sdk.Application, msgs []sdk.Message)
+}
+
+//importHooks are the hooks of the application imports, called in their registration order
+var importHooks []ImportHook
+
+//RegisterImportHook registers hooks called by each application import
+func RegisterImportHook(h ...ImportHook) {
+	importHooks = append(importHooks, h...)
+}
+
+//beforeApplicationImport calls the hooks before the import of an application. All the hooks are called to
+//report every rejection at once, a message is sent for each of them.
+func beforeApplicationImport(hooks []ImportHook, proj *sdk.Project, app *sdk.Application, msgChan chan<- sdk.Message) error {
+	var err error
+	for _, h := range hooks {
+		if errH := h.BeforeImport(proj, app); errH != nil {
+			if msgChan != nil {
+				msgChan <- sdk.NewMessage(sdk.MsgAppImportHookRejected, app.Name, errH.Error())
+			}
+			err = sdk.ErrWrongRequest
+		}
+	}
+	return err
+}
+
+//afterApplicationImport calls the hooks once an application is imported
+func afterApplicationImport(hooks []ImportHook, proj *sdk.Project, app *sdk.Application, msgs []sdk.Message) {
+	for _, h := range hooks {
+		h.AfterImport(proj, app, msgs)
+	}
+}
+
+//namePrefixImportPolicy is a sample import hook which only accepts the applications named with one of its prefixes
+type namePrefixImportPolicy struct {
+	prefixes []string
+}
+
+func (p namePrefixImportPolicy) BeforeImport(proj *sdk.Project, app *sdk.Application) error {
+	for _, prefix := range p.prefixes {
+		if strings.HasPrefix(app.Name, prefix) {
+			return nil
+		}
+	}
+	return fmt.Errorf("the name must start with one of %s", strings.Join(p.prefixes, ", "))
+}
+
+func (p namePrefixImportPolicy) AfterImport(proj *sdk.Project, app *sdk.Application, msgs []sdk.Message) {
+}
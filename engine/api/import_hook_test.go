@@ -0,0 +1,45 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ovh/cds/sdk"
+)
+
+type testImportHook struct {
+	after []sdk.Message
+}
+
+func (h *testImportHook) BeforeImport(proj *sdk.Project, app *sdk.Application) error {
+	return nil
+}
+
+func (h *testImportHook) AfterImport(proj *sdk.Project, app *sdk.Application, msgs []sdk.Message) {
+	h.after = msgs
+}
+
+func Test_beforeApplicationImport(t *testing.T) {
+	proj := &sdk.Project{Key: "KEY"}
+	recorder := &testImportHook{}
+	hooks := []ImportHook{recorder, namePrefixImportPolicy{prefixes: []string{"team-", "infra-"}}}
+
+	msgChan, collect := newMessageCollector()
+	assert.NoError(t, beforeApplicationImport(hooks, proj, &sdk.Application{Name: "team-app1"}, msgChan))
+	assert.Empty(t, collect())
+
+	//A rejection aborts the import with the message of the hook
+	msgChan, collect = newMessageCollector()
+	assert.Equal(t, sdk.ErrWrongRequest, beforeApplicationImport(hooks, proj, &sdk.Application{Name: "app1"}, msgChan))
+	msgs := collect()
+	if assert.Len(t, msgs, 1) {
+		assert.Equal(t, sdk.MsgAppImportHookRejected.ID, msgs[0].ID)
+		assert.Contains(t, msgs[0].String("en-US"), "must start with one of team-, infra-")
+	}
+
+	assert.NoError(t, beforeApplicationImport(nil, proj, &sdk.Application{Name: "app1"}, nil))
+
+	afterApplicationImport(hooks, proj, &sdk.Application{Name: "team-app1"}, msgs)
+	assert.Equal(t, msgs, recorder.after)
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"time"
+
+	"github.com/ovh/cds/sdk"
+	"github.com/ovh/cds/sdk/log"
+)
+
+//importLockPollInterval is the delay between two attempts to take the lock of an import
+const importLockPollInterval = 100 * time.Millisecond
+
+//lockApplicationImport takes the advisory lock of the imports of an application. The lock is taken on a
+//connection of its own, so that it is held until unlock is called whatever the transactions of the import.
+//If another import holds the lock, it waits up to wait, or fails at once if wait is zero.
+func lockApplicationImport(ctx context.Context, db *sql.DB, projectKey, appName string, wait time.Duration) (unlock func(), err error) {
+	conn, errC := db.Conn(ctx)
+	if errC != nil {
+		return nil, sdk.WrapError(errC, "lockApplicationImport> Unable to get a connection")
+	}
+
+	key := "import:" + projectKey + "/" + appName
+	deadline := time.Now().Add(wait)
+	for {
+		var locked bool
+		if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock(hashtext($1))", key).Scan(&locked); err != nil {
+			conn.Close()
+			return nil, sdk.WrapError(err, "lockApplicationImport> Unable to lock the import of application %s", appName)
+		}
+		if locked {
+			break
+		}
+		if !time.Now().Before(deadline) {
+			conn.Close()
+			return nil, sdk.WrapError(sdk.ErrImportInProgress, "lockApplicationImport> Application %s of project %s is being imported", appName, projectKey)
+		}
+		select {
+		case <-ctx.Done():
+			conn.Close()
+			return nil, sdk.WrapError(ctx.Err(), "lockApplicationImport> Import of application %s canceled", appName)
+		case <-time.After(importLockPollInterval):
+		}
+	}
+
+	return func() {
+		// The request may be canceled, the lock is released anyway
+		if _, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock(hashtext($1))", key); err != nil {
+			log.Warning("lockApplicationImport> Unable to unlock the import of application %s: %s", appName, err)
+			// The connection still holds the lock, it is closed instead of being returned to the pool
+			discardConn(conn)
+			return
+		}
+		conn.Close()
+	}, nil
+}
+
+//discardConn closes the driver connection of conn, which is not returned to the pool
+func discardConn(conn *sql.Conn) {
+	if err := conn.Raw(func(interface{}) error { return driver.ErrBadConn }); err != nil && err != driver.ErrBadConn {
+		log.Warning("discardConn> Unable to discard connection: %s", err)
+	}
+	conn.Close()
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ovh/cds/engine/api/test"
+	"github.com/ovh/cds/sdk"
+)
+
+func Test_lockApplicationImport(t *testing.T) {
+	db := test.SetupPG(t)
+	key := sdk.RandomString(10)
+	ctx := context.Background()
+
+	unlock, err := lockApplicationImport(ctx, db.Db, key, "app1", 0)
+	test.NoError(t, err)
+
+	//1. A concurrent import fails at once
+	_, err = lockApplicationImport(ctx, db.Db, key, "app1", 0)
+	assert.True(t, sdk.ErrorIs(err, sdk.ErrImportInProgress), "%v", err)
+
+	//2. The imports of the other applications are not locked
+	unlockOther, err := lockApplicationImport(ctx, db.Db, key, "app2", 0)
+	test.NoError(t, err)
+	unlockOther()
+
+	//3. A concurrent import waits for the lock
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		unlock()
+	}()
+	unlock, err = lockApplicationImport(ctx, db.Db, key, "app1", 5*time.Second)
+	test.NoError(t, err)
+	unlock()
+}
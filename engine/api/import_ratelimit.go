@@ -0,0 +1,91 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/ovh/cds/engine/api/cache"
+	"github.com/ovh/cds/sdk"
+)
+
+//importBucket is the token bucket of a project key. It is stored in the shared cache if the rate limit is shared.
+type importBucket struct {
+	Tokens float64   `json:"tokens"`
+	Last   time.Time `json:"last"`
+}
+
+//take refills the bucket since its last use, then takes a token. If there is no token, it returns the delay
+//before the next one.
+func (b *importBucket) take(now time.Time, rate float64, burst int) (bool, time.Duration) {
+	if b.Last.IsZero() {
+		b.Tokens = float64(burst)
+	} else if elapsed := now.Sub(b.Last).Seconds(); elapsed > 0 {
+		b.Tokens = math.Min(float64(burst), b.Tokens+elapsed*rate)
+	}
+	b.Last = now
+
+	if b.Tokens >= 1 {
+		b.Tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - b.Tokens) / rate * float64(time.Second))
+}
+
+//importRateLimiter limits the number of imports per project key
+type importRateLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]*importBucket
+}
+
+var importLimiter = &importRateLimiter{buckets: map[string]*importBucket{}}
+
+//allow takes a token in the bucket of the project key. The bucket is kept in memory, or in the shared
+//cache so that the limit is shared by all the instances of the API. The buckets in the shared cache are
+//not locked between instances, a few concurrent imports may go over the limit.
+func (l *importRateLimiter) allow(key string, rate float64, burst int, shared bool) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if !shared {
+		b, ok := l.buckets[key]
+		if !ok {
+			b = &importBucket{}
+			l.buckets[key] = b
+		}
+		return b.take(now, rate, burst)
+	}
+
+	k := cache.Key("import", "ratelimit", key)
+	b := &importBucket{}
+	cache.Get(k, b)
+	ok, retry := b.take(now, rate, burst)
+	// A bucket unused for the time to refill it is full again
+	cache.SetWithTTL(k, b, int(math.Ceil(float64(burst)/rate))+1)
+	return ok, retry
+}
+
+//checkImportRateLimit checks the rate limit of the imports of a project. When it is exceeded, the Retry-After
+//header is set and sdk.ErrTooManyRequests is returned. A rate lower or equal to 0 disables the limit.
+func checkImportRateLimit(w http.ResponseWriter, key string) error {
+	rate := viper.GetFloat64(viperImportRateLimitRate)
+	if rate <= 0 {
+		return nil
+	}
+	burst := viper.GetInt(viperImportRateLimitBurst)
+	if burst <= 0 {
+		burst = defaultImportRateLimitBurst
+	}
+
+	ok, retry := importLimiter.allow(key, rate, burst, viper.GetBool(viperImportRateLimitShared))
+	if ok {
+		return nil
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retry.Seconds()))))
+	return sdk.WrapError(sdk.ErrTooManyRequests, "checkImportRateLimit> Too many imports on project %s", key)
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ovh/cds/sdk"
+)
+
+func Test_importBucketTake(t *testing.T) {
+	now := time.Now()
+	b := &importBucket{}
+
+	//A new bucket is full
+	for i := 0; i < 3; i++ {
+		ok, _ := b.take(now, 1, 3)
+		assert.True(t, ok)
+	}
+	ok, retry := b.take(now, 1, 3)
+	assert.False(t, ok)
+	assert.Equal(t, time.Second, retry)
+
+	//One token is added each second, up to the burst
+	ok, _ = b.take(now.Add(time.Second), 1, 3)
+	assert.True(t, ok)
+	ok, _ = b.take(now.Add(time.Second), 1, 3)
+	assert.False(t, ok)
+	b.take(now.Add(time.Hour), 1, 3)
+	assert.Equal(t, 2.0, b.Tokens)
+}
+
+func Test_checkImportRateLimit(t *testing.T) {
+	viper.Set(viperImportRateLimitRate, 0.5)
+	viper.Set(viperImportRateLimitBurst, 1)
+	defer viper.Set(viperImportRateLimitRate, nil)
+	defer viper.Set(viperImportRateLimitBurst, nil)
+
+	key := sdk.RandomString(10)
+	assert.NoError(t, checkImportRateLimit(httptest.NewRecorder(), key))
+
+	w := httptest.NewRecorder()
+	err := checkImportRateLimit(w, key)
+	if assert.Error(t, err) {
+		_, code := sdk.ProcessError(err, "")
+		assert.Equal(t, 429, code)
+	}
+	assert.Equal(t, "2", w.Header().Get("Retry-After"))
+
+	//The limit is per project
+	assert.NoError(t, checkImportRateLimit(httptest.NewRecorder(), sdk.RandomString(10)))
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"github.com/ovh/cds/sdk"
+)
+
+//SecretResolver resolves the references to external secrets used as values of imported variables,
+//such as vault://path#field, so that the payload doesn't embed the secret values.
+type SecretResolver interface {
+	//Resolve returns the value referenced by ref. It returns false if ref is not a reference it resolves.
+	Resolve(ref string) (string, bool, error)
+}
+
+//noopSecretResolver doesn't resolve any reference, the values are imported as they are
+type noopSecretResolver struct{}
+
+func (noopSecretResolver) Resolve(ref string) (string, bool, error) {
+	return ref, false, nil
+}
+
+//importSecretResolver is the resolver of the imported variables
+var importSecretResolver SecretResolver = noopSecretResolver{}
+
+//resolveApplicationImportSecrets replaces the values of the variables of an imported application which are
+//references to external secrets by the resolved values. The resolved values must never be logged.
+func resolveApplicationImportSecrets(resolver SecretResolver, app *sdk.Application, msgChan chan<- sdk.Message) error {
+	for i := range app.Variable {
+		v := &app.Variable[i]
+		value, ok, err := resolver.Resolve(v.Value)
+		if err != nil {
+			return sdk.WrapError(sdk.ErrWrongRequest, "resolveApplicationImportSecrets> Unable to resolve variable %s of application %s: %s", v.Name, app.Name, err)
+		}
+		if !ok {
+			continue
+		}
+		v.Value = value
+		if msgChan != nil {
+			msgChan <- sdk.NewMessage(sdk.MsgAppImportVariableResolved, v.Name, app.Name)
+		}
+	}
+	return nil
+}
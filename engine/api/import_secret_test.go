@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ovh/cds/sdk"
+)
+
+type testSecretResolver map[string]string
+
+func (r testSecretResolver) Resolve(ref string) (string, bool, error) {
+	if !strings.HasPrefix(ref, "vault://") {
+		return ref, false, nil
+	}
+	v, ok := r[ref]
+	if !ok {
+		return "", false, fmt.Errorf("secret %s not found", ref)
+	}
+	return v, true, nil
+}
+
+func Test_resolveApplicationImportSecrets(t *testing.T) {
+	resolver := testSecretResolver{"vault://app1#password": "s3cr3t"}
+	app := &sdk.Application{
+		Name: "app1",
+		Variable: []sdk.Variable{
+			{Name: "password", Type: sdk.SecretVariable, Value: "vault://app1#password"},
+			{Name: "tier", Type: sdk.StringVariable, Value: "1"},
+		},
+	}
+	msgChan, collect := newMessageCollector()
+	assert.NoError(t, resolveApplicationImportSecrets(resolver, app, msgChan))
+	msgs := collect()
+	assert.Equal(t, "s3cr3t", app.Variable[0].Value)
+	assert.Equal(t, "1", app.Variable[1].Value)
+	if assert.Len(t, msgs, 1) {
+		assert.Equal(t, sdk.MsgAppImportVariableResolved.ID, msgs[0].ID)
+	}
+
+	//The default resolver keeps the values
+	app.Variable[0].Value = "vault://app1#password"
+	assert.NoError(t, resolveApplicationImportSecrets(noopSecretResolver{}, app, nil))
+	assert.Equal(t, "vault://app1#password", app.Variable[0].Value)
+
+	app.Variable[0].Value = "vault://app1#token"
+	assert.Error(t, resolveApplicationImportSecrets(resolver, app, nil))
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/go-gorp/gorp"
+	"github.com/pkg/errors"
+
+	"github.com/ovh/cds/sdk"
+	"github.com/ovh/cds/sdk/log"
+)
+
+//importStreamResult is the last event sent on an import stream
+type importStreamResult struct {
+	Status    int               `json:"status"`
+	Committed bool              `json:"committed"`
+	Summary   sdk.ImportSummary `json:"summary"`
+	Error     string            `json:"error,omitempty"`
+	Export    string            `json:"export,omitempty"`
+}
+
+//importStream sends the messages of an import as server-sent events
+type importStream struct {
+	w         http.ResponseWriter
+	f         http.Flusher
+	al        string
+	verbosity sdk.MessageLevel
+}
+
+//isImportStream returns true if the import messages must be streamed as server-sent events
+func isImportStream(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "text/event-stream")
+}
+
+//newImportStream writes the headers of the event stream
+func newImportStream(w http.ResponseWriter, r *http.Request) (*importStream, error) {
+	f, ok := w.(http.Flusher)
+	if !ok {
+		return nil, sdk.WrapError(sdk.ErrWrongRequest, "newImportStream> Streaming unsupported")
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	f.Flush()
+
+	// The verbosity is checked before the import
+	verbosity, _ := importVerbosity(r)
+	return &importStream{w: w, f: f, al: r.Header.Get("Accept-Language"), verbosity: verbosity}, nil
+}
+
+func (s *importStream) send(event string, data interface{}) {
+	b, err := json.Marshal(data)
+	if err != nil {
+		log.Warning("importStream.send> Unable to marshal %s event: %s", event, err)
+		return
+	}
+	fmt.Fprintf(s.w, "event: %s\ndata: %s\n\n", event, b)
+	s.f.Flush()
+}
+
+//message sends an import message
+func (s *importStream) message(m sdk.Message) {
+	if sm := m.Structured(s.al); sm.Message != "" {
+		s.sendMessage(sm)
+	}
+}
+
+//sendMessage sends a structured message if it is kept by the verbosity
+func (s *importStream) sendMessage(sm sdk.StructuredMessage) {
+	if sm.Level.AtLeast(s.verbosity) {
+		s.send("message", sm)
+	}
+}
+
+//result sends the last event of the import, with the export of the imported application if it has been asked
+func (s *importStream) result(status int, committed bool, summary sdk.ImportSummary, export string) error {
+	s.send("result", importStreamResult{Status: status, Committed: committed, Summary: summary, Export: export})
+	return nil
+}
+
+//fail sends the last event of a failed import
+func (s *importStream) fail(err error) error {
+	msg, status := sdk.ProcessError(err, s.al)
+	if errors.Cause(err) == context.Canceled {
+		log.Info("importStream.fail> Import canceled by the client")
+	} else if status == sdk.ErrUnknownError.Status {
+		log.Warning("importStream.fail> %s", err)
+	}
+	s.send("result", importStreamResult{Status: status, Error: msg})
+	return nil
+}
+
+//contextExecutor fails the requests on the database once the context is done, so that an import
+//stops as soon as its client disconnects. QueryRow can't return an error and is not checked.
+type contextExecutor struct {
+	gorp.SqlExecutor
+	ctx context.Context
+}
+
+func newContextExecutor(ctx context.Context, db gorp.SqlExecutor) gorp.SqlExecutor {
+	return &contextExecutor{SqlExecutor: db, ctx: ctx}
+}
+
+func (e *contextExecutor) Get(i interface{}, keys ...interface{}) (interface{}, error) {
+	if err := e.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return e.SqlExecutor.Get(i, keys...)
+}
+
+func (e *contextExecutor) Insert(list ...interface{}) error {
+	if err := e.ctx.Err(); err != nil {
+		return err
+	}
+	return e.SqlExecutor.Insert(list...)
+}
+
+func (e *contextExecutor) Update(list ...interface{}) (int64, error) {
+	if err := e.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return e.SqlExecutor.Update(list...)
+}
+
+func (e *contextExecutor) Delete(list ...interface{}) (int64, error) {
+	if err := e.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return e.SqlExecutor.Delete(list...)
+}
+
+func (e *contextExecutor) Exec(query string, args ...interface{}) (sql.Result, error) {
+	if err := e.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return e.SqlExecutor.Exec(query, args...)
+}
+
+func (e *contextExecutor) Select(i interface{}, query string, args ...interface{}) ([]interface{}, error) {
+	if err := e.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return e.SqlExecutor.Select(i, query, args...)
+}
+
+func (e *contextExecutor) SelectInt(query string, args ...interface{}) (int64, error) {
+	if err := e.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return e.SqlExecutor.SelectInt(query, args...)
+}
+
+func (e *contextExecutor) SelectNullInt(query string, args ...interface{}) (sql.NullInt64, error) {
+	if err := e.ctx.Err(); err != nil {
+		return sql.NullInt64{}, err
+	}
+	return e.SqlExecutor.SelectNullInt(query, args...)
+}
+
+func (e *contextExecutor) SelectFloat(query string, args ...interface{}) (float64, error) {
+	if err := e.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return e.SqlExecutor.SelectFloat(query, args...)
+}
+
+func (e *contextExecutor) SelectNullFloat(query string, args ...interface{}) (sql.NullFloat64, error) {
+	if err := e.ctx.Err(); err != nil {
+		return sql.NullFloat64{}, err
+	}
+	return e.SqlExecutor.SelectNullFloat(query, args...)
+}
+
+func (e *contextExecutor) SelectStr(query string, args ...interface{}) (string, error) {
+	if err := e.ctx.Err(); err != nil {
+		return "", err
+	}
+	return e.SqlExecutor.SelectStr(query, args...)
+}
+
+func (e *contextExecutor) SelectNullStr(query string, args ...interface{}) (sql.NullString, error) {
+	if err := e.ctx.Err(); err != nil {
+		return sql.NullString{}, err
+	}
+	return e.SqlExecutor.SelectNullStr(query, args...)
+}
+
+func (e *contextExecutor) SelectOne(holder interface{}, query string, args ...interface{}) error {
+	if err := e.ctx.Err(); err != nil {
+		return err
+	}
+	return e.SqlExecutor.SelectOne(holder, query, args...)
+}
+
+func (e *contextExecutor) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	if err := e.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return e.SqlExecutor.Query(query, args...)
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ovh/cds/sdk"
+)
+
+func Test_importStream(t *testing.T) {
+	req, err := http.NewRequest("POST", "/project/KEY/import/application", nil)
+	assert.NoError(t, err)
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("Accept-Language", "en-US")
+	assert.True(t, isImportStream(req))
+
+	w := httptest.NewRecorder()
+	stream, err := newImportStream(w, req)
+	assert.NoError(t, err)
+
+	stream.message(sdk.NewMessage(sdk.MsgAppCreated, "app1"))
+	assert.NoError(t, stream.result(http.StatusOK, true, sdk.ImportSummary{Warnings: 1}, ""))
+
+	assert.Equal(t, "text/event-stream", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Body.String(), "event: message\ndata: {\"id\":\"MsgAppCreated\",\"level\":\"info\",\"args\":[\"app1\"],\"message\":\"Application app1 successfully created\"}\n\n")
+	assert.Contains(t, w.Body.String(), "event: result\ndata: {\"status\":200,\"committed\":true,")
+	assert.Contains(t, w.Body.String(), "\"warnings\":1}}\n\n")
+}
+
+func Test_contextExecutor(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	db := newContextExecutor(ctx, nil)
+	_, err := db.Exec("DELETE FROM application")
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, context.Canceled, db.Insert(&sdk.Application{}))
+}
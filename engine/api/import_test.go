@@ -0,0 +1,218 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ovh/cds/sdk"
+	"github.com/ovh/cds/sdk/exportentities"
+)
+
+func Test_newMessageCollector(t *testing.T) {
+	msgChan, collect := newMessageCollector()
+	msgChan <- sdk.NewMessage(sdk.MsgAppCreated, "foo")
+	msgChan <- sdk.NewMessage(sdk.MsgPipelineAttached, "build", "foo")
+
+	allMsg := collect()
+	assert.Equal(t, []sdk.Message{
+		sdk.NewMessage(sdk.MsgAppCreated, "foo"),
+		sdk.NewMessage(sdk.MsgPipelineAttached, "build", "foo"),
+	}, allMsg)
+
+	// Calling it again must not panic
+	assert.Equal(t, allMsg, collect())
+}
+
+func Test_newMessageCollectorEarlyReturn(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	importWithEarlyError := func() error {
+		msgChan, collect := newMessageCollector()
+		defer collect()
+
+		msgChan <- sdk.NewMessage(sdk.MsgAppCreated, "foo")
+		return fmt.Errorf("unable to load group")
+	}
+
+	for i := 0; i < 10; i++ {
+		assert.Error(t, importWithEarlyError())
+	}
+
+	after := runtime.NumGoroutine()
+	for i := 0; i < 10 && after > before; i++ {
+		time.Sleep(10 * time.Millisecond)
+		after = runtime.NumGoroutine()
+	}
+	assert.True(t, after <= before, "%d goroutines leaked", after-before)
+}
+
+func Test_checkImportURL(t *testing.T) {
+	tests := []struct {
+		url     string
+		wantErr bool
+	}{
+		{"https://raw.githubusercontent.com/ovh/cds/master/app.yml", false},
+		{"http://raw.githubusercontent.com/ovh/cds/master/app.yml", true},
+		{"https://127.0.0.1/app.yml", true},
+		{"https://10.1.2.3/app.yml", true},
+		{"https://169.254.169.254/latest/meta-data", true},
+		{"https://[::1]/app.yml", true},
+		{"https://8.8.8.8/app.yml", false},
+		{"https://[::]/app.yml", true},
+		{"https://[64:ff9b::7f00:1]/app.yml", true},
+		{"https://[::127.0.0.1]/app.yml", true},
+		{"https://[::ffff:127.0.0.1]/app.yml", true},
+		{"https://[fd00::1]/app.yml", true},
+		{"https://[64:ff9b::808:808]/app.yml", false},
+		{"https://[2001:4860:4860::8888]/app.yml", false},
+	}
+	for _, tt := range tests {
+		u, err := url.Parse(tt.url)
+		assert.NoError(t, err)
+		err = checkImportURL(u)
+		assert.Equal(t, tt.wantErr, err != nil, "checkImportURL(%s) = %v", tt.url, err)
+	}
+}
+
+func Test_newImportHTTPClient(t *testing.T) {
+	//A proxy would dial its own address instead of the checked one
+	tr, ok := newImportHTTPClient(time.Second).Transport.(*http.Transport)
+	if assert.True(t, ok) {
+		assert.Nil(t, tr.Proxy)
+	}
+}
+
+func Test_sendImportCallback(t *testing.T) {
+	var calls int32
+	var received importCallback
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+	}))
+	defer s.Close()
+
+	u, err := url.Parse(s.URL)
+	assert.NoError(t, err)
+	viper.Set(viperImportURLAllowlist, []string{u.Hostname()})
+	defer viper.Set(viperImportURLAllowlist, nil)
+	importCallbackBackoff = time.Millisecond
+
+	select {
+	case <-sendImportCallback(u, importCallback{
+		ProjectKey:  "KEY",
+		Application: "app1",
+		Status:      importCallbackSuccess,
+		Result:      &sdk.ImportResult{Summary: sdk.ImportSummary{Warnings: 1}},
+	}):
+	case <-time.After(5 * time.Second):
+		t.Fatal("callback not sent")
+	}
+
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+	assert.Equal(t, "app1", received.Application)
+	assert.Equal(t, importCallbackSuccess, received.Status)
+	assert.Equal(t, 1, received.Result.Summary.Warnings)
+}
+
+func Test_readImportBody(t *testing.T) {
+	gzipped := func(s string) *bytes.Buffer {
+		b := new(bytes.Buffer)
+		gz := gzip.NewWriter(b)
+		_, err := gz.Write([]byte(s))
+		assert.NoError(t, err)
+		assert.NoError(t, gz.Close())
+		return b
+	}
+
+	data, err := readImportBody(strings.NewReader("name: app1\n"), "")
+	assert.NoError(t, err)
+	assert.Equal(t, "name: app1\n", string(data))
+
+	data, err = readImportBody(gzipped("name: app1\n"), "gzip")
+	assert.NoError(t, err)
+	assert.Equal(t, "name: app1\n", string(data))
+
+	_, err = readImportBody(strings.NewReader("name: app1\n"), "gzip")
+	assert.Error(t, err)
+
+	_, err = readImportBody(strings.NewReader("name: app1\n"), "br")
+	assert.Error(t, err)
+	assert.Equal(t, sdk.ErrWrongRequest, importBodyError(err))
+
+	//The decompressed size is limited
+	viper.Set(viperImportGzipMaxSize, 1024)
+	defer viper.Set(viperImportGzipMaxSize, nil)
+	_, err = readImportBody(gzipped(strings.Repeat("a", 1025)), "gzip")
+	assert.Error(t, err)
+	assert.Equal(t, sdk.ErrImportPayloadTooLarge, importBodyError(err))
+
+	//The body size is limited, even if it is gzip encoded
+	viper.Set(viperImportBodyMaxSize, 16)
+	defer viper.Set(viperImportBodyMaxSize, nil)
+	data, err = readImportBody(strings.NewReader(strings.Repeat("a", 16)), "")
+	assert.NoError(t, err)
+	assert.Len(t, data, 16)
+	_, err = readImportBody(strings.NewReader(strings.Repeat("a", 17)), "")
+	assert.Equal(t, sdk.ErrImportPayloadTooLarge, importBodyError(err))
+	_, err = readImportBody(bytes.NewReader(make([]byte, 17)), "gzip")
+	assert.Error(t, err)
+}
+
+func Test_importParseError(t *testing.T) {
+	assert.Equal(t, sdk.ErrImportParse, importParseError(fmt.Errorf("unexpected token")))
+	assert.Equal(t, sdk.ErrImportUnknownFormat, importParseError(exportentities.ErrUnsupportedFormat))
+	assert.Equal(t, sdk.ErrImportGroupNotFound, importGroupError(sdk.ErrGroupNotFound))
+
+	//A rejected YAML document keeps its reason
+	viper.Set(viperImportYAMLMaxNodes, 4)
+	defer viper.Set(viperImportYAMLMaxNodes, nil)
+	err := importParseError(unmarshalImportYAML([]byte("a: [1, 2, 3, 4, 5]\n"), &map[string]interface{}{}))
+	e, ok := err.(*sdk.Error)
+	if assert.True(t, ok) {
+		assert.Equal(t, sdk.ErrImportParse.ID, e.ID)
+		assert.Error(t, e.Root)
+	}
+}
+
+func Test_importFormat(t *testing.T) {
+	tests := []struct {
+		format, contentType string
+		want                exportentities.Format
+	}{
+		{"yaml", "application/json", exportentities.FormatYAML},
+		{"", "application/json", exportentities.FormatJSON},
+		{"", "application/json; charset=utf-8", exportentities.FormatJSON},
+		{"", "application/x-yaml", exportentities.FormatYAML},
+		{"", "text/yaml", exportentities.FormatYAML},
+		{"", "application/hcl", exportentities.FormatHCL},
+		{"", "application/toml", exportentities.FormatTOML},
+	}
+	for _, tt := range tests {
+		f, err := importFormat(tt.format, tt.contentType)
+		assert.NoError(t, err, "format %q, content type %q", tt.format, tt.contentType)
+		assert.Equal(t, tt.want, f, "format %q, content type %q", tt.format, tt.contentType)
+	}
+
+	_, err := importFormat("", "")
+	assert.Error(t, err)
+	_, err = importFormat("", "application/octet-stream")
+	assert.Error(t, err)
+	_, err = importFormat("xml", "application/json")
+	assert.Error(t, err)
+}
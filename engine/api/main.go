@@ -30,6 +30,7 @@ import (
 	"github.com/ovh/cds/engine/api/poller"
 	"github.com/ovh/cds/engine/api/queue"
 	"github.com/ovh/cds/engine/api/repositoriesmanager"
+	"github.com/ovh/cds/engine/api/sanity"
 	"github.com/ovh/cds/engine/api/scheduler"
 	"github.com/ovh/cds/engine/api/secret"
 	"github.com/ovh/cds/engine/api/sessionstore"
@@ -188,7 +189,12 @@ var mainCmd = &cobra.Command{
 		}
 
 		//Initiliaze hook package
-		hook.Init(viper.GetString(viperURLAPI))
+		hook.Init(viper.GetString(viperURLAPI), viper.GetInt(viperImportHooksConcurrency))
+
+		//Initialize the import policies
+		if prefixes := viper.GetStringSlice(viperImportPolicyNamePrefixes); len(prefixes) > 0 {
+			RegisterImportHook(namePrefixImportPolicy{prefixes: prefixes})
+		}
 
 		//Intialize notification package
 		notification.Init(viper.GetString(viperURLAPI), baseURL)
@@ -246,12 +252,14 @@ var mainCmd = &cobra.Command{
 		go pipeline.AWOLPipelineKiller(ctx, database.GetDBMap)
 		go hatchery.Heartbeat(ctx, database.GetDBMap)
 		go auditCleanerRoutine(ctx, database.GetDBMap)
+		go importAuditCleanerRoutine(ctx, database.GetDBMap)
 
 		go repositoriesmanager.ReceiveEvents(ctx, database.GetDBMap)
 
 		go stats.StartRoutine(ctx, database.GetDBMap)
 		go action.RequirementsCacheLoader(ctx, 5*time.Second, database.GetDBMap)
 		go hookRecoverer(ctx, database.GetDBMap)
+		go hook.OutboxWorker(ctx, database.GetDBMap, sanity.CheckRepositoryHooks)
 
 		go user.PersistentSessionTokenCleaner(ctx, database.GetDBMap)
 
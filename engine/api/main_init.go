@@ -78,6 +78,20 @@ const (
 	viperVCSRepoBitbucketStatusDisabled = "vcs.repositories.bitbucket.statuses_disabled"
 	viperVCSRepoBitbucketConsumerKey    = "vcs.repositories.bitbucket.consumerkey"
 	viperVCSRepoBitbucketPrivateKey     = "vcs.repositories.bitbucket.privatekey"
+	viperImportURLAllowlist             = "import.url.allowlist"
+	viperImportURLTimeout               = "import.url.timeout"
+	viperImportURLMaxSize               = "import.url.maxsize"
+	viperImportHooksConcurrency         = "import.hooks.concurrency"
+	viperImportGzipMaxSize              = "import.gzip.maxsize"
+	viperImportBodyMaxSize              = "import.body.maxsize"
+	viperImportYAMLMaxNodes             = "import.yaml.maxnodes"
+	viperImportYAMLMaxDepth             = "import.yaml.maxdepth"
+	viperImportRateLimitRate            = "import.ratelimit.rate"
+	viperImportRateLimitBurst           = "import.ratelimit.burst"
+	viperImportRateLimitShared          = "import.ratelimit.shared"
+	viperImportAuditRetention           = "import.audit.retention"
+	viperImportLockWait                 = "import.lock.wait"
+	viperImportPolicyNamePrefixes       = "import.policy.nameprefixes"
 	vaultConfKey                        = "/secret/cds/conf"
 )
 
@@ -426,4 +440,34 @@ disabled = false #This is mainly for dev purpose, you should not have to change
     [vcs.repositories.bitbucket]
     statuses_disabled = false
     privatekey = ""
+
+#######################
+# CDS Import Settings #
+#######################
+[import]
+    [import.url]
+    allowlist = [] # Hosts allowed to be fetched over HTTP or on a private network
+    timeout = 10 # Timeout in seconds to fetch an imported file
+    maxsize = 1048576 # Max size in bytes of an imported file
+
+    [import.hooks]
+    concurrency = 4 # Max number of hooks created at the same time on a repositories manager
+
+    [import.body]
+    maxsize = 5242880 # Max size in bytes of the body of an import request
+
+    [import.gzip]
+    maxsize = 10485760 # Max size in bytes of a decompressed gzip encoded import
+
+    [import.yaml]
+    maxnodes = 100000 # Max number of nodes of a YAML import once its aliases are expanded
+    maxdepth = 100 # Max depth of a YAML import once its aliases are expanded
+
+    [import.ratelimit]
+    rate = 0 # Number of imports per second allowed on a project, 0 disables the limit
+    burst = 10 # Max number of imports allowed at once on a project
+    shared = false # Set to true to share the limit between the instances of the API through the cache
+
+    [import.audit]
+    retention = 30 # Number of days the payload of an import is kept to be replayed
 `
@@ -81,6 +81,7 @@ func (router *Router) init() {
 	router.Handle("/project/{key}/application/{permApplicationName}/branches", GET(getApplicationBranchHandler))
 	router.Handle("/project/{key}/application/{permApplicationName}/version", GET(getApplicationBranchVersionHandler))
 	router.Handle("/project/{key}/application/{permApplicationName}/clone", POST(cloneApplicationHandler))
+	router.Handle("/project/{key}/application/{permApplicationName}/enable", POST(enableApplicationHandler))
 	router.Handle("/project/{key}/application/{permApplicationName}/group", POST(addGroupInApplicationHandler), PUT(updateGroupsInApplicationHandler, DEPRECATED))
 	router.Handle("/project/{key}/application/{permApplicationName}/group/{group}", PUT(updateGroupRoleOnApplicationHandler), DELETE(deleteGroupFromApplicationHandler))
 	router.Handle("/project/{key}/application/{permApplicationName}/history/branch", GET(getPipelineBuildBranchHistoryHandler))
@@ -119,6 +120,23 @@ func (router *Router) init() {
 
 	router.Handle("/project/{permProjectKey}/pipeline", GET(getPipelinesHandler), POST(addPipeline))
 	router.Handle("/project/{permProjectKey}/import/pipeline", POST(importPipelineHandler))
+	router.Handle("/project/{permProjectKey}/import/application", POST(importApplicationHandler))
+	router.Handle("/project/{permProjectKey}/import/application/diff", POST(diffImportApplicationHandler))
+	router.Handle("/project/{permProjectKey}/import/application/validate", POST(validateApplicationHandler))
+	router.Handle("/project/{permProjectKey}/import/application/hooks", POST(previewImportApplicationHooksHandler))
+	router.Handle("/project/{permProjectKey}/import/application/repository", POST(importApplicationFromRepositoryHandler))
+	router.Handle("/project/{permProjectKey}/import/application/batch", POST(importApplicationsBatchHandler))
+	router.Handle("/project/{key}/import/application/{permApplicationName}", POST(importApplicationByNameHandler), PATCH(patchApplicationHandler))
+	router.Handle("/project/{permProjectKey}/import/audit", GET(getImportAuditsHandler))
+	router.Handle("/project/{permProjectKey}/import/audit/{id}/replay", POST(replayImportAuditHandler))
+	router.Handle("/project/{permProjectKey}/import/template", GET(getImportTemplatesHandler), POST(addImportTemplateHandler))
+	router.Handle("/project/{permProjectKey}/import/template/{name}", GET(getImportTemplateHandler), PUT(updateImportTemplateHandler), DELETE(deleteImportTemplateHandler))
+	router.Handle("/project/{key}/export/application/{permApplicationName}", GET(exportApplicationHandler))
+	router.Handle("/project/{permProjectKey}/export", GET(exportProjectHandler))
+	router.Handle("/project/{permProjectKey}/import/environment", POST(importEnvironmentHandler))
+	router.Handle("/project/{permProjectKey}/import", POST(importProjectHandler))
+	router.Handle("/project/{permProjectKey}/import/archive", POST(importProjectArchiveHandler))
+	router.Handle("/import/application/schema", GET(getApplicationImportSchemaHandler))
 	router.Handle("/project/{key}/pipeline/{permPipelineKey}/application", GET(getApplicationUsingPipelineHandler))
 	router.Handle("/project/{key}/pipeline/{permPipelineKey}/group", POST(addGroupInPipelineHandler), PUT(updateGroupsOnPipelineHandler, DEPRECATED))
 	router.Handle("/project/{key}/pipeline/{permPipelineKey}/group/{group}", PUT(updateGroupRoleOnPipelineHandler), DELETE(deleteGroupFromPipelineHandler))
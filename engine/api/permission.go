@@ -40,7 +40,7 @@ func getPermissionByMethod(method string, isExecution bool) int {
 			return permission.PermissionReadExecute
 		}
 		return permission.PermissionReadWriteExecute
-	case "PUT":
+	case "PUT", "PATCH":
 		return permission.PermissionReadWriteExecute
 	case "DELETE":
 		return permission.PermissionReadWriteExecute
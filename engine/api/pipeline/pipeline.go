@@ -3,6 +3,8 @@ package pipeline
 import (
 	"database/sql"
 	"encoding/json"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/go-gorp/gorp"
@@ -56,11 +58,11 @@ func LoadPipeline(db gorp.SqlExecutor, projectKey, name string, deep bool) (*sdk
 	var p sdk.Pipeline
 
 	var lastModified time.Time
-	query := `SELECT pipeline.id, pipeline.name, pipeline.project_id, pipeline.type, pipeline.last_modified FROM pipeline
+	query := `SELECT pipeline.id, pipeline.name, pipeline.slug, pipeline.project_id, pipeline.type, pipeline.last_modified FROM pipeline
 	 		JOIN project on pipeline.project_id = project.id
 	 		WHERE pipeline.name = $1 AND project.projectKey = $2`
 
-	err := db.QueryRow(query, name, projectKey).Scan(&p.ID, &p.Name, &p.ProjectID, &p.Type, &lastModified)
+	err := db.QueryRow(query, name, projectKey).Scan(&p.ID, &p.Name, &p.Slug, &p.ProjectID, &p.Type, &lastModified)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, sdk.ErrPipelineNotFound
@@ -82,11 +84,11 @@ func LoadPipeline(db gorp.SqlExecutor, projectKey, name string, deep bool) (*sdk
 // LoadPipelineByID loads a pipeline from database
 func LoadPipelineByID(db gorp.SqlExecutor, pipelineID int64, deep bool) (*sdk.Pipeline, error) {
 	var p sdk.Pipeline
-	query := `SELECT pipeline.name, pipeline.type, project.projectKey FROM pipeline
+	query := `SELECT pipeline.name, pipeline.slug, pipeline.type, project.projectKey FROM pipeline
 	JOIN project on pipeline.project_id = project.id
 	WHERE pipeline.id = $1`
 
-	err := db.QueryRow(query, pipelineID).Scan(&p.Name, &p.Type, &p.ProjectKey)
+	err := db.QueryRow(query, pipelineID).Scan(&p.Name, &p.Slug, &p.Type, &p.ProjectKey)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, sdk.ErrPipelineNotFound
@@ -202,13 +204,13 @@ func LoadPipelines(db gorp.SqlExecutor, projectID int64, loadDependencies bool,
 	var errquery error
 
 	if user == nil || user.Admin {
-		query := `SELECT id, name, project_id, type, last_modified
+		query := `SELECT id, name, slug, project_id, type, last_modified
 			  FROM pipeline
 			  WHERE project_id = $1
 			  ORDER BY pipeline.name`
 		rows, errquery = db.Query(query, projectID)
 	} else {
-		query := `SELECT distinct(pipeline.id), pipeline.name, pipeline.project_id, pipeline.type, last_modified
+		query := `SELECT distinct(pipeline.id), pipeline.name, pipeline.slug, pipeline.project_id, pipeline.type, last_modified
 			  FROM pipeline
 			  JOIN pipeline_group ON pipeline.id = pipeline_group.pipeline_id
 			  JOIN group_user ON pipeline_group.group_id = group_user.group_id
@@ -228,7 +230,7 @@ func LoadPipelines(db gorp.SqlExecutor, projectID int64, loadDependencies bool,
 		var lastModified time.Time
 
 		// scan pipeline id
-		if err := rows.Scan(&p.ID, &p.Name, &p.ProjectID, &p.Type, &lastModified); err != nil {
+		if err := rows.Scan(&p.ID, &p.Name, &p.Slug, &p.ProjectID, &p.Type, &lastModified); err != nil {
 			return nil, err
 		}
 		p.LastModified = lastModified.Unix()
@@ -326,7 +328,7 @@ func UpdatePipeline(db gorp.SqlExecutor, p *sdk.Pipeline) error {
 
 // InsertPipeline inserts pipeline informations in database
 func InsertPipeline(db gorp.SqlExecutor, proj *sdk.Project, p *sdk.Pipeline, u *sdk.User) error {
-	query := `INSERT INTO pipeline (name, project_id, type, last_modified) VALUES ($1,$2,$3, current_timestamp) RETURNING id`
+	query := `INSERT INTO pipeline (name, slug, project_id, type, last_modified) VALUES ($1,$2,$3,$4, current_timestamp) RETURNING id`
 
 	if p.Name == "" {
 		return sdk.ErrInvalidName
@@ -340,7 +342,23 @@ func InsertPipeline(db gorp.SqlExecutor, proj *sdk.Project, p *sdk.Pipeline, u *
 		return sdk.WrapError(sdk.ErrInvalidProject, "InsertPipeline>")
 	}
 
-	if err := db.QueryRow(query, p.Name, p.ProjectID, string(p.Type)).Scan(&p.ID); err != nil {
+	// The slug is kept when the pipeline is renamed, it must be unique in the project
+	if p.Slug == "" {
+		p.Slug = p.Name
+	}
+	slug := p.Slug
+	for i := 2; ; i++ {
+		exist, err := existPipelineSlug(db, p.ProjectID, p.Slug)
+		if err != nil {
+			return sdk.WrapError(err, "InsertPipeline> Unable to check slug %s", p.Slug)
+		}
+		if !exist {
+			break
+		}
+		p.Slug = fmt.Sprintf("%s-%d", slug, i)
+	}
+
+	if err := db.QueryRow(query, p.Name, p.Slug, p.ProjectID, string(p.Type)).Scan(&p.ID); err != nil {
 		return err
 	}
 
@@ -368,6 +386,63 @@ func ExistPipeline(db gorp.SqlExecutor, projectID int64, name string) (bool, err
 	return false, nil
 }
 
+func existPipelineSlug(db gorp.SqlExecutor, projectID int64, slug string) (bool, error) {
+	query := `SELECT COUNT(id) FROM pipeline WHERE pipeline.project_id = $1 AND pipeline.slug = $2`
+
+	var nb int64
+	if err := db.QueryRow(query, projectID, slug).Scan(&nb); err != nil {
+		return false, err
+	}
+	return nb != 0, nil
+}
+
+// LoadPipelineSlugs returns the id and the name of all the pipelines of the project by slug
+func LoadPipelineSlugs(db gorp.SqlExecutor, projectID int64) (map[string]sdk.Pipeline, error) {
+	query := `SELECT pipeline.id, pipeline.name, pipeline.slug FROM pipeline WHERE pipeline.project_id = $1`
+	rows, err := db.Query(query, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := map[string]sdk.Pipeline{}
+	for rows.Next() {
+		p := sdk.Pipeline{ProjectID: projectID}
+		if err := rows.Scan(&p.ID, &p.Name, &p.Slug); err != nil {
+			return nil, err
+		}
+		res[p.Slug] = p
+	}
+	return res, nil
+}
+
+// ExistPipelines checks in a single query which of the given pipelines exist in the project
+func ExistPipelines(db gorp.SqlExecutor, projectID int64, names []string) (map[string]bool, error) {
+	res := make(map[string]bool, len(names))
+	if len(names) == 0 {
+		return res, nil
+	}
+	for _, n := range names {
+		res[n] = false
+	}
+
+	query := `SELECT pipeline.name FROM pipeline WHERE pipeline.project_id = $1 AND pipeline.name = ANY(string_to_array($2, ','))`
+	rows, err := db.Query(query, projectID, strings.Join(names, ","))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		res[name] = true
+	}
+	return res, nil
+}
+
 // AttachPipelinesWarnings add warnings about optional steps for several PipelineBuild
 func AttachPipelinesWarnings(pbs *[]sdk.PipelineBuild) {
 	for iPb := range *pbs {
@@ -1,13 +1,11 @@
 package main
 
 import (
-	"io/ioutil"
 	"net/http"
 
 	"github.com/go-gorp/gorp"
 	"github.com/gorilla/mux"
 	"github.com/hashicorp/hcl"
-	"gopkg.in/yaml.v2"
 
 	"github.com/ovh/cds/engine/api/businesscontext"
 	"github.com/ovh/cds/engine/api/group"
@@ -24,6 +22,10 @@ func importPipelineHandler(w http.ResponseWriter, r *http.Request, db *gorp.DbMa
 	key := vars["permProjectKey"]
 	format := r.FormValue("format")
 	forceUpdate := FormBool(r, "forceUpdate")
+	verbosity, errV := importVerbosity(r)
+	if errV != nil {
+		return sdk.WrapError(errV, "importPipelineHandler> Invalid verbosity")
+	}
 
 	// Load project
 	proj, errp := project.Load(db, key, c.User, project.LoadOptions.Default)
@@ -36,15 +38,15 @@ func importPipelineHandler(w http.ResponseWriter, r *http.Request, db *gorp.DbMa
 	}
 
 	// Get body
-	data, errRead := ioutil.ReadAll(r.Body)
+	data, errRead := readImportBody(r.Body, "")
 	if errRead != nil {
-		return sdk.WrapError(sdk.ErrWrongRequest, "importPipelineHandler> Unable to read body")
+		return sdk.WrapError(importBodyError(errRead), "importPipelineHandler> Unable to read body: %s", errRead)
 	}
 
 	// Compute format
-	f, errF := exportentities.GetFormat(format)
+	f, errF := importFormat(format, r.Header.Get("Content-Type"))
 	if errF != nil {
-		return sdk.WrapError(sdk.ErrWrongRequest, "importPipelineHandler> Unable to get format : %s", errF)
+		return sdk.WrapError(sdk.ErrImportUnknownFormat, "importPipelineHandler> Unable to get format: %s", errF)
 	}
 
 	// Parse the pipeline
@@ -54,12 +56,12 @@ func importPipelineHandler(w http.ResponseWriter, r *http.Request, db *gorp.DbMa
 	case exportentities.FormatJSON, exportentities.FormatHCL:
 		errorParse = hcl.Unmarshal(data, payload)
 	case exportentities.FormatYAML:
-		errorParse = yaml.Unmarshal(data, payload)
+		errorParse = unmarshalImportYAML(data, payload)
 	}
 
 	if errorParse != nil {
 		log.Warning("importNewEnvironmentHandler> Cannot parsing: %s\n", errorParse)
-		return sdk.ErrWrongRequest
+		return importParseError(errorParse)
 	}
 
 	// Check if pipeline exists
@@ -79,25 +81,13 @@ func importPipelineHandler(w http.ResponseWriter, r *http.Request, db *gorp.DbMa
 		eg := &pip.GroupPermission[i]
 		g, errg := group.LoadGroup(db, eg.Group.Name)
 		if errg != nil {
-			return sdk.WrapError(errg, "importPipelineHandler> Error loading groups for permission")
+			return sdk.WrapError(importGroupError(errg), "importPipelineHandler> Error loading groups for permission")
 		}
 		eg.Group = *g
 	}
 
-	allMsg := []sdk.Message{}
-	msgChan := make(chan sdk.Message, 1)
-	done := make(chan bool)
-
-	go func() {
-		for {
-			msg, ok := <-msgChan
-			allMsg = append(allMsg, msg)
-			if !ok {
-				done <- true
-				return
-			}
-		}
-	}()
+	msgChan, collectMessages := newMessageCollector()
+	defer collectMessages()
 
 	tx, errBegin := db.Begin()
 	if errBegin != nil {
@@ -107,6 +97,10 @@ func importPipelineHandler(w http.ResponseWriter, r *http.Request, db *gorp.DbMa
 
 	defer tx.Rollback()
 
+	if err := project.LockByKey(tx, proj.Key); err != nil {
+		return sdk.WrapError(err, "importPipelineHandler> Unable to lock project")
+	}
+
 	var globalError error
 
 	if exist && !forceUpdate {
@@ -117,15 +111,13 @@ func importPipelineHandler(w http.ResponseWriter, r *http.Request, db *gorp.DbMa
 		globalError = pipeline.Import(tx, proj, pip, msgChan, c.User)
 	}
 
-	close(msgChan)
-	<-done
-
+	allMsg := sdk.DedupMessages(collectMessages())
 	al := r.Header.Get("Accept-Language")
 	msgListString := []string{}
 
 	for _, m := range allMsg {
 		s := m.String(al)
-		if s != "" {
+		if s != "" && m.Level().AtLeast(verbosity) {
 			msgListString = append(msgListString, s)
 		}
 	}
@@ -148,13 +140,13 @@ func importPipelineHandler(w http.ResponseWriter, r *http.Request, db *gorp.DbMa
 		return sdk.WrapError(err, "importPipelineHandler> Cannot commit transaction")
 	}
 
-	var errlp error
-	proj.Pipelines, errlp = pipeline.LoadPipelines(db, proj.ID, true, c.User)
+	// Only the imported pipeline is reloaded and checked
+	imported, errlp := pipeline.LoadPipeline(db, proj.Key, pip.Name, true)
 	if errlp != nil {
-		return sdk.WrapError(errlp, "importPipelineHandler> Unable to reload pipelines for project %s", proj.Key)
+		return sdk.WrapError(errlp, "importPipelineHandler> Unable to reload pipeline %s", pip.Name)
 	}
 
-	if err := sanity.CheckProjectPipelines(db, proj); err != nil {
+	if err := sanity.CheckPipeline(db, proj, imported); err != nil {
 		return sdk.WrapError(err, "importPipelineHandler> Cannot check warnings")
 	}
 
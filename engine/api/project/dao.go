@@ -136,6 +136,15 @@ func Update(db gorp.SqlExecutor, proj *sdk.Project, u *sdk.User) error {
 	return UpdateLastModified(db, u, proj)
 }
 
+// LockByKey locks the row of a project until the end of the transaction. The transactions which update the
+// entities of a project take the lock first, so that they wait for each other instead of deadlocking.
+func LockByKey(db gorp.SqlExecutor, key string) error {
+	if _, err := db.Exec("SELECT id FROM project WHERE projectkey = $1 FOR UPDATE", key); err != nil {
+		return sdk.WrapError(err, "LockByKey> Unable to lock project %s", key)
+	}
+	return nil
+}
+
 // UpdateLastModified updates last_modified date on a project given its key
 func UpdateLastModified(db gorp.SqlExecutor, u *sdk.User, proj *sdk.Project) error {
 	t := time.Now()
@@ -148,8 +157,14 @@ func UpdateLastModified(db gorp.SqlExecutor, u *sdk.User, proj *sdk.Project) err
 		}, 0)
 	}
 
-	_, err := db.Exec("update project set last_modified = $2 where projectkey = $1", proj.Key, t)
-	proj.LastModified = t
+	// The date only moves forward, so that a transaction which waited for the lock of the project
+	// doesn't overwrite the date of a concurrent transaction committed after it started
+	var lastModified time.Time
+	err := db.QueryRow("update project set last_modified = greatest(last_modified, $2) where projectkey = $1 returning last_modified", proj.Key, t).Scan(&lastModified)
+	if err != nil {
+		return sdk.WrapError(err, "UpdateLastModified> Unable to update project %s", proj.Key)
+	}
+	proj.LastModified = lastModified
 
 	if u != nil {
 		updates := sdk.LastModification{
@@ -163,7 +178,6 @@ func UpdateLastModified(db gorp.SqlExecutor, u *sdk.User, proj *sdk.Project) err
 		if errP == nil {
 			cache.Publish("lastUpdates", string(b))
 		}
-		return err
 	}
 	return nil
 }
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/go-gorp/gorp"
+	"github.com/gorilla/mux"
+
+	"github.com/ovh/cds/engine/api/application"
+	"github.com/ovh/cds/engine/api/businesscontext"
+	"github.com/ovh/cds/engine/api/environment"
+	"github.com/ovh/cds/engine/api/pipeline"
+	"github.com/ovh/cds/engine/api/project"
+	"github.com/ovh/cds/sdk"
+	"github.com/ovh/cds/sdk/exportentities"
+)
+
+//exportProjectHandler exports the environments, pipelines and applications of a project in a single document,
+//which is imported back by importProjectHandler. Secrets are masked.
+func exportProjectHandler(w http.ResponseWriter, r *http.Request, db *gorp.DbMap, c *businesscontext.Ctx) error {
+	key := mux.Vars(r)["permProjectKey"]
+
+	f, errF := exportApplicationFormat(r)
+	if errF != nil {
+		return sdk.WrapError(sdk.ErrWrongRequest, "exportProjectHandler> Unable to get format : %s", errF)
+	}
+	// The project import only reads these formats
+	if f != exportentities.FormatJSON && f != exportentities.FormatYAML {
+		return sdk.WrapError(sdk.ErrWrongRequest, "exportProjectHandler> Format %s is not supported", f)
+	}
+
+	proj, errP := project.Load(db, key, c.User)
+	if errP != nil {
+		return sdk.WrapError(errP, "exportProjectHandler> Unable to load project %s", key)
+	}
+
+	msgChan, collectMessages := newMessageCollector()
+	p, errE := exportProject(db, proj, c.User, msgChan)
+	msgs := collectMessages()
+	if errE != nil {
+		return sdk.WrapError(errE, "exportProjectHandler> Unable to export project %s", key)
+	}
+
+	b, errM := exportentities.Marshal(p, f)
+	if errM != nil {
+		return sdk.WrapError(errM, "exportProjectHandler> Unable to export project %s", key)
+	}
+
+	al := r.Header.Get("Accept-Language")
+	for _, m := range msgs {
+		w.Header().Add("Warning", fmt.Sprintf("199 cds %q", m.String(al)))
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment;filename=\"%s.%s\"", proj.Key, exportFormatExtension(f)))
+	w.WriteHeader(http.StatusOK)
+	_, err := w.Write(b)
+	return err
+}
+
+//exportProject loads the environments, pipelines and applications of a project which are visible by the user.
+//They are sorted by name, so that the export of an unchanged project is unchanged.
+func exportProject(db gorp.SqlExecutor, proj *sdk.Project, u *sdk.User, msgChan chan<- sdk.Message) (exportentities.Project, error) {
+	p := exportentities.Project{}
+
+	envs, errE := environment.LoadEnvironments(db, proj.Key, true, u)
+	if errE != nil {
+		return p, sdk.WrapError(errE, "exportProject> Unable to load environments")
+	}
+	sort.Slice(envs, func(i, j int) bool { return envs[i].Name < envs[j].Name })
+	for i := range envs {
+		for j := range envs[i].Variable {
+			if v := &envs[i].Variable[j]; sdk.NeedPlaceholder(v.Type) {
+				v.Value = sdk.PasswordPlaceholder
+			}
+		}
+		p.Environments = append(p.Environments, *exportentities.NewEnvironment(&envs[i]))
+	}
+
+	pips, errL := pipeline.LoadPipelines(db, proj.ID, false, u)
+	if errL != nil {
+		return p, sdk.WrapError(errL, "exportProject> Unable to load pipelines")
+	}
+	sort.Slice(pips, func(i, j int) bool { return pips[i].Name < pips[j].Name })
+	for _, lp := range pips {
+		pip, errP := pipeline.LoadPipeline(db, proj.Key, lp.Name, true)
+		if errP != nil {
+			return p, sdk.WrapError(errP, "exportProject> Unable to load pipeline %s", lp.Name)
+		}
+		for j := range pip.Parameter {
+			if param := &pip.Parameter[j]; sdk.NeedPlaceholder(param.Type) {
+				param.Value = sdk.PasswordPlaceholder
+			}
+		}
+		p.Pipelines = append(p.Pipelines, *exportentities.NewPipeline(pip))
+	}
+
+	apps, errA := application.LoadAll(db, proj.Key, u)
+	if errA != nil {
+		return p, sdk.WrapError(errA, "exportProject> Unable to load applications")
+	}
+	sort.Slice(apps, func(i, j int) bool { return apps[i].Name < apps[j].Name })
+	for _, la := range apps {
+		a, errX := application.Export(db, proj, la.Name, u, msgChan)
+		if errX != nil {
+			return p, sdk.WrapError(errX, "exportProject> Unable to export application %s", la.Name)
+		}
+		p.Applications = append(p.Applications, a)
+	}
+	return p, nil
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ovh/cds/engine/api/application"
+	"github.com/ovh/cds/engine/api/auth"
+	"github.com/ovh/cds/engine/api/test"
+	"github.com/ovh/cds/engine/api/test/assets"
+	"github.com/ovh/cds/sdk"
+)
+
+func TestExportProjectHandler(t *testing.T) {
+	db := test.SetupPG(t)
+
+	router = newRouter(auth.TestLocalAuth(t), mux.NewRouter(), "/TestExportProjectHandler")
+	router.init()
+
+	u, pass := assets.InsertAdminUser(db)
+	proj := assets.InsertTestProject(t, db, sdk.RandomString(10), sdk.RandomString(10), nil)
+	test.NotNil(t, proj)
+	projGroup := proj.ProjectGroups[0].Group.Name
+
+	do := func(method, uri, payload string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(method, uri, strings.NewReader(payload))
+		test.NoError(t, err)
+		assets.AuthentifyRequest(t, req, u, pass)
+		w := httptest.NewRecorder()
+		router.mux.ServeHTTP(w, req)
+		return w
+	}
+
+	importURI := router.getRoute("POST", importProjectHandler, map[string]string{"permProjectKey": proj.Key})
+	test.NotEmpty(t, importURI)
+	w := do("POST", importURI+"?format=yaml", `
+environments:
+- name: staging
+  values:
+    password:
+      type: password
+      value: s3cr3t
+  permissions:
+    `+projGroup+`: 7
+- name: production
+  permissions:
+    `+projGroup+`: 7
+pipelines:
+- name: deploy
+  type: deployment
+- name: build
+applications:
+- name: app2
+- name: app1
+  pipelines:
+    build: {}
+`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	//The export is sorted and the secrets are masked
+	exportURI := router.getRoute("GET", exportProjectHandler, map[string]string{"permProjectKey": proj.Key})
+	test.NotEmpty(t, exportURI)
+	w = do("GET", exportURI+"?format=yaml", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	export := w.Body.String()
+	assert.NotContains(t, export, "s3cr3t")
+	assert.Contains(t, export, sdk.PasswordPlaceholder)
+	assert.True(t, strings.Index(export, "name: production") < strings.Index(export, "name: staging"))
+	assert.True(t, strings.Index(export, "name: app1") < strings.Index(export, "name: app2"))
+
+	w = do("GET", exportURI+"?format=yaml", "")
+	assert.Equal(t, export, w.Body.String())
+
+	w = do("GET", exportURI+"?format=hcl", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	//The export is imported in another project, whose group has the same name
+	proj2 := assets.InsertTestProject(t, db, sdk.RandomString(10), proj.Name, nil)
+	test.NotNil(t, proj2)
+	importURI = router.getRoute("POST", importProjectHandler, map[string]string{"permProjectKey": proj2.Key})
+	w = do("POST", importURI+"?format=yaml", export)
+	assert.Equal(t, http.StatusOK, w.Code)
+	app, err := application.LoadByName(db, proj2.Key, "app1", u, application.LoadOptions.WithPipelines)
+	test.NoError(t, err)
+	assert.Len(t, app.Pipelines, 1)
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/go-gorp/gorp"
+	"github.com/gorilla/mux"
+	"github.com/hashicorp/hcl"
+	"github.com/pkg/errors"
+
+	"github.com/ovh/cds/engine/api/application"
+	"github.com/ovh/cds/engine/api/businesscontext"
+	"github.com/ovh/cds/engine/api/environment"
+	"github.com/ovh/cds/engine/api/group"
+	"github.com/ovh/cds/engine/api/pipeline"
+	"github.com/ovh/cds/engine/api/project"
+	"github.com/ovh/cds/engine/api/sanity"
+	"github.com/ovh/cds/sdk"
+	"github.com/ovh/cds/sdk/exportentities"
+	"github.com/ovh/cds/sdk/log"
+)
+
+func importProjectHandler(w http.ResponseWriter, r *http.Request, db *gorp.DbMap, c *businesscontext.Ctx) error {
+	vars := mux.Vars(r)
+	key := vars["permProjectKey"]
+	format := r.FormValue("format")
+	forceUpdate := FormBool(r, "forceUpdate")
+
+	// Load project
+	proj, errp := project.Load(db, key, c.User, project.LoadOptions.Default, project.LoadOptions.WithGroups)
+	if errp != nil {
+		return sdk.WrapError(errp, "importProjectHandler> Unable to load project %s", key)
+	}
+
+	// Get body
+	data, errRead := readImportBody(r.Body, "")
+	if errRead != nil {
+		return sdk.WrapError(importBodyError(errRead), "importProjectHandler> Unable to read body: %s", errRead)
+	}
+
+	// Compute format
+	f, errF := importFormat(format, r.Header.Get("Content-Type"))
+	if errF != nil {
+		return sdk.WrapError(sdk.ErrImportUnknownFormat, "importProjectHandler> Unable to get format: %s", errF)
+	}
+
+	// Parse the project
+	payload := &exportentities.Project{}
+	var errorParse error
+	switch f {
+	case exportentities.FormatJSON, exportentities.FormatHCL:
+		errorParse = hcl.Unmarshal(data, payload)
+	case exportentities.FormatYAML:
+		errorParse = unmarshalImportYAML(data, payload)
+	default:
+		errorParse = exportentities.ErrUnsupportedFormat
+	}
+
+	if errorParse != nil {
+		log.Warning("importProjectHandler> Cannot parsing: %s\n", errorParse)
+		return importParseError(errorParse)
+	}
+
+	return doImportProject(w, r, db, c, proj, payload, forceUpdate)
+}
+
+//doImportProject imports the entities of the project in a single transaction, and writes the messages of the import
+func doImportProject(w http.ResponseWriter, r *http.Request, db *gorp.DbMap, c *businesscontext.Ctx, proj *sdk.Project, payload *exportentities.Project, forceUpdate bool) error {
+	verbosity, errV := importVerbosity(r)
+	if errV != nil {
+		return sdk.WrapError(errV, "doImportProject> Invalid verbosity")
+	}
+
+	// The environments get the values of the environment they inherit from
+	cycle, errI := resolveEnvironmentInheritance(db, proj, payload.Environments, c.User)
+	if errI != nil {
+		return sdk.WrapError(errI, "doImportProject> Unable to resolve inheritance of environments")
+	}
+	if cycle != nil {
+		m := sdk.NewMessage(sdk.MsgEnvironmentInheritanceCycle, strings.Join(cycle, " -> "))
+		return WriteJSON(w, r, []string{m.String(r.Header.Get("Accept-Language"))}, http.StatusBadRequest)
+	}
+
+	// The references to the values of other environments are replaced by the values
+	refs, unresolved, errR := resolveEnvironmentReferences(db, proj, payload.Environments, c.User)
+	if errR != nil {
+		return sdk.WrapError(errR, "doImportProject> Unable to resolve references of environments")
+	}
+	if len(unresolved) > 0 {
+		return WriteJSON(w, r, importMessageStrings(r, unresolved), http.StatusBadRequest)
+	}
+
+	msgChan, collectMessages := newMessageCollector()
+	defer collectMessages()
+	for _, m := range refs {
+		msgChan <- m
+	}
+
+	tx, errBegin := db.Begin()
+	if errBegin != nil {
+		return sdk.WrapError(errBegin, "doImportProject> Cannot start transaction")
+	}
+
+	// Nothing is kept if one of the entities can't be imported
+	defer tx.Rollback()
+
+	if err := project.LockByKey(tx, proj.Key); err != nil {
+		return sdk.WrapError(err, "doImportProject> Unable to lock project")
+	}
+
+	apps, globalError := importProject(tx, proj, payload, forceUpdate, msgChan, c.User)
+
+	allMsg := sdk.DedupMessages(collectMessages())
+	al := r.Header.Get("Accept-Language")
+	msgListString := []string{}
+
+	for _, m := range allMsg {
+		s := m.String(al)
+		if s != "" && m.Level().AtLeast(verbosity) {
+			msgListString = append(msgListString, s)
+		}
+	}
+
+	log.Debug("doImportProject >>> %v", msgListString)
+
+	if globalError != nil {
+		myError, ok := errors.Cause(globalError).(*sdk.Error)
+		if ok {
+			return WriteJSON(w, r, msgListString, myError.Status)
+		}
+		return sdk.WrapError(globalError, "doImportProject> Unable import project %s", proj.Key)
+	}
+
+	if err := project.UpdateLastModified(tx, c.User, proj); err != nil {
+		return sdk.WrapError(err, "doImportProject> Unable to update project")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return sdk.WrapError(err, "doImportProject> Cannot commit transaction")
+	}
+
+	for _, app := range apps {
+		afterApplicationImport(importHooks, proj, app, allMsg)
+	}
+
+	// The pipelines are loaded by the check
+	if err := sanity.CheckProjectPipelines(db, proj); err != nil {
+		return sdk.WrapError(err, "doImportProject> Cannot check warnings")
+	}
+
+	return WriteJSON(w, r, msgListString, http.StatusOK)
+}
+
+//importProject imports the environments, then the pipelines and then the applications of the project,
+//so that each entity can reference the ones imported before. The import hooks are called before each
+//application is written, the imported applications are returned for the hooks called after the commit.
+func importProject(db gorp.SqlExecutor, proj *sdk.Project, payload *exportentities.Project, forceUpdate bool, msgChan chan<- sdk.Message, u *sdk.User) ([]*sdk.Application, error) {
+	for i := range payload.Environments {
+		env := payload.Environments[i].Environment()
+		if err := loadImportGroupPermissions(db, env.EnvironmentGroups); err != nil {
+			return nil, sdk.WrapError(err, "importProject> Unable to load groups of environment %s", env.Name)
+		}
+
+		exist, errE := environment.Exists(db, proj.Key, env.Name)
+		if errE != nil {
+			return nil, sdk.WrapError(errE, "importProject> Unable to check if environment %s exists", env.Name)
+		}
+
+		if !exist {
+			if err := environment.Import(db, proj, env, msgChan, u); err != nil {
+				return nil, sdk.WrapError(err, "importProject> Unable to import environment %s", env.Name)
+			}
+			continue
+		}
+
+		if !forceUpdate {
+			return nil, sdk.WrapError(sdk.ErrEnvironmentExist, "importProject> Environment %s already exists", env.Name)
+		}
+		if err := environment.Lock(db, proj.Key, env.Name); err != nil {
+			return nil, sdk.WrapError(err, "importProject> Cannot lock env %s/%s", proj.Key, env.Name)
+		}
+		oldEnv, errEnv := environment.LoadEnvironmentByName(db, proj.Key, env.Name)
+		if errEnv != nil {
+			return nil, sdk.WrapError(errEnv, "importProject> Cannot load env %s/%s", proj.Key, env.Name)
+		}
+		if err := environment.ImportInto(db, proj, env, oldEnv, msgChan, u); err != nil {
+			return nil, sdk.WrapError(err, "importProject> Unable to import environment %s", env.Name)
+		}
+	}
+
+	for i := range payload.Pipelines {
+		pip, errP := payload.Pipelines[i].Pipeline()
+		if errP != nil {
+			return nil, sdk.WrapError(sdk.ErrImportParse, "importProject> Unable to parse pipeline %s: %s", payload.Pipelines[i].Name, errP)
+		}
+		if err := loadImportGroupPermissions(db, pip.GroupPermission); err != nil {
+			return nil, sdk.WrapError(err, "importProject> Unable to load groups of pipeline %s", pip.Name)
+		}
+
+		exist, errE := pipeline.ExistPipeline(db, proj.ID, pip.Name)
+		if errE != nil {
+			return nil, sdk.WrapError(errE, "importProject> Unable to check if pipeline %s exists", pip.Name)
+		}
+
+		if exist && !forceUpdate {
+			return nil, sdk.WrapError(sdk.ErrPipelineAlreadyExists, "importProject> Pipeline %s already exists", pip.Name)
+		} else if exist {
+			if err := pipeline.ImportUpdate(db, proj, pip, msgChan, u); err != nil {
+				return nil, sdk.WrapError(err, "importProject> Unable to update pipeline %s", pip.Name)
+			}
+		} else if err := pipeline.Import(db, proj, pip, msgChan, u); err != nil {
+			return nil, sdk.WrapError(err, "importProject> Unable to import pipeline %s", pip.Name)
+		}
+	}
+
+	var apps []*sdk.Application
+	for i := range payload.Applications {
+		app, errA := payload.Applications[i].Application()
+		if errA != nil {
+			return nil, sdk.WrapError(sdk.ErrImportParse, "importProject> Unable to parse application %s: %s", payload.Applications[i].Name, errA)
+		}
+
+		exist, errE := application.Exists(db, proj.Key, app.Name)
+		if errE != nil {
+			return nil, sdk.WrapError(errE, "importProject> Unable to check if application %s exists", app.Name)
+		}
+		if exist && !forceUpdate {
+			return nil, sdk.WrapError(sdk.ErrApplicationExist, "importProject> Application %s already exists", app.Name)
+		}
+
+		if err := loadApplicationImportDependencies(db, proj, app, false, msgChan); err != nil {
+			return nil, sdk.WrapError(err, "importProject> Unable to load dependencies of application %s", app.Name)
+		}
+		if err := beforeApplicationImport(importHooks, proj, app, msgChan); err != nil {
+			return nil, sdk.WrapError(err, "importProject> Application %s rejected by import hooks", app.Name)
+		}
+		if err := importApplication(db, proj, app, exist, false, true, false, false, msgChan, u); err != nil {
+			return nil, sdk.WrapError(err, "importProject> Unable to import application %s", app.Name)
+		}
+		apps = append(apps, app)
+	}
+
+	return apps, nil
+}
+
+//loadImportGroupPermissions loads the groups referenced by their names in the permissions
+func loadImportGroupPermissions(db gorp.SqlExecutor, permissions []sdk.GroupPermission) error {
+	for i := range permissions {
+		gp := &permissions[i]
+		g, errg := group.LoadGroup(db, gp.Group.Name)
+		if errg != nil {
+			return sdk.WrapError(importGroupError(errg), "loadImportGroupPermissions> Error loading group %s for permission", gp.Group.Name)
+		}
+		gp.Group = *g
+	}
+	return nil
+}
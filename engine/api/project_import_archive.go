@@ -0,0 +1,242 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/go-gorp/gorp"
+	"github.com/gorilla/mux"
+	"github.com/spf13/viper"
+
+	"github.com/ovh/cds/engine/api/businesscontext"
+	"github.com/ovh/cds/engine/api/project"
+	"github.com/ovh/cds/sdk"
+	"github.com/ovh/cds/sdk/exportentities"
+	"github.com/ovh/cds/sdk/log"
+)
+
+// Directories of the entities in an imported archive
+const (
+	importArchiveEnvironments = "environments"
+	importArchivePipelines    = "pipelines"
+	importArchiveApplications = "applications"
+)
+
+//importProjectArchiveHandler imports the environments, pipelines and applications of a tar.gz archive in a
+//single transaction. Each file is in the directory of its kind, and its format is given by its extension.
+func importProjectArchiveHandler(w http.ResponseWriter, r *http.Request, db *gorp.DbMap, c *businesscontext.Ctx) error {
+	vars := mux.Vars(r)
+	key := vars["permProjectKey"]
+	forceUpdate := FormBool(r, "forceUpdate")
+
+	// Load project
+	proj, errp := project.Load(db, key, c.User, project.LoadOptions.Default, project.LoadOptions.WithGroups)
+	if errp != nil {
+		return sdk.WrapError(errp, "importProjectArchiveHandler> Unable to load project %s", key)
+	}
+
+	maxSize := viper.GetInt64(viperImportGzipMaxSize)
+	if maxSize <= 0 {
+		maxSize = defaultImportGzipMaxSize
+	}
+
+	payload, msgs, errR := readImportArchive(r.Body, maxSize)
+	if errR != nil {
+		return sdk.WrapError(importBodyError(errR), "importProjectArchiveHandler> Unable to read archive: %s", errR)
+	}
+
+	if len(msgs) == 0 {
+		var cycle []string
+		payload.Applications, cycle = orderImportApplications(proj, payload.Applications)
+		if cycle != nil {
+			msgs = append(msgs, sdk.NewMessage(sdk.MsgAppImportTriggerCycle, strings.Join(cycle, " -> ")))
+		}
+	}
+
+	// Nothing is imported if a file is invalid
+	if len(msgs) > 0 {
+		al := r.Header.Get("Accept-Language")
+		msgListString := make([]string, len(msgs))
+		for i := range msgs {
+			msgListString[i] = msgs[i].String(al)
+		}
+		log.Warning("importProjectArchiveHandler> %d invalid files in archive of project %s", len(msgs), proj.Key)
+		return WriteJSON(w, r, msgListString, http.StatusBadRequest)
+	}
+
+	return doImportProject(w, r, db, c, proj, payload, forceUpdate)
+}
+
+//readImportArchive reads the entities of a tar.gz archive. The files which can't be read are returned
+//as messages, the other ones are still read so that all the invalid files are reported at once. An error
+//is returned if the archive itself can't be read, or if it is bigger than maxSize once decompressed.
+func readImportArchive(body io.Reader, maxSize int64) (*exportentities.Project, []sdk.Message, error) {
+	gz, errG := gzip.NewReader(body)
+	if errG != nil {
+		return nil, nil, fmt.Errorf("unable to read gzip archive: %s", errG)
+	}
+	defer gz.Close()
+
+	payload := &exportentities.Project{}
+	msgs := []sdk.Message{}
+	invalid := func(name string, err error) {
+		msgs = append(msgs, sdk.NewMessage(sdk.MsgImportArchiveFileInvalid, name, err))
+	}
+
+	var size int64
+	tr := tar.NewReader(gz)
+	for {
+		hdr, errN := tr.Next()
+		if errN == io.EOF {
+			break
+		}
+		if errN != nil {
+			return nil, nil, fmt.Errorf("unable to read tar archive: %s", errN)
+		}
+		if hdr.Typeflag != tar.TypeReg && hdr.Typeflag != tar.TypeRegA {
+			continue
+		}
+
+		name := strings.TrimPrefix(path.Clean(hdr.Name), "/")
+		if isHiddenImportArchiveFile(name) {
+			continue
+		}
+
+		data, errR := ioutil.ReadAll(io.LimitReader(tr, maxSize-size+1))
+		if errR != nil {
+			return nil, nil, fmt.Errorf("unable to read %s: %s", name, errR)
+		}
+		size += int64(len(data))
+		if size > maxSize {
+			return nil, nil, importBodyTooLargeError{maxSize: maxSize, decompressed: true}
+		}
+
+		f, errF := exportentities.GetFormatFromPath(name)
+		if errF != nil {
+			invalid(name, errF)
+			continue
+		}
+
+		var errP error
+		switch strings.SplitN(name, "/", 2)[0] {
+		case importArchiveEnvironments:
+			env := exportentities.Environment{}
+			if errP = unmarshalImport(data, f, &env); errP == nil {
+				payload.Environments = append(payload.Environments, env)
+			}
+		case importArchivePipelines:
+			pip := exportentities.Pipeline{}
+			if errP = unmarshalImport(data, f, &pip); errP == nil {
+				if _, errP = pip.Pipeline(); errP == nil {
+					payload.Pipelines = append(payload.Pipelines, pip)
+				}
+			}
+		case importArchiveApplications:
+			app := exportentities.Application{}
+			if errP = unmarshalImport(data, f, &app); errP == nil {
+				if _, errP = app.Application(); errP == nil {
+					payload.Applications = append(payload.Applications, app)
+				}
+			}
+		default:
+			errP = fmt.Errorf("a file must be in the %s, %s or %s directory", importArchiveEnvironments, importArchivePipelines, importArchiveApplications)
+		}
+		if errP != nil {
+			if e, ok := errP.(*sdk.Error); ok && e.Root != nil {
+				errP = e.Root
+			}
+			invalid(name, errP)
+		}
+	}
+	return payload, msgs, nil
+}
+
+//isHiddenImportArchiveFile returns true for the files of an archive which are not entities, such as the
+//metadata added by some archivers or the files of a git repository
+func isHiddenImportArchiveFile(name string) bool {
+	for _, elem := range strings.Split(name, "/") {
+		if strings.HasPrefix(elem, ".") {
+			return true
+		}
+	}
+	return false
+}
+
+//orderImportApplications orders the applications so that the applications triggered by another one are
+//imported before it. If applications trigger each other, the cycle is returned.
+func orderImportApplications(proj *sdk.Project, apps []exportentities.Application) ([]exportentities.Application, []string) {
+	index := make(map[string]int, len(apps))
+	for i := range apps {
+		index[apps[i].Name] = i
+	}
+
+	deps := make([][]string, len(apps))
+	for i := range apps {
+		app, errA := apps[i].Application()
+		if errA != nil {
+			continue
+		}
+		destApps := map[string]bool{}
+		for _, ap := range app.Pipelines {
+			for _, t := range ap.Triggers {
+				if t.DestProject.Key != "" && t.DestProject.Key != proj.Key {
+					continue
+				}
+				if _, ok := index[t.DestApplication.Name]; ok && t.DestApplication.Name != app.Name {
+					destApps[t.DestApplication.Name] = true
+				}
+			}
+		}
+		for name := range destApps {
+			deps[i] = append(deps[i], name)
+		}
+		sort.Strings(deps[i])
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make([]int, len(apps))
+	ordered := make([]exportentities.Application, 0, len(apps))
+	var stack []string
+	var visit func(i int) []string
+	visit = func(i int) []string {
+		switch state[i] {
+		case visited:
+			return nil
+		case visiting:
+			for j, name := range stack {
+				if name == apps[i].Name {
+					return append(append([]string{}, stack[j:]...), apps[i].Name)
+				}
+			}
+		}
+		state[i] = visiting
+		stack = append(stack, apps[i].Name)
+		for _, name := range deps[i] {
+			if cycle := visit(index[name]); cycle != nil {
+				return cycle
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[i] = visited
+		ordered = append(ordered, apps[i])
+		return nil
+	}
+
+	for i := range apps {
+		if cycle := visit(i); cycle != nil {
+			return apps, cycle
+		}
+	}
+	return ordered, nil
+}
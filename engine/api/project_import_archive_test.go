@@ -0,0 +1,103 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ovh/cds/sdk"
+	"github.com/ovh/cds/sdk/exportentities"
+)
+
+func importArchive(t *testing.T, files map[string]string) *bytes.Buffer {
+	buf := &bytes.Buffer{}
+	gz := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		assert.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}))
+		_, err := tw.Write([]byte(content))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, tw.Close())
+	assert.NoError(t, gz.Close())
+	return buf
+}
+
+func Test_readImportArchive(t *testing.T) {
+	archive := importArchive(t, map[string]string{
+		"environments/production.yml": "name: production\n",
+		"./pipelines/build.json":      `{"name": "build", "type": "build"}`,
+		"applications/app1.yml":       "name: app1\n",
+		"applications/.app1.yml.swp":  "not an application",
+	})
+
+	payload, msgs, err := readImportArchive(archive, 1<<20)
+	assert.NoError(t, err)
+	assert.Empty(t, msgs)
+	if assert.Len(t, payload.Environments, 1) {
+		assert.Equal(t, "production", payload.Environments[0].Name)
+	}
+	if assert.Len(t, payload.Pipelines, 1) {
+		assert.Equal(t, "build", payload.Pipelines[0].Name)
+	}
+	if assert.Len(t, payload.Applications, 1) {
+		assert.Equal(t, "app1", payload.Applications[0].Name)
+	}
+}
+
+func Test_readImportArchiveInvalidFiles(t *testing.T) {
+	archive := importArchive(t, map[string]string{
+		"applications/app1.txt": "name: app1\n",
+		"applications/app2.yml": "name: [app2\n",
+		"app3.yml":              "name: app3\n",
+	})
+
+	_, msgs, err := readImportArchive(archive, 1<<20)
+	assert.NoError(t, err)
+	files := []string{}
+	for _, m := range msgs {
+		assert.Equal(t, sdk.MsgImportArchiveFileInvalid.ID, m.ID)
+		files = append(files, strings.SplitN(m.String("en-US"), " ", 3)[1])
+	}
+	sort.Strings(files)
+	assert.Equal(t, []string{"app3.yml", "applications/app1.txt", "applications/app2.yml"}, files)
+
+	_, _, err = readImportArchive(importArchive(t, map[string]string{"applications/app1.yml": strings.Repeat("a", 1025)}), 1024)
+	assert.Error(t, err)
+
+	_, _, err = readImportArchive(strings.NewReader("name: app1\n"), 1024)
+	assert.Error(t, err)
+}
+
+func Test_orderImportApplications(t *testing.T) {
+	trigger := func(destApp string) map[string]exportentities.ApplicationPipeline {
+		return map[string]exportentities.ApplicationPipeline{
+			"build": {Triggers: map[string]exportentities.ApplicationPipelineTrigger{
+				"deploy": {ApplicationName: &destApp},
+			}},
+		}
+	}
+	proj := &sdk.Project{Key: "KEY"}
+	apps := []exportentities.Application{
+		{Name: "front", Pipelines: trigger("back")},
+		{Name: "back", Pipelines: trigger("db")},
+		{Name: "db"},
+	}
+
+	ordered, cycle := orderImportApplications(proj, apps)
+	assert.Nil(t, cycle)
+	names := []string{}
+	for _, a := range ordered {
+		names = append(names, a.Name)
+	}
+	assert.Equal(t, []string{"db", "back", "front"}, names)
+
+	apps[2].Pipelines = trigger("front")
+	_, cycle = orderImportApplications(proj, apps)
+	assert.Equal(t, []string{"front", "back", "db", "front"}, cycle)
+}
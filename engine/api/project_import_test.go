@@ -0,0 +1,132 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ovh/cds/engine/api/application"
+	"github.com/ovh/cds/engine/api/auth"
+	"github.com/ovh/cds/engine/api/environment"
+	"github.com/ovh/cds/engine/api/pipeline"
+	"github.com/ovh/cds/engine/api/test"
+	"github.com/ovh/cds/engine/api/test/assets"
+	"github.com/ovh/cds/sdk"
+)
+
+func TestImportProjectHandler(t *testing.T) {
+	db := test.SetupPG(t)
+
+	router = newRouter(auth.TestLocalAuth(t), mux.NewRouter(), "/TestImportProjectHandler")
+	router.init()
+
+	//1. Create admin user
+	u, pass := assets.InsertAdminUser(db)
+
+	//2. Create project
+	proj := assets.InsertTestProject(t, db, sdk.RandomString(10), sdk.RandomString(10), nil)
+	test.NotNil(t, proj)
+
+	uri := router.getRoute("POST", importProjectHandler, map[string]string{"permProjectKey": proj.Key})
+	test.NotEmpty(t, uri)
+
+	doImport := func(payload string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest("POST", uri+"?format=yaml", strings.NewReader(payload))
+		test.NoError(t, err)
+		assets.AuthentifyRequest(t, req, u, pass)
+		w := httptest.NewRecorder()
+		router.mux.ServeHTTP(w, req)
+		return w
+	}
+
+	//3. A failing application rolls back the whole import
+	w := doImport(`
+environments:
+- name: staging
+pipelines:
+- name: build
+applications:
+- name: app1
+  pipelines:
+    unknown: {}
+`)
+	assert.NotEqual(t, 200, w.Code)
+
+	exist, err := environment.Exists(db, proj.Key, "staging")
+	test.NoError(t, err)
+	assert.False(t, exist)
+
+	//4. Import the environment, the pipeline and the application using them
+	w = doImport(`
+environments:
+- name: staging
+pipelines:
+- name: build
+applications:
+- name: app1
+  pipelines:
+    build:
+      options:
+      - environment: staging
+        notifications:
+          email:
+            on_success: change
+            on_failure: always
+`)
+	assert.Equal(t, 200, w.Code)
+
+	exist, err = environment.Exists(db, proj.Key, "staging")
+	test.NoError(t, err)
+	assert.True(t, exist)
+
+	exist, err = pipeline.ExistPipeline(db, proj.ID, "build")
+	test.NoError(t, err)
+	assert.True(t, exist)
+
+	exist, err = application.Exists(db, proj.Key, "app1")
+	test.NoError(t, err)
+	assert.True(t, exist)
+}
+
+func TestImportProjectHandlerImportHooks(t *testing.T) {
+	db := test.SetupPG(t)
+
+	router = newRouter(auth.TestLocalAuth(t), mux.NewRouter(), "/TestImportProjectHandlerImportHooks")
+	router.init()
+
+	recorder := &testImportHook{}
+	defer func(hooks []ImportHook) { importHooks = hooks }(importHooks)
+	importHooks = []ImportHook{recorder, namePrefixImportPolicy{prefixes: []string{"team-"}}}
+
+	u, pass := assets.InsertAdminUser(db)
+	proj := assets.InsertTestProject(t, db, sdk.RandomString(10), sdk.RandomString(10), nil)
+	test.NotNil(t, proj)
+
+	uri := router.getRoute("POST", importProjectHandler, map[string]string{"permProjectKey": proj.Key})
+	test.NotEmpty(t, uri)
+	doImport := func(payload string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest("POST", uri+"?format=yaml", strings.NewReader(payload))
+		test.NoError(t, err)
+		assets.AuthentifyRequest(t, req, u, pass)
+		w := httptest.NewRecorder()
+		router.mux.ServeHTTP(w, req)
+		return w
+	}
+
+	//The rejection of an application by the hooks rolls back the whole import
+	w := doImport("applications:\n- name: team-app1\n- name: app2\n")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "must start with one of team-")
+	exist, err := application.Exists(db, proj.Key, "team-app1")
+	test.NoError(t, err)
+	assert.False(t, exist)
+	assert.Nil(t, recorder.after)
+
+	w = doImport("applications:\n- name: team-app1\n")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotNil(t, recorder.after)
+}
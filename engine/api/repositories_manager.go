@@ -524,7 +524,7 @@ func addHookOnRepositoriesManagerHandler(w http.ResponseWriter, r *http.Request,
 	}
 	defer tx.Rollback()
 
-	if _, err := hook.CreateHook(tx, projectKey, rm, repoFullname, app, pipeline); err != nil {
+	if _, err := hook.CreateHook(tx, projectKey, rm, repoFullname, app, pipeline, sdk.HookBranches{}); err != nil {
 		return sdk.WrapError(err, "addHookOnRepositoriesManagerHandler> cannot create hook")
 	}
 
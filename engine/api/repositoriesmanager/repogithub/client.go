@@ -2,6 +2,7 @@ package repogithub
 
 import (
 	"bytes"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -519,6 +520,37 @@ func (g *GithubClient) Commit(repo, hash string) (sdk.VCSCommit, error) {
 	return commit, nil
 }
 
+// FileContent Get the content of a file at a ref. Only the file is downloaded, the submodules are not followed
+// https://developer.github.com/v3/repos/contents/#get-contents
+func (g *GithubClient) FileContent(repo, path, ref string) ([]byte, error) {
+	uri := "/repos/" + repo + "/contents/" + strings.TrimPrefix(path, "/") + "?ref=" + url.QueryEscape(ref)
+	status, body, _, err := g.get(uri, withoutETag)
+	if err != nil {
+		log.Warning("GithubClient.FileContent> Error %s", err)
+		return nil, err
+	}
+	if status == http.StatusNotFound {
+		return nil, sdk.NewError(sdk.ErrNotFound, fmt.Errorf("file %s not found at %s", path, ref))
+	}
+	if status >= 400 {
+		return nil, sdk.NewError(sdk.ErrRepoNotFound, ErrorAPI(body))
+	}
+
+	c := Content{}
+	if err := json.Unmarshal(body, &c); err != nil {
+		// The content of a directory is a list
+		return nil, fmt.Errorf("%s is not a file", path)
+	}
+	if c.Type != "file" {
+		return nil, fmt.Errorf("%s is a %s, not a file", path, c.Type)
+	}
+	// The content of the files larger than 1MB is not returned
+	if c.Encoding != "base64" {
+		return nil, fmt.Errorf("file %s is too large (%d bytes)", path, c.Size)
+	}
+	return base64.StdEncoding.DecodeString(c.Content)
+}
+
 //CreateHook is not implemented
 func (g *GithubClient) CreateHook(repo, url string) error {
 	return fmt.Errorf("Not yet implemented on github")
@@ -529,6 +561,11 @@ func (g *GithubClient) DeleteHook(repo, url string) error {
 	return fmt.Errorf("Not yet implemented on github")
 }
 
+//Hooks is not implemented
+func (g *GithubClient) Hooks(repo string) ([]sdk.VCSHook, error) {
+	return nil, fmt.Errorf("Not yet implemented on github")
+}
+
 // RateLimit Get your current rate limit status
 // https://developer.github.com/v3/rate_limit/#get-your-current-rate-limit-status
 func (g *GithubClient) RateLimit() error {
@@ -421,3 +421,14 @@ type RateLimit struct {
 func (r *RateLimit) String() string {
 	return fmt.Sprintf("Limit: %d - Remaining: %d - Reset: %d", r.Rate.Limit, r.Rate.Remaining, r.Rate.Reset)
 }
+
+// Content represents a file of a repository
+type Content struct {
+	Type     string `json:"type"`
+	Encoding string `json:"encoding"`
+	Size     int    `json:"size"`
+	Name     string `json:"name"`
+	Path     string `json:"path"`
+	Content  string `json:"content"`
+	Sha      string `json:"sha"`
+}
@@ -2,6 +2,7 @@ package repostash
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/url"
@@ -9,6 +10,7 @@ import (
 	"time"
 
 	"github.com/facebookgo/httpcontrol"
+	"github.com/go-stash/go-stash/oauth1"
 	"github.com/go-stash/go-stash/stash"
 	"github.com/mitchellh/mapstructure"
 
@@ -35,6 +37,23 @@ type StashClient struct {
 	disableSetStatus bool
 }
 
+//FileContent returns the content of a file at a ref. Only the file is browsed, the submodules are not followed
+func (s *StashClient) FileContent(repo, path, ref string) ([]byte, error) {
+	t := strings.Split(repo, "/")
+	if len(t) != 2 {
+		return nil, fmt.Errorf("fullname %s must be <project>/<slug>", repo)
+	}
+
+	content, err := s.client.Contents.Find(t[0], t[1], strings.TrimPrefix(path, "/")+"?at="+url.QueryEscape(ref))
+	if err == stash.ErrNotFound {
+		return nil, sdk.NewError(sdk.ErrNotFound, fmt.Errorf("file %s not found at %s", path, ref))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return []byte(content), nil
+}
+
 // Release not implemented on bitbucket
 func (s *StashClient) Release(repo string, tagName string, title string, releaseNote string) (*sdk.VCSRelease, error) {
 	return nil, fmt.Errorf("Stash do not provide release system")
@@ -336,6 +355,73 @@ func (s *StashClient) DeleteHook(repo, url string) error {
 	return nil
 }
 
+//Hooks returns the HTTP POST Hook of the repository in Stash. The hook has only one url, a disabled
+//or not configured hook is not returned.
+func (s *StashClient) Hooks(repo string) ([]sdk.VCSHook, error) {
+	t := strings.Split(repo, "/")
+	if len(t) != 2 {
+		return nil, fmt.Errorf("fullname %s must be <project>/<slug>", repo)
+	}
+	hookPath := fmt.Sprintf("/projects/%s/repos/%s/settings/hooks/%s", t[0], t[1], stashHookKey)
+	h := stash.Hook{}
+	settings := map[string]interface{}{}
+	if err := s.getJSON(hookPath, &h); err != nil {
+		return nil, err
+	}
+	if err := s.getJSON(hookPath+"/settings", &settings); err != nil {
+		return nil, err
+	}
+	url, _ := settings["url"].(string)
+	if !h.Enabled || url == "" {
+		return []sdk.VCSHook{}, nil
+	}
+	return []sdk.VCSHook{{URL: url, Enabled: h.Enabled}}, nil
+}
+
+//getJSON sends a GET request on the core API of Stash, signed as the requests of the stash client, and
+//decodes the response in v. The stash client has no call for the settings of a hook.
+func (s *StashClient) getJSON(path string, v interface{}) error {
+	uri, err := url.Parse(s.client.GetFullApiUrl("core") + path)
+	if err != nil {
+		return err
+	}
+	req := &http.Request{
+		URL:        uri,
+		Method:     http.MethodGet,
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Close:      true,
+		Header:     http.Header{},
+	}
+
+	consumer := oauth1.Consumer{
+		ConsumerKey:           s.client.ConsumerKey,
+		ConsumerSecret:        s.client.ConsumerSecret,
+		ConsumerPrivateKeyPem: s.client.ConsumerPrivateKeyPem,
+	}
+	if err := consumer.Sign(req, oauth1.NewAccessToken(s.client.AccessToken, s.client.TokenSecret, nil)); err != nil {
+		return err
+	}
+
+	resp, err := stash.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return json.NewDecoder(resp.Body).Decode(v)
+	case http.StatusUnauthorized:
+		return sdk.ErrNoReposManagerClientAuth
+	case http.StatusNotFound:
+		return stash.ErrNotFound
+	case http.StatusForbidden:
+		return stash.ErrForbidden
+	}
+	return fmt.Errorf("GET %s: %s", path, resp.Status)
+}
+
 //GetEvents is not implemented
 func (s *StashClient) GetEvents(repo string, dateRef time.Time) ([]interface{}, time.Duration, error) {
 	return nil, 0.0, fmt.Errorf("Not implemented on stash")
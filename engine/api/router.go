@@ -168,8 +168,8 @@ func (r *Router) Handle(uri string, handlers ...*HandlerConfig) {
 		req.Close = true
 		// Authorization
 		w.Header().Add("Access-Control-Allow-Origin", "*")
-		w.Header().Add("Access-Control-Allow-Methods", "GET,OPTIONS,PUT,POST,DELETE")
-		w.Header().Add("Access-Control-Allow-Headers", "Accept, Origin, Referer, User-Agent, Content-Type, Authorization, Session-Token, Last-Event-Id, If-Modified-Since, Content-Disposition")
+		w.Header().Add("Access-Control-Allow-Methods", "GET,OPTIONS,PUT,PATCH,POST,DELETE")
+		w.Header().Add("Access-Control-Allow-Headers", "Accept, Origin, Referer, User-Agent, Content-Type, Authorization, Session-Token, Last-Event-Id, If-Modified-Since, If-Match, Content-Disposition")
 		w.Header().Add("Access-Control-Expose-Headers", "Accept, Origin, Referer, User-Agent, Content-Type, Authorization, Session-Token, Last-Event-Id, ETag, Content-Disposition")
 		w.Header().Add("X-Api-Time", time.Now().Format(time.RFC3339))
 		w.Header().Add("ETag", fmt.Sprintf("%d", time.Now().Unix()))
@@ -309,7 +309,7 @@ func (r *Router) Handle(uri string, handlers ...*HandlerConfig) {
 			return
 		}
 
-		if req.Method == "POST" || req.Method == "PUT" || req.Method == "DELETE" {
+		if req.Method == "POST" || req.Method == "PUT" || req.Method == "PATCH" || req.Method == "DELETE" {
 			deleteUserPermissionCache(c)
 		}
 	}
@@ -370,6 +370,18 @@ func PUT(h Handler, cfg ...HandlerConfigParam) *HandlerConfig {
 	return rc
 }
 
+// PATCH will set given handler only for PATCH request
+func PATCH(h Handler, cfg ...HandlerConfigParam) *HandlerConfig {
+	rc := new(HandlerConfig)
+	rc.handler = h
+	rc.auth = true
+	rc.method = "PATCH"
+	for _, c := range cfg {
+		c(rc)
+	}
+	return rc
+}
+
 // DELETE will set given handler only for DELETE request
 func DELETE(h Handler, cfg ...HandlerConfigParam) *HandlerConfig {
 	rc := new(HandlerConfig)
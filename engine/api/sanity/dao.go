@@ -25,6 +25,7 @@ func processWarning(w *sdk.Warning, acceptedlanguage string) error {
 
 	// Set message value
 	w.Message = buffer.String()
+	w.Level = warningLevel(w.ID)
 	return nil
 }
 
@@ -1,5 +1,7 @@
 package sanity
 
+import "github.com/ovh/cds/sdk"
+
 // Warning unique identifiers
 const (
 	_ = iota
@@ -19,8 +21,25 @@ const (
 	EnvironmentVariableUsedInApplicationDoesNotExist
 	InvalidVariableFormatUsedInApplication
 	MissingEnvironment
+	HookGivenUp
 )
 
+// errorWarnings lists the warnings which prevent the entity from working, the other ones are only warnings
+var errorWarnings = map[int64]bool{
+	MultipleWorkerModelWarning:             true,
+	MultipleHostnameRequirement:            true,
+	InvalidVariableFormatUsedInApplication: true,
+	HookGivenUp:                            true,
+}
+
+// warningLevel returns the severity of a warning
+func warningLevel(id int64) sdk.MessageLevel {
+	if errorWarnings[id] {
+		return sdk.MessageLevelError
+	}
+	return sdk.MessageLevelWarning
+}
+
 var messageAmericanEnglish = map[int64]string{
 	MultipleWorkerModelWarning:                       `Action {{index . "ActionName"}}{{if index . "PipelineName"}} in pipeline {{index . "ProjectKey"}}/{{index . "PipelineName"}}{{end}} has multiple Worker Model as requirement. It will never start building.`,
 	NoWorkerModelMatchRequirement:                    `Action {{index . "ActionName"}}{{if index . "PipelineName"}} in pipeline {{index . "ProjectKey"}}/{{index . "PipelineName"}}{{end}}: No worker model matches all required binaries`,
@@ -37,4 +56,5 @@ var messageAmericanEnglish = map[int64]string{
 	GitURLWithoutKey:                                 `Action {{index . "ActionName"}}{{if index . "PipelineName"}} in pipeline {{index . "ProjectKey"}}/{{index . "PipelineName"}}{{end}} is used but no ssh key were found. Git clone will failed`,
 	MissingEnvironment:                               `Application {{index . "ApplicationName"}}: At least one environment with one variable should be defined`,
 	EnvironmentVariableUsedInApplicationDoesNotExist: `Application {{index . "ApplicationName"}}: Environment variable {{index . "VarName"}} used but doesn't exist in all environments`,
-	InvalidVariableFormatUsedInApplication:           `Application {{index . "ApplicationName"}}: Invalid variable format '{{index . "VarName"}}'`}
+	InvalidVariableFormatUsedInApplication:           `Application {{index . "ApplicationName"}}: Invalid variable format '{{index . "VarName"}}'`,
+	HookGivenUp:                                      `Application {{index . "ApplicationName"}}: Hook {{index . "HookLink"}} could not be {{if eq (index . "Action") "delete"}}deleted from{{else}}created on{{end}} repository {{index . "RepoFullname"}}: {{index . "Error"}}`}
@@ -11,28 +11,52 @@ import (
 
 // CheckApplication checks all application variables
 func CheckApplication(db gorp.SqlExecutor, proj *sdk.Project, app *sdk.Application) error {
-	warChan := make(chan []sdk.Warning)
-	done := make(chan bool)
-
 	if err := DeleteAllApplicationWarnings(db, proj.ID, app.ID); err != nil {
 		return err
 	}
 
+	ws := computeApplicationWarnings(proj, app)
+	log.Debug("CheckApplication> Inserting warnings %v", ws)
+	for _, w := range ws {
+		w.Application.ID = app.ID
+		if w.Pipeline.ID == 0 && w.Action.ID == 0 {
+			if err := InsertApplicationWarning(db, proj.ID, app.ID, &w); err != nil {
+				log.Warning("CheckApplication> Error inserting warnings %s", err)
+			}
+		}
+	}
+
+	// The warnings of the hooks given up on the repositories manager are kept
+	if app.RepositoryFullname != "" {
+		if err := CheckRepositoryHooks(db, proj.Key, app.RepositoryFullname); err != nil {
+			log.Warning("CheckApplication> Error checking hooks of %s: %s", app.RepositoryFullname, err)
+		}
+	}
+
+	return nil
+}
+
+// ApplicationWarnings returns the warnings CheckApplication would insert, with their message
+// computed for the accepted language
+func ApplicationWarnings(proj *sdk.Project, app *sdk.Application, al string) ([]sdk.Warning, error) {
+	ws := computeApplicationWarnings(proj, app)
+	for i := range ws {
+		if err := processWarning(&ws[i], al); err != nil {
+			return nil, err
+		}
+	}
+	return ws, nil
+}
+
+func computeApplicationWarnings(proj *sdk.Project, app *sdk.Application) []sdk.Warning {
+	warChan := make(chan []sdk.Warning)
+	done := make(chan bool)
+	warnings := []sdk.Warning{}
+
 	go func() {
 		for {
 			ws, ok := <-warChan
-			if ws != nil {
-				log.Debug("CheckApplication> Inserting warnings %v", ws)
-
-				for _, w := range ws {
-					w.Application.ID = app.ID
-					if w.Pipeline.ID == 0 && w.Action.ID == 0 {
-						if err := InsertApplicationWarning(db, proj.ID, app.ID, &w); err != nil {
-							log.Warning("CheckApplication> Error inserting warnings %s", err)
-						}
-					}
-				}
-			}
+			warnings = append(warnings, ws...)
 			if !ok {
 				done <- true
 				return
@@ -57,7 +81,7 @@ func CheckApplication(db gorp.SqlExecutor, proj *sdk.Project, app *sdk.Applicati
 	close(warChan)
 	<-done
 
-	return nil
+	return warnings
 }
 
 func checkApplicationVariable(project *sdk.Project, app *sdk.Application, variable *sdk.Variable) ([]sdk.Warning, error) {
@@ -114,3 +114,24 @@ func Test_checkApplicationVariable(t *testing.T) {
 		test.EqualValuesWithoutOrder(t, tt.wantMsg, gotMsg, "%q. checkApplicationVariable() = %v, want %v", tt.name, gotMsg, tt.wantMsg)
 	}
 }
+
+func TestApplicationWarningsLevel(t *testing.T) {
+	app := &sdk.Application{
+		Name: "MyApp",
+		Variable: []sdk.Variable{
+			{Name: "bad", Value: "{{cds.env.blabla}}"},
+			{Name: "env", Value: "{{.cds.env.blabla}}"},
+		},
+	}
+	ws, err := ApplicationWarnings(&sdk.Project{}, app, "en-US")
+	test.NoError(t, err)
+
+	levels := map[int64]sdk.MessageLevel{}
+	for _, w := range ws {
+		levels[w.ID] = w.Level
+	}
+	test.Equal(t, map[int64]sdk.MessageLevel{
+		InvalidVariableFormatUsedInApplication: sdk.MessageLevelError,
+		MissingEnvironment:                     sdk.MessageLevelWarning,
+	}, levels)
+}
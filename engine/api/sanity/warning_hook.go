@@ -0,0 +1,58 @@
+package sanity
+
+import (
+	"github.com/go-gorp/gorp"
+
+	"github.com/ovh/cds/engine/api/hook"
+	"github.com/ovh/cds/sdk"
+)
+
+// CheckRepositoryHooks stores a warning on the applications of the repository for each hook whose creation
+// or deletion on the repositories manager has been given up
+func CheckRepositoryHooks(db gorp.SqlExecutor, projectKey, repoFullName string) error {
+	hooks, err := hook.LoadGivenUpHooks(db, projectKey, repoFullName)
+	if err != nil {
+		return err
+	}
+
+	query := `SELECT application.id, application.project_id, application.name
+		FROM application
+		JOIN project ON project.id = application.project_id
+		WHERE project.projectkey = $1 AND application.repo_fullname = $2`
+	rows, err := db.Query(query, projectKey, repoFullName)
+	if err != nil {
+		return err
+	}
+	apps := []sdk.Application{}
+	for rows.Next() {
+		var app sdk.Application
+		if err := rows.Scan(&app.ID, &app.ProjectID, &app.Name); err != nil {
+			rows.Close()
+			return err
+		}
+		apps = append(apps, app)
+	}
+	rows.Close()
+
+	for _, app := range apps {
+		if _, err := db.Exec(`DELETE FROM warning WHERE app_id = $1 AND warning_id = $2`, app.ID, HookGivenUp); err != nil {
+			return err
+		}
+		for _, h := range hooks {
+			w := sdk.Warning{
+				ID: HookGivenUp,
+				MessageParam: map[string]string{
+					"ApplicationName": app.Name,
+					"HookLink":        h.Link,
+					"Action":          h.Action,
+					"RepoFullname":    repoFullName,
+					"Error":           h.Error,
+				},
+			}
+			if err := InsertApplicationWarning(db, app.ProjectID, app.ID, &w); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -71,9 +71,12 @@ func ApplyTemplate(db *gorp.DbMap, proj *sdk.Project, opts sdk.ApplyTemplatesOpt
 		}
 	}(&msgList)
 
-	if err := application.Import(tx, proj, app, app.RepositoriesManager, user, msgChan); err != nil {
+	if err := application.Import(tx, proj, app, app.RepositoriesManager, false, user, msgChan); err != nil {
 		log.Warning("ApplyTemplate> error applying template : %s", err)
 		close(msgChan)
+		if _, ok := err.(*sdk.MultiError); ok {
+			return msgList, sdk.ErrImportResourcesFailed
+		}
 		return msgList, err
 	}
 
@@ -436,6 +436,47 @@ func LoadTriggerByApp(db gorp.SqlExecutor, appID int64) ([]sdk.PipelineTrigger,
 	return triggers, nil
 }
 
+// LoadTriggersByProject loads, without parameters and prerequisites, all the triggers from or to the applications of a project
+func LoadTriggersByProject(db gorp.SqlExecutor, projectKey string) ([]sdk.PipelineTrigger, error) {
+	query := `
+	SELECT pipeline_trigger.id,
+	src_application_id, src_app.name,
+	src_pipeline_id, src_pip.name, src_pip.type,
+	src_environment_id, src_env.name,
+	src_project.id, src_project.projectkey, src_project.name,
+	dest_application_id, dest_app.name,
+	dest_pipeline_id, dest_pip.name, dest_pip.type,
+	dest_environment_id, dest_env.name,
+	dest_project.id, dest_project.projectkey, dest_project.name,
+	manual
+	FROM pipeline_trigger
+	JOIN pipeline as src_pip ON src_pip.id = src_pipeline_id
+	JOIN application AS src_app ON src_app.id = src_application_id
+	JOIN project AS src_project ON src_project.id = src_app.project_id
+	JOIN pipeline as dest_pip ON dest_pip.id = dest_pipeline_id
+	JOIN application AS dest_app ON dest_app.id = dest_application_id
+	JOIN project AS dest_project ON dest_project.id = dest_app.project_id
+	LEFT JOIN environment AS src_env ON src_env.id = src_environment_id
+	LEFT JOIN environment AS dest_env ON dest_env.id = dest_environment_id
+	WHERE src_project.projectkey = $1 OR dest_project.projectkey = $1
+	`
+	rows, err := db.Query(query, projectKey)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	triggers := []sdk.PipelineTrigger{}
+	for rows.Next() {
+		t, err := loadTrigger(db, rows, false)
+		if err != nil {
+			return nil, err
+		}
+		triggers = append(triggers, t)
+	}
+	return triggers, nil
+}
+
 // LoadTrigger load the given trigger
 func LoadTrigger(db gorp.SqlExecutor, triggerID int64) (*sdk.PipelineTrigger, error) {
 	query := `
@@ -0,0 +1,9 @@
+-- +migrate Up
+ALTER TABLE pipeline ADD COLUMN slug VARCHAR(256);
+UPDATE pipeline SET slug = name;
+ALTER TABLE pipeline ALTER COLUMN slug SET NOT NULL;
+ALTER TABLE pipeline ADD CONSTRAINT "UNIQ_PIPELINE_PROJECT_SLUG" UNIQUE (project_id, slug);
+
+-- +migrate Down
+ALTER TABLE pipeline DROP CONSTRAINT "UNIQ_PIPELINE_PROJECT_SLUG";
+ALTER TABLE pipeline DROP COLUMN slug;
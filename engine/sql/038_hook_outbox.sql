@@ -0,0 +1,17 @@
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS "hook_outbox" (
+    id BIGSERIAL PRIMARY KEY,
+    hook_id BIGINT NOT NULL,
+    project_key VARCHAR(256) NOT NULL,
+    repositories_manager VARCHAR(256) NOT NULL,
+    repo_fullname VARCHAR(256) NOT NULL,
+    link TEXT NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT,
+    creation_date TIMESTAMP WITH TIME ZONE DEFAULT LOCALTIMESTAMP
+);
+SELECT create_foreign_key_idx_cascade('FK_HOOK_OUTBOX_HOOK', 'hook_outbox', 'hook', 'hook_id', 'id');
+SELECT create_unique_index('hook_outbox', 'IDX_HOOK_OUTBOX_HOOK_ID', 'hook_id');
+
+-- +migrate Down
+DROP TABLE hook_outbox;
@@ -0,0 +1,16 @@
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS "import_audit" (
+    id BIGSERIAL PRIMARY KEY,
+    project_key VARCHAR(256) NOT NULL,
+    application_name VARCHAR(256) NOT NULL,
+    author VARCHAR(256) NOT NULL,
+    format VARCHAR(16) NOT NULL,
+    force_update BOOLEAN NOT NULL DEFAULT false,
+    payload_hash VARCHAR(64) NOT NULL,
+    summary JSONB,
+    creation_date TIMESTAMP WITH TIME ZONE DEFAULT LOCALTIMESTAMP
+);
+SELECT create_index('import_audit', 'IDX_IMPORT_AUDIT_PROJECT_KEY', 'project_key');
+
+-- +migrate Down
+DROP TABLE import_audit;
@@ -0,0 +1,8 @@
+-- +migrate Up
+ALTER TABLE hook_outbox ADD COLUMN action VARCHAR(16) NOT NULL DEFAULT 'create';
+ALTER TABLE hook_outbox ALTER COLUMN hook_id DROP NOT NULL;
+
+-- +migrate Down
+DELETE FROM hook_outbox WHERE hook_id IS NULL;
+ALTER TABLE hook_outbox ALTER COLUMN hook_id SET NOT NULL;
+ALTER TABLE hook_outbox DROP COLUMN action;
@@ -0,0 +1,14 @@
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS "import_template" (
+    id BIGSERIAL PRIMARY KEY,
+    project_key VARCHAR(256) NOT NULL,
+    name VARCHAR(256) NOT NULL,
+    format VARCHAR(16) NOT NULL,
+    content TEXT NOT NULL,
+    parameters JSONB,
+    creation_date TIMESTAMP WITH TIME ZONE DEFAULT LOCALTIMESTAMP
+);
+SELECT create_unique_index('import_template', 'IDX_IMPORT_TEMPLATE_PROJECT_KEY_NAME', 'project_key,name');
+
+-- +migrate Down
+DROP TABLE import_template;
@@ -0,0 +1,5 @@
+-- +migrate Up
+ALTER TABLE import_audit ADD COLUMN payload BYTEA;
+
+-- +migrate Down
+ALTER TABLE import_audit DROP COLUMN payload;
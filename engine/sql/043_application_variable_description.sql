@@ -0,0 +1,5 @@
+-- +migrate Up
+ALTER TABLE application_variable ADD COLUMN var_description TEXT NOT NULL DEFAULT '';
+
+-- +migrate Down
+ALTER TABLE application_variable DROP COLUMN var_description;
@@ -0,0 +1,7 @@
+-- +migrate Up
+ALTER TABLE application ADD COLUMN retention_runs INT NOT NULL DEFAULT 0;
+ALTER TABLE application ADD COLUMN retention_days INT NOT NULL DEFAULT 0;
+
+-- +migrate Down
+ALTER TABLE application DROP COLUMN retention_runs;
+ALTER TABLE application DROP COLUMN retention_days;
@@ -0,0 +1,5 @@
+-- +migrate Up
+ALTER TABLE application ADD COLUMN import_generation BIGINT NOT NULL DEFAULT 0;
+
+-- +migrate Down
+ALTER TABLE application DROP COLUMN import_generation;
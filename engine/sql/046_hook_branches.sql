@@ -0,0 +1,5 @@
+-- +migrate Up
+ALTER TABLE hook ADD COLUMN branches TEXT NOT NULL DEFAULT '{}';
+
+-- +migrate Down
+ALTER TABLE hook DROP COLUMN branches;
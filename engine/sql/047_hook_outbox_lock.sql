@@ -0,0 +1,5 @@
+-- +migrate Up
+ALTER TABLE hook_outbox ADD COLUMN locked_until TIMESTAMP WITH TIME ZONE;
+
+-- +migrate Down
+ALTER TABLE hook_outbox DROP COLUMN locked_until;
@@ -36,6 +36,9 @@ type Application struct {
 	Schedulers          []PipelineScheduler   `json:"schedulers,omitempty" db:"-"`
 	Metadata            Metadata              `json:"metadata" yaml:"metadata" db:"-"`
 	Keys                []ApplicationKey      `json:"keys" yaml:"keys" db:"-"`
+	RetentionRuns       int                   `json:"retention_runs" db:"retention_runs"`
+	RetentionDays       int                   `json:"retention_days" db:"retention_days"`
+	Generation          int                   `json:"generation" db:"import_generation"`
 }
 
 // ApplicationVariableAudit represents an audit on an application variable
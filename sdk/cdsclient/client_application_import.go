@@ -0,0 +1,158 @@
+package cdsclient
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/ovh/cds/sdk"
+	"github.com/ovh/cds/sdk/exportentities"
+)
+
+// ImportOptions are the options of an import
+type ImportOptions struct {
+	// ForceUpdate updates the entity if it already exists
+	ForceUpdate bool
+	// Language is the Accept-Language of the returned messages
+	Language string
+	// Strict rejects the content if it has unknown fields, which are ignored otherwise. It is recommended
+	// in CI, so that a typo fails the import instead of being dropped.
+	Strict bool
+	// FailOnSanityError rejects the application if the sanity checks find errors, which are only
+	// returned as messages otherwise
+	FailOnSanityError bool
+	// Verify reloads the created hooks, pollers and notifications after the import, and returns an
+	// error message for each of them which is not found
+	Verify bool
+	// Disabled creates the hooks and pollers disabled, they don't trigger the pipelines until the
+	// application is enabled
+	Disabled bool
+	// RespectGeneration only updates the existing application if the generation of the content is greater
+	// than its stored generation, the import fails with a conflict otherwise
+	RespectGeneration bool
+	// SkipHooks imports the application without creating nor deleting its hooks and pollers, when the
+	// repositories manager can't be reached
+	SkipHooks bool
+	// StopOnError skips the following lines of a batch once a line fails
+	StopOnError bool
+}
+
+//query returns the options of an import as query parameters
+func (opts ImportOptions) query() url.Values {
+	q := url.Values{}
+	if opts.ForceUpdate {
+		q.Set("forceUpdate", "true")
+	}
+	if opts.Strict {
+		q.Set("strict", "true")
+	}
+	if opts.FailOnSanityError {
+		q.Set("failOnSanityError", "true")
+	}
+	if opts.Verify {
+		q.Set("verify", "true")
+	}
+	if opts.Disabled {
+		q.Set("disabled", "true")
+	}
+	if opts.RespectGeneration {
+		q.Set("respectGeneration", "true")
+	}
+	if opts.SkipHooks {
+		q.Set("skipHooks", "true")
+	}
+	return q
+}
+
+func (c *client) ApplicationImport(projectKey string, content []byte, format string, opts ImportOptions) ([]sdk.Message, error) {
+	if _, err := exportentities.GetFormat(format); err != nil {
+		return nil, err
+	}
+
+	q := opts.query()
+	q.Set("format", format)
+	q.Set("messageFormat", "structured")
+
+	mods := []RequestModifier{}
+	if opts.Language != "" {
+		mods = append(mods, SetHeader("Accept-Language", opts.Language))
+	}
+
+	body, code, err := c.Request(http.MethodPost, "/project/"+projectKey+"/import/application?"+q.Encode(), content, mods...)
+	if err != nil {
+		return nil, err
+	}
+
+	res := sdk.ImportResult{}
+	if err := json.Unmarshal(body, &res); err != nil {
+		return nil, fmt.Errorf("HTTP Code %d: %s", code, err)
+	}
+
+	msgs := make([]sdk.Message, 0, len(res.Messages))
+	var errMsg string
+	for _, sm := range res.Messages {
+		if m, ok := sdk.Messages[sm.ID]; ok {
+			msgs = append(msgs, sdk.NewMessage(m, sm.Args...))
+		} else if sm.Level == sdk.MessageLevelError {
+			errMsg = sm.Message
+		}
+	}
+
+	if code != 200 {
+		if errMsg != "" {
+			return msgs, fmt.Errorf("HTTP Code %d: %s", code, errMsg)
+		}
+		return msgs, fmt.Errorf("HTTP Code %d", code)
+	}
+	return msgs, nil
+}
+
+func (c *client) ApplicationImportBatch(projectKey string, content []byte, opts ImportOptions) (*sdk.ImportBatchResult, error) {
+	q := opts.query()
+	if opts.StopOnError {
+		q.Set("stopOnError", "true")
+	}
+
+	mods := []RequestModifier{SetHeader("Content-Type", "application/x-ndjson")}
+	if opts.Language != "" {
+		mods = append(mods, SetHeader("Accept-Language", opts.Language))
+	}
+
+	body, code, err := c.Request(http.MethodPost, "/project/"+projectKey+"/import/application/batch?"+q.Encode(), content, mods...)
+	if err != nil {
+		return nil, err
+	}
+	if code != 200 {
+		return nil, fmt.Errorf("HTTP Code %d", code)
+	}
+
+	res := &sdk.ImportBatchResult{}
+	if err := json.Unmarshal(body, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (c *client) ApplicationExport(projectKey string, appName string, format string) ([]byte, error) {
+	f, err := exportentities.GetFormat(format)
+	if err != nil {
+		return nil, err
+	}
+
+	withOptions := func(req *http.Request) {
+		q := req.URL.Query()
+		for _, o := range []string{"withHooks", "withNotifs", "withPollers", "withTriggers", "withSchedulers", "withKeys"} {
+			q.Set(o, "true")
+		}
+		req.URL.RawQuery = q.Encode()
+	}
+
+	app, err := c.ApplicationGet(projectKey, appName, withOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	return exportentities.Marshal(exportentities.NewApplication(app), f)
+}
+
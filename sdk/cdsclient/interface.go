@@ -16,6 +16,9 @@ type Interface interface {
 	ApplicationDelete(string, string) error
 	ApplicationGet(string, string, ...RequestModifier) (*sdk.Application, error)
 	ApplicationList(string) ([]sdk.Application, error)
+	ApplicationImport(string, []byte, string, ImportOptions) ([]sdk.Message, error)
+	ApplicationImportBatch(string, []byte, ImportOptions) (*sdk.ImportBatchResult, error)
+	ApplicationExport(string, string, string) ([]byte, error)
 	ApplicationKeysList(string, string) ([]sdk.ApplicationKey, error)
 	ApplicationKeyCreate(string, string, *sdk.ApplicationKey) error
 	ApplicationKeysDelete(string, string, string) error
@@ -116,6 +116,17 @@ var (
 	ErrParameterNotExists                    = &Error{ID: 100, Status: http.StatusNotFound}
 	ErrUnknownKeyType                        = &Error{ID: 101, Status: http.StatusBadRequest}
 	ErrInvalidKeyPattern                     = &Error{ID: 102, Status: http.StatusBadRequest}
+	ErrPreconditionFailed                    = &Error{ID: 103, Status: http.StatusPreconditionFailed}
+	ErrImportResourcesFailed                 = &Error{ID: 104, Status: http.StatusBadRequest}
+	ErrTooManyRequests                       = &Error{ID: 105, Status: http.StatusTooManyRequests}
+	ErrImportPayloadExpired                  = &Error{ID: 106, Status: http.StatusGone}
+	ErrSanityCheckFailed                     = &Error{ID: 107, Status: http.StatusBadRequest}
+	ErrImportParse                           = &Error{ID: 108, Status: http.StatusBadRequest}
+	ErrImportUnknownFormat                   = &Error{ID: 109, Status: http.StatusUnsupportedMediaType}
+	ErrImportPayloadTooLarge                 = &Error{ID: 110, Status: http.StatusRequestEntityTooLarge}
+	ErrImportGroupNotFound                   = &Error{ID: 111, Status: http.StatusBadRequest}
+	ErrImportGenerationConflict              = &Error{ID: 112, Status: http.StatusConflict}
+	ErrImportInProgress                      = &Error{ID: 113, Status: http.StatusConflict}
 )
 
 var errorsAmericanEnglish = map[int]string{
@@ -221,6 +232,17 @@ var errorsAmericanEnglish = map[int]string{
 	ErrParameterNotExists.ID:                    "This parameter doesn't exist",
 	ErrUnknownKeyType.ID:                        "Unknown key type",
 	ErrInvalidKeyPattern.ID:                     "key name must respect the following pattern: '^[a-zA-Z0-9.-_-]{1,}$'",
+	ErrPreconditionFailed.ID:                    "the resource has been modified since it has been read",
+	ErrImportResourcesFailed.ID:                 "some resources of the import can't be created",
+	ErrTooManyRequests.ID:                       "too many requests, retry later",
+	ErrImportPayloadExpired.ID:                  "the payload of this import is no longer kept",
+	ErrSanityCheckFailed.ID:                     "sanity checks found errors",
+	ErrImportParse.ID:                           "the imported payload can't be parsed",
+	ErrImportUnknownFormat.ID:                   "the format of the imported payload is not supported",
+	ErrImportPayloadTooLarge.ID:                 "the imported payload is too large",
+	ErrImportGroupNotFound.ID:                   "a group of the imported permissions does not exist",
+	ErrImportGenerationConflict.ID:              "the stored generation is greater than or equal to the imported one",
+	ErrImportInProgress.ID:                      "an import of this application is in progress, retry later",
 }
 
 var errorsFrench = map[int]string{
@@ -326,6 +348,17 @@ var errorsFrench = map[int]string{
 	ErrParameterNotExists.ID:                    "Ce paramètre n'existe pas",
 	ErrUnknownKeyType.ID:                        "Le type de clé n'est pas connu",
 	ErrInvalidKeyPattern.ID:                     "le nom de la clé doit respecter le pattern suivant; '^[a-zA-Z0-9.-_-]{1,}$'",
+	ErrPreconditionFailed.ID:                    "la ressource a été modifiée depuis sa lecture",
+	ErrImportResourcesFailed.ID:                 "certaines ressources de l'import ne peuvent pas être créées",
+	ErrTooManyRequests.ID:                       "trop de requêtes, réessayez plus tard",
+	ErrImportPayloadExpired.ID:                  "le contenu de cet import n'est plus conservé",
+	ErrSanityCheckFailed.ID:                     "les vérifications de cohérence ont trouvé des erreurs",
+	ErrImportParse.ID:                           "le contenu importé ne peut pas être lu",
+	ErrImportUnknownFormat.ID:                   "le format du contenu importé n'est pas supporté",
+	ErrImportPayloadTooLarge.ID:                 "le contenu importé est trop volumineux",
+	ErrImportGroupNotFound.ID:                   "un groupe des permissions importées n'existe pas",
+	ErrImportGenerationConflict.ID:              "la génération enregistrée est supérieure ou égale à celle importée",
+	ErrImportInProgress.ID:                      "un import de cette application est en cours, réessayez plus tard",
 }
 
 var errorsLanguages = []map[int]string{
@@ -353,14 +386,8 @@ func ProcessError(target error, al string) (string, int) {
 	if !ok {
 		return errorsAmericanEnglish[ErrUnknownError.ID], ErrUnknownError.Status
 	}
-	acceptedLanguages, _, err := language.ParseAcceptLanguage(al)
-	if err != nil {
-		return errorsAmericanEnglish[ErrUnknownError.ID], ErrUnknownError.Status
-	}
-
-	tag, _, _ := matcher.Match(acceptedLanguages...)
 	var msg string
-	switch tag {
+	switch MatchLanguage(al) {
 	case language.French:
 		msg, ok = errorsFrench[cdsErr.ID]
 		break
@@ -1,6 +1,11 @@
 package exportentities
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
 	"text/template"
 
 	"github.com/ovh/cds/sdk"
@@ -8,59 +13,166 @@ import (
 
 // Application represents exported sdk.Application
 type Application struct {
-	Name              string                         `json:"name" yaml:"name"`
-	RepositoryManager string                         `json:"repo_manager,omitempty" yaml:"repo_manager,omitempty"`
-	RepositoryName    string                         `json:"repo_name,omitempty" yaml:"repo_name,omitempty"`
-	Permissions       map[string]int                 `json:"permissions,omitempty" yaml:"permissions,omitempty"`
-	Variables         map[string]VariableValue       `json:"variables,omitempty" yaml:"variables,omitempty"`
-	Pipelines         map[string]ApplicationPipeline `json:"pipelines,omitempty" yaml:"pipelines,omitempty"`
+	Name              string                         `json:"name" yaml:"name" toml:"name"`
+	Description       string                         `json:"description,omitempty" yaml:"description,omitempty" toml:"description,omitempty"`
+	Generation        int                            `json:"generation,omitempty" yaml:"generation,omitempty" toml:"generation,omitempty"`
+	RepositoryManager string                         `json:"repo_manager,omitempty" yaml:"repo_manager,omitempty" toml:"repo_manager,omitempty"`
+	RepositoryName    string                         `json:"repo_name,omitempty" yaml:"repo_name,omitempty" toml:"repo_name,omitempty"`
+	Permissions       map[string]int                 `json:"permissions,omitempty" yaml:"permissions,omitempty" toml:"permissions,omitempty"`
+	Variables         map[string]VariableValue       `json:"variables,omitempty" yaml:"variables,omitempty" toml:"variables,omitempty"`
+	Pipelines         map[string]ApplicationPipeline `json:"pipelines,omitempty" yaml:"pipelines,omitempty" toml:"pipelines,omitempty"`
+	Keys              map[string]ApplicationKey      `json:"keys,omitempty" yaml:"keys,omitempty" toml:"keys,omitempty"`
+	Metadata          map[string]string              `json:"metadata,omitempty" yaml:"metadata,omitempty" toml:"metadata,omitempty"`
+	Retention         *ApplicationRetention          `json:"retention,omitempty" yaml:"retention,omitempty" toml:"retention,omitempty"`
+	// Notifications are set on all the pipelines of the application, on each of their environments
+	Notifications map[string]ApplicationPipelineNotification `json:"notifications,omitempty" yaml:"notifications,omitempty" toml:"notifications,omitempty"`
 }
 
-// ApplicationPipeline represents exported sdk.ApplicationPipeline
+// NotificationExpansion is a notification of an application set on a pipeline and an environment
+type NotificationExpansion struct {
+	Type        string
+	Pipeline    string
+	Environment string
+}
+
+// ApplicationRetention is the retention of the runs of an application: the number of last runs kept, and the
+// number of days the runs are kept. A zero value is not set.
+type ApplicationRetention struct {
+	KeepRuns int `json:"keep_runs,omitempty" yaml:"keep_runs,omitempty" toml:"keep_runs,omitempty" hcl:"keep_runs"`
+	KeepDays int `json:"keep_days,omitempty" yaml:"keep_days,omitempty" toml:"keep_days,omitempty" hcl:"keep_days"`
+}
+
+// ApplicationKey represents exported sdk.ApplicationKey. Only the type of the key is exported,
+// the public and private parts are generated on import.
+type ApplicationKey struct {
+	Type string `json:"type" yaml:"type" toml:"type"`
+}
+
+// ApplicationTemplateRef is an imported application which references an import template of the project.
+// The template is expanded with the parameters, and the application it describes is imported.
+type ApplicationTemplateRef struct {
+	Template   string            `json:"template" yaml:"template" toml:"template"`
+	Parameters map[string]string `json:"parameters,omitempty" yaml:"parameters,omitempty" toml:"parameters,omitempty"`
+}
+
+// ApplicationPipeline represents exported sdk.ApplicationPipeline. The pipeline is referenced by its slug
+// if ref is set, else by its name.
 type ApplicationPipeline struct {
-	Parameters map[string]VariableValue              `json:"parameters,omitempty" yaml:"parameters,omitempty"`
-	Triggers   map[string]ApplicationPipelineTrigger `json:"triggers,omitempty" yaml:"triggers,omitempty"`
-	Options    []ApplicationPipelineOptions          `json:"options,omitempty" yaml:"options,omitempty"`
+	Ref        string                                `json:"ref,omitempty" yaml:"ref,omitempty" toml:"ref,omitempty"`
+	Definition *Pipeline                             `json:"definition,omitempty" yaml:"definition,omitempty" toml:"definition,omitempty"`
+	Parameters map[string]VariableValue              `json:"parameters,omitempty" yaml:"parameters,omitempty" toml:"parameters,omitempty"`
+	Triggers   map[string]ApplicationPipelineTrigger `json:"triggers,omitempty" yaml:"triggers,omitempty" toml:"triggers,omitempty"`
+	Options    []ApplicationPipelineOptions          `json:"options,omitempty" yaml:"options,omitempty" toml:"options,omitempty"`
 }
 
 // ApplicationPipelineOptions represents presence of hooks, pollers, notifications and scheduler for an tuple application pipeline environment
 type ApplicationPipelineOptions struct {
-	Environment   *string                                    `json:"environment,omitempty" yaml:"environment,omitempty"`
-	Hook          *bool                                      `json:"hook,omitempty" yaml:"hook,omitempty"`
-	Polling       *bool                                      `json:"polling,omitempty" yaml:"polling,omitempty"`
-	Notifications map[string]ApplicationPipelineNotification `json:"notifications,omitempty" yaml:"notifications,omitempty"`
-	Schedulers    []ApplicationPipelineScheduler             `json:"schedulers,omitempty" yaml:"schedulers,omitempty"`
+	Environment   *string                                    `json:"environment,omitempty" yaml:"environment,omitempty" toml:"environment,omitempty"`
+	Hook          *bool                                      `json:"hook,omitempty" yaml:"hook,omitempty" toml:"hook,omitempty"`
+	Polling       *bool                                      `json:"polling,omitempty" yaml:"polling,omitempty" toml:"polling,omitempty"`
+	Notifications map[string]ApplicationPipelineNotification `json:"notifications,omitempty" yaml:"notifications,omitempty" toml:"notifications,omitempty"`
+	Schedulers    []ApplicationPipelineScheduler             `json:"schedulers,omitempty" yaml:"schedulers,omitempty" toml:"schedulers,omitempty"`
+	// Disabled hooks and pollers are created but not triggered
+	Disabled bool `json:"disabled,omitempty" yaml:"disabled,omitempty" toml:"disabled,omitempty"`
+	// Branches and ExcludedBranches are the regular expressions of the branches triggering the hook
+	Branches         []string `json:"branches,omitempty" yaml:"branches,omitempty" toml:"branches,omitempty"`
+	ExcludedBranches []string `json:"excluded_branches,omitempty" yaml:"excluded_branches,omitempty" toml:"excluded_branches,omitempty" hcl:"excluded_branches"`
 }
 
 // ApplicationPipelineScheduler represents exported sdk.PipelineScheduler
 type ApplicationPipelineScheduler struct {
-	CronExpr   string                   `json:"cron_expr" yaml:"cron_expr"`
-	Parameters map[string]VariableValue `json:"parameters,omitempty" yaml:"parameters,omitempty"`
+	CronExpr   string                   `json:"cron_expr" yaml:"cron_expr" toml:"cron_expr"`
+	Parameters map[string]VariableValue `json:"parameters,omitempty" yaml:"parameters,omitempty" toml:"parameters,omitempty"`
 }
 
 // ApplicationPipelineNotification represents exported notification
-type ApplicationPipelineNotification sdk.UserNotificationSettings
+type ApplicationPipelineNotification struct {
+	OnSuccess    string                                   `json:"on_success" yaml:"on_success" toml:"on_success"`
+	OnFailure    string                                   `json:"on_failure" yaml:"on_failure" toml:"on_failure"`
+	OnStart      bool                                     `json:"on_start" yaml:"on_start" toml:"on_start"`
+	SendToGroups bool                                     `json:"send_to_groups" yaml:"send_to_groups" toml:"send_to_groups"`
+	SendToAuthor bool                                     `json:"send_to_author" yaml:"send_to_author" toml:"send_to_author"`
+	Recipients   []string                                 `json:"recipients,omitempty" yaml:"recipients,omitempty" toml:"recipients,omitempty"`
+	Template     *ApplicationPipelineNotificationTemplate `json:"template,omitempty" yaml:"template,omitempty" toml:"template,omitempty"`
+}
+
+// ApplicationPipelineNotificationTemplate represents exported sdk.UserNotificationTemplate
+type ApplicationPipelineNotificationTemplate struct {
+	Subject string `json:"subject,omitempty" yaml:"subject,omitempty" toml:"subject,omitempty"`
+	Body    string `json:"body,omitempty" yaml:"body,omitempty" toml:"body,omitempty"`
+}
+
+func newApplicationPipelineNotification(s sdk.UserNotificationSettings) ApplicationPipelineNotification {
+	n := ApplicationPipelineNotification{
+		OnSuccess: string(s.Success()),
+		OnFailure: string(s.Failure()),
+		OnStart:   s.Start(),
+	}
+	if je, ok := s.(*sdk.JabberEmailUserNotificationSettings); ok {
+		n.SendToGroups = je.SendToGroups
+		n.SendToAuthor = je.SendToAuthor
+		n.Recipients = je.Recipients
+		if je.Template.Subject != "" || je.Template.Body != "" {
+			n.Template = &ApplicationPipelineNotificationTemplate{
+				Subject: je.Template.Subject,
+				Body:    je.Template.Body,
+			}
+		}
+	}
+	return n
+}
+
+//Settings returns the sdk.UserNotificationSettings of the exported notification
+func (n ApplicationPipelineNotification) Settings() sdk.UserNotificationSettings {
+	s := &sdk.JabberEmailUserNotificationSettings{
+		OnSuccess:    sdk.UserNotificationEventType(n.OnSuccess),
+		OnFailure:    sdk.UserNotificationEventType(n.OnFailure),
+		OnStart:      n.OnStart,
+		SendToGroups: n.SendToGroups,
+		SendToAuthor: n.SendToAuthor,
+		Recipients:   n.Recipients,
+	}
+	if n.Template != nil {
+		s.Template = sdk.UserNotificationTemplate{
+			Subject: n.Template.Subject,
+			Body:    n.Template.Body,
+		}
+	}
+	return s
+}
+
+//JSON returns json as string
+func (n ApplicationPipelineNotification) JSON() string {
+	b, _ := json.Marshal(n)
+	return string(b)
+}
 
 // ApplicationPipelineTrigger represents an exported pipeline trigger
 type ApplicationPipelineTrigger struct {
-	ProjectKey      *string     `json:"project_key" yaml:"project_key"`
-	ApplicationName *string     `json:"application_name" yaml:"application_name"`
-	FromEnvironment *string     `json:"from_environment,omitempty" yaml:"from_environment,omitempty"`
-	ToEnvironment   *string     `json:"to_environment,omitempty" yaml:"to_environment,omitempty"`
-	Manual          bool        `json:"manual" yaml:"manual"`
-	Conditions      []Condition `json:"conditions,omitempty" yaml:"conditions,omitempty"`
+	Ref             string      `json:"ref,omitempty" yaml:"ref,omitempty" toml:"ref,omitempty"`
+	ProjectKey      *string     `json:"project_key" yaml:"project_key" toml:"project_key"`
+	ApplicationName *string     `json:"application_name" yaml:"application_name" toml:"application_name"`
+	FromEnvironment *string     `json:"from_environment,omitempty" yaml:"from_environment,omitempty" toml:"from_environment,omitempty"`
+	ToEnvironment   *string     `json:"to_environment,omitempty" yaml:"to_environment,omitempty" toml:"to_environment,omitempty"`
+	Manual          bool        `json:"manual" yaml:"manual" toml:"manual"`
+	Conditions      []Condition `json:"conditions,omitempty" yaml:"conditions,omitempty" toml:"conditions,omitempty"`
 }
 
 // Condition represents sdk.Prerequisite
 type Condition struct {
-	Variable string `json:"variable" yaml:"variable"`
-	Expected string `json:"expected" yaml:"expected"`
+	Variable string `json:"variable" yaml:"variable" toml:"variable"`
+	Expected string `json:"expected" yaml:"expected" toml:"expected"`
 }
 
 // NewApplication instanciance an exportable application from an sdk.Application
 func NewApplication(app *sdk.Application) (a *Application) {
 	a = new(Application)
 	a.Name = app.Name
+	a.Description = app.Description
+	a.Generation = app.Generation
+	if app.RetentionRuns > 0 || app.RetentionDays > 0 {
+		a.Retention = &ApplicationRetention{KeepRuns: app.RetentionRuns, KeepDays: app.RetentionDays}
+	}
 
 	if app.RepositoriesManager != nil {
 		a.RepositoryManager = app.RepositoriesManager.Name
@@ -70,8 +182,9 @@ func NewApplication(app *sdk.Application) (a *Application) {
 	a.Variables = make(map[string]VariableValue, len(app.Variable))
 	for _, v := range app.Variable {
 		a.Variables[v.Name] = VariableValue{
-			Type:  string(v.Type),
-			Value: v.Value,
+			Type:        string(v.Type),
+			Value:       v.Value,
+			Description: v.Description,
 		}
 	}
 	a.Permissions = make(map[string]int, len(app.ApplicationGroups))
@@ -79,15 +192,30 @@ func NewApplication(app *sdk.Application) (a *Application) {
 		a.Permissions[p.Group.Name] = p.Permission
 	}
 
+	if len(app.Keys) > 0 {
+		a.Keys = make(map[string]ApplicationKey, len(app.Keys))
+		for _, k := range app.Keys {
+			a.Keys[k.Name] = ApplicationKey{Type: k.Type}
+		}
+	}
+
+	if len(app.Metadata) > 0 {
+		a.Metadata = make(map[string]string, len(app.Metadata))
+		for k, v := range app.Metadata {
+			a.Metadata[k] = v
+		}
+	}
+
 	a.Pipelines = make(map[string]ApplicationPipeline, len(app.Pipelines))
 	for _, ap := range app.Pipelines {
-		pip := ApplicationPipeline{}
+		pip := ApplicationPipeline{Ref: ap.Pipeline.Slug}
 
 		pip.Parameters = make(map[string]VariableValue, len(ap.Parameters))
 		for _, param := range ap.Parameters {
 			pip.Parameters[param.Name] = VariableValue{
-				Type:  string(param.Type),
-				Value: param.Value,
+				Type:        string(param.Type),
+				Value:       param.Value,
+				Description: param.Description,
 			}
 		}
 
@@ -95,8 +223,7 @@ func NewApplication(app *sdk.Application) (a *Application) {
 		for _, t := range ap.Triggers {
 
 			c := make([]Condition, len(t.Prerequisites))
-			var i int
-			for _, pr := range t.Prerequisites {
+			for i, pr := range t.Prerequisites {
 				c[i] = Condition{
 					Variable: pr.Parameter,
 					Expected: pr.ExpectedValue,
@@ -117,6 +244,7 @@ func NewApplication(app *sdk.Application) (a *Application) {
 				appName = &t.DestApplication.Name
 			}
 			pip.Triggers[t.DestPipeline.Name] = ApplicationPipelineTrigger{
+				Ref:             t.DestPipeline.Slug,
 				ProjectKey:      pKey,
 				ApplicationName: appName,
 				ToEnvironment:   destEnv,
@@ -129,27 +257,33 @@ func NewApplication(app *sdk.Application) (a *Application) {
 		mapEnvOpts := map[string]*ApplicationPipelineOptions{}
 		//Hooks
 		for _, h := range app.Hooks {
-			if h.Enabled && h.Pipeline.Name == ap.Pipeline.Name {
+			if h.Pipeline.Name == ap.Pipeline.Name {
 				if _, ok := mapEnvOpts[sdk.DefaultEnv.Name]; !ok {
 					mapEnvOpts[sdk.DefaultEnv.Name] = &ApplicationPipelineOptions{}
 				}
 				o := mapEnvOpts[sdk.DefaultEnv.Name]
-				if h.Enabled {
-					var ok = true
-					o.Hook = &ok
+				var ok = true
+				o.Hook = &ok
+				if !h.Enabled {
+					o.Disabled = true
 				}
+				o.Branches = h.Branches.Include
+				o.ExcludedBranches = h.Branches.Exclude
 			}
 		}
 
 		//Pollers
 		for _, p := range app.RepositoryPollers {
-			if p.Enabled && p.Pipeline.Name == ap.Pipeline.Name {
+			if p.Pipeline.Name == ap.Pipeline.Name {
 				if _, ok := mapEnvOpts[sdk.DefaultEnv.Name]; !ok {
 					mapEnvOpts[sdk.DefaultEnv.Name] = &ApplicationPipelineOptions{}
 				}
 				o := mapEnvOpts[sdk.DefaultEnv.Name]
 				var ok = true
 				o.Polling = &ok
+				if !p.Enabled {
+					o.Disabled = true
+				}
 			}
 
 		}
@@ -165,7 +299,7 @@ func NewApplication(app *sdk.Application) (a *Application) {
 					if o.Notifications == nil {
 						o.Notifications = make(map[string]ApplicationPipelineNotification)
 					}
-					o.Notifications[string(t)] = n
+					o.Notifications[string(t)] = newApplicationPipelineNotification(n)
 				}
 			}
 		}
@@ -183,18 +317,29 @@ func NewApplication(app *sdk.Application) (a *Application) {
 				aps := ApplicationPipelineScheduler{
 					CronExpr: s.Crontab,
 				}
+				//The timezone is exported with the expression, UTC is the default one
+				if s.Timezone != "" && s.Timezone != "UTC" {
+					aps.CronExpr = sdk.CronTimezonePrefix + s.Timezone + " " + s.Crontab
+				}
 				aps.Parameters = make(map[string]VariableValue, len(s.Args))
 				for _, p := range s.Args {
-					aps.Parameters[p.Name] = VariableValue{Type: string(p.Type), Value: p.Value}
+					aps.Parameters[p.Name] = VariableValue{Type: string(p.Type), Value: p.Value, Description: p.Description}
 				}
 				o.Schedulers = append(o.Schedulers, aps)
 			}
 		}
 
-		//Compute all
+		//Compute all, sorted by environment to get a stable export
+		envNames := make([]string, 0, len(mapEnvOpts))
+		for k := range mapEnvOpts {
+			envNames = append(envNames, k)
+		}
+		sort.Strings(envNames)
+
 		pip.Options = make([]ApplicationPipelineOptions, len(mapEnvOpts))
 		var i int
-		for k, v := range mapEnvOpts {
+		for _, k := range envNames {
+			v := mapEnvOpts[k]
 			if k != sdk.DefaultEnv.Name {
 				s := k
 				pip.Options[i].Environment = &s
@@ -205,8 +350,20 @@ func NewApplication(app *sdk.Application) (a *Application) {
 			if v.Polling != nil {
 				pip.Options[i].Polling = v.Polling
 			}
+			pip.Options[i].Disabled = v.Disabled
+			pip.Options[i].Branches = v.Branches
+			pip.Options[i].ExcludedBranches = v.ExcludedBranches
 			pip.Options[i].Notifications = v.Notifications
 			pip.Options[i].Schedulers = v.Schedulers
+			sort.Slice(pip.Options[i].Schedulers, func(x, y int) bool {
+				sx, sy := pip.Options[i].Schedulers[x], pip.Options[i].Schedulers[y]
+				if sx.CronExpr != sy.CronExpr {
+					return sx.CronExpr < sy.CronExpr
+				}
+				px, _ := json.Marshal(sx.Parameters)
+				py, _ := json.Marshal(sy.Parameters)
+				return string(px) < string(py)
+			})
 
 			i++
 		}
@@ -217,12 +374,308 @@ func NewApplication(app *sdk.Application) (a *Application) {
 	return
 }
 
+//Checksum returns a hash of the exported application. Maps are marshalled with sorted keys,
+//so the checksum only changes when the application changes.
+func (a *Application) Checksum() (string, error) {
+	b, err := json.Marshal(a)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+//Application returns a sdk.Application entity. Pipelines, environments, groups
+//and repositories manager are only referenced by their names.
+func (a *Application) Application() (*sdk.Application, error) {
+	app := new(sdk.Application)
+	app.Name = a.Name
+	app.Description = a.Description
+	app.Generation = a.Generation
+	if a.Retention != nil {
+		app.RetentionRuns = a.Retention.KeepRuns
+		app.RetentionDays = a.Retention.KeepDays
+	}
+
+	if a.RepositoryManager != "" {
+		app.RepositoriesManager = &sdk.RepositoriesManager{Name: a.RepositoryManager}
+		app.RepositoryFullname = a.RepositoryName
+	}
+
+	app.Variable = make([]sdk.Variable, 0, len(a.Variables))
+	for _, k := range sortedVariableKeys(a.Variables) {
+		v := a.Variables[k]
+		app.Variable = append(app.Variable, sdk.Variable{
+			Name:        k,
+			Type:        v.Type,
+			Value:       v.Value,
+			Description: v.Description,
+		})
+	}
+
+	//If no permission is provided, let the application inherit from the project
+	if len(a.Permissions) > 0 {
+		groupNames := make([]string, 0, len(a.Permissions))
+		for k := range a.Permissions {
+			groupNames = append(groupNames, k)
+		}
+		sort.Strings(groupNames)
+		app.ApplicationGroups = make([]sdk.GroupPermission, len(groupNames))
+		for i, k := range groupNames {
+			app.ApplicationGroups[i] = sdk.GroupPermission{
+				Group:      sdk.Group{Name: k},
+				Permission: a.Permissions[k],
+			}
+		}
+	}
+
+	if len(a.Metadata) > 0 {
+		app.Metadata = make(sdk.Metadata, len(a.Metadata))
+		for k, v := range a.Metadata {
+			app.Metadata[k] = v
+		}
+	}
+
+	keyNames := make([]string, 0, len(a.Keys))
+	for k := range a.Keys {
+		keyNames = append(keyNames, k)
+	}
+	sort.Strings(keyNames)
+	for _, k := range keyNames {
+		app.Keys = append(app.Keys, sdk.ApplicationKey{
+			Key: sdk.Key{
+				Name: k,
+				Type: a.Keys[k].Type,
+			},
+		})
+	}
+
+	pipNames := make([]string, 0, len(a.Pipelines))
+	for k := range a.Pipelines {
+		pipNames = append(pipNames, k)
+	}
+	sort.Strings(pipNames)
+
+	app.Pipelines = make([]sdk.ApplicationPipeline, len(pipNames))
+	for i, pipName := range pipNames {
+		ap := a.Pipelines[pipName]
+		appPip := &app.Pipelines[i]
+		appPip.Pipeline.Name = pipName
+		appPip.Pipeline.Slug = ap.Ref
+
+		//An embedded definition is used to create the pipeline if it doesn't exist
+		if ap.Definition != nil {
+			ap.Definition.Name = pipName
+			pip, err := ap.Definition.Pipeline()
+			if err != nil {
+				return nil, err
+			}
+			pip.Slug = ap.Ref
+			appPip.Pipeline = *pip
+		}
+
+		appPip.Parameters = make([]sdk.Parameter, 0, len(ap.Parameters))
+		for _, k := range sortedVariableKeys(ap.Parameters) {
+			v := ap.Parameters[k]
+			appPip.Parameters = append(appPip.Parameters, sdk.Parameter{
+				Name:        k,
+				Type:        v.Type,
+				Value:       v.Value,
+				Description: v.Description,
+			})
+		}
+
+		destNames := make([]string, 0, len(ap.Triggers))
+		for k := range ap.Triggers {
+			destNames = append(destNames, k)
+		}
+		sort.Strings(destNames)
+
+		for _, destName := range destNames {
+			t := ap.Triggers[destName]
+			trig := sdk.PipelineTrigger{
+				DestPipeline: sdk.Pipeline{Name: destName, Slug: t.Ref},
+				Manual:       t.Manual,
+			}
+			if t.ProjectKey != nil {
+				trig.DestProject.Key = *t.ProjectKey
+			}
+			if t.ApplicationName != nil {
+				trig.DestApplication.Name = *t.ApplicationName
+			}
+			if t.FromEnvironment != nil {
+				trig.SrcEnvironment.Name = *t.FromEnvironment
+			}
+			if t.ToEnvironment != nil {
+				trig.DestEnvironment.Name = *t.ToEnvironment
+			}
+			for _, c := range t.Conditions {
+				trig.Prerequisites = append(trig.Prerequisites, sdk.Prerequisite{
+					Parameter:     c.Variable,
+					ExpectedValue: c.Expected,
+				})
+			}
+			appPip.Triggers = append(appPip.Triggers, trig)
+		}
+
+		for _, o := range ap.Options {
+			envName := sdk.DefaultEnv.Name
+			if o.Environment != nil {
+				envName = *o.Environment
+			}
+
+			if o.Hook != nil && *o.Hook {
+				if envName != sdk.DefaultEnv.Name {
+					return nil, fmt.Errorf("Hook on pipeline %s cannot be set on environment %s", pipName, envName)
+				}
+				app.Hooks = append(app.Hooks, sdk.Hook{
+					Pipeline: sdk.Pipeline{Name: pipName},
+					Enabled:  !o.Disabled,
+					Branches: sdk.HookBranches{Include: o.Branches, Exclude: o.ExcludedBranches},
+				})
+			} else if len(o.Branches) > 0 || len(o.ExcludedBranches) > 0 {
+				return nil, fmt.Errorf("Branches on pipeline %s can only be set with a hook", pipName)
+			}
+
+			if o.Polling != nil && *o.Polling {
+				if envName != sdk.DefaultEnv.Name {
+					return nil, fmt.Errorf("Polling on pipeline %s cannot be set on environment %s", pipName, envName)
+				}
+				app.RepositoryPollers = append(app.RepositoryPollers, sdk.RepositoryPoller{
+					Pipeline: sdk.Pipeline{Name: pipName},
+					Enabled:  !o.Disabled,
+				})
+			}
+
+			if len(o.Notifications) > 0 {
+				n := sdk.UserNotification{
+					Pipeline:      sdk.Pipeline{Name: pipName},
+					Environment:   sdk.Environment{Name: envName},
+					Notifications: make(map[sdk.UserNotificationSettingsType]sdk.UserNotificationSettings, len(o.Notifications)),
+				}
+				for t, s := range o.Notifications {
+					n.Notifications[sdk.UserNotificationSettingsType(t)] = s.Settings()
+				}
+				app.Notifications = append(app.Notifications, n)
+			}
+
+			for _, sc := range o.Schedulers {
+				s := sdk.PipelineScheduler{
+					PipelineName:    pipName,
+					EnvironmentName: envName,
+				}
+				s.Crontab, s.Timezone = sdk.SplitCrontab(sc.CronExpr)
+				for _, k := range sortedVariableKeys(sc.Parameters) {
+					v := sc.Parameters[k]
+					s.Args = append(s.Args, sdk.Parameter{
+						Name:        k,
+						Type:        v.Type,
+						Value:       v.Value,
+						Description: v.Description,
+					})
+				}
+				app.Schedulers = append(app.Schedulers, s)
+			}
+		}
+	}
+
+	//The notifications of the application are set on each pipeline
+	for _, e := range a.ExpandNotifications() {
+		var n *sdk.UserNotification
+		for i := range app.Notifications {
+			if app.Notifications[i].Pipeline.Name == e.Pipeline && app.Notifications[i].Environment.Name == e.Environment {
+				n = &app.Notifications[i]
+				break
+			}
+		}
+		if n == nil {
+			app.Notifications = append(app.Notifications, sdk.UserNotification{
+				Pipeline:      sdk.Pipeline{Name: e.Pipeline},
+				Environment:   sdk.Environment{Name: e.Environment},
+				Notifications: map[sdk.UserNotificationSettingsType]sdk.UserNotificationSettings{},
+			})
+			n = &app.Notifications[len(app.Notifications)-1]
+		}
+		n.Notifications[sdk.UserNotificationSettingsType(e.Type)] = a.Notifications[e.Type].Settings()
+	}
+
+	return app, nil
+}
+
+//ExpandNotifications returns the pipelines and environments the notifications of the application are set on.
+//A notification is set on each environment of each pipeline, unless the pipeline sets a notification of the
+//same type on this environment. A pipeline without options is set on the default environment.
+func (a *Application) ExpandNotifications() []NotificationExpansion {
+	if len(a.Notifications) == 0 {
+		return nil
+	}
+	types := make([]string, 0, len(a.Notifications))
+	for t := range a.Notifications {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	pipNames := make([]string, 0, len(a.Pipelines))
+	for k := range a.Pipelines {
+		pipNames = append(pipNames, k)
+	}
+	sort.Strings(pipNames)
+
+	expansions := []NotificationExpansion{}
+	for _, pipName := range pipNames {
+		//The types of notification set by the pipeline on each environment
+		envs := map[string]map[string]bool{}
+		for _, o := range a.Pipelines[pipName].Options {
+			envName := sdk.DefaultEnv.Name
+			if o.Environment != nil {
+				envName = *o.Environment
+			}
+			if envs[envName] == nil {
+				envs[envName] = map[string]bool{}
+			}
+			for t := range o.Notifications {
+				envs[envName][t] = true
+			}
+		}
+		if len(envs) == 0 {
+			envs[sdk.DefaultEnv.Name] = map[string]bool{}
+		}
+		envNames := make([]string, 0, len(envs))
+		for k := range envs {
+			envNames = append(envNames, k)
+		}
+		sort.Strings(envNames)
+
+		for _, envName := range envNames {
+			for _, t := range types {
+				if !envs[envName][t] {
+					expansions = append(expansions, NotificationExpansion{Type: t, Pipeline: pipName, Environment: envName})
+				}
+			}
+		}
+	}
+	return expansions
+}
+
+func sortedVariableKeys(m map[string]VariableValue) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 //HCLTemplate returns text/template
 func (a *Application) HCLTemplate() (*template.Template, error) {
 	tmpl := `name = "{{.Name}}"
-
-repo_manager = "{{.RepositoryManager}}
-repo_name = "{{.RepositoryName}}
+{{if .Description}}
+description = {{ hclValue .Description }}
+{{end}}
+{{- if .Generation}}
+generation = {{ .Generation }}
+{{end}}
+repo_manager = "{{.RepositoryManager}}"
+repo_name = "{{.RepositoryName}}"
 
 permissions = { {{ range $key, $value := .Permissions }}
 	"{{$key}}" = {{$value}}{{ end }}
@@ -231,42 +684,68 @@ permissions = { {{ range $key, $value := .Permissions }}
 variables = { 
 {{ range $key, $value := .Variables }}
 	"{{ $key }}" {
-		{{if eq $value.Type "text" -}} 
 		type = "{{$value.Type}}"
-		value = <<EOV
-{{$value.Value}}
-EOV
-		{{- else -}}
-		type = "{{$value.Type}}"
-		value = "{{$value.Value}}"
+		value = {{ hclValue $value.Value }}
+		{{- if $value.Description}}
+		description = {{ hclValue $value.Description }}
 		{{- end}}
 	} 
 {{ end }}
+}
+
+{{if .Keys -}}
+keys = { {{ range $key, $value := .Keys }}
+	"{{ $key }}" {
+		type = "{{ $value.Type }}"
+	}{{ end }}
+}
+{{- end}}
+
+{{if .Retention -}}
+retention {
+	{{if .Retention.KeepRuns -}} keep_runs = {{ .Retention.KeepRuns }} {{- end}}
+	{{if .Retention.KeepDays -}} keep_days = {{ .Retention.KeepDays }} {{- end}}
+}
+{{- end}}
+
+{{if .Metadata -}}
+metadata = { {{ range $key, $value := .Metadata }}
+	"{{ $key }}" = {{ hclValue $value }}{{ end }}
+}
+{{- end}}
 
 pipelines = {
 {{ range $key, $value := .Pipelines }}
     "{{ $key }}" {
+        {{if .Ref -}} ref = "{{ .Ref }}" {{- end}}
         {{if .Triggers -}}
-        triggers : {
+        triggers = {
             {{ range $key, $value := .Triggers }}
             "{{ $key }}" {
-                {{if $value.ProjectKey -}} project_key: "{{ $value.ProjectKey }}" {{- end}}
-                {{if $value.ApplicationName -}} application_name: "{{ $value.ApplicationName }}" {{- end}}
-                {{if $value.FromEnvironment -}} from_environment: "{{ $value.FromEnvironment }}" {{- end}}
-                {{if $value.ToEnvironment -}} to_environment: "{{ $value.ToEnvironment }}" {{- end}}
-                manual: {{ $value.Manual }}
-                {{range .Conditions -}}
-                conditions {
-                    variable: "{{ .Variable }}"
-                    expected: "{{ .Expected }}"
-                } 
+                {{if $value.Ref -}} ref = "{{ $value.Ref }}" {{- end}}
+                {{if $value.ProjectKey -}} project_key = "{{ $value.ProjectKey }}" {{- end}}
+                {{if $value.ApplicationName -}} application_name = "{{ $value.ApplicationName }}" {{- end}}
+                {{if $value.FromEnvironment -}} from_environment = "{{ $value.FromEnvironment }}" {{- end}}
+                {{if $value.ToEnvironment -}} to_environment = "{{ $value.ToEnvironment }}" {{- end}}
+                manual = {{ $value.Manual }}
+                {{if .Conditions -}}
+                conditions = [
+                    {{- range .Conditions }}
+                    {
+                        variable = {{ hclValue .Variable }}
+                        expected = {{ hclValue .Expected }}
+                    },
+                    {{- end}}
+                ]
                 {{- end}}
             }
             {{ end }}
         }
         {{- end}}
-        {{ range .Options }}
-        options {
+        {{ if .Options -}}
+        options = [
+        {{- range $i, $o := .Options }}{{if $i}},{{end}}
+        {
             {{if .Notifications -}}
             notifications {
                 {{ range $key, $value := .Notifications -}}
@@ -274,24 +753,23 @@ pipelines = {
                 {{- end}}
             }
             {{- end}}
-            {{if .Environment -}} environment: "{{ .Environment }}" {{- end}}
-            {{if .Hook -}} hook: "{{ .Hook }}" {{- end}}
-            {{if .Polling -}} polling: "{{ .Polling }}" {{- end}}
+            {{if .Environment -}} environment = "{{ .Environment }}" {{- end}}
+            {{if .Hook -}} hook = {{ .Hook }} {{- end}}
+            {{if .Polling -}} polling = {{ .Polling }} {{- end}}
+            {{if .Disabled -}} disabled = true {{- end}}
+            {{if .Branches -}} branches = [{{ range $i, $b := .Branches }}{{if $i}}, {{end}}{{ hclValue $b }}{{ end }}] {{- end}}
+            {{if .ExcludedBranches -}} excluded_branches = [{{ range $i, $b := .ExcludedBranches }}{{if $i}}, {{end}}{{ hclValue $b }}{{ end }}] {{- end}}
             {{ range .Schedulers -}}
             schedulers {
-                cron_expr: "{{.CronExpr}}"
+                cron_expr = "{{.CronExpr}}"
                 {{if .Parameters -}}
                 parameters {
                     {{ range $key, $value := .Parameters }}
                     "{{ $key }}" {
-                        {{if eq $value.Type "text" -}} 
-                        type = "{{$value.Type}}"
-                        value = <<EOV
-{{$value.Value}}
-EOV
-                        {{- else -}}
                         type = "{{$value.Type}}"
-                        value = "{{$value.Value}}"
+                        value = {{ hclValue $value.Value }}
+                        {{- if $value.Description}}
+                        description = {{ hclValue $value.Description }}
                         {{- end}}
                     } 
                 {{ end }}
@@ -300,11 +778,13 @@ EOV
             }
             {{- end}}
         }
-        {{- end}}       
+        {{- end}}
+        ]
+        {{- end}}
     }
 {{end }}
 }
 `
-	t := template.New("t")
+	t := template.New("t").Funcs(hclFuncs)
 	return t.Parse(tmpl)
 }
@@ -3,11 +3,16 @@ package exportentities
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"net/http"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"text/template"
 	"time"
 
+	"github.com/BurntSushi/toml"
 	"gopkg.in/yaml.v2"
 )
 
@@ -29,7 +34,45 @@ func GetFormat(f string) (Format, error) {
 	}
 }
 
-//Marshal suppoets JSON, YAML and HCL
+//GetFormatFromPath return the format of a file from its extension
+func GetFormatFromPath(path string) (Format, error) {
+	ext := strings.ToLower(filepath.Ext(path))
+	switch ext {
+	case ".yaml", ".yml":
+		return FormatYAML, nil
+	case ".json":
+		return FormatJSON, nil
+	case ".hcl", ".tf":
+		return FormatHCL, nil
+	case ".toml", ".tml":
+		return FormatTOML, nil
+	default:
+		return UnknownFormat, fmt.Errorf("unsupported file extension %q for %s: expected .yml, .yaml, .json, .hcl, .tf, .toml or .tml", ext, path)
+	}
+}
+
+//hclFuncs are the functions of the HCL templates
+var hclFuncs = template.FuncMap{"hclValue": hclValue}
+
+//hclValue returns a value written in an HCL template, so that it is read back unchanged. Multiline values
+//ending with a newline are written in a heredoc, the others in a quoted string.
+func hclValue(s string) string {
+	if strings.HasSuffix(s, "\n") && !strings.Contains(s, "\r") {
+		heredoc := true
+		for _, l := range strings.Split(s, "\n") {
+			if strings.HasSuffix(l, "EOV") {
+				heredoc = false
+				break
+			}
+		}
+		if heredoc {
+			return "<<EOV\n" + s + "EOV"
+		}
+	}
+	return strconv.Quote(s)
+}
+
+//Marshal suppoets JSON, YAML, HCL and TOML
 func Marshal(i interface{}, f Format) ([]byte, error) {
 	o, ok := i.(HCLable)
 	if f == FormatHCL && !ok {
@@ -51,17 +94,46 @@ func Marshal(i interface{}, f Format) ([]byte, error) {
 		buff := new(bytes.Buffer)
 		errMarshal = t.Execute(buff, o)
 		btes = buff.Bytes()
+	case FormatTOML:
+		buff := new(bytes.Buffer)
+		errMarshal = toml.NewEncoder(buff).Encode(i)
+		btes = buff.Bytes()
 	}
 	return btes, errMarshal
 }
 
+//Annotate writes lines as comments at the top of a document: # for YAML and TOML, // for HCL. The comments
+//are ignored by the decoders, so the document is imported as if it were not annotated. JSON has no comments.
+func Annotate(data []byte, f Format, lines []string) ([]byte, error) {
+	var prefix string
+	switch f {
+	case FormatYAML, FormatTOML:
+		prefix = "# "
+	case FormatHCL:
+		prefix = "// "
+	case FormatJSON:
+		return nil, ErrUnsupportedComments
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+
+	buff := new(bytes.Buffer)
+	for _, l := range lines {
+		// A line break would end the comment
+		for _, c := range strings.Split(l, "\n") {
+			buff.WriteString(strings.TrimRight(prefix+strings.TrimRight(c, "\r"), " "))
+			buff.WriteByte('\n')
+		}
+	}
+	buff.Write(data)
+	return buff.Bytes(), nil
+}
+
 // ReadFile reads the file and return the content, the format and eventually an error
 func ReadFile(filename string) ([]byte, Format, error) {
-	format := FormatYAML
-	if strings.HasSuffix(filename, ".json") {
-		format = FormatJSON
-	} else if strings.HasSuffix(filename, ".hcl") {
-		format = FormatHCL
+	format, errF := GetFormatFromPath(filename)
+	if errF != nil {
+		format = FormatYAML
 	}
 
 	btes, err := ioutil.ReadFile(filename)
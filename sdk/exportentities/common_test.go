@@ -13,3 +13,26 @@ func TestReadURL(t *testing.T) {
 	b, _, _ = ReadURL("https://raw.githubusercontent.com/ovh/tat/master/.travis.yml", "yml")
 	assert.True(t, len(b) > 0)
 }
+
+func TestGetFormatFromPath(t *testing.T) {
+	tests := []struct {
+		path    string
+		want    Format
+		wantErr bool
+	}{
+		{"app.yml", FormatYAML, false},
+		{"app.YAML", FormatYAML, false},
+		{"dir/app.Json", FormatJSON, false},
+		{"my.app.v1.hcl", FormatHCL, false},
+		{"/tmp/pipeline.build.TF", FormatHCL, false},
+		{"app.Toml", FormatTOML, false},
+		{"my.app.yml.txt", UnknownFormat, true},
+		{"Makefile", UnknownFormat, true},
+		{"dir.yml/app", UnknownFormat, true},
+	}
+	for _, tt := range tests {
+		got, err := GetFormatFromPath(tt.path)
+		assert.Equal(t, tt.wantErr, err != nil, "GetFormatFromPath(%s) error = %v", tt.path, err)
+		assert.Equal(t, tt.want, got, "GetFormatFromPath(%s)", tt.path)
+	}
+}
@@ -0,0 +1,136 @@
+package exportentities
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/ovh/cds/sdk"
+)
+
+// EntityDiff lists the names of the added, removed and changed entities
+type EntityDiff struct {
+	Added   []string `json:"added,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+	Changed []string `json:"changed,omitempty"`
+}
+
+// ApplicationDiff is the difference between two applications
+type ApplicationDiff struct {
+	Variables     EntityDiff `json:"variables"`
+	Pipelines     EntityDiff `json:"pipelines"`
+	Parameters    EntityDiff `json:"parameters"`
+	Triggers      EntityDiff `json:"triggers"`
+	Hooks         EntityDiff `json:"hooks"`
+	Notifications EntityDiff `json:"notifications"`
+}
+
+// DiffApplications computes the difference between two applications. Both applications must be built the
+// same way, as by Application(), so that their pipelines, environments and triggers are only referenced by names.
+func DiffApplications(oldApp, newApp *sdk.Application) ApplicationDiff {
+	return ApplicationDiff{
+		Variables:     diffEntities(applicationVariables(oldApp), applicationVariables(newApp)),
+		Pipelines:     diffEntities(applicationPipelines(oldApp), applicationPipelines(newApp)),
+		Parameters:    diffEntities(applicationParameters(oldApp), applicationParameters(newApp)),
+		Triggers:      diffEntities(applicationTriggers(oldApp), applicationTriggers(newApp)),
+		Hooks:         diffEntities(applicationHooks(oldApp), applicationHooks(newApp)),
+		Notifications: diffEntities(applicationNotifications(oldApp), applicationNotifications(newApp)),
+	}
+}
+
+func diffEntities(oldEntities, newEntities map[string]interface{}) EntityDiff {
+	d := EntityDiff{}
+	for k, v := range newEntities {
+		ov, ok := oldEntities[k]
+		if !ok {
+			d.Added = append(d.Added, k)
+		} else if !reflect.DeepEqual(ov, v) {
+			d.Changed = append(d.Changed, k)
+		}
+	}
+	for k := range oldEntities {
+		if _, ok := newEntities[k]; !ok {
+			d.Removed = append(d.Removed, k)
+		}
+	}
+	sort.Strings(d.Added)
+	sort.Strings(d.Removed)
+	sort.Strings(d.Changed)
+	return d
+}
+
+func applicationVariables(app *sdk.Application) map[string]interface{} {
+	m := map[string]interface{}{}
+	for _, v := range app.Variable {
+		m[v.Name] = VariableValue{Type: string(v.Type), Value: v.Value}
+	}
+	return m
+}
+
+func applicationPipelines(app *sdk.Application) map[string]interface{} {
+	m := map[string]interface{}{}
+	for _, ap := range app.Pipelines {
+		params := map[string]VariableValue{}
+		for _, p := range ap.Parameters {
+			params[p.Name] = VariableValue{Type: string(p.Type), Value: p.Value}
+		}
+		m[ap.Pipeline.Name] = params
+	}
+	return m
+}
+
+//applicationParameters returns the default values of the pipeline parameters, by pipeline.parameter
+func applicationParameters(app *sdk.Application) map[string]interface{} {
+	m := map[string]interface{}{}
+	for _, ap := range app.Pipelines {
+		for _, p := range ap.Parameters {
+			m[ap.Pipeline.Name+"."+p.Name] = VariableValue{Type: string(p.Type), Value: p.Value}
+		}
+	}
+	return m
+}
+
+func applicationTriggers(app *sdk.Application) map[string]interface{} {
+	m := map[string]interface{}{}
+	for _, ap := range app.Pipelines {
+		for _, t := range ap.Triggers {
+			dest := t.DestPipeline.Name
+			if t.DestApplication.Name != "" {
+				dest = t.DestApplication.Name + "/" + dest
+			}
+			if t.DestProject.Key != "" {
+				dest = t.DestProject.Key + "/" + dest
+			}
+			k := fmt.Sprintf("%s[%s] -> %s[%s]", ap.Pipeline.Name, t.SrcEnvironment.Name, dest, t.DestEnvironment.Name)
+			prerequisites := map[string]string{}
+			for _, p := range t.Prerequisites {
+				prerequisites[p.Parameter] = p.ExpectedValue
+			}
+			m[k] = struct {
+				Manual        bool
+				Prerequisites map[string]string
+			}{t.Manual, prerequisites}
+		}
+	}
+	return m
+}
+
+func applicationHooks(app *sdk.Application) map[string]interface{} {
+	m := map[string]interface{}{}
+	for _, h := range app.Hooks {
+		m[h.Pipeline.Name] = h.Enabled
+	}
+	return m
+}
+
+func applicationNotifications(app *sdk.Application) map[string]interface{} {
+	m := map[string]interface{}{}
+	for _, n := range app.Notifications {
+		settings := map[string]string{}
+		for t, s := range n.Notifications {
+			settings[string(t)] = newApplicationPipelineNotification(s).JSON()
+		}
+		m[n.Pipeline.Name+"["+n.Environment.Name+"]"] = settings
+	}
+	return m
+}
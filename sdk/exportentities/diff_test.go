@@ -0,0 +1,38 @@
+package exportentities
+
+import (
+	"testing"
+
+	"github.com/ovh/cds/engine/api/test"
+	"github.com/ovh/cds/sdk"
+)
+
+func TestDiffApplications(t *testing.T) {
+	old, err := NewApplication(newTestApplication()).Application()
+	test.NoError(t, err)
+
+	// No change between the stored application and its export
+	same, err := NewApplication(newTestApplication()).Application()
+	test.NoError(t, err)
+	test.Equal(t, ApplicationDiff{}, DiffApplications(old, same))
+
+	a := newTestApplication()
+	a.Variable[0].Value = "newValue"
+	a.Variable = append(a.Variable, sdk.Variable{Name: "var3", Type: sdk.StringVariable, Value: "value3"})
+	a.Pipelines[0].Triggers[0].Manual = false
+	a.Pipelines[0].Parameters[0].Value = "newValue"
+	a.Pipelines = append(a.Pipelines, sdk.ApplicationPipeline{Pipeline: sdk.Pipeline{ID: 3, Name: "test"}})
+	a.Hooks = nil
+	a.Notifications = nil
+	updated, err := NewApplication(a).Application()
+	test.NoError(t, err)
+
+	test.Equal(t, ApplicationDiff{
+		Variables:     EntityDiff{Added: []string{"var3"}, Changed: []string{"var1"}},
+		Pipelines:     EntityDiff{Added: []string{"test"}, Changed: []string{"build"}},
+		Parameters:    EntityDiff{Changed: []string{"build.param1"}},
+		Triggers:      EntityDiff{Changed: []string{"build[] -> deploy[production]"}},
+		Hooks:         EntityDiff{Removed: []string{"build"}},
+		Notifications: EntityDiff{Removed: []string{"deploy[production]"}},
+	}, DiffApplications(old, updated))
+}
@@ -6,9 +6,11 @@ import (
 	"github.com/ovh/cds/sdk"
 )
 
-// Environment is a struct to export sdk.Environment
+// Environment is a struct to export sdk.Environment. On import, an environment which inherits from another one
+// gets its values, unless it sets them.
 type Environment struct {
 	Name        string                   `json:"name" yaml:"name"`
+	Inherits    string                   `json:"inherits,omitempty" yaml:"inherits,omitempty"`
 	Values      map[string]VariableValue `json:"values" yaml:"values"`
 	Permissions map[string]int           `json:"permissions" yaml:"permissions"`
 }
@@ -45,19 +47,12 @@ permissions = { {{ range $key, $value := .Permissions }}
 values = { 
 {{ range $key, $value := .Values }}
 	"{{ $key }}" {
-		{{if eq $value.Type "text" -}} 
 		type = "{{$value.Type}}"
-		value = <<EOV
-{{$value.Value}}
-EOV
-		{{- else -}}
-		type = "{{$value.Type}}"
-		value = "{{$value.Value}}"
-		{{- end}}
+		value = {{ hclValue $value.Value }}
 	} 
 {{ end }}
 }`
-	t := template.New("t")
+	t := template.New("t").Funcs(hclFuncs)
 	return t.Parse(tmpl)
 }
 
@@ -0,0 +1,53 @@
+package exportentities
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	"github.com/hashicorp/hcl"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
+
+	"github.com/ovh/cds/sdk"
+)
+
+func TestEnvironmentMultilineValues(t *testing.T) {
+	pem := `-----BEGIN CERTIFICATE-----
+MIIBszCCAVmgAwIBAgIUQ0fDz1HcQ0Y2b0p3PgvTHtUsuJwwCgYIKoZIzj0EAwIw
+  indented line with "quotes", a tab	and a backslash \
+-----END CERTIFICATE-----
+`
+	env := &sdk.Environment{
+		Name: "production",
+		Variable: []sdk.Variable{
+			{Name: "cert", Type: sdk.TextVariable, Value: pem},
+			{Name: "cert-no-newline", Type: sdk.TextVariable, Value: pem[:len(pem)-1]},
+			{Name: "crlf", Type: sdk.TextVariable, Value: "line1\r\nline2\r\n"},
+			{Name: "anchor", Type: sdk.TextVariable, Value: "foo\nEOV\nbar\n"},
+			{Name: "empty-lines", Type: sdk.TextVariable, Value: "\n\nfoo\n\n"},
+			{Name: "quoted", Type: sdk.StringVariable, Value: `say "hello"`},
+		},
+	}
+	e := NewEnvironment(env)
+
+	unmarshal := map[Format]func([]byte, interface{}) error{
+		FormatJSON: json.Unmarshal,
+		FormatYAML: yaml.Unmarshal,
+		FormatHCL:  hcl.Unmarshal,
+		FormatTOML: toml.Unmarshal,
+	}
+	for f, u := range unmarshal {
+		b, err := Marshal(e, f)
+		assert.NoError(t, err)
+
+		imported := &Environment{}
+		if !assert.NoError(t, u(b, imported), "format %d:\n%s", f, b) {
+			continue
+		}
+		for _, v := range env.Variable {
+			assert.Equal(t, v.Value, imported.Values[v.Name].Value, "format %d, variable %s", f, v.Name)
+			assert.Equal(t, v.Type, imported.Values[v.Name].Type, "format %d, variable %s", f, v.Name)
+		}
+	}
+}
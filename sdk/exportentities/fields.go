@@ -0,0 +1,234 @@
+package exportentities
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/hashicorp/hcl"
+	"gopkg.in/yaml.v2"
+)
+
+//UnknownFields returns the path of the fields of a document which are not fields of out, and are ignored
+//when the document is unmarshalled in out. The fields are matched as the decoder of the format does.
+func UnknownFields(data []byte, f Format, out interface{}) ([]string, error) {
+	var raw interface{}
+	var err error
+	switch f {
+	case FormatJSON:
+		err = json.Unmarshal(data, &raw)
+	case FormatYAML:
+		err = yaml.Unmarshal(data, &raw)
+	case FormatHCL:
+		m := map[string]interface{}{}
+		err = hcl.Unmarshal(data, &m)
+		raw = m
+	case FormatTOML:
+		m := map[string]interface{}{}
+		err = toml.Unmarshal(data, &m)
+		raw = m
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	unknown := []string{}
+	unknownFields(reflect.ValueOf(raw), reflect.TypeOf(out), f, "", &unknown)
+	sort.Strings(unknown)
+	return unknown, nil
+}
+
+func unknownFields(v reflect.Value, t reflect.Type, f Format, path string, unknown *[]string) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	for v.IsValid() && v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return
+	}
+
+	// HCL decodes the blocks as lists of objects
+	if v.Kind() == reflect.Slice && (t.Kind() == reflect.Struct || t.Kind() == reflect.Map) {
+		for i := 0; i < v.Len(); i++ {
+			unknownFields(v.Index(i), t, f, path, unknown)
+		}
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if v.Kind() != reflect.Map {
+			return
+		}
+		for _, k := range v.MapKeys() {
+			name := fmt.Sprint(k.Interface())
+			field, ok := fieldByKey(t, name, f)
+			if !ok {
+				*unknown = append(*unknown, fieldPath(path, name))
+				continue
+			}
+			unknownFields(v.MapIndex(k), field.Type, f, fieldPath(path, name), unknown)
+		}
+	case reflect.Map:
+		if v.Kind() != reflect.Map {
+			return
+		}
+		for _, k := range v.MapKeys() {
+			unknownFields(v.MapIndex(k), t.Elem(), f, fieldPath(path, fmt.Sprint(k.Interface())), unknown)
+		}
+	case reflect.Slice:
+		if v.Kind() != reflect.Slice {
+			return
+		}
+		for i := 0; i < v.Len(); i++ {
+			unknownFields(v.Index(i), t.Elem(), f, fmt.Sprintf("%s[%d]", path, i), unknown)
+		}
+	}
+}
+
+//fieldByKey returns the field of a struct decoded from a key. YAML matches the key with the name of
+//the tag, or the lowercased name of the field. The other formats ignore the case.
+func fieldByKey(t reflect.Type, key string, f Format) (reflect.StructField, bool) {
+	tagName := map[Format]string{FormatJSON: "json", FormatYAML: "yaml", FormatHCL: "hcl", FormatTOML: "toml"}[f]
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		name := strings.Split(field.Tag.Get(tagName), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if f == FormatYAML {
+			if name == "" {
+				name = strings.ToLower(field.Name)
+			}
+			if name == key {
+				return field, true
+			}
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		if strings.EqualFold(name, key) {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+//DuplicatePipelines returns the number of entries of the pipelines listed several times by an application
+//document. The decoders keep only the last entry of a duplicated key, so the document itself is read.
+func DuplicatePipelines(data []byte, f Format) (map[string]int, error) {
+	return duplicateKeys(data, f, "pipelines")
+}
+
+//DuplicateVariables returns the number of entries of the variables declared several times by an application
+//document, as DuplicatePipelines does for the pipelines.
+func DuplicateVariables(data []byte, f Format) (map[string]int, error) {
+	return duplicateKeys(data, f, "variables")
+}
+
+//duplicateKeys returns the number of entries of the keys listed several times in an object of the root object
+func duplicateKeys(data []byte, f Format, field string) (map[string]int, error) {
+	counts := map[string]int{}
+	switch f {
+	case FormatYAML:
+		// The nested objects of a yaml.MapSlice are decoded as yaml.MapSlice too, with their duplicated keys
+		root := yaml.MapSlice{}
+		if err := yaml.Unmarshal(data, &root); err != nil {
+			return nil, err
+		}
+		for _, item := range root {
+			if fmt.Sprint(item.Key) != field {
+				continue
+			}
+			obj, _ := item.Value.(yaml.MapSlice)
+			for _, e := range obj {
+				counts[fmt.Sprint(e.Key)]++
+			}
+		}
+	case FormatJSON:
+		names, err := jsonObjectKeys(data, field)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			counts[name]++
+		}
+	case FormatHCL:
+		m := map[string]interface{}{}
+		if err := hcl.Unmarshal(data, &m); err != nil {
+			return nil, err
+		}
+		// Each block is decoded as an object of the list
+		blocks, _ := m[field].([]map[string]interface{})
+		for _, b := range blocks {
+			for name := range b {
+				counts[name]++
+			}
+		}
+	default:
+		// The TOML decoder rejects duplicated keys
+		return map[string]int{}, nil
+	}
+
+	for name, n := range counts {
+		if n < 2 {
+			delete(counts, name)
+		}
+	}
+	return counts, nil
+}
+
+//jsonObjectKeys returns the keys, with their duplicates, of an object in the root object of a JSON document
+func jsonObjectKeys(data []byte, field string) ([]string, error) {
+	root := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &root); err != nil {
+		return nil, err
+	}
+	var raw json.RawMessage
+	for k, v := range root {
+		if strings.EqualFold(k, field) {
+			raw = v
+		}
+	}
+	if raw == nil {
+		return nil, nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	if t, err := dec.Token(); err != nil || t != json.Delim('{') {
+		return nil, err
+	}
+	keys := []string{}
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, fmt.Sprint(t))
+		// Skip the value
+		var v json.RawMessage
+		if err := dec.Decode(&v); err != nil {
+			return nil, err
+		}
+	}
+	return keys, nil
+}
+
+func fieldPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
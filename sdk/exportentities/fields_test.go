@@ -0,0 +1,62 @@
+package exportentities
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnknownFields(t *testing.T) {
+	tests := []struct {
+		format Format
+		data   string
+	}{
+		{FormatYAML, "name: app1\nnmae: app2\nkeys:\n  ssh:\n    type: ssh\n    tpye: ssh\n"},
+		{FormatJSON, `{"Name": "app1", "nmae": "app2", "keys": {"ssh": {"type": "ssh", "tpye": "ssh"}}}`},
+		{FormatTOML, "name = \"app1\"\nnmae = \"app2\"\n[keys.ssh]\ntype = \"ssh\"\ntpye = \"ssh\"\n"},
+		{FormatHCL, "name = \"app1\"\nnmae = \"app2\"\nkeys \"ssh\" {\n  type = \"ssh\"\n  tpye = \"ssh\"\n}\n"},
+	}
+	for _, tt := range tests {
+		unknown, err := UnknownFields([]byte(tt.data), tt.format, &Application{})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"keys.ssh.tpye", "nmae"}, unknown, "format %d", tt.format)
+	}
+
+	//The steps of a pipeline are free maps
+	unknown, err := UnknownFields([]byte("name: build\nsteps:\n- script: make\n  enabled: false\n- artifactUpload: {}\n  triger: {}\n"), FormatYAML, &Pipeline{})
+	assert.NoError(t, err)
+	assert.Empty(t, unknown)
+
+	//YAML matches the lowercased name of the fields which have no tag
+	unknown, err = UnknownFields([]byte("image: debian\nImage: debian\n"), FormatYAML, &struct{ Image string }{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Image"}, unknown)
+}
+
+func TestDuplicatePipelines(t *testing.T) {
+	yml := "name: app1\npipelines:\n  build: {}\n  deploy: {}\n  build:\n    parameters: {}\n"
+	counts, err := DuplicatePipelines([]byte(yml), FormatYAML)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"build": 2}, counts)
+
+	js := `{"name": "app1", "pipelines": {"build": {}, "deploy": {"triggers": {"build": {}}}, "deploy": {}, "deploy": {}}}`
+	counts, err = DuplicatePipelines([]byte(js), FormatJSON)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"deploy": 3}, counts)
+
+	counts, err = DuplicatePipelines([]byte(`{"name": "app1"}`), FormatJSON)
+	assert.NoError(t, err)
+	assert.Empty(t, counts)
+}
+
+func TestDuplicateVariables(t *testing.T) {
+	yml := "name: app1\nvariables:\n  tier:\n    value: \"1\"\n  tier:\n    value: \"2\"\n  region:\n    value: gra\npipelines:\n  build: {}\n"
+	counts, err := DuplicateVariables([]byte(yml), FormatYAML)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"tier": 2}, counts)
+
+	js := `{"name": "app1", "variables": {"tier": {"value": "1"}, "tier": {"value": "2"}}}`
+	counts, err = DuplicateVariables([]byte(js), FormatJSON)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"tier": 2}, counts)
+}
@@ -0,0 +1,220 @@
+package exportentities
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+//MergeResult describes how the documents of a payload have been merged
+type MergeResult struct {
+	Documents int
+	Overrides []MergeOverride
+}
+
+//MergeOverride is a value of a document replaced by a following document
+type MergeOverride struct {
+	Path     string
+	Document int
+}
+
+//MergeDocuments merges the documents of a payload: the documents of a YAML stream separated by ---, or
+//the objects of a JSON array. The documents are deep merged in order. Objects are merged key by key, lists
+//of objects are merged by name and the other lists are appended, the other values of the last document win.
+//A payload with a single document is returned unchanged. Each document is decoded with unmarshal.
+func MergeDocuments(data []byte, f Format, unmarshal func([]byte, interface{}) error) ([]byte, MergeResult, error) {
+	var docs []interface{}
+	switch f {
+	case FormatYAML:
+		for _, d := range splitYAMLDocuments(data) {
+			var doc interface{}
+			if err := unmarshal(d, &doc); err != nil {
+				return nil, MergeResult{}, err
+			}
+			if doc != nil {
+				docs = append(docs, normalizeDocument(doc))
+			}
+		}
+	case FormatJSON:
+		if !bytes.HasPrefix(bytes.TrimSpace(data), []byte("[")) {
+			return data, MergeResult{Documents: 1}, nil
+		}
+		if err := unmarshal(data, &docs); err != nil {
+			return nil, MergeResult{}, err
+		}
+	default:
+		return data, MergeResult{Documents: 1}, nil
+	}
+
+	// A JSON array is always merged, to import its single object
+	if len(docs) <= 1 && f == FormatYAML {
+		return data, MergeResult{Documents: len(docs)}, nil
+	}
+	if len(docs) == 0 {
+		return nil, MergeResult{}, fmt.Errorf("no document to merge")
+	}
+
+	res := MergeResult{Documents: len(docs)}
+	merged := docs[0]
+	for i := 1; i < len(docs); i++ {
+		merged = mergeDocument(merged, docs[i], "", i+1, &res)
+	}
+	if _, ok := merged.(map[string]interface{}); !ok {
+		return nil, MergeResult{}, fmt.Errorf("documents must be objects")
+	}
+	sort.Slice(res.Overrides, func(i, j int) bool {
+		if res.Overrides[i].Document != res.Overrides[j].Document {
+			return res.Overrides[i].Document < res.Overrides[j].Document
+		}
+		return res.Overrides[i].Path < res.Overrides[j].Path
+	})
+
+	var out []byte
+	var err error
+	if f == FormatYAML {
+		out, err = yaml.Marshal(merged)
+	} else {
+		out, err = json.Marshal(merged)
+	}
+	if err != nil {
+		return nil, MergeResult{}, err
+	}
+	return out, res, nil
+}
+
+//splitYAMLDocuments splits a YAML stream on the --- separators
+func splitYAMLDocuments(data []byte) [][]byte {
+	docs := [][]byte{}
+	current := &bytes.Buffer{}
+	s := bufio.NewScanner(bytes.NewReader(data))
+	s.Buffer(make([]byte, 64*1024), len(data)+1)
+	for s.Scan() {
+		line := s.Text()
+		if line == "---" || strings.HasPrefix(line, "--- ") || strings.HasPrefix(line, "---\t") {
+			docs = append(docs, current.Bytes())
+			current = &bytes.Buffer{}
+			// The separator may be followed by the content of the document
+			line = strings.TrimLeft(line[3:], " \t")
+		}
+		current.WriteString(line)
+		current.WriteByte('\n')
+	}
+	return append(docs, current.Bytes())
+}
+
+//normalizeDocument converts the YAML mappings to objects with string keys, as JSON documents
+func normalizeDocument(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for k, e := range t {
+			m[fmt.Sprint(k)] = normalizeDocument(e)
+		}
+		return m
+	case []interface{}:
+		for i := range t {
+			t[i] = normalizeDocument(t[i])
+		}
+		return t
+	}
+	return v
+}
+
+func mergeDocument(dst, src interface{}, path string, doc int, res *MergeResult) interface{} {
+	switch s := src.(type) {
+	case map[string]interface{}:
+		d, ok := dst.(map[string]interface{})
+		if !ok {
+			break
+		}
+		for k, v := range s {
+			if e, exists := d[k]; exists {
+				d[k] = mergeDocument(e, v, fieldPath(path, k), doc, res)
+			} else {
+				d[k] = v
+			}
+		}
+		return d
+	case []interface{}:
+		d, ok := dst.([]interface{})
+		if !ok {
+			break
+		}
+		if !namedObjects(d) || !namedObjects(s) {
+			return append(d, s...)
+		}
+		for _, v := range s {
+			name := v.(map[string]interface{})["name"]
+			merged := false
+			for i := range d {
+				if d[i].(map[string]interface{})["name"] == name {
+					d[i] = mergeDocument(d[i], v, fmt.Sprintf("%s[%v]", path, name), doc, res)
+					merged = true
+					break
+				}
+			}
+			if !merged {
+				d = append(d, v)
+			}
+		}
+		return d
+	}
+
+	if dst != nil && !reflect.DeepEqual(dst, src) {
+		res.Overrides = append(res.Overrides, MergeOverride{Path: path, Document: doc})
+	}
+	return src
+}
+
+//namedObjects returns true if all the elements of a list are objects with a name
+func namedObjects(l []interface{}) bool {
+	for _, e := range l {
+		m, ok := e.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		if _, ok := m["name"].(string); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+//MergePatch applies a JSON merge patch (RFC 7386) on a JSON document: the members of the objects of
+//the patch replace the members of the document, a null member removes it, and any other value,
+//including a list, replaces the value of the document.
+func MergePatch(doc, patch []byte) ([]byte, error) {
+	var d, p interface{}
+	if err := json.Unmarshal(doc, &d); err != nil {
+		return nil, fmt.Errorf("invalid document: %s", err)
+	}
+	if err := json.Unmarshal(patch, &p); err != nil {
+		return nil, fmt.Errorf("invalid merge patch: %s", err)
+	}
+	return json.Marshal(mergePatch(d, p))
+}
+
+func mergePatch(target, patch interface{}) interface{} {
+	p, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	t, ok := target.(map[string]interface{})
+	if !ok {
+		t = map[string]interface{}{}
+	}
+	for k, v := range p {
+		if v == nil {
+			delete(t, k)
+			continue
+		}
+		t[k] = mergePatch(t[k], v)
+	}
+	return t
+}
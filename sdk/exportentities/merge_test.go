@@ -0,0 +1,104 @@
+package exportentities
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
+)
+
+func TestMergeDocuments(t *testing.T) {
+	payload := `name: app1
+variables:
+  tier:
+    value: "1"
+  region:
+    value: gra
+pipelines:
+  build:
+    parameters:
+      version:
+        value: "1.0"
+---
+variables:
+  tier:
+    value: "2"
+pipelines:
+  deploy:
+    environments:
+      production: {}
+`
+	data, res, err := MergeDocuments([]byte(payload), FormatYAML, yaml.Unmarshal)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, res.Documents)
+	assert.Equal(t, []MergeOverride{{Path: "variables.tier.value", Document: 2}}, res.Overrides)
+
+	app := Application{}
+	assert.NoError(t, yaml.Unmarshal(data, &app))
+	assert.Equal(t, "app1", app.Name)
+	assert.Equal(t, "2", app.Variables["tier"].Value)
+	assert.Equal(t, "gra", app.Variables["region"].Value)
+	assert.Len(t, app.Pipelines, 2)
+
+	//A single document is unchanged
+	single := "---\nname: app1\n"
+	data, res, err = MergeDocuments([]byte(single), FormatYAML, yaml.Unmarshal)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, res.Documents)
+	assert.Equal(t, single, string(data))
+}
+
+func TestMergeDocumentsJSON(t *testing.T) {
+	payload := `[
+		{"name": "app1", "steps": [{"name": "build", "value": "a"}, {"name": "test"}], "tags": ["a"]},
+		{"steps": [{"name": "build", "value": "b"}, {"name": "deploy"}], "tags": ["b"]}
+	]`
+	data, res, err := MergeDocuments([]byte(payload), FormatJSON, json.Unmarshal)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, res.Documents)
+	assert.Equal(t, []MergeOverride{{Path: "steps[build].value", Document: 2}}, res.Overrides)
+
+	merged := map[string]interface{}{}
+	assert.NoError(t, json.Unmarshal(data, &merged))
+	assert.Equal(t, []interface{}{"a", "b"}, merged["tags"])
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"name": "build", "value": "b"},
+		map[string]interface{}{"name": "test"},
+		map[string]interface{}{"name": "deploy"},
+	}, merged["steps"])
+
+	data, _, err = MergeDocuments([]byte(`{"name": "app1"}`), FormatJSON, json.Unmarshal)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name": "app1"}`, string(data))
+
+	_, _, err = MergeDocuments([]byte(`["app1"]`), FormatJSON, json.Unmarshal)
+	assert.Error(t, err)
+}
+
+func TestMergePatch(t *testing.T) {
+	//Cases of RFC 7386
+	for _, c := range []struct{ doc, patch, expected string }{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"e":null}`, `{"a":1}`, `{"a":1,"e":null}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	} {
+		out, err := MergePatch([]byte(c.doc), []byte(c.patch))
+		assert.NoError(t, err)
+		assert.JSONEq(t, c.expected, string(out), "patch %s on %s", c.patch, c.doc)
+	}
+
+	_, err := MergePatch([]byte(`{}`), []byte(`{`))
+	assert.Error(t, err)
+}
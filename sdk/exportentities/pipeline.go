@@ -15,6 +15,7 @@ import (
 // Pipeline represents exported sdk.Pipeline
 type Pipeline struct {
 	Name         string                    `json:"name,omitempty" yaml:"name,omitempty"`
+	Slug         string                    `json:"slug,omitempty" yaml:"slug,omitempty"`
 	Type         string                    `json:"type,omitempty" yaml:"type,omitempty"`
 	Permissions  map[string]int            `json:"permissions,omitempty" yaml:"permissions,omitempty"`
 	Parameters   map[string]ParameterValue `json:"parameters,omitempty" yaml:"parameters,omitempty"`
@@ -332,6 +333,16 @@ func NewPipeline(pip *sdk.Pipeline) (p *Pipeline) {
 		p.Type = pip.Type
 	}
 
+	// The slug is only exported if it is not the one computed from the name on import, so that
+	// the applications still reference the pipeline once imported
+	name := p.Name
+	if name == "" {
+		name = strings.Title(pip.Type)
+	}
+	if pip.Slug != name {
+		p.Slug = pip.Slug
+	}
+
 	if len(pip.GroupPermission) > 0 {
 		p.Permissions = make(map[string]int, len(pip.GroupPermission))
 		for _, perm := range pip.GroupPermission {
@@ -345,6 +356,7 @@ func NewPipeline(pip *sdk.Pipeline) (p *Pipeline) {
 			p.Parameters[v.Name] = ParameterValue{
 				Type:         string(v.Type),
 				DefaultValue: v.Value,
+				Description:  v.Description,
 			}
 		}
 	}
@@ -557,6 +569,7 @@ func (p *Pipeline) Pipeline() (*sdk.Pipeline, error) {
 	}
 
 	pip.Name = p.Name
+	pip.Slug = p.Slug
 	pip.Type = p.Type
 
 	//Compute permissions
@@ -571,9 +584,10 @@ func (p *Pipeline) Pipeline() (*sdk.Pipeline, error) {
 	//Compute parameters
 	for p, v := range p.Parameters {
 		param := sdk.Parameter{
-			Name:  p,
-			Type:  v.Type,
-			Value: v.DefaultValue,
+			Name:        p,
+			Type:        v.Type,
+			Value:       v.DefaultValue,
+			Description: v.Description,
 		}
 		pip.Parameter = append(pip.Parameter, param)
 	}
@@ -490,6 +490,25 @@ func TestExportAndImportPipeline_YAML(t *testing.T) {
 	}
 }
 
+func TestExportPipelineSlug(t *testing.T) {
+	// The slug computed from the name is not exported
+	p := NewPipeline(&sdk.Pipeline{Name: "deploy", Slug: "deploy", Type: sdk.DeploymentPipeline})
+	assert.Empty(t, p.Slug)
+	p = NewPipeline(&sdk.Pipeline{Name: "build", Slug: "build", Type: sdk.BuildPipeline})
+	assert.Equal(t, "build", p.Slug)
+
+	// The slug of a renamed pipeline is kept
+	p = NewPipeline(&sdk.Pipeline{Name: "deploy-prod", Slug: "deploy", Type: sdk.DeploymentPipeline})
+	b, err := Marshal(p, FormatYAML)
+	test.NoError(t, err)
+	importedP := Pipeline{}
+	test.NoError(t, yaml.Unmarshal(b, &importedP))
+	pip, err := importedP.Pipeline()
+	test.NoError(t, err)
+	assert.Equal(t, "deploy-prod", pip.Name)
+	assert.Equal(t, "deploy", pip.Slug)
+}
+
 func Test_ImportPipelineWithRequirements(t *testing.T) {
 	in := `name: build-all-images
 type: build
@@ -0,0 +1,8 @@
+package exportentities
+
+// Project represents the environments, pipelines and applications of a project imported at once
+type Project struct {
+	Environments []Environment `json:"environments,omitempty" yaml:"environments,omitempty"`
+	Pipelines    []Pipeline    `json:"pipelines,omitempty" yaml:"pipelines,omitempty"`
+	Applications []Application `json:"applications,omitempty" yaml:"applications,omitempty"`
+}
@@ -0,0 +1,88 @@
+package exportentities
+
+import (
+	"reflect"
+	"strings"
+)
+
+// JSONSchema returns the JSON schema describing an exported entity. The schema is computed
+// from the json struct tags: a field is required unless it is tagged omitempty, or is a pointer or a boolean.
+func JSONSchema(i interface{}) map[string]interface{} {
+	t := reflect.TypeOf(i)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	s := jsonSchemaType(t)
+	s["$schema"] = "http://json-schema.org/draft-04/schema#"
+	s["title"] = t.Name()
+	return s
+}
+
+func jsonSchemaType(t reflect.Type) map[string]interface{} {
+	switch t.Kind() {
+	case reflect.Ptr:
+		s := jsonSchemaType(t.Elem())
+		if typ, ok := s["type"]; ok {
+			s["type"] = []interface{}{typ, "null"}
+		}
+		return s
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{
+			"type":  "array",
+			"items": jsonSchemaType(t.Elem()),
+		}
+	case reflect.Map:
+		return map[string]interface{}{
+			"type":                 "object",
+			"additionalProperties": jsonSchemaType(t.Elem()),
+		}
+	case reflect.Struct:
+		properties := map[string]interface{}{}
+		required := []string{}
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if f.PkgPath != "" {
+				continue
+			}
+			tag := strings.Split(f.Tag.Get("json"), ",")
+			name := tag[0]
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			properties[name] = jsonSchemaType(f.Type)
+
+			var omitempty bool
+			for _, o := range tag[1:] {
+				if o == "omitempty" {
+					omitempty = true
+				}
+			}
+			if !omitempty && f.Type.Kind() != reflect.Ptr && f.Type.Kind() != reflect.Bool {
+				required = append(required, name)
+			}
+		}
+		s := map[string]interface{}{
+			"type":                 "object",
+			"properties":           properties,
+			"additionalProperties": false,
+		}
+		if len(required) > 0 {
+			s["required"] = required
+		}
+		return s
+	default:
+		return map[string]interface{}{}
+	}
+}
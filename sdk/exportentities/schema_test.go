@@ -0,0 +1,27 @@
+package exportentities
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xeipuuv/gojsonschema"
+
+	"github.com/ovh/cds/engine/api/test"
+)
+
+func TestJSONSchema_Application(t *testing.T) {
+	schema := JSONSchema(Application{})
+	assert.Equal(t, []string{"name"}, schema["required"])
+
+	b, err := json.Marshal(NewApplication(newTestApplication()))
+	test.NoError(t, err)
+
+	res, err := gojsonschema.Validate(gojsonschema.NewGoLoader(schema), gojsonschema.NewStringLoader(string(b)))
+	test.NoError(t, err)
+	assert.True(t, res.Valid(), "%v", res.Errors())
+
+	res, err = gojsonschema.Validate(gojsonschema.NewGoLoader(schema), gojsonschema.NewStringLoader(`{"repo_name": "ovh/cds", "unknown": true}`))
+	test.NoError(t, err)
+	assert.False(t, res.Valid())
+}
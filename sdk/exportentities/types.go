@@ -14,14 +14,16 @@ type (
 
 	// VariableValue is a struct to export a value of Variable
 	VariableValue struct {
-		Type  string `json:"type" yaml:"type"`
-		Value string `json:"value" yaml:"value"`
+		Type        string `json:"type" yaml:"type" toml:"type"`
+		Value       string `json:"value" yaml:"value" toml:"value"`
+		Description string `json:"description,omitempty" yaml:"description,omitempty" toml:"description,omitempty"`
 	}
 
 	// ParameterValue is a struct to export a defautl value of Parameter
 	ParameterValue struct {
-		Type         string `json:"type" yaml:"type"`
-		DefaultValue string `json:"default" yaml:"default"`
+		Type         string `json:"type" yaml:"type" toml:"type"`
+		DefaultValue string `json:"default" yaml:"default" toml:"default"`
+		Description  string `json:"description,omitempty" yaml:"description,omitempty" toml:"description,omitempty"`
 	}
 )
 
@@ -39,4 +41,22 @@ var (
 	ErrUnsupportedHCLFormat = errors.New("HCL Format is not supported for this entity")
 	// ErrUnsupportedFormat is for unknown format
 	ErrUnsupportedFormat = errors.New("Format is not supported")
+	// ErrUnsupportedComments is for formats without comments, as JSON
+	ErrUnsupportedComments = errors.New("Comments are not supported by this format")
 )
+
+//String returns the name of the format
+func (f Format) String() string {
+	switch f {
+	case FormatJSON:
+		return "json"
+	case FormatYAML:
+		return "yaml"
+	case FormatHCL:
+		return "hcl"
+	case FormatTOML:
+		return "toml"
+	default:
+		return "unknown"
+	}
+}
@@ -0,0 +1,82 @@
+package exportentities
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v2"
+)
+
+//CheckYAMLExpansion checks that a YAML document, once its aliases are expanded, has at most maxNodes nodes
+//nested on at most maxDepth levels. The document is walked level by level, so that a document with nested
+//aliases is rejected before it is expanded in memory.
+func CheckYAMLExpansion(data []byte, maxNodes, maxDepth int) error {
+	w := &yamlWalker{maxNodes: maxNodes, maxDepth: maxDepth}
+	return yaml.Unmarshal(data, &yamlGuard{w: w})
+}
+
+type yamlWalker struct {
+	nodes    int
+	maxNodes int
+	maxDepth int
+}
+
+//yamlGuard walks the document from its root
+type yamlGuard struct {
+	w *yamlWalker
+}
+
+//UnmarshalYAML implements yaml.Unmarshaler
+func (g *yamlGuard) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	return g.w.walk(unmarshal, 1)
+}
+
+//yamlNode keeps the function decoding a node, without decoding it
+type yamlNode struct {
+	unmarshal func(interface{}) error
+}
+
+//UnmarshalYAML implements yaml.Unmarshaler
+func (n *yamlNode) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	n.unmarshal = unmarshal
+	return nil
+}
+
+func (w *yamlWalker) walk(unmarshal func(interface{}) error, depth int) error {
+	w.nodes++
+	if w.maxNodes > 0 && w.nodes > w.maxNodes {
+		return fmt.Errorf("yaml document has more than %d nodes once its aliases are expanded", w.maxNodes)
+	}
+	if w.maxDepth > 0 && depth > w.maxDepth {
+		return fmt.Errorf("yaml document has more than %d levels once its aliases are expanded", w.maxDepth)
+	}
+
+	// A type error means that the node is not a mapping, or not a sequence
+	var m map[interface{}]yamlNode
+	err := unmarshal(&m)
+	if err == nil {
+		for _, n := range m {
+			if err := w.walk(n.unmarshal, depth+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if _, ok := err.(*yaml.TypeError); !ok {
+		return err
+	}
+
+	var s []yamlNode
+	err = unmarshal(&s)
+	if err == nil {
+		for _, n := range s {
+			if err := w.walk(n.unmarshal, depth+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if _, ok := err.(*yaml.TypeError); !ok {
+		return err
+	}
+	return nil
+}
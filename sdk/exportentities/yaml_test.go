@@ -0,0 +1,49 @@
+package exportentities
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckYAMLExpansion(t *testing.T) {
+	anchors := `
+build: &build
+  parameters:
+    version:
+      type: string
+      value: "1.0"
+pipelines:
+  build: *build
+  build-arm:
+    <<: *build
+`
+	assert.NoError(t, CheckYAMLExpansion([]byte(anchors), 100, 10))
+
+	//Each level references the previous one ten times
+	laughs := `
+a: &a ["lol","lol","lol","lol","lol","lol","lol","lol","lol","lol"]
+b: &b [*a,*a,*a,*a,*a,*a,*a,*a,*a,*a]
+c: &c [*b,*b,*b,*b,*b,*b,*b,*b,*b,*b]
+d: &d [*c,*c,*c,*c,*c,*c,*c,*c,*c,*c]
+e: &e [*d,*d,*d,*d,*d,*d,*d,*d,*d,*d]
+f: &f [*e,*e,*e,*e,*e,*e,*e,*e,*e,*e]
+g: &g [*f,*f,*f,*f,*f,*f,*f,*f,*f,*f]
+h: &h [*g,*g,*g,*g,*g,*g,*g,*g,*g,*g]
+i: &i [*h,*h,*h,*h,*h,*h,*h,*h,*h,*h]
+`
+	err := CheckYAMLExpansion([]byte(laughs), 10000, 100)
+	assert.EqualError(t, err, "yaml document has more than 10000 nodes once its aliases are expanded")
+
+	deep := `
+a: &a [x]
+b: &b [*a]
+c: &c [*b]
+d: &d [*c]
+e: [*d]
+`
+	err = CheckYAMLExpansion([]byte(deep), 10000, 5)
+	assert.EqualError(t, err, "yaml document has more than 5 levels once its aliases are expanded")
+
+	assert.Error(t, CheckYAMLExpansion([]byte("a: *unknown"), 100, 10))
+}
@@ -4,20 +4,74 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"regexp"
 )
 
 // Hook used to link a git repository to a given pipeline
 type Hook struct {
-	ID            int64    `json:"id"`
-	UID           string   `json:"uid"`
-	Pipeline      Pipeline `json:"pipeline"`
-	ApplicationID int64    `json:"application_id"`
-	Kind          string   `json:"kind"`
-	Host          string   `json:"host"`
-	Project       string   `json:"project"`
-	Repository    string   `json:"repository"`
-	Enabled       bool     `json:"enabled"`
-	Link          string   `json:"link"`
+	ID            int64        `json:"id"`
+	UID           string       `json:"uid"`
+	Pipeline      Pipeline     `json:"pipeline"`
+	ApplicationID int64        `json:"application_id"`
+	Kind          string       `json:"kind"`
+	Host          string       `json:"host"`
+	Project       string       `json:"project"`
+	Repository    string       `json:"repository"`
+	Enabled       bool         `json:"enabled"`
+	Link          string       `json:"link"`
+	Branches      HookBranches `json:"branches"`
+}
+
+// HookBranches filters the branches triggering a hook. The patterns are regular expressions matching the whole
+// name of the branch. A branch triggers the hook if it matches an included pattern, or if there is none, and
+// if it matches no excluded pattern.
+type HookBranches struct {
+	Include []string `json:"include,omitempty"`
+	Exclude []string `json:"exclude,omitempty"`
+}
+
+// IsEmpty returns true if no branch is filtered
+func (b HookBranches) IsEmpty() bool {
+	return len(b.Include) == 0 && len(b.Exclude) == 0
+}
+
+// Match returns true if the branch triggers the hook. An invalid pattern matches no branch.
+func (b HookBranches) Match(branch string) bool {
+	match := func(patterns []string) bool {
+		for _, p := range patterns {
+			if r, err := CompileHookBranchPattern(p); err == nil && r.MatchString(branch) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(b.Include) > 0 && !match(b.Include) {
+		return false
+	}
+	return !match(b.Exclude)
+}
+
+// CompileHookBranchPattern compiles a branch pattern of a hook, anchored on the whole name of the branch
+func CompileHookBranchPattern(p string) (*regexp.Regexp, error) {
+	return regexp.Compile("^(?:" + p + ")$")
+}
+
+// ExternalHooksDiff is the difference between the hooks of an application to import and the hooks found
+// on its repository. Nothing is applied: the import creates the added hooks and deletes the removed ones.
+type ExternalHooksDiff struct {
+	RepositoriesManager string         `json:"repositories_manager"`
+	Repository          string         `json:"repository"`
+	Added               []ExternalHook `json:"added"`
+	Removed             []ExternalHook `json:"removed"`
+	Unchanged           []ExternalHook `json:"unchanged"`
+	// Missing hooks exist in CDS but not on the repository, the import doesn't create them again
+	Missing []ExternalHook `json:"missing"`
+}
+
+// ExternalHook is a hook of a pipeline on a repository. The url of a hook which doesn't exist yet is unknown.
+type ExternalHook struct {
+	Pipeline string `json:"pipeline"`
+	URL      string `json:"url,omitempty"`
 }
 
 // AddHook creates a new hook between a pipeline and a repository
@@ -0,0 +1,30 @@
+package sdk
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHookBranchesMatch(t *testing.T) {
+	b := HookBranches{}
+	assert.True(t, b.IsEmpty())
+	assert.True(t, b.Match("feat/foo"))
+
+	b = HookBranches{Include: []string{"master", `release/\d+`}, Exclude: []string{"release/0"}}
+	assert.False(t, b.IsEmpty())
+	assert.True(t, b.Match("master"))
+	assert.True(t, b.Match("release/12"))
+	assert.False(t, b.Match("release/0"))
+	assert.False(t, b.Match("release/1.x"))
+	//The patterns match the whole name of the branch
+	assert.False(t, b.Match("old-master"))
+
+	b = HookBranches{Exclude: []string{"wip/.*|tmp"}}
+	assert.True(t, b.Match("master"))
+	assert.False(t, b.Match("wip/foo"))
+	assert.False(t, b.Match("tmp"))
+
+	_, err := CompileHookBranchPattern("release/(")
+	assert.Error(t, err)
+}
@@ -0,0 +1,156 @@
+package sdk
+
+import (
+	"regexp"
+	"time"
+)
+
+// ImportCount counts the resources created, updated and skipped by an import
+type ImportCount struct {
+	Created int `json:"created"`
+	Updated int `json:"updated"`
+	Skipped int `json:"skipped"`
+}
+
+// ImportSummary sums up what has been done by an import
+type ImportSummary struct {
+	Pipelines     ImportCount `json:"pipelines"`
+	Hooks         ImportCount `json:"hooks"`
+	Pollers       ImportCount `json:"pollers"`
+	Notifications ImportCount `json:"notifications"`
+	Schedulers    ImportCount `json:"schedulers"`
+	Warnings      int         `json:"warnings"`
+	// Schedules lists the next execution of the imported schedulers
+	Schedules []ImportSchedule `json:"schedules,omitempty"`
+}
+
+// ImportSchedule is the next execution of an imported scheduler
+type ImportSchedule struct {
+	Pipeline      string    `json:"pipeline"`
+	Environment   string    `json:"environment"`
+	Crontab       string    `json:"crontab"`
+	Timezone      string    `json:"timezone"`
+	NextExecution time.Time `json:"next_execution"`
+}
+
+// ImportResult is the structured response of an import
+type ImportResult struct {
+	Messages []StructuredMessage `json:"messages"`
+	Summary  ImportSummary       `json:"summary"`
+	// Export is the imported application as it is stored, if it has been asked with echo
+	Export string `json:"export,omitempty"`
+}
+
+// Status of a line of an import batch
+const (
+	ImportBatchSuccess = "Success"
+	ImportBatchFail    = "Fail"
+	ImportBatchSkipped = "Skipped"
+)
+
+// ImportBatchLine is the result of the import of a line of a batch
+type ImportBatchLine struct {
+	Line        int                 `json:"line"`
+	Application string              `json:"application,omitempty"`
+	Status      string              `json:"status"`
+	HTTPStatus  int                 `json:"http_status,omitempty"`
+	Error       string              `json:"error,omitempty"`
+	Messages    []StructuredMessage `json:"messages,omitempty"`
+	Summary     *ImportSummary      `json:"summary,omitempty"`
+	Export      string              `json:"export,omitempty"`
+}
+
+// ImportBatchResult is the response of an import batch, with the result of each line
+type ImportBatchResult struct {
+	Imported int               `json:"imported"`
+	Failed   int               `json:"failed"`
+	Skipped  int               `json:"skipped"`
+	Lines    []ImportBatchLine `json:"lines"`
+}
+
+//Add counts the resource reported by an import message
+func (s *ImportSummary) Add(m Message) {
+	switch m.ID {
+	case MsgPipelineCreated.ID:
+		s.Pipelines.Created++
+	case MsgPipelineExists.ID:
+		s.Pipelines.Skipped++
+	case MsgHookCreated.ID:
+		s.Hooks.Created++
+	case MsgHookExists.ID:
+		s.Hooks.Skipped++
+	case MsgPollerCreated.ID:
+		s.Pollers.Created++
+	case MsgPollerExists.ID:
+		s.Pollers.Skipped++
+	case MsgAppNotificationUpdated.ID:
+		s.Notifications.Updated++
+	case MsgSchedulerCreated.ID:
+		s.Schedulers.Created++
+	case MsgSchedulerExists.ID:
+		s.Schedulers.Skipped++
+	}
+}
+
+// ImportAudit records who has imported an application, and what has been done
+type ImportAudit struct {
+	ID              int64         `json:"id"`
+	ProjectKey      string        `json:"project_key"`
+	ApplicationName string        `json:"application_name"`
+	Author          string        `json:"author"`
+	Format          string        `json:"format"`
+	ForceUpdate     bool          `json:"force_update"`
+	PayloadHash     string        `json:"payload_hash"`
+	Summary         ImportSummary `json:"summary"`
+	Created         time.Time     `json:"created"`
+	// Payload is the imported payload, kept to replay the import until the retention delay is over
+	Payload []byte `json:"-"`
+}
+
+// ImportTemplate is an application import payload stored in a project. An import which references it
+// is replaced by its content, where the {{.name}} placeholders of its parameters are substituted.
+type ImportTemplate struct {
+	ID         int64                     `json:"id"`
+	ProjectKey string                    `json:"project_key"`
+	Name       string                    `json:"name"`
+	Format     string                    `json:"format"`
+	Content    string                    `json:"content"`
+	Parameters []ImportTemplateParameter `json:"parameters"`
+	Created    time.Time                 `json:"created"`
+}
+
+// ImportTemplateParameter is a parameter of an import template
+type ImportTemplateParameter struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Default     string `json:"default,omitempty"`
+	Required    bool   `json:"required"`
+}
+
+var importTemplatePlaceholder = regexp.MustCompile(`\{\{\s*\.([a-zA-Z0-9_-]+)\s*\}\}`)
+
+//Expand substitutes the parameters in the content of the template. The placeholders which are not
+//parameters of the template, such as the CDS variables, are kept. The required parameters which have
+//no value are returned.
+func (t *ImportTemplate) Expand(params map[string]string) (string, []string) {
+	values := make(map[string]string, len(t.Parameters))
+	var missing []string
+	for _, p := range t.Parameters {
+		v, ok := params[p.Name]
+		if !ok {
+			v = p.Default
+		}
+		if v == "" && p.Required {
+			missing = append(missing, p.Name)
+		}
+		values[p.Name] = v
+	}
+
+	content := importTemplatePlaceholder.ReplaceAllStringFunc(t.Content, func(s string) string {
+		if v, ok := values[importTemplatePlaceholder.FindStringSubmatch(s)[1]]; ok {
+			return v
+		}
+		return s
+	})
+	return content, missing
+}
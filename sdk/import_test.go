@@ -0,0 +1,62 @@
+package sdk
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestImportSummaryAdd(t *testing.T) {
+	msgs := []Message{
+		NewMessage(MsgAppCreated, "foo"),
+		NewMessage(MsgPipelineCreated, "build"),
+		NewMessage(MsgPipelineExists, "deploy"),
+		NewMessage(MsgHookCreated, "ovh/cds", "build"),
+		NewMessage(MsgHookCreated, "ovh/cds", "deploy"),
+		NewMessage(MsgHookExists, "ovh/cds", "test"),
+		NewMessage(MsgPollerCreated, "ovh/cds", "build"),
+		NewMessage(MsgAppNotificationUpdated, "build", "foo", "NoEnv"),
+		NewMessage(MsgSchedulerCreated, "0 * * * *", "build", "NoEnv"),
+		NewMessage(MsgSchedulerExists, "0 0 * * *", "build", "NoEnv"),
+	}
+	want := ImportSummary{
+		Pipelines:     ImportCount{Created: 1, Skipped: 1},
+		Hooks:         ImportCount{Created: 2, Skipped: 1},
+		Pollers:       ImportCount{Created: 1},
+		Notifications: ImportCount{Updated: 1},
+		Schedulers:    ImportCount{Created: 1, Skipped: 1},
+	}
+
+	got := ImportSummary{}
+	for _, m := range msgs {
+		got.Add(m)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ImportSummary.Add() = %+v, want %+v", got, want)
+	}
+}
+
+func TestImportTemplateExpand(t *testing.T) {
+	tmpl := ImportTemplate{
+		Name:    "service",
+		Content: "name: {{.name}}\nrepo_name: {{ .repo }}\nvariables:\n  env:\n    value: \"{{.cds.env.name}} {{.tier}}\"\n",
+		Parameters: []ImportTemplateParameter{
+			{Name: "name", Required: true},
+			{Name: "repo", Required: true},
+			{Name: "tier", Default: "3"},
+		},
+	}
+
+	content, missing := tmpl.Expand(map[string]string{"name": "billing", "repo": "team/billing"})
+	if len(missing) != 0 {
+		t.Errorf("Expand() missing = %v, want none", missing)
+	}
+	want := "name: billing\nrepo_name: team/billing\nvariables:\n  env:\n    value: \"{{.cds.env.name}} 3\"\n"
+	if content != want {
+		t.Errorf("Expand() = %q, want %q", content, want)
+	}
+
+	_, missing = tmpl.Expand(map[string]string{"repo": "team/billing", "tier": "1"})
+	if !reflect.DeepEqual(missing, []string{"name"}) {
+		t.Errorf("Expand() missing = %v, want [name]", missing)
+	}
+}
@@ -2,6 +2,7 @@ package sdk
 
 import (
 	"fmt"
+	"strings"
 
 	"golang.org/x/text/language"
 )
@@ -29,6 +30,78 @@ var (
 	MsgAppGroupSetPermission               = &Message{"MsgAppGroupSetPermission", trad{FR: "Permission accordée au groupe %s sur l'application %s", EN: "Permission applied to group %s to application %s"}, nil}
 	MsgAppVariablesCreated                 = &Message{"MsgAppVariablesCreated", trad{FR: "Les variables ont été ajoutées avec succès sur l'application %s", EN: "Application variable for %s are successfully created"}, nil}
 	MsgHookCreated                         = &Message{"MsgHookCreated", trad{FR: "Hook créé sur le depôt %s vers le pipeline %s", EN: "Hook created on repository %s to pipeline %s"}, nil}
+	MsgAppUpdated                          = &Message{"MsgAppUpdated", trad{FR: "L'application %s a été mise à jour avec succès", EN: "Application %s successfully updated"}, nil}
+	MsgAppVariableCreated                  = &Message{"MsgAppVariableCreated", trad{FR: "La variable %s de l'application %s a été ajoutée", EN: "Variable %s on application %s has been added"}, nil}
+	MsgAppVariableUpdated                  = &Message{"MsgAppVariableUpdated", trad{FR: "La variable %s de l'application %s a été mise à jour", EN: "Variable %s on application %s has been updated"}, nil}
+	MsgAppGroupUpdated                     = &Message{"MsgAppGroupUpdated", trad{FR: "Les permissions du groupe %s sur l'application %s ont été mises à jour", EN: "Permission for group %s on application %s has been updated"}, nil}
+	MsgAppPipelineParametersUpdated        = &Message{"MsgAppPipelineParametersUpdated", trad{FR: "Les paramètres du pipeline %s de l'application %s ont été mis à jour", EN: "Parameters of pipeline %s on application %s have been updated"}, nil}
+	MsgAppNotificationUpdated              = &Message{"MsgAppNotificationUpdated", trad{FR: "Les notifications du pipeline %s de l'application %s sur l'environnement %s ont été mises à jour", EN: "Notifications of pipeline %s on application %s for environment %s have been updated"}, nil}
+	MsgPollerCreated                       = &Message{"MsgPollerCreated", trad{FR: "Polling créé sur le dépôt %s vers le pipeline %s", EN: "Poller created on repository %s to pipeline %s"}, nil}
+	MsgSchedulerCreated                    = &Message{"MsgSchedulerCreated", trad{FR: "Planification %s créée sur le pipeline %s pour l'environnement %s", EN: "Scheduler %s created on pipeline %s for environment %s"}, nil}
+	MsgHookExists                          = &Message{"MsgHookExists", trad{FR: "Le hook sur le dépôt %s vers le pipeline %s existe déjà", EN: "Hook on repository %s to pipeline %s already exists"}, nil}
+	MsgPollerExists                        = &Message{"MsgPollerExists", trad{FR: "Le polling sur le dépôt %s vers le pipeline %s existe déjà", EN: "Poller on repository %s to pipeline %s already exists"}, nil}
+	MsgSchedulerExists                     = &Message{"MsgSchedulerExists", trad{FR: "La planification %s sur le pipeline %s pour l'environnement %s existe déjà", EN: "Scheduler %s on pipeline %s for environment %s already exists"}, nil}
+	MsgAppImportRenamed                    = &Message{"MsgAppImportRenamed", trad{FR: "%s a été renommé en %s", EN: "%s has been renamed to %s"}, nil}
+	MsgAppImportTriggerProjectRewritten    = &Message{"MsgAppImportTriggerProjectRewritten", trad{FR: "Le trigger vers le pipeline %s pointe désormais sur le projet %s au lieu de %s", EN: "Trigger to pipeline %s now targets project %s instead of %s"}, nil}
+	MsgAppImportTriggerSkipped             = &Message{"MsgAppImportTriggerSkipped", trad{FR: "Le trigger du pipeline %s vers le pipeline %s est ignoré car sa destination n'est pas importée", EN: "Trigger from pipeline %s to pipeline %s is skipped because its destination is not imported"}, nil}
+	MsgAppImportSecretPreserved            = &Message{"MsgAppImportSecretPreserved", trad{FR: "La valeur de la variable secrète %s de l'application %s est masquée : la valeur existante a été conservée", EN: "Value of secret variable %s on application %s is masked: the stored value has been kept"}, nil}
+	MsgAppImportSecretSkipped              = &Message{"MsgAppImportSecretSkipped", trad{FR: "La valeur de la variable secrète %s de l'application %s est masquée : la variable n'a pas été créée", EN: "Value of secret variable %s on application %s is masked: the variable has not been created"}, nil}
+	MsgAppImportGroupNotFound              = &Message{"MsgAppImportGroupNotFound", trad{FR: "Le groupe %s n'existe pas : sa permission sur l'application %s est ignorée", EN: "Group %s does not exist: its permission on application %s is ignored"}, nil}
+	MsgAppImportKeyCreated                 = &Message{"MsgAppImportKeyCreated", trad{FR: "La clé %s de type %s a été créée sur l'application %s", EN: "Key %s of type %s has been created on application %s"}, nil}
+	MsgAppImportKeyExists                  = &Message{"MsgAppImportKeyExists", trad{FR: "La clé %s existe déjà sur l'application %s : elle a été conservée", EN: "Key %s already exists on application %s: it has been kept"}, nil}
+	MsgAppImportKeyRegenerated             = &Message{"MsgAppImportKeyRegenerated", trad{FR: "La clé %s de type %s a été regénérée sur l'application %s", EN: "Key %s of type %s has been regenerated on application %s"}, nil}
+	MsgAppImportNotifInvalid               = &Message{"MsgAppImportNotifInvalid", trad{FR: "Notification %s invalide sur le pipeline %s de l'application %s : champ %s invalide", EN: "Invalid %s notification on pipeline %s of application %s: invalid field %s"}, nil}
+	MsgAppImportOverrideApplied            = &Message{"MsgAppImportOverrideApplied", trad{FR: "La valeur de %s a été surchargée sur %s", EN: "Value of %s has been overridden on %s"}, nil}
+	MsgAppImportPipelineRefMissing         = &Message{"MsgAppImportPipelineRefMissing", trad{FR: "Le pipeline %s de l'application %s est référencé par son nom, ajoutez sa référence (ref)", EN: "Pipeline %s of application %s is referenced by its name, add its reference (ref)"}, nil}
+	MsgAppImportPipelineRefResolved        = &Message{"MsgAppImportPipelineRefResolved", trad{FR: "La référence %s de l'application %s correspond au pipeline %s", EN: "Reference %s of application %s matches pipeline %s"}, nil}
+	MsgAppImportFieldInvalid               = &Message{"MsgAppImportFieldInvalid", trad{FR: "Le champ %s de l'application %s est manquant ou invalide", EN: "Field %s of application %s is missing or invalid"}, nil}
+	MsgSchedulerInvalidCron                = &Message{"MsgSchedulerInvalidCron", trad{FR: "L'expression cron %s du pipeline %s de l'application %s est invalide : %s", EN: "Cron expression %s of pipeline %s of application %s is invalid: %s"}, nil}
+	MsgAppImportResourceFailed             = &Message{"MsgAppImportResourceFailed", trad{FR: "Impossible de créer la ressource %s %s de l'application %s : %s", EN: "Unable to create %s %s of application %s: %s"}, nil}
+	MsgAppImportResourcePruned             = &Message{"MsgAppImportResourcePruned", trad{FR: "La ressource %s %s de l'application %s a été supprimée car elle n'est plus importée", EN: "%s %s of application %s has been deleted as it is not imported anymore"}, nil}
+	MsgAppImportPermissionsKept            = &Message{"MsgAppImportPermissionsKept", trad{FR: "Les permissions de l'application %s n'ont pas été modifiées", EN: "Permissions of application %s have been left unchanged"}, nil}
+	MsgAppMetadataUpdated                  = &Message{"MsgAppMetadataUpdated", trad{FR: "La métadonnée %s de l'application %s a été mise à jour", EN: "Metadata %s of application %s has been updated"}, nil}
+	MsgImportArchiveFileInvalid            = &Message{"MsgImportArchiveFileInvalid", trad{FR: "Le fichier %s de l'archive est invalide : %s", EN: "File %s of the archive is invalid: %s"}, nil}
+	MsgAppImportTemplateParamMissing       = &Message{"MsgAppImportTemplateParamMissing", trad{FR: "Le paramètre %s du template %s est obligatoire", EN: "Parameter %s of template %s is required"}, nil}
+	MsgAppImportUnknownField               = &Message{"MsgAppImportUnknownField", trad{FR: "Le champ %s de l'application %s est inconnu", EN: "Field %s of application %s is unknown"}, nil}
+	MsgEnvironmentInheritanceCycle         = &Message{"MsgEnvironmentInheritanceCycle", trad{FR: "Boucle d'héritage d'environnements détectée : %s", EN: "Environment inheritance cycle detected: %s"}, nil}
+	MsgAppImportNotificationExpanded       = &Message{"MsgAppImportNotificationExpanded", trad{FR: "La notification %s de l'application %s est ajoutée au pipeline %s sur l'environnement %s", EN: "Notification %s of application %s is added to pipeline %s on environment %s"}, nil}
+	MsgAppImportDocumentsMerged            = &Message{"MsgAppImportDocumentsMerged", trad{FR: "L'application %s est fusionnée à partir de %d documents", EN: "Application %s is merged from %d documents"}, nil}
+	MsgAppImportValueOverridden            = &Message{"MsgAppImportValueOverridden", trad{FR: "La valeur %s de l'application %s est remplacée par le document %d", EN: "Value %s of application %s is overridden by document %d"}, nil}
+	MsgAppImportDestPipelineNotAttached    = &Message{"MsgAppImportDestPipelineNotAttached", trad{FR: "Le pipeline %s déclenché par le pipeline %s n'est pas lié à l'application %s", EN: "Pipeline %s triggered by pipeline %s is not attached to application %s"}, nil}
+	MsgAppImportParamDefaultSet            = &Message{"MsgAppImportParamDefaultSet", trad{FR: "La valeur par défaut du paramètre %s du pipeline %s est définie sur l'application %s", EN: "Default value of parameter %s of pipeline %s is set on application %s"}, nil}
+	MsgAppImportDuplicatePipeline          = &Message{"MsgAppImportDuplicatePipeline", trad{FR: "Le pipeline %s est listé %d fois dans l'application %s", EN: "Pipeline %s is listed %d times in application %s"}, nil}
+	MsgAppImportDuplicatePipelineKept      = &Message{"MsgAppImportDuplicatePipelineKept", trad{FR: "Le pipeline %s est listé %d fois dans l'application %s, le dernier est conservé", EN: "Pipeline %s is listed %d times in application %s, the last one is kept"}, nil}
+	MsgAppImportGroupUnchanged             = &Message{"MsgAppImportGroupUnchanged", trad{FR: "Les permissions du groupe %s sur l'application %s sont inchangées", EN: "Permission for group %s on application %s is unchanged"}, nil}
+	MsgAppImportGroupRevoked               = &Message{"MsgAppImportGroupRevoked", trad{FR: "Les permissions du groupe %s sur l'application %s sont révoquées", EN: "Permission for group %s on application %s is revoked"}, nil}
+	MsgAppImportVariableResolved           = &Message{"MsgAppImportVariableResolved", trad{FR: "La variable %s de l'application %s est résolue depuis une source externe", EN: "Variable %s of application %s is resolved from an external source"}, nil}
+	MsgAppExportSecretsOmitted             = &Message{"MsgAppExportSecretsOmitted", trad{FR: "Les secrets de l'application %s ne sont pas exportés, la permission d'écriture est requise", EN: "Secrets of application %s are not exported, write permission is required"}, nil}
+	MsgAppImportVerifyFailed               = &Message{"MsgAppImportVerifyFailed", trad{FR: "%s %s de l'application %s introuvable après l'import", EN: "%s %s of application %s not found after import"}, nil}
+	MsgAppImportPayloadTooLarge            = &Message{"MsgAppImportPayloadTooLarge", trad{FR: "Le contenu importé est trop gros, la taille maximale est de %d octets", EN: "Payload too large, max size is %d bytes"}, nil}
+	MsgAppImportEnvCaseCorrected           = &Message{"MsgAppImportEnvCaseCorrected", trad{FR: "L'environnement %s de l'application %s est résolu en %s, la casse du nom doit être corrigée", EN: "Environment %s of application %s is resolved as %s, the case of the name should be fixed"}, nil}
+	MsgAppDescriptionUpdated               = &Message{"MsgAppDescriptionUpdated", trad{FR: "La description de l'application %s a été mise à jour", EN: "Description of application %s has been updated"}, nil}
+	MsgAppImportCreatedDisabled            = &Message{"MsgAppImportCreatedDisabled", trad{FR: "Le %s du pipeline %s de l'application %s est créé désactivé", EN: "The %s of pipeline %s of application %s is created disabled"}, nil}
+	MsgAppResourceEnabled                  = &Message{"MsgAppResourceEnabled", trad{FR: "Le %s du pipeline %s de l'application %s est activé", EN: "The %s of pipeline %s of application %s is enabled"}, nil}
+	MsgEnvironmentReferenceResolved        = &Message{"MsgEnvironmentReferenceResolved", trad{FR: "La référence %s de la variable %s de l'environnement %s est résolue", EN: "Reference %s of variable %s of environment %s is resolved"}, nil}
+	MsgEnvironmentReferenceUnknown         = &Message{"MsgEnvironmentReferenceUnknown", trad{FR: "La référence %s de la variable %s de l'environnement %s est introuvable", EN: "Reference %s of variable %s of environment %s is not found"}, nil}
+	MsgEnvironmentReferenceSecret          = &Message{"MsgEnvironmentReferenceSecret", trad{FR: "La référence %s de la variable %s de l'environnement %s est un secret ou une clé, la variable doit être un secret ou une clé", EN: "Reference %s of variable %s of environment %s is a secret or a key, the variable must be a secret or a key"}, nil}
+	MsgEnvironmentReferenceCycle           = &Message{"MsgEnvironmentReferenceCycle", trad{FR: "Boucle de références entre environnements détectée : %s", EN: "Environment reference cycle detected: %s"}, nil}
+	MsgAppImportRetentionSet               = &Message{"MsgAppImportRetentionSet", trad{FR: "La rétention de l'application %s est définie : %d exécutions, %d jours", EN: "Retention of application %s is set: %d runs, %d days"}, nil}
+	MsgAppImportRetentionInvalid           = &Message{"MsgAppImportRetentionInvalid", trad{FR: "La rétention de l'application %s est invalide : %s doit être positif", EN: "Retention of application %s is invalid: %s must be positive"}, nil}
+	MsgAppImportPrerequisiteInvalid        = &Message{"MsgAppImportPrerequisiteInvalid", trad{FR: "Le prérequis %s du déclencheur du pipeline %s vers le pipeline %s de l'application %s est invalide : %s", EN: "Prerequisite %s of the trigger from pipeline %s to pipeline %s of application %s is invalid: %s"}, nil}
+	MsgAppImportPrerequisitesUpdated       = &Message{"MsgAppImportPrerequisitesUpdated", trad{FR: "Les prérequis du déclencheur du pipeline %s vers le pipeline %s de l'application %s ont été mis à jour", EN: "Prerequisites of the trigger from pipeline %s to pipeline %s of application %s have been updated"}, nil}
+	MsgAppImportGenerationConflict         = &Message{"MsgAppImportGenerationConflict", trad{FR: "La génération %d de l'application %s n'est pas supérieure à sa génération enregistrée %d", EN: "Generation %d of application %s is not greater than its stored generation %d"}, nil}
+	MsgAppImportRepositoryFetched          = &Message{"MsgAppImportRepositoryFetched", trad{FR: "Fichier %s récupéré depuis le dépôt %s au commit %s (%s)", EN: "File %s fetched from repository %s at commit %s (%s)"}, nil}
+	MsgSchedulerArgsSet                    = &Message{"MsgSchedulerArgsSet", trad{FR: "Arguments de la planification %s sur le pipeline %s pour l'environnement %s : %s", EN: "Arguments of scheduler %s on pipeline %s for environment %s: %s"}, nil}
+	MsgSchedulerArgUnknown                 = &Message{"MsgSchedulerArgUnknown", trad{FR: "L'argument %s de la planification %s n'est pas un paramètre du pipeline %s", EN: "Argument %s of scheduler %s is not a parameter of pipeline %s"}, nil}
+	MsgAppImportHooksSkipped               = &Message{"MsgAppImportHooksSkipped", trad{FR: "%d hooks et %d pollers ignorés : les hooks et les pollers de l'application %s ne sont ni créés ni supprimés", EN: "%d hooks and %d pollers skipped: hooks and pollers of application %s are neither created nor deleted"}, nil}
+	MsgAppImportVariableInvalid            = &Message{"MsgAppImportVariableInvalid", trad{FR: "Le nom de variable %s dans %s de l'application %s est invalide, seuls les lettres, les chiffres et les caractères _ . - sont autorisés", EN: "Variable name %s in %s of application %s is invalid, only letters, digits and the characters _ . - are allowed"}, nil}
+	MsgAppImportVariableDuplicate          = &Message{"MsgAppImportVariableDuplicate", trad{FR: "La variable %s est déclarée %d fois dans %s de l'application %s", EN: "Variable %s is declared %d times in %s of application %s"}, nil}
+	MsgHookBranchesUpdated                 = &Message{"MsgHookBranchesUpdated", trad{FR: "Les filtres de branches du hook du pipeline %s de l'application %s ont été mis à jour", EN: "Branch filters of the hook of pipeline %s in application %s have been updated"}, nil}
+	MsgAppImportHookBranchInvalid          = &Message{"MsgAppImportHookBranchInvalid", trad{FR: "Le filtre de branches %s du hook du pipeline %s de l'application %s est invalide : %s", EN: "Branch filter %s of the hook of pipeline %s in application %s is invalid: %s"}, nil}
+	MsgAppImportInvalidPermissionLevel     = &Message{"MsgAppImportInvalidPermissionLevel", trad{FR: "Le niveau de permission %d du groupe %s sur l'application %s est invalide, les niveaux autorisés sont 4 (lecture), 5 (lecture et exécution) et 7 (lecture, écriture et exécution)", EN: "Permission level %d of group %s on application %s is invalid, allowed levels are 4 (read), 5 (read and execute) and 7 (read, write and execute)"}, nil}
+	MsgAppImportHookRejected               = &Message{"MsgAppImportHookRejected", trad{FR: "L'import de l'application %s a été refusé : %s", EN: "Import of application %s has been rejected: %s"}, nil}
+	MsgAppImportTriggerCycle               = &Message{"MsgAppImportTriggerCycle", trad{FR: "Boucle de triggers détectée : %s", EN: "Trigger cycle detected: %s"}, nil}
+	MsgAppImportTriggerSameSourceDest      = &Message{"MsgAppImportTriggerSameSourceDest", trad{FR: "Le pipeline %s ne peut pas se déclencher lui-même", EN: "Pipeline %s cannot trigger itself"}, nil}
 	MsgEnvironmentExists                   = &Message{"MsgEnvironmentExists", trad{FR: "L'environnement %s existe déjà", EN: "Environment %s already exist"}, nil}
 	MsgEnvironmentCreated                  = &Message{"MsgEnvironmentCreated", trad{FR: "L'environnement %s a été créé avec succès", EN: "Environment %s successfully created"}, nil}
 	MsgEnvironmentVariableUpdated          = &Message{"MsgEnvironmentVariableUpdated", trad{FR: "La variable %s de l'environnement %s a été mise à jour", EN: "Variable %s on environment %s has been updated"}, nil}
@@ -74,6 +147,78 @@ var Messages = map[string]*Message{
 	MsgAppGroupSetPermission.ID:               MsgAppGroupSetPermission,
 	MsgAppVariablesCreated.ID:                 MsgAppVariablesCreated,
 	MsgHookCreated.ID:                         MsgHookCreated,
+	MsgAppUpdated.ID:                          MsgAppUpdated,
+	MsgAppVariableCreated.ID:                  MsgAppVariableCreated,
+	MsgAppVariableUpdated.ID:                  MsgAppVariableUpdated,
+	MsgAppGroupUpdated.ID:                     MsgAppGroupUpdated,
+	MsgAppPipelineParametersUpdated.ID:        MsgAppPipelineParametersUpdated,
+	MsgAppNotificationUpdated.ID:              MsgAppNotificationUpdated,
+	MsgPollerCreated.ID:                       MsgPollerCreated,
+	MsgSchedulerCreated.ID:                    MsgSchedulerCreated,
+	MsgHookExists.ID:                          MsgHookExists,
+	MsgPollerExists.ID:                        MsgPollerExists,
+	MsgSchedulerExists.ID:                     MsgSchedulerExists,
+	MsgAppImportRenamed.ID:                    MsgAppImportRenamed,
+	MsgAppImportTriggerProjectRewritten.ID:    MsgAppImportTriggerProjectRewritten,
+	MsgAppImportTriggerSkipped.ID:             MsgAppImportTriggerSkipped,
+	MsgAppImportSecretPreserved.ID:            MsgAppImportSecretPreserved,
+	MsgAppImportSecretSkipped.ID:              MsgAppImportSecretSkipped,
+	MsgAppImportGroupNotFound.ID:              MsgAppImportGroupNotFound,
+	MsgAppImportKeyCreated.ID:                 MsgAppImportKeyCreated,
+	MsgAppImportKeyExists.ID:                  MsgAppImportKeyExists,
+	MsgAppImportKeyRegenerated.ID:             MsgAppImportKeyRegenerated,
+	MsgAppImportNotifInvalid.ID:               MsgAppImportNotifInvalid,
+	MsgAppImportOverrideApplied.ID:            MsgAppImportOverrideApplied,
+	MsgAppImportPipelineRefMissing.ID:         MsgAppImportPipelineRefMissing,
+	MsgAppImportPipelineRefResolved.ID:        MsgAppImportPipelineRefResolved,
+	MsgAppImportFieldInvalid.ID:               MsgAppImportFieldInvalid,
+	MsgSchedulerInvalidCron.ID:                MsgSchedulerInvalidCron,
+	MsgAppImportResourceFailed.ID:             MsgAppImportResourceFailed,
+	MsgAppImportResourcePruned.ID:             MsgAppImportResourcePruned,
+	MsgAppImportPermissionsKept.ID:            MsgAppImportPermissionsKept,
+	MsgAppMetadataUpdated.ID:                  MsgAppMetadataUpdated,
+	MsgImportArchiveFileInvalid.ID:            MsgImportArchiveFileInvalid,
+	MsgAppImportTemplateParamMissing.ID:       MsgAppImportTemplateParamMissing,
+	MsgAppImportUnknownField.ID:               MsgAppImportUnknownField,
+	MsgEnvironmentInheritanceCycle.ID:         MsgEnvironmentInheritanceCycle,
+	MsgAppImportNotificationExpanded.ID:       MsgAppImportNotificationExpanded,
+	MsgAppImportDocumentsMerged.ID:            MsgAppImportDocumentsMerged,
+	MsgAppImportValueOverridden.ID:            MsgAppImportValueOverridden,
+	MsgAppImportDestPipelineNotAttached.ID:    MsgAppImportDestPipelineNotAttached,
+	MsgAppImportParamDefaultSet.ID:            MsgAppImportParamDefaultSet,
+	MsgAppImportDuplicatePipeline.ID:          MsgAppImportDuplicatePipeline,
+	MsgAppImportDuplicatePipelineKept.ID:      MsgAppImportDuplicatePipelineKept,
+	MsgAppImportGroupUnchanged.ID:             MsgAppImportGroupUnchanged,
+	MsgAppImportGroupRevoked.ID:               MsgAppImportGroupRevoked,
+	MsgAppImportVariableResolved.ID:           MsgAppImportVariableResolved,
+	MsgAppExportSecretsOmitted.ID:             MsgAppExportSecretsOmitted,
+	MsgAppImportVerifyFailed.ID:               MsgAppImportVerifyFailed,
+	MsgAppImportPayloadTooLarge.ID:            MsgAppImportPayloadTooLarge,
+	MsgAppImportEnvCaseCorrected.ID:           MsgAppImportEnvCaseCorrected,
+	MsgAppDescriptionUpdated.ID:               MsgAppDescriptionUpdated,
+	MsgAppImportCreatedDisabled.ID:            MsgAppImportCreatedDisabled,
+	MsgAppResourceEnabled.ID:                  MsgAppResourceEnabled,
+	MsgEnvironmentReferenceResolved.ID:        MsgEnvironmentReferenceResolved,
+	MsgEnvironmentReferenceUnknown.ID:         MsgEnvironmentReferenceUnknown,
+	MsgEnvironmentReferenceSecret.ID:          MsgEnvironmentReferenceSecret,
+	MsgEnvironmentReferenceCycle.ID:           MsgEnvironmentReferenceCycle,
+	MsgAppImportRetentionSet.ID:               MsgAppImportRetentionSet,
+	MsgAppImportRetentionInvalid.ID:           MsgAppImportRetentionInvalid,
+	MsgAppImportPrerequisiteInvalid.ID:        MsgAppImportPrerequisiteInvalid,
+	MsgAppImportPrerequisitesUpdated.ID:       MsgAppImportPrerequisitesUpdated,
+	MsgAppImportGenerationConflict.ID:         MsgAppImportGenerationConflict,
+	MsgAppImportRepositoryFetched.ID:          MsgAppImportRepositoryFetched,
+	MsgSchedulerArgsSet.ID:                    MsgSchedulerArgsSet,
+	MsgSchedulerArgUnknown.ID:                 MsgSchedulerArgUnknown,
+	MsgAppImportHooksSkipped.ID:               MsgAppImportHooksSkipped,
+	MsgAppImportVariableInvalid.ID:            MsgAppImportVariableInvalid,
+	MsgAppImportVariableDuplicate.ID:          MsgAppImportVariableDuplicate,
+	MsgHookBranchesUpdated.ID:                 MsgHookBranchesUpdated,
+	MsgAppImportHookBranchInvalid.ID:          MsgAppImportHookBranchInvalid,
+	MsgAppImportInvalidPermissionLevel.ID:     MsgAppImportInvalidPermissionLevel,
+	MsgAppImportHookRejected.ID:               MsgAppImportHookRejected,
+	MsgAppImportTriggerCycle.ID:               MsgAppImportTriggerCycle,
+	MsgAppImportTriggerSameSourceDest.ID:      MsgAppImportTriggerSameSourceDest,
 	MsgEnvironmentExists.ID:                   MsgEnvironmentExists,
 	MsgEnvironmentCreated.ID:                  MsgEnvironmentCreated,
 	MsgEnvironmentVariableUpdated.ID:          MsgEnvironmentVariableUpdated,
@@ -112,14 +257,153 @@ type Message struct {
 	Args   []interface{}
 }
 
+// MessageLevel is the severity of a message
+type MessageLevel string
+
+// All the message levels
+const (
+	MessageLevelInfo    MessageLevel = "info"
+	MessageLevelWarning MessageLevel = "warning"
+	MessageLevelError   MessageLevel = "error"
+)
+
+// Verbosities of a list of messages, from the less to the most verbose
+const (
+	MessageVerbosityErrors   = "errors"
+	MessageVerbosityWarnings = "warnings"
+	MessageVerbosityAll      = "all"
+)
+
+// messageLevelSeverity orders the message levels
+var messageLevelSeverity = map[MessageLevel]int{
+	MessageLevelInfo:    0,
+	MessageLevelWarning: 1,
+	MessageLevelError:   2,
+}
+
+//AtLeast returns true if the level is as severe as min, or more
+func (l MessageLevel) AtLeast(min MessageLevel) bool {
+	return messageLevelSeverity[l] >= messageLevelSeverity[min]
+}
+
+//VerbosityLevel returns the lowest level of the messages kept for a verbosity. An empty verbosity keeps all
+//the messages.
+func VerbosityLevel(verbosity string) (MessageLevel, error) {
+	switch strings.ToLower(strings.TrimSpace(verbosity)) {
+	case MessageVerbosityErrors:
+		return MessageLevelError, nil
+	case MessageVerbosityWarnings:
+		return MessageLevelWarning, nil
+	case "", MessageVerbosityAll:
+		return MessageLevelInfo, nil
+	default:
+		return MessageLevelInfo, fmt.Errorf("unknown verbosity %q: expected %s, %s or %s", verbosity, MessageVerbosityErrors, MessageVerbosityWarnings, MessageVerbosityAll)
+	}
+}
+
+// messagesLevel lists the messages which are not informational
+var messagesLevel = map[string]MessageLevel{
+	MsgPipelineCreationAborted.ID:            MessageLevelError,
+	MsgAppImportPipelineRefMissing.ID:        MessageLevelWarning,
+	MsgAppImportFieldInvalid.ID:              MessageLevelError,
+	MsgSchedulerInvalidCron.ID:               MessageLevelError,
+	MsgAppImportResourceFailed.ID:            MessageLevelError,
+	MsgAppImportResourcePruned.ID:            MessageLevelWarning,
+	MsgImportArchiveFileInvalid.ID:           MessageLevelError,
+	MsgAppImportTemplateParamMissing.ID:      MessageLevelError,
+	MsgAppImportUnknownField.ID:              MessageLevelError,
+	MsgEnvironmentInheritanceCycle.ID:        MessageLevelError,
+	MsgAppImportDestPipelineNotAttached.ID:   MessageLevelError,
+	MsgAppImportDuplicatePipeline.ID:         MessageLevelError,
+	MsgAppImportDuplicatePipelineKept.ID:     MessageLevelWarning,
+	MsgAppImportGroupRevoked.ID:              MessageLevelWarning,
+	MsgAppExportSecretsOmitted.ID:            MessageLevelWarning,
+	MsgAppImportVerifyFailed.ID:              MessageLevelError,
+	MsgAppImportPayloadTooLarge.ID:           MessageLevelError,
+	MsgAppImportEnvCaseCorrected.ID:          MessageLevelWarning,
+	MsgAppImportCreatedDisabled.ID:           MessageLevelWarning,
+	MsgEnvironmentReferenceUnknown.ID:        MessageLevelError,
+	MsgEnvironmentReferenceSecret.ID:         MessageLevelError,
+	MsgEnvironmentReferenceCycle.ID:          MessageLevelError,
+	MsgAppImportRetentionInvalid.ID:          MessageLevelError,
+	MsgAppImportPrerequisiteInvalid.ID:       MessageLevelError,
+	MsgAppImportGenerationConflict.ID:        MessageLevelError,
+	MsgSchedulerArgUnknown.ID:                MessageLevelError,
+	MsgAppImportVariableInvalid.ID:           MessageLevelError,
+	MsgAppImportVariableDuplicate.ID:         MessageLevelError,
+	MsgAppImportHookBranchInvalid.ID:         MessageLevelError,
+	MsgAppImportInvalidPermissionLevel.ID:    MessageLevelError,
+	MsgAppImportHookRejected.ID:              MessageLevelError,
+	MsgAppImportTriggerCycle.ID:              MessageLevelError,
+	MsgAppImportNotifInvalid.ID:              MessageLevelError,
+	MsgAppImportTriggerSameSourceDest.ID:     MessageLevelError,
+	MsgPipelineExists.ID:                     MessageLevelWarning,
+	MsgAppImportTriggerSkipped.ID:            MessageLevelWarning,
+	MsgAppImportSecretSkipped.ID:             MessageLevelWarning,
+	MsgAppImportGroupNotFound.ID:             MessageLevelWarning,
+	MsgEnvironmentExists.ID:                  MessageLevelWarning,
+	MsgEnvironmentVariableCannotBeUpdated.ID: MessageLevelError,
+	MsgEnvironmentVariableCannotBeCreated.ID: MessageLevelError,
+	MsgEnvironmentGroupCannotBeUpdated.ID:    MessageLevelError,
+	MsgEnvironmentGroupCannotBeCreated.ID:    MessageLevelError,
+	MsgJobNotValidActionNotFound.ID:          MessageLevelError,
+	MsgJobNotValidInvalidActionParameter.ID:  MessageLevelError,
+	MsgSpawnInfoHatcheryErrorSpawn.ID:        MessageLevelError,
+	MsgSpawnInfoWorkerForJobError.ID:         MessageLevelWarning,
+	MsgSpawnInfoJobError.ID:                  MessageLevelError,
+	MsgWorkflowError.ID:                      MessageLevelError,
+}
+
+// StructuredMessage is the serializable form of a message
+type StructuredMessage struct {
+	ID      string        `json:"id,omitempty"`
+	Level   MessageLevel  `json:"level"`
+	Args    []interface{} `json:"args,omitempty"`
+	Message string        `json:"message"`
+}
+
 //NewMessage instanciantes a new message
 func NewMessage(m *Message, args ...interface{}) Message {
 	return Message{
+		ID:     m.ID,
 		Format: m.Format,
 		Args:   args,
 	}
 }
 
+//DedupMessages removes the duplicated messages, keyed on their ID and arguments, and keeps the insertion order
+func DedupMessages(msgs []Message) []Message {
+	seen := make(map[string]struct{}, len(msgs))
+	res := make([]Message, 0, len(msgs))
+	for _, m := range msgs {
+		k := m.ID + fmt.Sprintf("%#v", m.Args)
+		if _, ok := seen[k]; ok {
+			continue
+		}
+		seen[k] = struct{}{}
+		res = append(res, m)
+	}
+	return res
+}
+
+//Level returns the severity of the message
+func (m *Message) Level() MessageLevel {
+	if l, ok := messagesLevel[m.ID]; ok {
+		return l
+	}
+	return MessageLevelInfo
+}
+
+//Structured returns the serializable form of the message for the specified language
+func (m *Message) Structured(al string) StructuredMessage {
+	return StructuredMessage{
+		ID:      m.ID,
+		Level:   m.Level(),
+		Args:    m.Args,
+		Message: m.String(al),
+	}
+}
+
 // SupportedLanguages on API errors
 var (
 	SupportedLanguages = []language.Tag{
@@ -129,20 +413,30 @@ var (
 	matcher = language.NewMatcher(SupportedLanguages)
 )
 
-//String returns formated string for the specified language
-func (m *Message) String(al string) string {
+//MatchLanguage returns the supported language which best matches an Accept-Language header,
+//such as "fr;q=0.8, en;q=0.9". English is returned if no supported language matches.
+func MatchLanguage(al string) language.Tag {
 	acceptedLanguages, _, err := language.ParseAcceptLanguage(al)
-	if err != nil {
-		return fmt.Sprintf(m.Format[EN], m.Args...)
+	if err != nil || len(acceptedLanguages) == 0 {
+		return language.AmericanEnglish
 	}
 
-	t, _, _ := matcher.Match(acceptedLanguages...)
-	switch t {
-	case language.French, language.AmericanEnglish:
-		return fmt.Sprintf(m.Format[lang(t)], m.Args...)
-	default:
-		return fmt.Sprintf(m.Format[EN], m.Args...)
+	// Languages are sorted by quality value, the first one supported wins.
+	// The matched tag may carry the region of the request, the index gives the supported language.
+	for _, t := range acceptedLanguages {
+		if _, i, c := matcher.Match(t); c != language.No {
+			return SupportedLanguages[i]
+		}
+	}
+	return language.AmericanEnglish
+}
+
+//String returns formated string for the specified language
+func (m *Message) String(al string) string {
+	if f, ok := m.Format[lang(MatchLanguage(al))]; ok {
+		return fmt.Sprintf(f, m.Args...)
 	}
+	return fmt.Sprintf(m.Format[EN], m.Args...)
 }
 
 // Errors implement error interface and is a set of error
@@ -159,7 +453,7 @@ func (e *Errors) TranslatedError(al string) string {
 		if i != 0 {
 			s += "\n"
 		}
-		s += err.String(language.AmericanEnglish.String())
+		s += err.String(al)
 	}
 	return s
 }
@@ -0,0 +1,113 @@
+package sdk
+
+import (
+	"reflect"
+	"testing"
+
+	"golang.org/x/text/language"
+)
+
+func TestMessageStructured(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  Message
+		al   string
+		want StructuredMessage
+	}{
+		{
+			"Informational message",
+			NewMessage(MsgAppCreated, "foo"),
+			"en-US",
+			StructuredMessage{ID: "MsgAppCreated", Level: MessageLevelInfo, Args: []interface{}{"foo"}, Message: "Application foo successfully created"},
+		},
+		{
+			"Error message",
+			NewMessage(MsgPipelineCreationAborted, "foo"),
+			"fr-FR",
+			StructuredMessage{ID: "MsgPipelineCreationAborted", Level: MessageLevelError, Args: []interface{}{"foo"}, Message: "La création du pipeline foo a été abandonnée"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.msg.Structured(tt.al); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Message.Structured() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDedupMessages(t *testing.T) {
+	msgs := []Message{
+		NewMessage(MsgAppCreated, "foo"),
+		NewMessage(MsgPipelineAttached, "build", "foo"),
+		NewMessage(MsgAppCreated, "foo"),
+		NewMessage(MsgAppCreated, "bar"),
+		NewMessage(MsgPipelineAttached, "build", "foo"),
+	}
+	want := []Message{
+		NewMessage(MsgAppCreated, "foo"),
+		NewMessage(MsgPipelineAttached, "build", "foo"),
+		NewMessage(MsgAppCreated, "bar"),
+	}
+	if got := DedupMessages(msgs); !reflect.DeepEqual(got, want) {
+		t.Errorf("DedupMessages() = %v, want %v", got, want)
+	}
+}
+
+func TestMatchLanguage(t *testing.T) {
+	tests := []struct {
+		al   string
+		want language.Tag
+	}{
+		{"", language.AmericanEnglish},
+		{"fr", language.French},
+		{"fr-FR", language.French},
+		{"en-GB", language.AmericanEnglish},
+		{"fr;q=0.8, en;q=0.9", language.AmericanEnglish},
+		{"en;q=0.8, fr-CA;q=0.9", language.French},
+		{"de-DE, fr;q=0.5", language.French},
+		{"de-DE, ja", language.AmericanEnglish},
+		{"*", language.AmericanEnglish},
+		{"not a;;language", language.AmericanEnglish},
+	}
+	for _, tt := range tests {
+		if got := MatchLanguage(tt.al); got != tt.want {
+			t.Errorf("MatchLanguage(%q) = %v, want %v", tt.al, got, tt.want)
+		}
+	}
+
+	m := NewMessage(MsgAppCreated, "foo")
+	if got := m.String("de-DE, fr;q=0.5"); got != "L'application foo a été créée avec succès" {
+		t.Errorf("Message.String() = %v", got)
+	}
+	if got := m.String("ja"); got != "Application foo successfully created" {
+		t.Errorf("Message.String() = %v", got)
+	}
+}
+
+func TestVerbosityLevel(t *testing.T) {
+	tests := []struct {
+		verbosity string
+		want      MessageLevel
+		wantErr   bool
+	}{
+		{"", MessageLevelInfo, false},
+		{"all", MessageLevelInfo, false},
+		{"Warnings", MessageLevelWarning, false},
+		{"errors", MessageLevelError, false},
+		{"debug", MessageLevelInfo, true},
+	}
+	for _, tt := range tests {
+		got, err := VerbosityLevel(tt.verbosity)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("VerbosityLevel(%q) error = %v, wantErr %v", tt.verbosity, err, tt.wantErr)
+		}
+		if got != tt.want {
+			t.Errorf("VerbosityLevel(%q) = %v, want %v", tt.verbosity, got, tt.want)
+		}
+	}
+
+	if !MessageLevelError.AtLeast(MessageLevelWarning) || !MessageLevelWarning.AtLeast(MessageLevelWarning) || MessageLevelInfo.AtLeast(MessageLevelWarning) {
+		t.Errorf("MessageLevel.AtLeast() doesn't order the levels")
+	}
+}
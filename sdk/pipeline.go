@@ -18,6 +18,7 @@ import (
 type Pipeline struct {
 	ID                  int64             `json:"id" yaml:"-"`
 	Name                string            `json:"name"`
+	Slug                string            `json:"slug,omitempty"`
 	Type                string            `json:"type"`
 	ProjectKey          string            `json:"projectKey"`
 	ProjectID           int64             `json:"-"`
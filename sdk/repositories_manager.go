@@ -296,9 +296,14 @@ type RepositoriesManagerClient interface {
 	Commits(repo, branch, since, until string) ([]VCSCommit, error)
 	Commit(repo, hash string) (VCSCommit, error)
 
+	//Contents
+	FileContent(repo, path, ref string) ([]byte, error)
+
 	//Hooks
+	//CreateHook must be idempotent: creating again a hook with the same url on a repository must not duplicate it
 	CreateHook(repo, url string) error
 	DeleteHook(repo, url string) error
+	Hooks(repo string) ([]VCSHook, error)
 
 	//Events
 	GetEvents(repo string, dateRef time.Time) ([]interface{}, time.Duration, error)
@@ -315,6 +320,12 @@ type RepositoriesManagerClient interface {
 	UploadReleaseFile(repo string, release *VCSRelease, runArtifact WorkflowNodeRunArtifact, file *bytes.Buffer) error
 }
 
+//VCSHook represents data about a hook found on a repository
+type VCSHook struct {
+	URL     string `json:"url"`
+	Enabled bool   `json:"enabled"`
+}
+
 // Release represents data about release on github, etc..
 type VCSRelease struct {
 	ID        int64  `json:"id"`
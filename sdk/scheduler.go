@@ -1,9 +1,16 @@
 package sdk
 
 import (
+	"fmt"
+	"strings"
 	"time"
+
+	"github.com/gorhill/cronexpr"
 )
 
+//CronTimezonePrefix prefixes the timezone of a cron expression, as in "CRON_TZ=Europe/Paris 0 30 6 * * * *"
+const CronTimezonePrefix = "CRON_TZ="
+
 //PipelineScheduler is a cron scheduler
 type PipelineScheduler struct {
 	ID              int64                       `json:"id" db:"id"`
@@ -11,6 +18,7 @@ type PipelineScheduler struct {
 	PipelineID      int64                       `json:"-" db:"pipeline_id"`
 	EnvironmentID   int64                       `json:"-" db:"environment_id"`
 	EnvironmentName string                      `json:"environment_name" db:"-"`
+	PipelineName    string                      `json:"pipeline_name,omitempty" db:"-"`
 	Args            []Parameter                 `json:"args,omitempty" db:"-"`
 	Crontab         string                      `json:"crontab,omitempty" db:"crontab"`
 	Timezone        string                      `json:"timezone" db:"timezone"`
@@ -28,3 +36,36 @@ type PipelineSchedulerExecution struct {
 	Executed             bool       `json:"executed" db:"executed"`
 	PipelineBuildVersion int64      `json:"pipeline_build_version" db:"pipeline_build_version"`
 }
+
+//SplitCrontab splits a cron expression prefixed by its timezone into the expression and the timezone.
+//The timezone is empty if the expression is not prefixed.
+func SplitCrontab(crontab string) (string, string) {
+	crontab = strings.TrimSpace(crontab)
+	if !strings.HasPrefix(crontab, CronTimezonePrefix) {
+		return crontab, ""
+	}
+	fields := strings.SplitN(strings.TrimPrefix(crontab, CronTimezonePrefix), " ", 2)
+	if len(fields) < 2 {
+		return "", fields[0]
+	}
+	return strings.TrimSpace(fields[1]), fields[0]
+}
+
+//ParseCrontab parses a cron expression with an optional timezone prefix. With seven fields, the first
+//one is the seconds. The default timezone is UTC.
+func ParseCrontab(crontab string) (*cronexpr.Expression, *time.Location, error) {
+	expr, tz := SplitCrontab(crontab)
+	loc := time.UTC
+	if tz != "" {
+		var err error
+		loc, err = time.LoadLocation(tz)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid timezone %s: %s", tz, err)
+		}
+	}
+	cronExpr, err := cronexpr.Parse(expr)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cronExpr, loc, nil
+}
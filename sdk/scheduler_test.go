@@ -0,0 +1,38 @@
+package sdk
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCrontab(t *testing.T) {
+	expr, tz := SplitCrontab("CRON_TZ=Europe/Paris 30 0 6 * * * *")
+	assert.Equal(t, "30 0 6 * * * *", expr)
+	assert.Equal(t, "Europe/Paris", tz)
+
+	expr, tz = SplitCrontab(" 0 6 * * * ")
+	assert.Equal(t, "0 6 * * *", expr)
+	assert.Equal(t, "", tz)
+
+	//With seven fields, the first one is the seconds
+	cronExpr, loc, err := ParseCrontab("CRON_TZ=Europe/Paris 30 0 6 * * * *")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "Europe/Paris", loc.String())
+		now := time.Date(2017, 6, 1, 12, 0, 0, 0, loc)
+		assert.Equal(t, time.Date(2017, 6, 2, 6, 0, 30, 0, loc), cronExpr.Next(now))
+	}
+
+	_, loc, err = ParseCrontab("0 6 * * *")
+	if assert.NoError(t, err) {
+		assert.Equal(t, time.UTC, loc)
+	}
+
+	_, _, err = ParseCrontab("CRON_TZ=Mars/Olympus 0 6 * * *")
+	assert.Error(t, err)
+	_, _, err = ParseCrontab("CRON_TZ=Europe/Paris")
+	assert.Error(t, err)
+	_, _, err = ParseCrontab("every day")
+	assert.Error(t, err)
+}
@@ -10,6 +10,8 @@ type Warning struct {
 	ID           int64             `json:"id"`
 	Message      string            `json:"message"`
 	MessageParam map[string]string `json:"message_param"`
+	// Level is error for the findings which prevent the entity from working, warning otherwise
+	Level MessageLevel `json:"level"`
 
 	Action      Action      `json:"action"`
 	StageID     int64       `json:"stage_id"`